@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxAuthorizedKeysSize bounds the keys read from the metadata service.
+const maxAuthorizedKeysSize = 64 << 10
+
+// runAuthorizedKeys prints the SSH keys pushed for a user through the admin API, for use as
+// the AuthorizedKeysCommand of sshd inside a guest:
+//
+//	AuthorizedKeysCommand /usr/local/bin/metadata-service authorized-keys %u
+//	AuthorizedKeysCommandUser nobody
+//
+// It runs without the configuration of the service. No keys pushed prints nothing, so sshd
+// falls back to the authorized_keys files of the user.
+func runAuthorizedKeys(args []string) {
+	flags := flag.NewFlagSet("authorized-keys", flag.ExitOnError)
+	endpoint := flags.String("endpoint", "http://169.254.169.254", "Address of the metadata service")
+	timeout := flags.Duration("timeout", 5*time.Second, "Timeout of the request, sshd waits for it before each login")
+	if err := flags.Parse(args); err != nil {
+		os.Exit(2)
+	}
+
+	if flags.NArg() != 1 {
+		fatalf("usage: metadata-service authorized-keys [--endpoint url] [--timeout duration] <user>")
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	resp, err := client.Get(strings.TrimSuffix(*endpoint, "/") + "/latest/meta-data/managed-ssh-keys/active-keys/" + url.PathEscape(flags.Arg(0)))
+	if err != nil {
+		fatalf("failed to reach the metadata service: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		fatalf("metadata service answered %s", resp.Status)
+	}
+
+	keys, err := io.ReadAll(io.LimitReader(resp.Body, maxAuthorizedKeysSize))
+	if err != nil {
+		fatalf("failed to read the keys: %v", err)
+	}
+
+	fmt.Print(string(keys))
+}
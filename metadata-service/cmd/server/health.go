@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/health"
+)
+
+// newHealthChecks builds the checks behind /livez and /readyz. Liveness only covers the
+// local database, so an Incus outage makes the service unready without restarting it.
+// Checks of the additional Incus remotes are suffixed with the remote name, e.g. incus:dc2.
+func newHealthChecks(cfg *config.Config, database health.Pinger, remotes []*remote) (*health.Registry, *health.Registry) {
+	liveness := health.NewRegistry(cfg.Health.CheckTimeout, cfg.Health.CheckCacheTTL)
+	liveness.Register("database", health.Database(database))
+
+	readiness := health.NewRegistry(cfg.Health.CheckTimeout, cfg.Health.CheckCacheTTL)
+	readiness.Register("database", health.Database(database))
+	for i, r := range remotes {
+		suffix := ""
+		if i > 0 {
+			suffix = ":" + r.config.Name
+		}
+
+		readiness.Register("incus"+suffix, health.Incus(r.client))
+		readiness.Register("events"+suffix, health.Condition(r.events.Connected, "not connected to the Incus event stream"))
+		readiness.Register("sync"+suffix, health.Condition(r.cache.Synced, "initial sync with Incus has not completed"))
+	}
+
+	return liveness, readiness
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	localtls "github.com/lxc/incus/shared/tls"
+)
+
+// runJoin enrolls a client certificate into a remote admin API using a join token,
+// the same way `incus remote add <name> <token>` does. By default it uses the
+// Incus client certificate the operator already holds.
+func runJoin(args []string) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf("failed to load configuration: %v", err)
+	}
+
+	flags := flag.NewFlagSet("join", flag.ExitOnError)
+	certFile := flags.String("cert", cfg.Incus.TLSClientCert, "Client certificate to enroll")
+	keyFile := flags.String("key", cfg.Incus.TLSClientKey, "Client key")
+	if err := flags.Parse(args); err != nil {
+		os.Exit(2)
+	}
+
+	if flags.NArg() != 1 {
+		fatalf("usage: metadata-service join [--cert client.crt] [--key client.key] <token>")
+	}
+
+	token, err := localtls.CertificateTokenDecode(flags.Arg(0))
+	if err != nil {
+		fatalf("invalid join token: %v", err)
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		fatalf("join token expired at %s", token.ExpiresAt)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+	if err != nil {
+		fatalf("failed to load client certificate: %v", err)
+	}
+
+	// The admin listener usually has a self-signed certificate, so pin the fingerprint carried by the token instead.
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		Certificates:       []tls.Certificate{keyPair},
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("server did not present a certificate")
+			}
+
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+
+			if localtls.CertFingerprint(cert) != token.Fingerprint {
+				return fmt.Errorf("server certificate fingerprint does not match the join token")
+			}
+
+			return nil
+		},
+	}
+
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+
+	body, err := json.Marshal(types.CertificatesPost{Name: token.ClientName, TrustToken: token.Secret})
+	if err != nil {
+		fatalf("failed to encode request: %v", err)
+	}
+
+	for _, address := range token.Addresses {
+		resp, err := client.Post("https://"+address+"/internal/certificates", "application/json", bytes.NewReader(body))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to reach %s: %v\n", address, err)
+			continue
+		}
+
+		response, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusCreated {
+			fatalf("server %s rejected the join token: %s", address, bytes.TrimSpace(response))
+		}
+
+		fmt.Printf("Certificate added to the trust store of %s as %q\n", address, token.ClientName)
+		return
+	}
+
+	fatalf("none of the addresses in the join token are reachable")
+}
@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/api"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
-	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/incus"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/health"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/metrics"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/retention"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/server"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
-	"github.com/gin-gonic/gin"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/tracing"
+	"github.com/mdlayher/vsock"
 )
 
 // StartServer initializes and starts the metadata service server.
@@ -21,39 +33,178 @@ func startServer() {
 	logs.InitLogger(cfg.LogLevel)
 	logs.Logger.Info().Msg("Starting metadata service server...")
 
+	// Export traces when a collector is configured
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logs.Logger.Fatal().Err(err).Msg("Failed to set up tracing")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logs.Logger.Error().Err(err).Msg("Failed to flush traces")
+		}
+	}()
+
 	// Connect to the database
-	db, err := db.ConnectDB(cfg)
+	queries, err := db.ConnectDB(cfg)
 	if err != nil {
 		logs.Logger.Fatal().Err(err).Msg("Failed to connect to the database")
 	}
 
-	incusClient, err := incus.ConnectToIncus(cfg)
+	// Record the latency of every query and trace it
+	db := metrics.NewQuerier(tracing.NewQuerier(queries))
 
+	remotes, err := connectRemotes(cfg, db)
 	if err != nil {
 		logs.Logger.Fatal().Err(err).Msg("Failed to connect to Incus")
 	}
 
+	store, adminTLSConfig, err := newTrustStore(cfg, db)
+	if err != nil {
+		logs.Logger.Fatal().Err(err).Msg("Failed to set up the admin trust store")
+	}
+
+	// Tell apart guests on networks that reuse the same addresses
+	networks, err := resolver.ParseNetworks(cfg.Guest.Networks, cfg.Guest.TrustedProxies)
+	if err != nil {
+		logs.Logger.Fatal().Err(err).Msg("Failed to parse guest networks")
+	}
+	networks.ProxySecret = []byte(cfg.Proxy.Secret)
+
+	envelope, err := newEnvelope(cfg.Secrets)
+	if err != nil {
+		logs.Logger.Fatal().Err(err).Msg("Failed to load the secrets key encryption key")
+	}
+
+	if envelope == nil {
+		logs.Logger.Warn().Msg("Instance identity signing keys are stored unencrypted, set SECRETS_CONFIG_KEY_FILE or SECRETS_CONFIG_KEY to seal them")
+	}
+
+	passwords, err := newPasswordKey(cfg.Passwords)
+	if err != nil {
+		logs.Logger.Fatal().Err(err).Msg("Failed to load the password public key")
+	}
+
+	liveness, readiness := newHealthChecks(cfg, queries, remotes)
+
+	accessLog := logs.NewAccessLog(cfg.AccessLog)
+
 	app := &api.App{
-		Config:   cfg,
-		Router:   gin.Default(),
-		Database: db,
-		Incus:    incusClient,
+		Config:    cfg,
+		Router:    api.NewGuestRouter(accessLog),
+		Admin:     api.NewAdminRouter(accessLog),
+		Health:    api.NewHealthRouter(),
+		Database:  db,
+		Incus:     remotes[0].client,
+		Trust:     store,
+		Liveness:  liveness,
+		Readiness: readiness,
+		Resolver:  newResolver(remotes, db, networks),
+		Identity:  identity.NewKeyring(db, cfg.Identity, envelope),
+		Secrets:   envelope,
+		Passwords: passwords,
 	}
 
 	// Register public API routes
 	api.SetupRouter(app)
 
-	logs.Logger.Info().Msg("Metadata service server started on port " + cfg.Port)
+	guestTLSConfig, err := server.LoadTLSConfig(cfg.Guest.TLSCert, cfg.Guest.TLSKey)
+	if err != nil {
+		logs.Logger.Fatal().Err(err).Msg("Failed to load guest listener TLS configuration")
+	}
 
-	// Start the server on the configured port
-	if err := app.Router.Run(":" + cfg.Port); err != nil {
+	healthTLSConfig, err := server.LoadTLSConfig(cfg.Health.TLSCert, cfg.Health.TLSKey)
+	if err != nil {
+		logs.Logger.Fatal().Err(err).Msg("Failed to load health listener TLS configuration")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Drop cached instances when Incus reports they changed
+	for _, r := range remotes {
+		go r.events.Run(ctx)
+	}
+
+	// Remove old logs and soft-deleted rows in the background
+	go (&retention.Worker{Database: db, Vacuumer: queries, Config: cfg.Retention}).Run(ctx)
+
+	// Apply safe configuration changes on SIGHUP
+	go (&reloader{current: cfg, accessLog: accessLog, checks: []*health.Registry{liveness, readiness}}).watch(ctx)
+
+	logs.Logger.Info().Str("admin_fingerprint", store.ServerFingerprint).Msg("Metadata service server started")
+
+	guestListener := server.New("guest", guestAddresses(cfg, networks), app.Router, cfg.Guest.TimeoutConfig, guestTLSConfig)
+
+	// Optionally serve the same guest API over vsock for VMs without networking
+	if cfg.Guest.VsockPort != 0 {
+		vsockListener, err := vsock.Listen(cfg.Guest.VsockPort, nil)
+		if err != nil {
+			logs.Logger.Fatal().Err(err).Msg("Failed to listen on vsock")
+		}
+
+		guestListener.Attach(vsockListener)
+	}
+
+	// Optionally serve the guest API on a unix socket shared with containers
+	if cfg.Guest.UnixSocket != "" {
+		unixListener, err := server.ListenUnix(cfg.Guest.UnixSocket)
+		if err != nil {
+			logs.Logger.Fatal().Err(err).Msg("Failed to listen on unix socket")
+		}
+
+		guestListener.Attach(unixListener)
+	}
+
+	// Serve guests, admins and health checks on independent listeners
+	err = server.Run(ctx,
+		guestListener,
+		server.New("admin", []string{cfg.Admin.Address}, app.Admin, cfg.Admin.TimeoutConfig, adminTLSConfig),
+		server.New("health", []string{cfg.Health.Address}, app.Health, cfg.Health.TimeoutConfig, healthTLSConfig),
+	)
+	if err != nil {
 		logs.Logger.Error().Err(err).Msg("Failed to start server")
 		panic("Failed to start server: " + err.Error())
 	}
 }
 
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: metadata-service [command]
+
+Commands:
+  serve   Run the metadata service (default)
+  trust   Manage the certificates trusted by the admin API
+  join    Add this client's certificate to a remote admin API using a join token
+  proxy   Forward the metadata requests of one network to the service, e.g. inside an OVN network namespace
+  authorized-keys
+          Print the SSH keys pushed for a user, as the AuthorizedKeysCommand of sshd inside a guest`)
+}
+
 // main function to run the server
 func main() {
-	// Start the metadata service server
-	startServer()
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		// Start the metadata service server
+		startServer()
+	case "trust":
+		runTrust(args)
+	case "join":
+		runJoin(args)
+	case "proxy":
+		runProxy(args)
+	case "authorized-keys":
+		runAuthorizedKeys(args)
+	case "help", "-h", "--help":
+		usage()
+	default:
+		usage()
+		os.Exit(2)
+	}
 }
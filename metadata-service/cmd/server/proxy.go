@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/api"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/proxy"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/server"
+)
+
+// runProxy runs the per-network metadata proxy, meant to be started inside the network
+// namespace of an Incus network, e.g. with `ip netns exec`.
+func runProxy(args []string) {
+	if len(args) > 0 {
+		fatalf("usage: metadata-service proxy")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf("failed to load configuration: %v", err)
+	}
+
+	logs.InitLogger(cfg.LogLevel)
+
+	p, err := proxy.New(cfg.Proxy)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	router := api.NewProxyRouter(logs.NewAccessLog(cfg.AccessLog))
+	p.Register(router)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logs.Logger.Info().Str("network", p.Network).Str("upstream", p.Upstream.String()).Msg("Metadata proxy started")
+
+	err = server.Run(ctx, server.New("proxy", []string{cfg.Proxy.Address}, router, cfg.Proxy.TimeoutConfig, nil))
+	if err != nil {
+		fatalf("%v", err)
+	}
+}
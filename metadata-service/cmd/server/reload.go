@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/health"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+)
+
+// reloader applies configuration changes on SIGHUP. Only settings tagged as
+// reloadable are applied, other changes are logged and wait for a restart.
+type reloader struct {
+	current   *config.Config
+	accessLog *logs.AccessLog
+	checks    []*health.Registry
+}
+
+// watch reloads the configuration on every SIGHUP until ctx is cancelled.
+func (r *reloader) watch(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.reload()
+		}
+	}
+}
+
+func (r *reloader) reload() {
+	next, err := config.LoadConfig()
+	if err != nil {
+		logs.Logger.Error().Err(err).Msg("Failed to reload configuration, keeping the current one")
+		return
+	}
+
+	changes := config.Diff(r.current, next)
+	for _, change := range changes {
+		if change.Reloadable {
+			logs.Logger.Info().Str("setting", change.Setting).Str("old", change.Old).Str("new", change.New).Msg("Configuration setting changed")
+		} else {
+			logs.Logger.Warn().Str("setting", change.Setting).Str("old", change.Old).Str("new", change.New).Msg("Configuration setting changed, restart to apply it")
+		}
+	}
+
+	r.current = config.Reload(r.current, next)
+	logs.SetLevel(r.current.LogLevel)
+	r.accessLog.Configure(r.current.AccessLog)
+	for _, registry := range r.checks {
+		registry.Configure(r.current.Health.CheckTimeout, r.current.Health.CheckCacheTTL)
+	}
+
+	logs.Logger.Info().Int("changes", len(changes)).Msg("Configuration reloaded")
+}
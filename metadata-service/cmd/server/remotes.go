@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/events"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/incus"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/metrics"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	incusclient "github.com/lxc/incus/client"
+)
+
+// remote holds the connections to one Incus remote, its event listener and its instance cache.
+type remote struct {
+	config *config.IncusConfig
+	client incusclient.InstanceServer
+	// instances lists instances across the remote, local only those on this host.
+	instances incus.InstanceLister
+	local     incus.InstanceLister
+	events    *events.Listener
+	cache     *events.Cache
+}
+
+// connectRemotes connects to the primary Incus remote and every additional remote.
+func connectRemotes(cfg *config.Config, database db.Store) ([]*remote, error) {
+	var remotes []*remote
+	for _, remoteConfig := range cfg.IncusRemotes() {
+		client, err := incus.ConnectToIncus(remoteConfig)
+		if err != nil {
+			return nil, fmt.Errorf("remote %s: %w", remoteConfig.Name, err)
+		}
+
+		// Read instance state from the cluster member hosting each instance
+		cluster, err := incus.ConnectCluster(remoteConfig, client)
+		if err != nil {
+			return nil, fmt.Errorf("remote %s: %w", remoteConfig.Name, err)
+		}
+
+		// Count failed Incus API calls made while resolving instances
+		instances := metrics.NewInstanceLister(cluster)
+
+		// Keep the instance cache in sync with the remote
+		cache := &events.Cache{Remote: remoteConfig.Name, Instances: instances, Database: database}
+
+		remotes = append(remotes, &remote{
+			config:    remoteConfig,
+			client:    client,
+			instances: instances,
+			local:     metrics.NewInstanceLister(cluster.Local()),
+			events:    &events.Listener{Remote: remoteConfig.Name, Incus: client, Handler: cache},
+			cache:     cache,
+		})
+	}
+
+	return remotes, nil
+}
+
+// newResolver builds the chain identifying guests. Callers over the unix socket are identified
+// by their PID namespace and callers over vsock by their context ID, both of which only exist
+// on the primary remote, which runs on this host. Everyone else is identified by source IP,
+// scoped to the remote whose guest addresses the request arrived on and to the network the
+// request came from.
+func newResolver(remotes []*remote, database db.Store, networks *resolver.Networks) resolver.Chain {
+	primary := remotes[0]
+	chain := resolver.Chain{
+		&resolver.UnixResolver{Database: database, Incus: primary.local, Remote: primary.config.Name},
+		&resolver.VsockResolver{Database: database, Incus: primary.local, Remote: primary.config.Name},
+	}
+
+	// The primary remote comes last, it answers requests on any other listener address
+	for _, r := range append(slices.Clone(remotes[1:]), primary) {
+		chain = append(chain, &resolver.IPResolver{
+			Database:  database,
+			Incus:     r.instances,
+			Remote:    r.config.Name,
+			Addresses: r.config.GuestAddresses,
+			Networks:  networks,
+		})
+	}
+
+	return chain
+}
+
+// guestAddresses returns the guest listener addresses, including those of every remote and network.
+func guestAddresses(cfg *config.Config, networks *resolver.Networks) []string {
+	addresses := slices.Clone(cfg.Guest.Addresses)
+	add := func(address string) {
+		if !slices.Contains(addresses, address) {
+			addresses = append(addresses, address)
+		}
+	}
+
+	for _, remoteConfig := range cfg.IncusRemotes() {
+		for _, address := range remoteConfig.GuestAddresses {
+			add(address)
+		}
+	}
+
+	for address := range networks.Listeners {
+		add(address)
+	}
+
+	return addresses
+}
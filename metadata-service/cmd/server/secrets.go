@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+)
+
+// newEnvelope loads the key encryption key of the secrets. Secrets and session tokens are
+// disabled without one, so the returned envelope is nil.
+func newEnvelope(cfg *config.SecretsConfig) (*secrets.Envelope, error) {
+	kek, err := cfg.KEK()
+	if err != nil || kek == nil {
+		return nil, err
+	}
+
+	envelope, err := secrets.New(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	logs.Logger.Info().Str("key_id", envelope.KeyID()).Msg("Secrets enabled")
+	return envelope, nil
+}
+
+// newPasswordKey loads the public key generated passwords are encrypted to. Passwords are
+// disabled without one, so the returned key is nil.
+func newPasswordKey(cfg *config.PasswordsConfig) (*secrets.PasswordKey, error) {
+	publicKey, err := cfg.PublicKey()
+	if err != nil || publicKey == nil {
+		return nil, err
+	}
+
+	key, err := secrets.NewPasswordKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	logs.Logger.Info().Str("key_fingerprint", key.Fingerprint()).Strs("projects", cfg.Projects).Msg("Generated passwords enabled")
+	return key, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	localtls "github.com/lxc/incus/shared/tls"
+)
+
+// newTrustStore loads the admin listener certificate and builds the trust store backed by the database.
+func newTrustStore(cfg *config.Config, database db.Querier) (*trust.Store, *tls.Config, error) {
+	tlsConfig, fingerprint, err := trust.ServerTLSConfig(cfg.Admin.TLSCert, cfg.Admin.TLSKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addresses, err := trust.AdvertisedAddresses(cfg.Admin.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store := &trust.Store{
+		Database:          database,
+		ServerFingerprint: fingerprint,
+		Addresses:         addresses,
+		TokenExpiry:       cfg.Admin.TokenExpiry,
+	}
+
+	return store, tlsConfig, nil
+}
+
+func trustUsage() {
+	fmt.Fprintln(os.Stderr, `Usage: metadata-service trust <command>
+
+Commands:
+  list                          List trusted certificates
+  add <name>                    Issue a one-time join token for a new client
+  add-certificate <cert.crt>    Trust an existing client certificate, e.g. an Incus client.crt
+  remove <fingerprint>          Remove a certificate from the trust store`)
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
+
+// runTrust manages the trust store directly through the local database,
+// which is how the first admin certificate gets added.
+func runTrust(args []string) {
+	if len(args) == 0 {
+		trustUsage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fatalf("failed to load configuration: %v", err)
+	}
+
+	logs.InitLogger(cfg.LogLevel)
+
+	database, err := db.ConnectDB(cfg)
+	if err != nil {
+		fatalf("failed to connect to the database: %v", err)
+	}
+
+	store, _, err := newTrustStore(cfg, database)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	ctx := context.Background()
+	command, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet("trust "+command, flag.ExitOnError)
+	name := flags.String("name", "", "Name of the certificate, defaults to its common name")
+	roleName := flags.String("role", "reader", "Role granted to the certificate: admin, operator or reader")
+	projects := flags.String("projects", "", "Comma separated list of projects the certificate is restricted to")
+
+	parse := func() (trust.Role, bool, []string) {
+		if err := flags.Parse(args); err != nil {
+			os.Exit(2)
+		}
+
+		role, err := trust.ParseRole(*roleName)
+		if err != nil {
+			fatalf("%v", err)
+		}
+
+		if *projects == "" {
+			return role, false, []string{}
+		}
+
+		return role, true, strings.Split(*projects, ",")
+	}
+
+	switch command {
+	case "list":
+		rows, err := database.ListCertificates(ctx)
+		if err != nil {
+			fatalf("failed to list certificates: %v", err)
+		}
+
+		for _, row := range rows {
+			identity, err := trust.ToIdentity(row)
+			if err != nil {
+				fatalf("%v", err)
+			}
+
+			scope := "all projects"
+			if identity.Restricted {
+				scope = strings.Join(identity.Projects, ",")
+			}
+
+			fmt.Printf("%s\t%s\t%s\t%s\n", identity.Fingerprint[:12], identity.Name, identity.Role, scope)
+		}
+	case "add":
+		role, restricted, projectList := parse()
+		if flags.NArg() != 1 {
+			fatalf("a client name is required")
+		}
+
+		token, err := store.IssueToken(ctx, flags.Arg(0), role, restricted, projectList)
+		if err != nil {
+			fatalf("%v", err)
+		}
+
+		fmt.Printf("Client %s certificate add token:\n%s\n", token.ClientName, token.String())
+	case "add-certificate":
+		role, restricted, projectList := parse()
+		if flags.NArg() != 1 {
+			fatalf("a certificate path is required")
+		}
+
+		cert, err := localtls.ReadCert(flags.Arg(0))
+		if err != nil {
+			fatalf("failed to read certificate: %v", err)
+		}
+
+		row, err := store.Add(ctx, cert, *name, role, restricted, projectList)
+		if err != nil {
+			fatalf("%v", err)
+		}
+
+		fmt.Printf("Certificate %s added with role %s\n", row.Fingerprint, row.Role)
+	case "remove":
+		if len(args) != 1 {
+			fatalf("a certificate fingerprint is required")
+		}
+
+		if err := database.DeleteCertificate(ctx, args[0]); err != nil {
+			fatalf("failed to remove certificate: %v", err)
+		}
+	default:
+		trustUsage()
+		os.Exit(2)
+	}
+}
@@ -2,10 +2,15 @@ package configs
 
 import (
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
 )
 
 type Handler struct {
 	Config   *config.Config
 	Database db.Querier
+	Identity *identity.Keyring
+	Secrets  *secrets.Envelope
+	Passwords *secrets.PasswordKey
 }
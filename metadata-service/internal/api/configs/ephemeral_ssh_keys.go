@@ -0,0 +1,49 @@
+package configs
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/gin-gonic/gin"
+)
+
+// ActiveSSHKeysHandler serves the SSH keys pushed for a user of the instance that haven't
+// expired yet, one per line, at the path EC2 Instance Connect uses. Nothing pushed answers 404.
+// The guest's sshd reads them through the authorized-keys command of the service:
+//
+//	AuthorizedKeysCommand /usr/local/bin/metadata-service authorized-keys %u
+//	AuthorizedKeysCommandUser nobody
+func (h *Handler) ActiveSSHKeysHandler(c *gin.Context) {
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	rows, err := h.Database.ListActiveEphemeralSSHKeys(c.Request.Context(), db.ListActiveEphemeralSSHKeysParams{
+		InstanceID: instance.ID,
+		OsUser:     c.Param("user"),
+		Now:        time.Now().Unix(),
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list active SSH keys")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list active SSH keys"})
+		return
+	}
+
+	if len(rows) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No active SSH keys"})
+		return
+	}
+
+	var keys strings.Builder
+	for _, row := range rows {
+		keys.WriteString(row.PublicKey + "\n")
+	}
+
+	c.String(http.StatusOK, keys.String())
+}
@@ -0,0 +1,45 @@
+package configs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActiveSSHKeysHandler_ServesPushedKeys(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	mockDB.On("ListActiveEphemeralSSHKeys", mock.Anything, mock.MatchedBy(func(arg db.ListActiveEphemeralSSHKeysParams) bool {
+		return arg.InstanceID == 42 && arg.OsUser == "ubuntu" && arg.Now > 0
+	})).Return([]db.EphemeralSshKey{
+		{PublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA"},
+		{PublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB"},
+	}, nil)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/latest/meta-data/managed-ssh-keys/active-keys/ubuntu", nil)
+	setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA\nssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB\n", rec.Body.String())
+}
+
+func TestActiveSSHKeysHandler_NothingPushed(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	mockDB.On("ListActiveEphemeralSSHKeys", mock.Anything, mock.Anything).Return([]db.EphemeralSshKey{}, nil).Once()
+	mockDB.On("ListActiveEphemeralSSHKeys", mock.Anything, mock.Anything).Return([]db.EphemeralSshKey(nil), errors.New("database is locked")).Once()
+
+	rec := httptest.NewRecorder()
+	setupReportingRouter(mockDB).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/meta-data/managed-ssh-keys/active-keys/root", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	setupReportingRouter(mockDB).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/meta-data/managed-ssh-keys/active-keys/root", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,79 @@
+package configs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"golang.org/x/crypto/ssh"
+)
+
+// hostKeys returns the host public keys sent with a phone home request.
+func hostKeys(request types.PhoneHome) []string {
+	var keys []string
+	for _, key := range []string{request.PubKeyDSA, request.PubKeyRSA, request.PubKeyECDSA, request.PubKeyEd25519} {
+		if strings.TrimSpace(key) != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// storeHostKeys records the SSH host keys published by an instance. Keys already known are
+// only marked as seen, a new key of the same type revokes the previous one so it stays in the
+// key history. Keys that fail to parse are skipped.
+func (h *Handler) storeHostKeys(ctx context.Context, instance db.Instance, keys []string) error {
+	known, err := h.Database.ListInstanceHostKeys(ctx, instance.ID)
+	if err != nil {
+		return err
+	}
+
+	active := map[string]db.InstanceHostKey{}
+	for _, key := range known {
+		if key.RevokedAt == nil {
+			active[key.PublicKey] = key
+		}
+	}
+
+	for _, value := range keys {
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(value))
+		if err != nil {
+			logs.Logger.Warn().Ctx(ctx).Err(err).Str("instance", instance.Name).Msg("Ignoring invalid host key")
+			continue
+		}
+
+		// Drop the comment, e.g. root@hostname
+		authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey)))
+		if key, ok := active[authorizedKey]; ok {
+			if err := h.Database.TouchInstanceHostKey(ctx, key.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err = h.Database.RevokeInstanceHostKeys(ctx, db.RevokeInstanceHostKeysParams{
+			RevokedReason: "replaced",
+			InstanceID:    instance.ID,
+			KeyType:       publicKey.Type(),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = h.Database.CreateInstanceHostKey(ctx, db.CreateInstanceHostKeyParams{
+			InstanceID:   instance.ID,
+			InstanceUuid: instance.Uuid,
+			KeyType:      publicKey.Type(),
+			PublicKey:    authorizedKey,
+			Fingerprint:  ssh.FingerprintSHA256(publicKey),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
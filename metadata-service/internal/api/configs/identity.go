@@ -0,0 +1,172 @@
+package configs
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/gin-gonic/gin"
+)
+
+// noncePattern matches the nonces a document can be requested with, which fit in a URL as is.
+var noncePattern = regexp.MustCompile(`^[A-Za-z0-9._~-]{1,128}$`)
+
+// IdentityDocumentHandler serves the instance identity document. Workloads send it along with
+// one of its signatures to prove which instance they run on, see pkg/identity. Every route
+// takes the nonce query parameter, which is copied to the document before it is signed.
+func (h *Handler) IdentityDocumentHandler(c *gin.Context) {
+	document, ok := h.identityDocument(c)
+	if !ok {
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", document)
+}
+
+// IdentitySignatureHandler serves the base64 encoded RSA SHA-256 signature of the document.
+func (h *Handler) IdentitySignatureHandler(c *gin.Context) {
+	h.signIdentityDocument(c, func(key identity.Key, document []byte) (string, error) {
+		return key.Signature(document)
+	})
+}
+
+// IdentityPKCS7Handler serves a detached PKCS #7 signature of the document, verified with
+// `openssl smime -verify -inform PEM -in pkcs7 -content document` once wrapped in PEM armor.
+func (h *Handler) IdentityPKCS7Handler(c *gin.Context) {
+	h.signIdentityDocument(c, func(key identity.Key, document []byte) (string, error) {
+		return key.PKCS7(document, true)
+	})
+}
+
+// IdentityRSA2048Handler serves a PKCS #7 signature embedding the document, signed with the
+// same 2048 bit RSA key.
+func (h *Handler) IdentityRSA2048Handler(c *gin.Context) {
+	h.signIdentityDocument(c, func(key identity.Key, document []byte) (string, error) {
+		return key.PKCS7(document, false)
+	})
+}
+
+// IdentityJWSHandler serves the document as a compact JWS, whose key ID names the key of the
+// JWKS that verifies it.
+func (h *Handler) IdentityJWSHandler(c *gin.Context) {
+	h.signIdentityDocument(c, func(key identity.Key, document []byte) (string, error) {
+		return key.JWS(document)
+	})
+}
+
+// maxAudienceLength bounds the audience a guest may ask a workload identity token for.
+const maxAudienceLength = 256
+
+// WorkloadTokenHandler serves a JWT asserting which instance the caller runs on, for the
+// audience named by the audience query parameter, such as a Vault role or an object store.
+// Relying parties verify it with the keys published at the issuer's /.well-known routes.
+func (h *Handler) WorkloadTokenHandler(c *gin.Context) {
+	issuer := h.Config.Identity.Issuer
+	if issuer == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workload identity tokens are not enabled"})
+		return
+	}
+
+	audience := c.Query("audience")
+	if audience == "" || len(audience) > maxAudienceLength || strings.IndexFunc(audience, func(r rune) bool { return unicode.IsSpace(r) || !unicode.IsPrint(r) }) >= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The audience query parameter must be set to a printable string of at most 256 characters without spaces"})
+		return
+	}
+
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	profiles, err := h.Database.ListInstanceProfiles(c, instance.ID)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list instance profiles")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue workload identity token"})
+		return
+	}
+
+	claims, err := identity.WorkloadClaims(instance, profiles, issuer, audience, time.Now(), h.Config.Identity.TokenLifetime)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to build workload identity claims")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue workload identity token"})
+		return
+	}
+
+	key, err := h.Identity.Current(c.Request.Context())
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to load the instance identity signing key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue workload identity token"})
+		return
+	}
+
+	token, err := key.Token(claims)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to sign workload identity token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue workload identity token"})
+		return
+	}
+
+	logs.FromContext(c).Info().Str("instance", instance.Name).Str("project", instance.Project).Str("audience", audience).Str("jti", claims.ID).Msg("Workload identity token issued")
+	c.String(http.StatusOK, token)
+}
+
+// identityDocument builds the document of the calling instance, answering the request itself on failure.
+func (h *Handler) identityDocument(c *gin.Context) ([]byte, bool) {
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return nil, false
+	}
+
+	nonce := c.Query("nonce")
+	if nonce != "" && !noncePattern.MatchString(nonce) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The nonce query parameter must be at most 128 letters, digits, dots, dashes, underscores and tildes"})
+		return nil, false
+	}
+
+	// Addresses of an instance Incus reported changes for are no longer trusted
+	addresses, err := h.Database.ListInstanceAddresses(c, instance.ID)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list instance addresses")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode instance identity document"})
+		return nil, false
+	}
+
+	document, err := identity.Document(instance, addresses, nonce)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to encode instance identity document")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode instance identity document"})
+		return nil, false
+	}
+
+	return document, true
+}
+
+func (h *Handler) signIdentityDocument(c *gin.Context, sign func(identity.Key, []byte) (string, error)) {
+	document, ok := h.identityDocument(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.Identity.Current(c.Request.Context())
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to load the instance identity signing key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign instance identity document"})
+		return
+	}
+
+	signature, err := sign(key, document)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to sign instance identity document")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign instance identity document"})
+		return
+	}
+
+	c.String(http.StatusOK, signature)
+}
@@ -0,0 +1,197 @@
+package configs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	pkgidentity "github.com/focadecombate/incus-metadata-service/metadata-service/pkg/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testSigningKey(t *testing.T) db.SigningKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	require.NoError(t, err)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	createdAt := time.Now()
+	return db.SigningKey{
+		ID:          1,
+		Kid:         "test",
+		Algorithm:   "RS256",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})),
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		ExpiresAt:   time.Now().Add(time.Hour),
+		CreatedAt:   &createdAt,
+	}
+}
+
+func TestIdentityDocument_SignaturesVerify(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockDB := new(mocks.MockQuerier)
+	mockDB.On("ListSigningKeys", mock.Anything, mock.Anything).Return([]db.SigningKey{testSigningKey(t)}, nil)
+
+	// The address last cached on the instance is stale, the current ones are signed
+	stale := "10.0.0.9"
+	mockDB.On("ListInstanceAddresses", mock.Anything, int64(42)).Return([]string{"10.0.0.5", "fd42::5"}, nil)
+
+	keyring := identity.NewKeyring(mockDB, &config.IdentityConfig{KeyRotation: time.Hour}, nil)
+	router := gin.New()
+	RegisterConfigRoutes(router, nil, mockDB, staticResolver{ID: 42, Name: "c1", Project: "default", Remote: "local", IpAddress: &stale}, keyring, nil, nil)
+
+	get := func(path string) []byte {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/dynamic/instance-identity/"+path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		return rec.Body.Bytes()
+	}
+
+	document := get("document")
+	var parsed types.InstanceIdentityDocument
+	require.NoError(t, json.Unmarshal(document, &parsed))
+	assert.Equal(t, types.InstanceIdentityDocument{InstanceID: "c1", Project: "default", Remote: "local", PrivateIP: "10.0.0.5", Version: types.InstanceIdentityDocumentVersion}, parsed)
+
+	jwks, err := keyring.JWKS(t.Context())
+	require.NoError(t, err)
+	encoded, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	verifier, err := pkgidentity.NewVerifier(encoded)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifySignature(document, string(get("signature")))
+	assert.NoError(t, err)
+
+	_, err = verifier.VerifyPKCS7(string(get("pkcs7")), document)
+	assert.NoError(t, err)
+
+	signed, err := verifier.VerifyPKCS7(string(get("rsa2048")), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "c1", signed.InstanceID)
+
+	signed, err = verifier.VerifyJWS(string(get("jws")))
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", signed.PrivateIP)
+}
+
+func TestIdentityDocument_SignsNonce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockDB := new(mocks.MockQuerier)
+	mockDB.On("ListSigningKeys", mock.Anything, mock.Anything).Return([]db.SigningKey{testSigningKey(t)}, nil)
+	mockDB.On("ListInstanceAddresses", mock.Anything, int64(42)).Return([]string(nil), nil)
+
+	keyring := identity.NewKeyring(mockDB, &config.IdentityConfig{KeyRotation: time.Hour}, nil)
+	router := gin.New()
+	RegisterConfigRoutes(router, nil, mockDB, staticResolver{ID: 42, Name: "c1", Project: "default", Remote: "local"}, keyring, nil, nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/dynamic/instance-identity/"+path, nil))
+		return rec
+	}
+
+	jwks, err := keyring.JWKS(t.Context())
+	require.NoError(t, err)
+	encoded, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	verifier, err := pkgidentity.NewVerifier(encoded)
+	require.NoError(t, err)
+
+	// The document and its detached signature are fetched separately, with the same nonce
+	document := get("document?nonce=3f9a1c").Body.Bytes()
+	signed, err := verifier.VerifySignature(document, get("signature?nonce=3f9a1c").Body.String())
+	require.NoError(t, err)
+	assert.NoError(t, pkgidentity.CheckNonce(signed, "3f9a1c"))
+
+	signed, err = verifier.VerifyJWS(get("jws?nonce=3f9a1c").Body.String())
+	require.NoError(t, err)
+	assert.NoError(t, pkgidentity.CheckNonce(signed, "3f9a1c"))
+
+	signed, err = verifier.VerifyJWS(get("jws").Body.String())
+	require.NoError(t, err)
+	assert.ErrorIs(t, pkgidentity.CheckNonce(signed, "3f9a1c"), pkgidentity.ErrNonceMismatch)
+
+	assert.Equal(t, http.StatusBadRequest, get("document?nonce="+strings.Repeat("a", 129)).Code)
+	assert.Equal(t, http.StatusBadRequest, get("jws?nonce=a%20b").Code)
+}
+
+func TestWorkloadToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockDB := new(mocks.MockQuerier)
+	mockDB.On("ListSigningKeys", mock.Anything, mock.Anything).Return([]db.SigningKey{testSigningKey(t)}, nil)
+	mockDB.On("ListInstanceProfiles", mock.Anything, int64(42)).Return([]string{"default", "web"}, nil)
+
+	uuid := "5c1f2f4e-8a8e-4c57-9d3e-2a1b0c9d8e7f"
+	cfg := &config.Config{Identity: &config.IdentityConfig{KeyRotation: time.Hour, Issuer: "https://metadata.example.com", TokenLifetime: 10 * time.Minute}}
+	keyring := identity.NewKeyring(mockDB, cfg.Identity, nil)
+	router := gin.New()
+	RegisterConfigRoutes(router, cfg, mockDB, staticResolver{ID: 42, Name: "c1", Project: "default", Remote: "local", Uuid: &uuid}, keyring, nil, nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/identity/token?audience=vault", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	jwks, err := keyring.JWKS(t.Context())
+	require.NoError(t, err)
+	encoded, err := json.Marshal(jwks)
+	require.NoError(t, err)
+	verifier, err := pkgidentity.NewVerifier(encoded)
+	require.NoError(t, err)
+
+	claims, err := verifier.VerifyToken(rec.Body.String(), "https://metadata.example.com", "vault")
+	require.NoError(t, err)
+	assert.Equal(t, "instance:local:default:c1", claims.Subject)
+	assert.Equal(t, "c1", claims.Instance)
+	assert.Equal(t, "default", claims.Project)
+	assert.Equal(t, []string{"default", "web"}, claims.Profiles)
+	assert.Equal(t, uuid, claims.InstanceUUID)
+	assert.Equal(t, int64(600), claims.Expiry-claims.IssuedAt)
+	assert.NotEmpty(t, claims.ID)
+
+	for _, audience := range []string{"", "two%20words", strings.Repeat("a", 257)} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/identity/token?audience="+audience, nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, audience)
+	}
+}
+
+func TestWorkloadToken_DisabledWithoutIssuer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockDB := new(mocks.MockQuerier)
+
+	cfg := &config.Config{Identity: &config.IdentityConfig{KeyRotation: time.Hour, TokenLifetime: 10 * time.Minute}}
+	router := gin.New()
+	RegisterConfigRoutes(router, cfg, mockDB, staticResolver{ID: 42, Name: "c1", Project: "default", Remote: "local"}, identity.NewKeyring(mockDB, cfg.Identity, nil), nil, nil)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/identity/token?audience=vault", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	mockDB.AssertNotCalled(t, "ListInstanceProfiles", mock.Anything, mock.Anything)
+}
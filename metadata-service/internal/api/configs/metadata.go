@@ -5,6 +5,8 @@ import (
 	"slices"
 
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/content_types"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
 	"github.com/gin-gonic/gin"
 )
@@ -59,6 +61,33 @@ func (h *Handler) AllMetadataHandler(c *gin.Context) {
 
 	metadata := mockMetadata()
 
+	// Identify the instance the same way Incus does for its own metadata
+	if instance, ok := resolver.InstanceFromContext(c); ok {
+		metadata.InstanceID = instance.Name
+		metadata.Hostname = instance.Name
+		metadata.LocalHostname = instance.Name
+		metadata.Placement.Project = instance.Project
+		if instance.IpAddress != nil {
+			metadata.LocalIPv4 = *instance.IpAddress
+		}
+
+		keys, err := h.authorizedKeys(c.Request.Context(), instance)
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to list SSH keys")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list SSH keys"})
+			return
+		}
+		metadata.PublicKeys = keys
+
+		tags, _, err := h.instanceTags(c.Request.Context(), instance)
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to list instance tags")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+			return
+		}
+		metadata.Tags = tags
+	}
+
 	// Return the metadata in the requested format
 
 	if slices.Contains(content_types.JsonContentTypes, requested_content_type) {
@@ -79,6 +108,25 @@ func (h *Handler) MetadataByKeyHandler(c *gin.Context) {
 		return
 	}
 
+	// Public keys are served on their own so guests can pick up rotated keys without
+	// fetching the whole metadata
+	if instance, ok := resolver.InstanceFromContext(c); ok && key == "public-keys" {
+		keys, err := h.authorizedKeys(c.Request.Context(), instance)
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to list SSH keys")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list SSH keys"})
+			return
+		}
+
+		if slices.Contains(content_types.JsonContentTypes, requested_content_type) {
+			c.JSON(http.StatusOK, keys)
+			return
+		}
+
+		c.YAML(http.StatusOK, keys)
+		return
+	}
+
 	if slices.Contains(content_types.JsonContentTypes, requested_content_type) {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "Metadata by key endpoint hit",
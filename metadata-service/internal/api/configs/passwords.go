@@ -0,0 +1,79 @@
+package configs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+)
+
+// instancePassword generates the password of the users of an instance on its first request and
+// keeps a copy encrypted to the public key of the operators. The service can't decrypt that
+// copy, so later requests, and instances passwords are disabled for, get an empty password.
+func (h *Handler) instancePassword(ctx context.Context, instance db.Instance) (string, error) {
+	if h.Passwords == nil {
+		return "", nil
+	}
+
+	cfg := h.Config.Passwords
+	if len(cfg.Projects) > 0 && !slices.Contains(cfg.Projects, instance.Project) {
+		return "", nil
+	}
+
+	row, err := h.Database.GetInstancePassword(ctx, instance.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+
+	// A rebuilt instance, which has a new volatile.uuid, gets a new password
+	if err == nil && sameUUID(row.InstanceUuid, instance.Uuid) {
+		return "", nil
+	}
+
+	password, err := secrets.GeneratePassword(cfg.Length)
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, err := h.Passwords.Encrypt(password)
+	if err != nil {
+		return "", err
+	}
+
+	// Concurrent first requests race to store their password, only the winner serves it
+	created, err := h.Database.CreateInstancePassword(ctx, db.CreateInstancePasswordParams{
+		InstanceID:        instance.ID,
+		InstanceUuid:      instance.Uuid,
+		KeyFingerprint:    h.Passwords.Fingerprint(),
+		EncryptedPassword: encrypted,
+	})
+	if err != nil || created == 0 {
+		return "", err
+	}
+
+	// The password is stored, failing the request now would leave the guest without the only
+	// plaintext copy, so a failed audit entry is only logged
+	_, err = h.Database.CreateInstanceLog(ctx, db.CreateInstanceLogParams{
+		InstanceID: instance.ID,
+		LogType:    "audit",
+		Level:      "info",
+		Message:    "Password generated, encrypted to key " + h.Passwords.Fingerprint(),
+	})
+	if err != nil {
+		logs.Logger.Error().Ctx(ctx).Err(err).Str("instance", instance.Name).Str("project", instance.Project).Str("key_fingerprint", h.Passwords.Fingerprint()).Msg("Failed to audit generated password")
+	}
+
+	return password, nil
+}
+
+func sameUUID(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
@@ -0,0 +1,144 @@
+package configs
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func setupPasswordsRouter(t *testing.T, mockDB *mocks.MockQuerier, projects []string) (*gin.Engine, *rsa.PrivateKey) {
+	gin.SetMode(gin.TestMode)
+
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	key, err := secrets.NewPasswordKey(&private.PublicKey)
+	require.NoError(t, err)
+
+	cfg := &config.Config{Passwords: &config.PasswordsConfig{Projects: projects, Length: 20}}
+	uuid := "5c1f2f4e-8a8e-4c57-9d3e-2a1b0c9d8e7f"
+
+	router := gin.New()
+	RegisterConfigRoutes(router, cfg, mockDB, staticResolver{ID: 42, Name: "c1", Project: "default", Remote: "local", Uuid: &uuid}, nil, nil, key)
+
+	return router, private
+}
+
+func fetchUserData(t *testing.T, router *gin.Engine) types.UserData {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/configs/user-data", nil)
+	req.Header.Set("Accept", "application/yaml")
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var userData types.UserData
+	require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &userData))
+
+	return userData
+}
+
+func TestUserData_GeneratesPasswordOnce(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	registeredKeys(mockDB)
+	router, private := setupPasswordsRouter(t, mockDB, nil)
+
+	var stored db.CreateInstancePasswordParams
+	mockDB.On("GetInstancePassword", mock.Anything, int64(42)).Return(db.InstancePassword{}, sql.ErrNoRows).Once()
+	mockDB.On("CreateInstancePassword", mock.Anything, mock.MatchedBy(func(arg db.CreateInstancePasswordParams) bool {
+		stored = arg
+		return arg.InstanceID == 42 && *arg.InstanceUuid == "5c1f2f4e-8a8e-4c57-9d3e-2a1b0c9d8e7f"
+	})).Return(int64(1), nil).Once()
+	mockDB.On("CreateInstanceLog", mock.Anything, mock.MatchedBy(func(arg db.CreateInstanceLogParams) bool {
+		return arg.InstanceID == 42 && arg.LogType == "audit"
+	})).Return(db.InstanceLog{}, nil).Once()
+
+	userData := fetchUserData(t, router)
+	require.NotNil(t, userData.Chpasswd)
+	assert.False(t, userData.Chpasswd.Expire)
+	require.Len(t, userData.Chpasswd.Users, len(userData.Users))
+
+	password := userData.Chpasswd.Users[0].Password
+	assert.Len(t, password, 20)
+	assert.Equal(t, userData.Users[0].Name, userData.Chpasswd.Users[0].Name)
+	assert.Equal(t, "text", userData.Chpasswd.Users[0].Type)
+
+	decrypted, err := rsa.DecryptOAEP(sha256.New(), nil, private, stored.EncryptedPassword, nil)
+	require.NoError(t, err)
+	assert.Equal(t, password, string(decrypted), "only the operators' private key decrypts the stored copy")
+
+	// The service can't decrypt the stored copy, later requests don't get the password
+	mockDB.On("GetInstancePassword", mock.Anything, int64(42)).Return(db.InstancePassword{ID: 1, InstanceID: 42, InstanceUuid: stored.InstanceUuid}, nil).Once()
+
+	userData = fetchUserData(t, router)
+	assert.Nil(t, userData.Chpasswd)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUserData_NewPasswordForRebuiltInstance(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	registeredKeys(mockDB)
+	router, _ := setupPasswordsRouter(t, mockDB, nil)
+
+	previous := "0b6a3f1e-2c4d-4e5f-8a9b-0c1d2e3f4a5b"
+	mockDB.On("GetInstancePassword", mock.Anything, int64(42)).Return(db.InstancePassword{ID: 1, InstanceID: 42, InstanceUuid: &previous}, nil).Once()
+	mockDB.On("CreateInstancePassword", mock.Anything, mock.Anything).Return(int64(1), nil).Once()
+	mockDB.On("CreateInstanceLog", mock.Anything, mock.Anything).Return(db.InstanceLog{}, nil).Once()
+
+	userData := fetchUserData(t, router)
+	require.NotNil(t, userData.Chpasswd)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUserData_PasswordLostRace(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	registeredKeys(mockDB)
+	router, _ := setupPasswordsRouter(t, mockDB, nil)
+
+	mockDB.On("GetInstancePassword", mock.Anything, int64(42)).Return(db.InstancePassword{}, sql.ErrNoRows).Once()
+	mockDB.On("CreateInstancePassword", mock.Anything, mock.Anything).Return(int64(0), nil).Once()
+
+	userData := fetchUserData(t, router)
+	assert.Nil(t, userData.Chpasswd, "a concurrent request stored its password first")
+	mockDB.AssertNotCalled(t, "CreateInstanceLog", mock.Anything, mock.Anything)
+}
+
+func TestUserData_PasswordServedWhenAuditFails(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	registeredKeys(mockDB)
+	router, _ := setupPasswordsRouter(t, mockDB, nil)
+
+	mockDB.On("GetInstancePassword", mock.Anything, int64(42)).Return(db.InstancePassword{}, sql.ErrNoRows).Once()
+	mockDB.On("CreateInstancePassword", mock.Anything, mock.Anything).Return(int64(1), nil).Once()
+	mockDB.On("CreateInstanceLog", mock.Anything, mock.Anything).Return(db.InstanceLog{}, errors.New("database is locked")).Once()
+
+	userData := fetchUserData(t, router)
+	require.NotNil(t, userData.Chpasswd, "the password is stored, it must reach the guest")
+	assert.Len(t, userData.Chpasswd.Users[0].Password, 20)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUserData_PasswordsLimitedToProjects(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	registeredKeys(mockDB)
+	router, _ := setupPasswordsRouter(t, mockDB, []string{"lab"})
+
+	userData := fetchUserData(t, router)
+	assert.Nil(t, userData.Chpasswd)
+	mockDB.AssertNotCalled(t, "GetInstancePassword", mock.Anything, mock.Anything)
+}
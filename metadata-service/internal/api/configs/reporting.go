@@ -0,0 +1,85 @@
+package configs
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// maxReportingEventSize bounds the events guests can post. cloud-init may attach log
+// files to events, which are not stored.
+const maxReportingEventSize = 1 << 20
+
+// ReportingHandler receives the events of cloud-init's webhook reporting handler and stores
+// them as event logs of the instance, so failed modules show up without logging into the guest.
+// Guests enable it with:
+//
+//	reporting:
+//	  metadata-service:
+//	    type: webhook
+//	    endpoint: http://169.254.169.254/configs/reporting
+func (h *Handler) ReportingHandler(c *gin.Context) {
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxReportingEventSize)
+
+	var event types.ReportingEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reporting event: " + err.Error()})
+		return
+	}
+
+	_, err := h.Database.CreateInstanceLog(c.Request.Context(), db.CreateInstanceLogParams{
+		InstanceID: instance.ID,
+		LogType:    "event",
+		Level:      eventLevel(event),
+		Message:    eventMessage(event),
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to store reporting event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store reporting event"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// eventLevel maps the result of a cloud-init event to a log level. Start events only
+// say a module is running, so they are kept at debug.
+func eventLevel(event types.ReportingEvent) string {
+	if event.EventType == "start" {
+		return "debug"
+	}
+
+	switch event.Result {
+	case "FAIL":
+		return "error"
+	case "WARN":
+		return "warn"
+	default:
+		return "info"
+	}
+}
+
+// eventMessage formats an event as e.g. "finish modules-config/config-ssh: FAIL: running config-ssh".
+func eventMessage(event types.ReportingEvent) string {
+	message := event.EventType + " " + event.Name
+	if event.Result != "" {
+		message += ": " + event.Result
+	}
+
+	if event.Description != "" {
+		message = fmt.Sprintf("%s: %s", message, event.Description)
+	}
+
+	return message
+}
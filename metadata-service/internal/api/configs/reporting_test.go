@@ -0,0 +1,93 @@
+package configs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// staticResolver resolves every request to the same instance
+type staticResolver db.Instance
+
+func (r staticResolver) Resolve(*http.Request) (db.Instance, error) {
+	return db.Instance(r), nil
+}
+
+func setupReportingRouter(mockDB *mocks.MockQuerier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	RegisterConfigRoutes(router, &config.Config{Tags: &config.TagsConfig{}}, mockDB, staticResolver{ID: 42, Name: "c1", Project: "default", Remote: "local"}, nil, nil, nil)
+
+	return router
+}
+
+func TestReportingHandler_StoresEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		level   string
+		message string
+	}{
+		{
+			name:    "failed module",
+			body:    `{"name":"modules-config/config-ssh","description":"running config-ssh with frequency once-per-instance","event_type":"finish","origin":"cloudinit","timestamp":1700000000.5,"result":"FAIL"}`,
+			level:   "error",
+			message: "finish modules-config/config-ssh: FAIL: running config-ssh with frequency once-per-instance",
+		},
+		{
+			name:    "module with warnings",
+			body:    `{"name":"init-network/config-users-groups","event_type":"finish","result":"WARN"}`,
+			level:   "warn",
+			message: "finish init-network/config-users-groups: WARN",
+		},
+		{
+			name:    "module started",
+			body:    `{"name":"init-network","description":"searching for network datasources","event_type":"start"}`,
+			level:   "debug",
+			message: "start init-network: searching for network datasources",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(mocks.MockQuerier)
+			mockDB.On("CreateInstanceLog", mock.Anything, db.CreateInstanceLogParams{
+				InstanceID: 42,
+				LogType:    "event",
+				Level:      tt.level,
+				Message:    tt.message,
+			}).Return(db.InstanceLog{ID: 1}, nil)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/configs/reporting", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReportingHandler_RejectsInvalidEvents(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+
+	for _, body := range []string{`not json`, `{"name":"init-network"}`, `{"name":"init-network","event_type":"progress"}`} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/configs/reporting", strings.NewReader(body))
+		setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	mockDB.AssertNotCalled(t, "CreateInstanceLog", mock.Anything, mock.Anything)
+}
@@ -2,17 +2,24 @@ package configs
 
 import (
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterConfigRoutes registers the public API routes for the metadata service.
-func RegisterConfigRoutes(router *gin.Engine, cfg *config.Config, db db.Querier) {
-	publicGroup := router.Group("/configs")
+// Every route requires the caller to be resolved to an Incus instance.
+func RegisterConfigRoutes(router *gin.Engine, cfg *config.Config, db db.Querier, instanceResolver resolver.Resolver, keyring *identity.Keyring, envelope *secrets.Envelope, passwords *secrets.PasswordKey) {
+	publicGroup := router.Group("/configs", resolver.Middleware(instanceResolver))
 
 	handlers := &Handler{
-		Config:   cfg,
-		Database: db,
+		Config:    cfg,
+		Database:  db,
+		Identity:  keyring,
+		Secrets:   envelope,
+		Passwords: passwords,
 	}
 
 	// Metadata endpoints
@@ -27,4 +34,34 @@ func RegisterConfigRoutes(router *gin.Engine, cfg *config.Config, db db.Querier)
 
 	// Network configuration endpoint
 	publicGroup.GET("/network-config", handlers.NetworkConfigHandler)
+
+	// cloud-init webhook reporting endpoint
+	publicGroup.POST("/reporting", handlers.ReportingHandler)
+
+	// Configuration status, reported by guests or cloud-init's phone_home module
+	publicGroup.POST("/status", handlers.StatusHandler)
+	publicGroup.POST("/phone-home", handlers.PhoneHomeHandler)
+
+	// SSH keys pushed through the admin API, served at the paths of EC2 Instance Connect
+	latestGroup := router.Group("/latest", resolver.Middleware(instanceResolver))
+	latestGroup.GET("/meta-data/managed-ssh-keys/active-keys/:user", handlers.ActiveSSHKeysHandler)
+
+	// Tags from the user.* keys of the instance, at the paths of EC2 instance tags
+	latestGroup.GET("/meta-data/tags/instance", handlers.InstanceTagsHandler)
+	latestGroup.GET("/meta-data/tags/instance/:key", handlers.InstanceTagHandler)
+
+	// Signed instance identity documents, with the signature variants served by EC2 and a JWS
+	latestGroup.GET("/dynamic/instance-identity/document", handlers.IdentityDocumentHandler)
+	latestGroup.GET("/dynamic/instance-identity/signature", handlers.IdentitySignatureHandler)
+	latestGroup.GET("/dynamic/instance-identity/pkcs7", handlers.IdentityPKCS7Handler)
+	latestGroup.GET("/dynamic/instance-identity/rsa2048", handlers.IdentityRSA2048Handler)
+	latestGroup.GET("/dynamic/instance-identity/jws", handlers.IdentityJWSHandler)
+
+	// OIDC workload identity tokens for a caller-chosen audience
+	latestGroup.GET("/identity/token", handlers.WorkloadTokenHandler)
+
+	// Session tokens in the manner of IMDSv2, required to read secrets
+	latestGroup.PUT("/api/token", handlers.SessionTokenHandler)
+	latestGroup.GET("/secrets", handlers.SecretsHandler)
+	latestGroup.GET("/secrets/:name", handlers.SecretHandler)
 }
@@ -0,0 +1,190 @@
+package configs
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/gin-gonic/gin"
+)
+
+// The headers of IMDSv2 session tokens, so EC2 tooling works unchanged.
+const (
+	sessionTokenHeader    = "X-aws-ec2-metadata-token"
+	sessionTokenTTLHeader = "X-aws-ec2-metadata-token-ttl-seconds"
+)
+
+// SessionTokenHandler issues a session token valid for the number of seconds in the TTL header,
+// up to six hours. Tokens are only issued to PUT requests with a custom header, which requests
+// forged through a guest application, such as a server-side request forgery, can rarely make.
+func (h *Handler) SessionTokenHandler(c *gin.Context) {
+	if h.Secrets == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session tokens are not enabled"})
+		return
+	}
+
+	ttl, err := strconv.Atoi(c.GetHeader(sessionTokenTTLHeader))
+	if err != nil || ttl < 1 || time.Duration(ttl)*time.Second > secrets.MaxSessionTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The " + sessionTokenTTLHeader + " header must be between 1 and 21600 seconds"})
+		return
+	}
+
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	c.Header(sessionTokenTTLHeader, strconv.Itoa(ttl))
+	c.String(http.StatusOK, h.Secrets.SessionToken(instance, time.Now().Add(time.Duration(ttl)*time.Second)))
+}
+
+// SecretsHandler lists the names of the secrets the instance can read, one per line.
+func (h *Handler) SecretsHandler(c *gin.Context) {
+	instance, rows, ok := h.instanceSecrets(c)
+	if !ok {
+		return
+	}
+
+	// Consumed one-time secrets still shadow the broader secrets sharing their name
+	names := make([]string, 0, len(rows))
+	for name, row := range mostSpecific(rows) {
+		if !row.Consumed {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No secrets"})
+		return
+	}
+	slices.Sort(names)
+
+	logs.FromContext(c).Debug().Str("instance", instance.Name).Int("secrets", len(names)).Msg("Listed instance secrets")
+	c.String(http.StatusOK, strings.Join(names, "\n")+"\n")
+}
+
+// SecretHandler serves the value of a secret. When secrets in several scopes share a name, the
+// instance scope wins over profiles, which win over the project. A one-time secret is served
+// once to each instance, later reads answer 410 rather than falling back to a broader scope.
+func (h *Handler) SecretHandler(c *gin.Context) {
+	instance, rows, ok := h.instanceSecrets(c)
+	if !ok {
+		return
+	}
+
+	row, ok := mostSpecific(rows)[c.Param("name")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Secret not found"})
+		return
+	}
+
+	if row.Consumed {
+		c.JSON(http.StatusGone, gin.H{"error": "Secret already read"})
+		return
+	}
+
+	secret := row.Secret
+	value, err := h.Secrets.Open(
+		secrets.Sealed{KeyID: secret.KeyID, WrappedKey: secret.WrappedKey, Ciphertext: secret.Ciphertext},
+		secrets.AdditionalData(secret.Project, secret.Scope, secret.Target, secret.Name),
+	)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Int64("secret_id", secret.ID).Msg("Failed to decrypt secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decrypt secret"})
+		return
+	}
+
+	if secret.OneTime {
+		// Claiming the read is atomic, so concurrent requests can't both be served the value. Reads
+		// are tied to the volatile.uuid of the instance, as a rebuilt instance keeps its row.
+		claimed, err := h.Database.ClaimSecretRead(c.Request.Context(), db.ClaimSecretReadParams{
+			SecretID:     secret.ID,
+			InstanceID:   instance.ID,
+			InstanceUuid: instance.Uuid,
+		})
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to claim secret read")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read secret"})
+			return
+		}
+
+		if claimed == 0 {
+			c.JSON(http.StatusGone, gin.H{"error": "Secret already read"})
+			return
+		}
+	}
+
+	message := "Secret " + secret.Name + " read"
+	if secret.OneTime {
+		message += ", once"
+	}
+
+	// Reads are audited before the value is served, a secret must not leave without a trace.
+	// Only claimed reads are audited, so the log never records a read that wasn't served.
+	_, err = h.Database.CreateInstanceLog(c.Request.Context(), db.CreateInstanceLogParams{
+		InstanceID: instance.ID,
+		LogType:    "audit",
+		Level:      "info",
+		Message:    message,
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to audit secret read")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to audit secret read"})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", value)
+}
+
+// mostSpecific returns the secret each name resolves to. Rows are ordered from the least to the
+// most specific scope, so later rows shadow earlier ones.
+func mostSpecific(rows []db.ListInstanceSecretsRow) map[string]db.ListInstanceSecretsRow {
+	resolved := make(map[string]db.ListInstanceSecretsRow, len(rows))
+	for _, row := range rows {
+		resolved[row.Secret.Name] = row
+	}
+
+	return resolved
+}
+
+// instanceSecrets checks the session token of the request and lists the secrets of the calling
+// instance, answering the request itself on failure.
+func (h *Handler) instanceSecrets(c *gin.Context) (db.Instance, []db.ListInstanceSecretsRow, bool) {
+	if h.Secrets == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Secrets are not enabled"})
+		return db.Instance{}, nil, false
+	}
+
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return db.Instance{}, nil, false
+	}
+
+	if !h.Secrets.VerifySessionToken(c.GetHeader(sessionTokenHeader), instance, time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "A valid session token is required, see PUT /latest/api/token"})
+		return db.Instance{}, nil, false
+	}
+
+	rows, err := h.Database.ListInstanceSecrets(c.Request.Context(), db.ListInstanceSecretsParams{
+		Remote:       instance.Remote,
+		Project:      instance.Project,
+		InstanceName: instance.Name,
+		InstanceID:   instance.ID,
+		InstanceUuid: instance.Uuid,
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list instance secrets")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list secrets"})
+		return db.Instance{}, nil, false
+	}
+
+	return instance, rows, true
+}
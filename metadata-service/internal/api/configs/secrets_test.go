@@ -0,0 +1,192 @@
+package configs
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// instanceUUID is the volatile.uuid of the instance secrets are served to.
+const instanceUUID = "0f6b2a8e-3c1d-4e5f-8a9b-7c6d5e4f3a2b"
+
+func setupSecretsRouter(t *testing.T, mockDB *mocks.MockQuerier) (*gin.Engine, *secrets.Envelope) {
+	gin.SetMode(gin.TestMode)
+
+	envelope, err := secrets.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	uuid := instanceUUID
+
+	router := gin.New()
+	RegisterConfigRoutes(router, nil, mockDB, staticResolver{ID: 42, Name: "c1", Project: "default", Remote: "local", Uuid: &uuid}, nil, envelope, nil)
+
+	return router, envelope
+}
+
+func testSecret(t *testing.T, envelope *secrets.Envelope, id int64, name string, scope string, target string, value string, oneTime bool) db.ListInstanceSecretsRow {
+	sealed, err := envelope.Seal([]byte(value), secrets.AdditionalData("default", scope, target, name))
+	require.NoError(t, err)
+
+	return db.ListInstanceSecretsRow{Secret: db.Secret{
+		ID:         id,
+		Name:       name,
+		Project:    "default",
+		Scope:      scope,
+		Target:     target,
+		KeyID:      sealed.KeyID,
+		WrappedKey: sealed.WrappedKey,
+		Ciphertext: sealed.Ciphertext,
+		OneTime:    oneTime,
+	}}
+}
+
+func sessionToken(t *testing.T, router *gin.Engine) string {
+	req := httptest.NewRequest(http.MethodPut, "/latest/api/token", nil)
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	return rec.Body.String()
+}
+
+func TestSecrets_RequireSessionToken(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	router, _ := setupSecretsRouter(t, mockDB)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/secrets/db_password", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/latest/secrets", nil)
+	req.Header.Set("X-aws-ec2-metadata-token", "1.forged")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	for _, ttl := range []string{"", "0", "21601"} {
+		req := httptest.NewRequest(http.MethodPut, "/latest/api/token", nil)
+		req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", ttl)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, ttl)
+	}
+
+	mockDB.AssertNotCalled(t, "ListInstanceSecrets", mock.Anything, mock.Anything)
+}
+
+func TestSecrets_ServeMostSpecificScope(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	router, envelope := setupSecretsRouter(t, mockDB)
+
+	uuid := instanceUUID
+	mockDB.On("ListInstanceSecrets", mock.Anything, db.ListInstanceSecretsParams{Remote: "local", Project: "default", InstanceName: "c1", InstanceID: 42, InstanceUuid: &uuid}).Return([]db.ListInstanceSecretsRow{
+		testSecret(t, envelope, 1, "db_password", "project", "", "shared", false),
+		testSecret(t, envelope, 2, "api_key", "profile", "web", "key", false),
+		testSecret(t, envelope, 3, "db_password", "instance", "c1", "own", false),
+	}, nil)
+	mockDB.On("CreateInstanceLog", mock.Anything, db.CreateInstanceLogParams{InstanceID: 42, LogType: "audit", Level: "info", Message: "Secret db_password read"}).Return(db.InstanceLog{}, nil).Once()
+
+	token := sessionToken(t, router)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/latest/secrets")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "api_key\ndb_password\n", rec.Body.String())
+
+	rec = get("/latest/secrets/db_password")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "own", rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, get("/latest/secrets/missing").Code)
+	mockDB.AssertExpectations(t)
+}
+
+func TestSecrets_OneTimeRead(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	router, envelope := setupSecretsRouter(t, mockDB)
+
+	mockDB.On("ListInstanceSecrets", mock.Anything, mock.Anything).Return([]db.ListInstanceSecretsRow{
+		testSecret(t, envelope, 7, "join_token", "instance", "c1", "t0ken", true),
+	}, nil)
+	mockDB.On("CreateInstanceLog", mock.Anything, mock.MatchedBy(func(arg db.CreateInstanceLogParams) bool {
+		return arg.LogType == "audit" && arg.Message == "Secret join_token read, once"
+	})).Return(db.InstanceLog{}, nil).Once()
+	uuid := instanceUUID
+	mockDB.On("ClaimSecretRead", mock.Anything, db.ClaimSecretReadParams{SecretID: 7, InstanceID: 42, InstanceUuid: &uuid}).Return(int64(1), nil).Once()
+	mockDB.On("ClaimSecretRead", mock.Anything, db.ClaimSecretReadParams{SecretID: 7, InstanceID: 42, InstanceUuid: &uuid}).Return(int64(0), nil).Once()
+
+	token := sessionToken(t, router)
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/latest/secrets/join_token", nil)
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get()
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "t0ken", rec.Body.String())
+
+	// A concurrent request that lost the claim isn't served the value, nor audited as a read
+	rec = get()
+	assert.Equal(t, http.StatusGone, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "t0ken")
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "DeleteSecret", mock.Anything, mock.Anything)
+}
+
+func TestSecrets_ConsumedSecretShadowsBroaderScopes(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	router, envelope := setupSecretsRouter(t, mockDB)
+
+	consumed := testSecret(t, envelope, 7, "join_token", "instance", "c1", "t0ken", true)
+	consumed.Consumed = true
+	mockDB.On("ListInstanceSecrets", mock.Anything, mock.Anything).Return([]db.ListInstanceSecretsRow{
+		testSecret(t, envelope, 1, "join_token", "project", "", "shared", false),
+		consumed,
+	}, nil)
+
+	token := sessionToken(t, router)
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-aws-ec2-metadata-token", token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/latest/secrets/join_token")
+	assert.Equal(t, http.StatusGone, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "shared")
+
+	assert.Equal(t, http.StatusNotFound, get("/latest/secrets").Code)
+	mockDB.AssertNotCalled(t, "ClaimSecretRead", mock.Anything, mock.Anything)
+	mockDB.AssertNotCalled(t, "CreateInstanceLog", mock.Anything, mock.Anything)
+}
+
+func TestSecrets_DisabledWithoutKey(t *testing.T) {
+	router := setupReportingRouter(new(mocks.MockQuerier))
+
+	req := httptest.NewRequest(http.MethodPut, "/latest/api/token", nil)
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
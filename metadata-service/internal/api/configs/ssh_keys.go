@@ -0,0 +1,36 @@
+package configs
+
+import (
+	"context"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+)
+
+// authorizedKeys returns the SSH keys registered for the project, profiles or name of an
+// instance, in authorized_keys format. Keys are looked up on every request, so rotated and
+// expired keys take effect the next time the guest fetches them.
+func (h *Handler) authorizedKeys(ctx context.Context, instance db.Instance) ([]string, error) {
+	rows, err := h.Database.ListInstanceSSHKeys(ctx, db.ListInstanceSSHKeysParams{
+		Remote:       instance.Remote,
+		Project:      instance.Project,
+		Now:          time.Now().Unix(),
+		InstanceName: instance.Name,
+		InstanceID:   instance.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, row := range rows {
+		key := row.PublicKey
+		if row.Comment != "" {
+			key += " " + row.Comment
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,67 @@
+package configs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func registeredKeys(mockDB *mocks.MockQuerier) {
+	mockDB.On("ListInstanceSSHKeys", mock.Anything, mock.MatchedBy(func(arg db.ListInstanceSSHKeysParams) bool {
+		return arg.Remote == "local" && arg.Project == "default" && arg.InstanceName == "c1" && arg.InstanceID == 42 && arg.Now > 0
+	})).Return([]db.SshKey{
+		{PublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA", Comment: "alice@laptop"},
+		{PublicKey: "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ"},
+	}, nil)
+}
+
+func TestMetadata_ServesRegisteredKeys(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	registeredKeys(mockDB)
+	mockDB.On("ListInstanceTags", mock.Anything, int64(42)).Return([]db.InstanceTag{}, nil)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/configs/meta-data", nil)
+	req.Header.Set("Accept", "application/json")
+	setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var metadata types.Metadata
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &metadata))
+	assert.Equal(t, []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA alice@laptop", "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ"}, metadata.PublicKeys)
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/configs/meta-data/public-keys", nil)
+	req.Header.Set("Accept", "application/json")
+	setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `["ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA alice@laptop", "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ"]`, rec.Body.String())
+}
+
+func TestUserData_ServesRegisteredKeys(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	registeredKeys(mockDB)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/configs/user-data", nil)
+	req.Header.Set("Accept", "application/yaml")
+	setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var userData types.UserData
+	require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &userData))
+	require.NotEmpty(t, userData.Users)
+	assert.Equal(t, []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA alice@laptop", "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ"}, userData.Users[0].SSHAuthorizedKeys)
+}
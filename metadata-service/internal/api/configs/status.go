@@ -0,0 +1,134 @@
+package configs
+
+import (
+	"net/http"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+	"github.com/lxc/incus/shared/api"
+)
+
+// maxStatusReportSize bounds the status reports and phone home requests guests can post.
+const maxStatusReportSize = 64 << 10
+
+// statusCodes maps the statuses guests report to the Incus status codes stored alongside them.
+var statusCodes = map[string]api.StatusCode{
+	types.StatusBooting:    api.Running,
+	types.StatusConfigDone: api.Success,
+	types.StatusFailed:     api.Failure,
+}
+
+// StatusHandler records the configuration status reported by a guest, so automation can wait
+// for instances to be configured. Guests report it from user-data, e.g. with a runcmd entry:
+//
+//	curl -fsS -d status=config-done http://169.254.169.254/configs/status
+func (h *Handler) StatusHandler(c *gin.Context) {
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxStatusReportSize)
+
+	var report types.StatusReport
+	if err := c.ShouldBind(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status report: " + err.Error()})
+		return
+	}
+
+	message := "status " + report.Status
+	if report.Message != "" {
+		message += ": " + report.Message
+	}
+
+	if !h.storeStatus(c, instance, report.Status, message) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PhoneHomeHandler receives the request of cloud-init's phone_home module, sent once the
+// guest finished booting, and records the instance as configured. The SSH host keys it sends
+// are stored for known_hosts files. Guests enable it with:
+//
+//	phone_home:
+//	  url: http://169.254.169.254/configs/phone-home
+//	  post: all
+func (h *Handler) PhoneHomeHandler(c *gin.Context) {
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxStatusReportSize)
+
+	var request types.PhoneHome
+	if err := c.ShouldBind(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid phone home request: " + err.Error()})
+		return
+	}
+
+	// The instance ID comes from the cloud-init cache, a mismatch means the guest
+	// still runs with the metadata of another instance, e.g. after a copy
+	if request.InstanceID != "" && request.InstanceID != instance.Name {
+		logs.FromContext(c).Warn().Str("instance_id", request.InstanceID).Msg("Phone home instance ID doesn't match the instance")
+	}
+
+	if keys := hostKeys(request); len(keys) > 0 {
+		if err := h.storeHostKeys(c.Request.Context(), instance, keys); err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to store host keys")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store host keys"})
+			return
+		}
+	}
+
+	message := "phone home"
+	if request.Hostname != "" {
+		message += " from " + request.Hostname
+	}
+
+	if !h.storeStatus(c, instance, types.StatusConfigDone, message) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// storeStatus updates the state of the instance and keeps the report in its event log,
+// answering the request itself on failure.
+func (h *Handler) storeStatus(c *gin.Context, instance db.Instance, status string, message string) bool {
+	_, err := h.Database.CreateOrUpdateInstanceState(c.Request.Context(), db.CreateOrUpdateInstanceStateParams{
+		InstanceID: instance.ID,
+		Status:     status,
+		StatusCode: int64(statusCodes[status]),
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to store instance status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store instance status"})
+		return false
+	}
+
+	level := "info"
+	if status == types.StatusFailed {
+		level = "error"
+	}
+
+	// The state is what automation waits on, a missing log entry is not worth failing the report
+	_, err = h.Database.CreateInstanceLog(c.Request.Context(), db.CreateInstanceLogParams{
+		InstanceID: instance.ID,
+		LogType:    "event",
+		Level:      level,
+		Message:    message,
+	})
+	if err != nil {
+		logs.FromContext(c).Warn().Err(err).Msg("Failed to log instance status")
+	}
+
+	return true
+}
@@ -0,0 +1,178 @@
+package configs
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestStatusHandler_StoresStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      string
+		code        int64
+		level       string
+		message     string
+	}{
+		{
+			name:        "form encoded",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "status=booting",
+			status:      "booting",
+			code:        103,
+			level:       "info",
+			message:     "status booting",
+		},
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"status":"config-done"}`,
+			status:      "config-done",
+			code:        200,
+			level:       "info",
+			message:     "status config-done",
+		},
+		{
+			name:        "failure with a message",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "status=failed&message=ansible-pull+failed",
+			status:      "failed",
+			code:        400,
+			level:       "error",
+			message:     "status failed: ansible-pull failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(mocks.MockQuerier)
+			mockDB.On("CreateOrUpdateInstanceState", mock.Anything, db.CreateOrUpdateInstanceStateParams{
+				InstanceID: 42,
+				Status:     tt.status,
+				StatusCode: tt.code,
+			}).Return(db.InstanceState{ID: 1}, nil)
+			mockDB.On("CreateInstanceLog", mock.Anything, db.CreateInstanceLogParams{
+				InstanceID: 42,
+				LogType:    "event",
+				Level:      tt.level,
+				Message:    tt.message,
+			}).Return(db.InstanceLog{ID: 1}, nil)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/configs/status", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestStatusHandler_RejectsUnknownStatuses(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+
+	for _, body := range []string{"", "status=done", "message=hello"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/configs/status", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	mockDB.AssertNotCalled(t, "CreateOrUpdateInstanceState", mock.Anything, mock.Anything)
+}
+
+func testHostKey(t *testing.T) ssh.PublicKey {
+	public, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	key, err := ssh.NewPublicKey(public)
+	require.NoError(t, err)
+
+	return key
+}
+
+func TestPhoneHomeHandler_MarksInstanceConfigured(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	mockDB.On("CreateOrUpdateInstanceState", mock.Anything, db.CreateOrUpdateInstanceStateParams{
+		InstanceID: 42,
+		Status:     "config-done",
+		StatusCode: 200,
+	}).Return(db.InstanceState{ID: 1}, nil)
+	// Logging the report is best effort
+	mockDB.On("CreateInstanceLog", mock.Anything, db.CreateInstanceLogParams{
+		InstanceID: 42,
+		LogType:    "event",
+		Level:      "info",
+		Message:    "phone home from c1",
+	}).Return(db.InstanceLog{}, errors.New("database is locked"))
+
+	body := "instance_id=c1&hostname=c1&fqdn=c1.example.org"
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/configs/phone-home", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "ListInstanceHostKeys", mock.Anything, mock.Anything)
+}
+
+func TestPhoneHomeHandler_StoresHostKeys(t *testing.T) {
+	known, rotated := testHostKey(t), testHostKey(t)
+	knownKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(known)))
+	rotatedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(rotated)))
+	revokedAt := time.Now()
+
+	mockDB := new(mocks.MockQuerier)
+	mockDB.On("ListInstanceHostKeys", mock.Anything, int64(42)).Return([]db.InstanceHostKey{
+		{ID: 3, KeyType: "ssh-ed25519", PublicKey: "ssh-ed25519 AAAA-old", RevokedAt: &revokedAt},
+		{ID: 2, KeyType: "ssh-rsa", PublicKey: knownKey},
+	}, nil)
+	mockDB.On("TouchInstanceHostKey", mock.Anything, int64(2)).Return(nil)
+	mockDB.On("RevokeInstanceHostKeys", mock.Anything, db.RevokeInstanceHostKeysParams{
+		RevokedReason: "replaced",
+		InstanceID:    42,
+		KeyType:       "ssh-ed25519",
+	}).Return(int64(1), nil)
+	mockDB.On("CreateInstanceHostKey", mock.Anything, db.CreateInstanceHostKeyParams{
+		InstanceID:  42,
+		KeyType:     "ssh-ed25519",
+		PublicKey:   rotatedKey,
+		Fingerprint: ssh.FingerprintSHA256(rotated),
+	}).Return(db.InstanceHostKey{ID: 4}, nil)
+	mockDB.On("CreateOrUpdateInstanceState", mock.Anything, mock.Anything).Return(db.InstanceState{ID: 1}, nil)
+	mockDB.On("CreateInstanceLog", mock.Anything, mock.Anything).Return(db.InstanceLog{ID: 1}, nil)
+
+	form := url.Values{
+		"instance_id": {"c1"},
+		// cloud-init sends the content of the public key files, with their comment
+		"pub_key_rsa":     {knownKey + " root@c1\n"},
+		"pub_key_ed25519": {rotatedKey + " root@c1\n"},
+		"pub_key_ecdsa":   {"not a key"},
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/configs/phone-home", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	setupReportingRouter(mockDB).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,82 @@
+package configs
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/gin-gonic/gin"
+)
+
+// InstanceTagsHandler lists the tag keys of the instance, one per line, as EC2 does at
+// /latest/meta-data/tags/instance.
+func (h *Handler) InstanceTagsHandler(c *gin.Context) {
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	tags, keys, err := h.instanceTags(c.Request.Context(), instance)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list instance tags")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+
+	if len(tags) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No tags"})
+		return
+	}
+
+	c.String(http.StatusOK, strings.Join(keys, "\n")+"\n")
+}
+
+// InstanceTagHandler serves the value of a tag of the instance.
+func (h *Handler) InstanceTagHandler(c *gin.Context) {
+	instance, ok := resolver.InstanceFromContext(c)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	tags, _, err := h.instanceTags(c.Request.Context(), instance)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list instance tags")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+
+	value, ok := tags[c.Param("key")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	c.String(http.StatusOK, value)
+}
+
+// instanceTags returns the tags of an instance that guests are allowed to see, along with
+// their keys in order.
+func (h *Handler) instanceTags(ctx context.Context, instance db.Instance) (map[string]string, []string, error) {
+	rows, err := h.Database.ListInstanceTags(ctx, instance.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tags := map[string]string{}
+	keys := []string{}
+	for _, row := range rows {
+		if !h.Config.Tags.Allowed(instance.Project, row.Key) {
+			continue
+		}
+
+		tags[row.Key] = row.Value
+		keys = append(keys, row.Key)
+	}
+
+	return tags, keys, nil
+}
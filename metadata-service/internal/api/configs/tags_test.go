@@ -0,0 +1,81 @@
+package configs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTagsRouter(mockDB *mocks.MockQuerier, tags *config.TagsConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	RegisterConfigRoutes(router, &config.Config{Tags: tags}, mockDB, staticResolver{ID: 42, Name: "c1", Project: "default", Remote: "local"}, nil, nil, nil)
+
+	return router
+}
+
+func instanceTags(mockDB *mocks.MockQuerier) {
+	mockDB.On("ListInstanceTags", mock.Anything, int64(42)).Return([]db.InstanceTag{
+		{InstanceID: 42, Key: "db_password", Value: "hunter2"},
+		{InstanceID: 42, Key: "env", Value: "staging"},
+		{InstanceID: 42, Key: "team", Value: "payments"},
+	}, nil)
+}
+
+func TestInstanceTags_ServedAtEC2Paths(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	instanceTags(mockDB)
+	router := setupTagsRouter(mockDB, &config.TagsConfig{Deny: []string{"*password*"}})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/meta-data/tags/instance", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "env\nteam\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/meta-data/tags/instance/team", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "payments", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/meta-data/tags/instance/db_password", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code, "denied tags are hidden")
+}
+
+func TestInstanceTags_InMetadata(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	registeredKeys(mockDB)
+	instanceTags(mockDB)
+	router := setupTagsRouter(mockDB, &config.TagsConfig{Allow: []string{"default=team"}})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/configs/meta-data", nil)
+	req.Header.Set("Accept", "application/json")
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var metadata types.Metadata
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &metadata))
+	assert.Equal(t, map[string]string{"team": "payments"}, metadata.Tags)
+}
+
+func TestInstanceTags_NoTags(t *testing.T) {
+	mockDB := new(mocks.MockQuerier)
+	mockDB.On("ListInstanceTags", mock.Anything, int64(42)).Return([]db.InstanceTag{}, nil)
+	router := setupTagsRouter(mockDB, &config.TagsConfig{})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/latest/meta-data/tags/instance", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/content_types"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
 )
 
@@ -45,12 +47,41 @@ func (h *Handler) UserDataHandler(c *gin.Context) {
 		return
 	}
 
+	userData := mockUserData()
+
+	if instance, ok := resolver.InstanceFromContext(c); ok {
+		keys, err := h.authorizedKeys(c.Request.Context(), instance)
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to list SSH keys")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list SSH keys"})
+			return
+		}
+
+		for i := range userData.Users {
+			userData.Users[i].SSHAuthorizedKeys = keys
+		}
+
+		password, err := h.instancePassword(c.Request.Context(), instance)
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to generate password")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate password"})
+			return
+		}
+
+		if password != "" {
+			userData.Chpasswd = &types.Chpasswd{}
+			for _, user := range userData.Users {
+				userData.Chpasswd.Users = append(userData.Chpasswd.Users, types.UserPassword{Name: user.Name, Password: password, Type: "text"})
+			}
+		}
+	}
+
 	if content_types.IsYamlContentType(requested_content_type) {
-		c.YAML(http.StatusOK, mockUserData())
+		c.YAML(http.StatusOK, userData)
 		return
 	}
 
 	// Need to implement the conversion to script format if requested.
 
-	c.YAML(http.StatusOK, mockUserData())
+	c.YAML(http.StatusOK, userData)
 }
@@ -16,7 +16,7 @@ func (h *Handler) VendorDataHandler(c *gin.Context) {
 	vendorData, err := h.Database.GetVendorData(c, "default")
 
 	if err == sql.ErrNoRows {
-		logs.Logger.Info().Msg("No vendor data found, returning empty response")
+		logs.FromContext(c).Info().Msg("No vendor data found, returning empty response")
 		c.JSON(http.StatusOK, gin.H{})
 		return
 	}
@@ -9,7 +9,7 @@ import (
 
 func HealthCheck(c *gin.Context) {
 	// Respond with a simple JSON message indicating the service is healthy
-	logs.Logger.Info().Msg("Health check endpoint hit")
+	logs.FromContext(c).Debug().Msg("Health check endpoint hit")
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "ok",
 		"message": "Metadata service is running",
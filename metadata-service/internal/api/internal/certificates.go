@@ -0,0 +1,264 @@
+package internal_routes
+
+import (
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+func toCertificate(row db.Certificate) (types.Certificate, error) {
+	identity, err := trust.ToIdentity(row)
+	if err != nil {
+		return types.Certificate{}, err
+	}
+
+	certificate := types.Certificate{
+		Fingerprint: identity.Fingerprint,
+		Name:        identity.Name,
+		Role:        string(identity.Role),
+		Restricted:  identity.Restricted,
+		Projects:    identity.Projects,
+		Certificate: row.Certificate,
+	}
+
+	if row.CreatedAt != nil {
+		certificate.CreatedAt = *row.CreatedAt
+	}
+
+	return certificate, nil
+}
+
+func (h Handler) ListCertificates(c *gin.Context) {
+	rows, err := h.Database.ListCertificates(c)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list certificates")
+		c.JSON(500, gin.H{"error": "Failed to list certificates"})
+		return
+	}
+
+	certificates := make([]types.Certificate, 0, len(rows))
+	for _, row := range rows {
+		certificate, err := toCertificate(row)
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Str("fingerprint", row.Fingerprint).Msg("Failed to parse certificate")
+			c.JSON(500, gin.H{"error": "Failed to parse certificate"})
+			return
+		}
+
+		certificates = append(certificates, certificate)
+	}
+
+	c.JSON(200, gin.H{"data": certificates})
+}
+
+func (h Handler) GetCertificate(c *gin.Context) {
+	fingerprint := c.Param("fingerprint")
+
+	row, err := h.Database.GetCertificate(c, fingerprint)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(404, gin.H{"error": "Certificate not found"})
+			return
+		}
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve certificate")
+		c.JSON(500, gin.H{"error": "Failed to retrieve certificate"})
+		return
+	}
+
+	certificate, err := toCertificate(row)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to parse certificate")
+		c.JSON(500, gin.H{"error": "Failed to parse certificate"})
+		return
+	}
+
+	c.JSON(200, gin.H{"data": certificate})
+}
+
+// CreateCertificate adds a certificate to the trust store. Admins provide the PEM certificate
+// in the request body; untrusted clients provide a join token and enroll the certificate they
+// are connecting with, similar to `incus remote add` with a token.
+func (h Handler) CreateCertificate(c *gin.Context) {
+	var req types.CertificatesPost
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if req.TrustToken != "" {
+		h.redeemCertificateToken(c, req.TrustToken)
+		return
+	}
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.HasRole(trust.RoleAdmin) {
+		c.JSON(403, gin.H{"error": "Only admins can add certificates without a join token"})
+		return
+	}
+
+	block, _ := pem.Decode([]byte(req.Certificate))
+	if block == nil {
+		c.JSON(400, gin.H{"error": "Certificate must be PEM encoded"})
+		return
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid certificate"})
+		return
+	}
+
+	role, err := trust.ParseRole(req.Role)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	row, err := h.Trust.Add(c, cert, req.Name, role, req.Restricted, req.Projects)
+	if err == trust.ErrAlreadyTrusted {
+		c.JSON(409, gin.H{"error": "Certificate is already trusted"})
+		return
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to add certificate")
+		c.JSON(500, gin.H{"error": "Failed to add certificate"})
+		return
+	}
+
+	logs.FromContext(c).Info().Str("fingerprint", row.Fingerprint).Str("added_by", identity.Name).Msg("Certificate added to trust store")
+	c.JSON(201, gin.H{"message": "Certificate added successfully", "fingerprint": row.Fingerprint})
+}
+
+func (h Handler) redeemCertificateToken(c *gin.Context, secret string) {
+	if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+		c.JSON(400, gin.H{"error": "A client certificate is required to redeem a join token"})
+		return
+	}
+
+	row, err := h.Trust.Redeem(c, secret, c.Request.TLS.PeerCertificates[0])
+	if err == trust.ErrInvalidToken {
+		logs.FromContext(c).Warn().Str("client", c.ClientIP()).Msg("Rejected invalid join token")
+		c.JSON(403, gin.H{"error": "Invalid or expired join token"})
+		return
+	}
+
+	if err == trust.ErrAlreadyTrusted {
+		c.JSON(409, gin.H{"error": "Certificate is already trusted"})
+		return
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to redeem join token")
+		c.JSON(500, gin.H{"error": "Failed to redeem join token"})
+		return
+	}
+
+	logs.FromContext(c).Info().Str("fingerprint", row.Fingerprint).Str("name", row.Name).Msg("Certificate added to trust store with join token")
+	c.JSON(201, gin.H{"message": "Certificate added successfully", "fingerprint": row.Fingerprint})
+}
+
+func (h Handler) UpdateCertificate(c *gin.Context) {
+	fingerprint := c.Param("fingerprint")
+
+	var req types.CertificatePut
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	role, err := trust.ParseRole(req.Role)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Projects == nil {
+		req.Projects = []string{}
+	}
+
+	projects, err := json.Marshal(req.Projects)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid projects"})
+		return
+	}
+
+	row, err := h.Database.UpdateCertificate(c, db.UpdateCertificateParams{
+		Name:        req.Name,
+		Role:        string(role),
+		Restricted:  req.Restricted,
+		Projects:    projects,
+		Fingerprint: fingerprint,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(404, gin.H{"error": "Certificate not found"})
+			return
+		}
+		logs.FromContext(c).Error().Err(err).Msg("Failed to update certificate")
+		c.JSON(500, gin.H{"error": "Failed to update certificate"})
+		return
+	}
+
+	certificate, err := toCertificate(row)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to parse certificate")
+		c.JSON(500, gin.H{"error": "Failed to parse certificate"})
+		return
+	}
+
+	c.JSON(200, gin.H{"data": certificate})
+}
+
+func (h Handler) DeleteCertificate(c *gin.Context) {
+	fingerprint := c.Param("fingerprint")
+
+	if _, err := h.Database.GetCertificate(c, fingerprint); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(404, gin.H{"error": "Certificate not found"})
+			return
+		}
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve certificate")
+		c.JSON(500, gin.H{"error": "Failed to retrieve certificate"})
+		return
+	}
+
+	if err := h.Database.DeleteCertificate(c, fingerprint); err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to delete certificate")
+		c.JSON(500, gin.H{"error": "Failed to delete certificate"})
+		return
+	}
+
+	logs.FromContext(c).Info().Str("fingerprint", fingerprint).Msg("Certificate removed from trust store")
+	c.JSON(200, gin.H{"message": "Certificate deleted successfully"})
+}
+
+func (h Handler) CreateCertificateToken(c *gin.Context) {
+	var req types.CertificateTokensPost
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	role, err := trust.ParseRole(req.Role)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.Trust.IssueToken(c, req.Name, role, req.Restricted, req.Projects)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to issue join token")
+		c.JSON(500, gin.H{"error": "Failed to issue join token"})
+		return
+	}
+
+	c.JSON(201, gin.H{"token": token.String(), "expires_at": token.ExpiresAt})
+}
@@ -0,0 +1,212 @@
+package internal_routes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+	localtls "github.com/lxc/incus/shared/tls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Helper function to create a handler with a trust store backed by the mock database
+func setupTrustHandler() (*Handler, *mocks.MockQuerier) {
+	handler, mockDB := setupTestHandler()
+	handler.Trust = &trust.Store{
+		Database:          mockDB,
+		ServerFingerprint: "server-fingerprint",
+		Addresses:         []string{"10.0.0.1:8443"},
+		TokenExpiry:       time.Hour,
+	}
+	return handler, mockDB
+}
+
+// Helper function to get a client certificate for the TLS connection state
+func testClientCertificate(t *testing.T) *x509.Certificate {
+	cert, err := localtls.TestingKeyPair().PublicKeyX509()
+	assert.NoError(t, err)
+	return cert
+}
+
+func withPeerCertificate(c *gin.Context, cert *x509.Certificate) {
+	c.Request.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+}
+
+func TestCreateCertificate_WithJoinToken(t *testing.T) {
+	handler, mockDB := setupTrustHandler()
+	cert := testClientCertificate(t)
+
+	token := db.CertificateToken{
+		ID:        7,
+		Name:      "operator@laptop",
+		Secret:    "secret",
+		Role:      "operator",
+		Projects:  []byte(`["default"]`),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mockDB.On("ClaimCertificateToken", mock.Anything, "secret").Return(token, nil)
+	mockDB.On("GetCertificate", mock.Anything, localtls.CertFingerprint(cert)).Return(db.Certificate{}, sql.ErrNoRows)
+	mockDB.On("CreateCertificate", mock.Anything, mock.MatchedBy(func(arg db.CreateCertificateParams) bool {
+		return arg.Name == "operator@laptop" && arg.Role == "operator" && arg.Fingerprint == localtls.CertFingerprint(cert)
+	})).Return(db.Certificate{Fingerprint: localtls.CertFingerprint(cert), Name: "operator@laptop", Role: "operator"}, nil)
+
+	c, w := setupTestContext("POST", "/internal/certificates", types.CertificatesPost{Name: "ignored", TrustToken: "secret"})
+	withPeerCertificate(c, cert)
+
+	handler.CreateCertificate(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	mockDB.AssertExpectations(t)
+}
+
+func TestCreateCertificate_ExpiredJoinToken(t *testing.T) {
+	handler, mockDB := setupTrustHandler()
+	cert := testClientCertificate(t)
+
+	token := db.CertificateToken{
+		ID:        7,
+		Name:      "operator@laptop",
+		Secret:    "secret",
+		Role:      "operator",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}
+
+	mockDB.On("ClaimCertificateToken", mock.Anything, "secret").Return(token, nil)
+
+	c, w := setupTestContext("POST", "/internal/certificates", types.CertificatesPost{TrustToken: "secret"})
+	withPeerCertificate(c, cert)
+
+	handler.CreateCertificate(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockDB.AssertNotCalled(t, "CreateCertificate", mock.Anything, mock.Anything)
+	mockDB.AssertExpectations(t)
+}
+
+func TestCreateCertificate_UnknownJoinToken(t *testing.T) {
+	handler, mockDB := setupTrustHandler()
+
+	mockDB.On("ClaimCertificateToken", mock.Anything, "bogus").Return(db.CertificateToken{}, sql.ErrNoRows)
+
+	c, w := setupTestContext("POST", "/internal/certificates", types.CertificatesPost{TrustToken: "bogus"})
+	withPeerCertificate(c, testClientCertificate(t))
+
+	handler.CreateCertificate(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockDB.AssertExpectations(t)
+}
+
+func TestCreateCertificate_RequiresAdminWithoutToken(t *testing.T) {
+	handler, mockDB := setupTrustHandler()
+
+	c, w := setupTestContext("POST", "/internal/certificates", types.CertificatesPost{Certificate: "not-used"})
+	c.Set("trust.identity", &trust.Identity{Name: "reader", Role: trust.RoleReader})
+
+	handler.CreateCertificate(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	mockDB.AssertExpectations(t)
+}
+
+func TestCreateCertificateToken_Success(t *testing.T) {
+	handler, mockDB := setupTrustHandler()
+
+	mockDB.On("CreateCertificateToken", mock.Anything, mock.MatchedBy(func(arg db.CreateCertificateTokenParams) bool {
+		return arg.Name == "ci-bastion" && arg.Role == "reader" && arg.Restricted && len(arg.Secret) == 64
+	})).Return(db.CertificateToken{Name: "ci-bastion", Secret: "abc", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	c, w := setupTestContext("POST", "/internal/certificates/tokens", types.CertificateTokensPost{
+		Name:       "ci-bastion",
+		Restricted: true,
+		Projects:   []string{"ci"},
+	})
+
+	handler.CreateCertificateToken(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	decoded, err := localtls.CertificateTokenDecode(response["token"].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, "ci-bastion", decoded.ClientName)
+	assert.Equal(t, "server-fingerprint", decoded.Fingerprint)
+	assert.Equal(t, []string{"10.0.0.1:8443"}, decoded.Addresses)
+	mockDB.AssertExpectations(t)
+}
+
+func TestInternalRoutes_Authorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cert := testClientCertificate(t)
+	fingerprint := localtls.CertFingerprint(cert)
+
+	tests := []struct {
+		name           string
+		certificate    db.Certificate
+		lookupErr      error
+		expectedStatus int
+	}{
+		{
+			name:           "untrusted certificate",
+			lookupErr:      sql.ErrNoRows,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "reader cannot manage the trust store",
+			certificate:    db.Certificate{Fingerprint: fingerprint, Name: "reader", Role: "reader"},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "admin can manage the trust store",
+			certificate:    db.Certificate{Fingerprint: fingerprint, Name: "admin", Role: "admin"},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := &mocks.MockQuerier{}
+			store := &trust.Store{Database: mockDB}
+			router := gin.New()
+			RegisterInternalRoutes(router, &config.Config{}, mockDB, store, nil, nil)
+
+			mockDB.On("GetCertificate", mock.Anything, fingerprint).Return(tt.certificate, tt.lookupErr)
+			mockDB.On("ListCertificates", mock.Anything).Return([]db.Certificate{tt.certificate}, nil).Maybe()
+
+			req := httptest.NewRequest("GET", "/internal/certificates", nil)
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+		})
+	}
+
+	t.Run("plain HTTP is rejected", func(t *testing.T) {
+		mockDB := &mocks.MockQuerier{}
+		router := gin.New()
+		RegisterInternalRoutes(router, &config.Config{}, mockDB, &trust.Store{Database: mockDB}, nil, nil)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/internal/vendor/default/data", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockDB.AssertExpectations(t)
+	})
+}
@@ -2,10 +2,16 @@ package internal_routes
 
 import (
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
 )
 
 type Handler struct {
 	Config   *config.Config
 	Database db.Querier
+	Trust    *trust.Store
+	Identity *identity.Keyring
+	Secrets  *secrets.Envelope
 }
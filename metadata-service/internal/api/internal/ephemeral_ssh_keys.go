@@ -0,0 +1,110 @@
+package internal_routes
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+// ephemeralSSHKeyLifetime is how long a pushed SSH key is served to the instance.
+const ephemeralSSHKeyLifetime = 60 * time.Second
+
+// osUserPattern matches the user names accepted by useradd.
+var osUserPattern = regexp.MustCompile(`^[a-z_][a-z0-9_.-]{0,31}$`)
+
+// PushEphemeralSSHKey authorizes an SSH key for a user of an instance for a minute, giving
+// just-in-time access without long-lived keys in images. The guest's sshd fetches it with the
+// authorized-keys command of the service. Every push is recorded in the audit log of the instance.
+func (h Handler) PushEphemeralSSHKey(c *gin.Context) {
+	project, name := c.Param("project"), c.Param("name")
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return
+	}
+
+	var req types.EphemeralSSHKeyPost
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if !osUserPattern.MatchString(req.OSUser) {
+		c.JSON(400, gin.H{"error": "Invalid OS user " + req.OSUser})
+		return
+	}
+
+	parsed, _, options, rest, err := ssh.ParseAuthorizedKey([]byte(req.PublicKey))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid SSH key: public key must be in authorized_keys format"})
+		return
+	}
+
+	if len(options) > 0 || len(strings.TrimSpace(string(rest))) > 0 {
+		c.JSON(400, gin.H{"error": "Invalid SSH key: public key must be a single key without options"})
+		return
+	}
+
+	remote := c.DefaultQuery("remote", h.Config.Incus.Name)
+	instance, err := h.Database.GetInstance(c, db.GetInstanceParams{Remote: remote, Name: name, Project: project})
+	if err == sql.ErrNoRows {
+		c.JSON(404, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve instance")
+		c.JSON(500, gin.H{"error": "Failed to retrieve instance"})
+		return
+	}
+
+	expiresAt := time.Now().Add(ephemeralSSHKeyLifetime)
+	fingerprint := ssh.FingerprintSHA256(parsed)
+
+	// Access must not be granted without a trace, so the push is audited before the key is served
+	_, err = h.Database.CreateInstanceLog(c, db.CreateInstanceLogParams{
+		InstanceID: instance.ID,
+		LogType:    "audit",
+		Level:      "info",
+		Message:    fmt.Sprintf("SSH key %s pushed for user %s by %s, valid until %s", fingerprint, req.OSUser, identity.Name, expiresAt.UTC().Format(time.RFC3339)),
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to audit SSH key push")
+		c.JSON(500, gin.H{"error": "Failed to audit SSH key push"})
+		return
+	}
+
+	row, err := h.Database.CreateEphemeralSSHKey(c, db.CreateEphemeralSSHKeyParams{
+		InstanceID:  instance.ID,
+		OsUser:      req.OSUser,
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(parsed))),
+		Fingerprint: fingerprint,
+		PushedBy:    identity.Name,
+		ExpiresAt:   expiresAt.Unix(),
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to push SSH key")
+		c.JSON(500, gin.H{"error": "Failed to push SSH key"})
+		return
+	}
+
+	logs.FromContext(c).Info().Str("instance", instance.Name).Str("project", instance.Project).Str("os_user", row.OsUser).Str("fingerprint", row.Fingerprint).Str("pushed_by", row.PushedBy).Msg("Ephemeral SSH key pushed")
+	c.JSON(201, gin.H{"data": types.EphemeralSSHKey{
+		Instance:    instance.Name,
+		Project:     instance.Project,
+		OSUser:      row.OsUser,
+		Fingerprint: row.Fingerprint,
+		PushedBy:    row.PushedBy,
+		ExpiresAt:   expiresAt.UTC().Truncate(time.Second),
+	}})
+}
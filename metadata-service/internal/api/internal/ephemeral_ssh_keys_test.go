@@ -0,0 +1,84 @@
+package internal_routes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPushEphemeralSSHKey_AuditsAndStoresKey(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+
+	key := testSSHKey(t)
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(testInstance, nil)
+	audit := mockDB.On("CreateInstanceLog", mock.Anything, mock.MatchedBy(func(arg db.CreateInstanceLogParams) bool {
+		return arg.InstanceID == 7 && arg.LogType == "audit" &&
+			strings.HasPrefix(arg.Message, "SSH key "+ssh.FingerprintSHA256(key)+" pushed for user ubuntu by operator")
+	})).Return(db.InstanceLog{}, nil).Once()
+	mockDB.On("CreateEphemeralSSHKey", mock.Anything, mock.MatchedBy(func(arg db.CreateEphemeralSSHKeyParams) bool {
+		return arg.InstanceID == 7 && arg.OsUser == "ubuntu" && arg.PublicKey == authorizedKey && arg.PushedBy == "operator"
+	})).Return(db.EphemeralSshKey{OsUser: "ubuntu", Fingerprint: ssh.FingerprintSHA256(key), PushedBy: "operator"}, nil).Once().NotBefore(audit)
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/instances/default/c1/ssh-keys", map[string]any{
+		"os_user":    "ubuntu",
+		"public_key": authorizedKey + " alice@laptop",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var body struct {
+		Data types.EphemeralSSHKey `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "c1", body.Data.Instance)
+	assert.Equal(t, "ubuntu", body.Data.OSUser)
+	assert.WithinDuration(t, time.Now().Add(ephemeralSSHKeyLifetime), body.Data.ExpiresAt, 5*time.Second)
+	mockDB.AssertExpectations(t)
+}
+
+func TestPushEphemeralSSHKey_RejectsInvalidRequests(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(testSSHKey(t))))
+
+	for name, body := range map[string]map[string]any{
+		"malformed key":    {"os_user": "ubuntu", "public_key": "ssh-ed25519 not-base64"},
+		"key with options": {"os_user": "ubuntu", "public_key": `command="/bin/true" ` + authorizedKey},
+		"missing user":     {"public_key": authorizedKey},
+		"invalid user":     {"os_user": "../root", "public_key": authorizedKey},
+	} {
+		resp := sendJSON(t, http.MethodPost, server.URL+"/internal/instances/default/c1/ssh-keys", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, name)
+	}
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/instances/other/c1/ssh-keys", map[string]any{"os_user": "ubuntu", "public_key": authorizedKey})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	mockDB.AssertNotCalled(t, "CreateEphemeralSSHKey", mock.Anything, mock.Anything)
+}
+
+func TestPushEphemeralSSHKey_NotServedWithoutAudit(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(testSSHKey(t))))
+
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(testInstance, nil)
+	mockDB.On("CreateInstanceLog", mock.Anything, mock.Anything).Return(db.InstanceLog{}, errors.New("database is locked"))
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/instances/default/c1/ssh-keys", map[string]any{"os_user": "ubuntu", "public_key": authorizedKey})
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	mockDB.AssertNotCalled(t, "CreateEphemeralSSHKey", mock.Anything, mock.Anything)
+}
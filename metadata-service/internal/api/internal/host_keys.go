@@ -0,0 +1,119 @@
+package internal_routes
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// GetKnownHosts serves the active SSH host keys of a project's instances as a known_hosts
+// file, so clients can trust new instances without accepting their key on first use. Each
+// key is listed for the instance name, the name within domain when given, and its addresses.
+func (h Handler) GetKnownHosts(c *gin.Context) {
+	project := c.DefaultQuery("project", "default")
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return
+	}
+
+	remote := c.DefaultQuery("remote", h.Config.Incus.Name)
+	domain := strings.Trim(c.Query("domain"), ".")
+
+	keys, err := h.Database.ListProjectHostKeys(c, db.ListProjectHostKeysParams{Remote: remote, Project: project})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list host keys")
+		c.JSON(500, gin.H{"error": "Failed to list host keys"})
+		return
+	}
+
+	addresses, err := h.Database.ListProjectInstanceAddresses(c, db.ListProjectInstanceAddressesParams{Remote: remote, Project: project})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list instance addresses")
+		c.JSON(500, gin.H{"error": "Failed to list instance addresses"})
+		return
+	}
+
+	instanceAddresses := map[int64][]string{}
+	for _, address := range addresses {
+		instanceAddresses[address.InstanceID] = append(instanceAddresses[address.InstanceID], address.IpAddress)
+	}
+
+	var knownHosts strings.Builder
+	for _, key := range keys {
+		hosts := []string{key.Name}
+		if domain != "" {
+			hosts = append(hosts, key.Name+"."+domain)
+		}
+
+		// Only the addresses instances currently hold, as an address may have been handed to another instance
+		for _, address := range instanceAddresses[key.InstanceID] {
+			if !slices.Contains(hosts, address) {
+				hosts = append(hosts, address)
+			}
+		}
+
+		knownHosts.WriteString(strings.Join(hosts, ",") + " " + key.PublicKey + "\n")
+	}
+
+	c.String(200, knownHosts.String())
+}
+
+// GetInstanceHostKeys lists the SSH host keys an instance published, newest first, including
+// revoked ones.
+func (h Handler) GetInstanceHostKeys(c *gin.Context) {
+	project, name := c.Param("project"), c.Param("name")
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return
+	}
+
+	remote := c.DefaultQuery("remote", h.Config.Incus.Name)
+	instance, err := h.Database.GetInstance(c, db.GetInstanceParams{Remote: remote, Name: name, Project: project})
+	if err == sql.ErrNoRows {
+		c.JSON(404, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve instance")
+		c.JSON(500, gin.H{"error": "Failed to retrieve instance"})
+		return
+	}
+
+	rows, err := h.Database.ListInstanceHostKeys(c, instance.ID)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list host keys")
+		c.JSON(500, gin.H{"error": "Failed to list host keys"})
+		return
+	}
+
+	keys := []types.HostKey{}
+	for _, row := range rows {
+		key := types.HostKey{Type: row.KeyType, PublicKey: row.PublicKey, Fingerprint: row.Fingerprint, RevokedAt: row.RevokedAt}
+		if row.CreatedAt != nil {
+			key.CreatedAt = *row.CreatedAt
+		}
+
+		if row.LastSeenAt != nil {
+			key.LastSeenAt = *row.LastSeenAt
+		}
+
+		if row.RevokedReason != nil {
+			key.RevokedReason = *row.RevokedReason
+		}
+
+		keys = append(keys, key)
+	}
+
+	c.JSON(200, keys)
+}
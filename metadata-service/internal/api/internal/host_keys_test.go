@@ -0,0 +1,80 @@
+package internal_routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetKnownHosts(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	mockDB.On("ListProjectHostKeys", mock.Anything, db.ListProjectHostKeysParams{Remote: "local", Project: "default"}).Return([]db.ListProjectHostKeysRow{
+		{InstanceID: 1, Name: "c1", PublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA"},
+		{InstanceID: 1, Name: "c1", PublicKey: "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ"},
+		{InstanceID: 2, Name: "c2", PublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB"},
+	}, nil)
+	mockDB.On("ListProjectInstanceAddresses", mock.Anything, db.ListProjectInstanceAddressesParams{Remote: "local", Project: "default"}).Return([]db.ListProjectInstanceAddressesRow{
+		{InstanceID: 1, IpAddress: "10.0.0.5"},
+		{InstanceID: 1, IpAddress: "fd42::5"},
+	}, nil)
+
+	resp, err := http.Get(server.URL + "/internal/known_hosts?domain=example.org.")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "c1,c1.example.org,10.0.0.5,fd42::5 ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA\n"+
+		"c1,c1.example.org,10.0.0.5,fd42::5 ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQ\n"+
+		"c2,c2.example.org ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB\n", string(body))
+}
+
+func TestGetKnownHosts_ChecksProjectAccess(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	resp, err := http.Get(server.URL + "/internal/known_hosts?project=other")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	mockDB.AssertNotCalled(t, "ListProjectHostKeys", mock.Anything, mock.Anything)
+}
+
+func TestGetInstanceHostKeys_IncludesHistory(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	seen := time.Unix(1700000000, 0).UTC()
+	reason := "rebuilt"
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(testInstance, nil)
+	mockDB.On("ListInstanceHostKeys", mock.Anything, int64(7)).Return([]db.InstanceHostKey{
+		{ID: 2, KeyType: "ssh-ed25519", PublicKey: "ssh-ed25519 AAAA-new", Fingerprint: "SHA256:new", CreatedAt: &seen, LastSeenAt: &seen},
+		{ID: 1, KeyType: "ssh-ed25519", PublicKey: "ssh-ed25519 AAAA-old", Fingerprint: "SHA256:old", RevokedAt: &seen, RevokedReason: &reason},
+	}, nil)
+
+	resp, err := http.Get(server.URL + "/internal/instances/default/c1/host-keys")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var keys []types.HostKey
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&keys))
+	require.Len(t, keys, 2)
+	assert.Nil(t, keys[0].RevokedAt)
+	assert.Equal(t, seen, keys[0].LastSeenAt)
+	assert.Equal(t, "SHA256:old", keys[1].Fingerprint)
+	assert.Equal(t, "rebuilt", keys[1].RevokedReason)
+}
@@ -0,0 +1,19 @@
+package internal_routes
+
+import (
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/gin-gonic/gin"
+)
+
+// GetIdentityJWKS serves the public keys instance identity documents are signed with,
+// including replaced keys still in their grace period.
+func (h Handler) GetIdentityJWKS(c *gin.Context) {
+	jwks, err := h.Identity.JWKS(c)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to load signing keys")
+		c.JSON(500, gin.H{"error": "Failed to load signing keys"})
+		return
+	}
+
+	c.JSON(200, jwks)
+}
@@ -0,0 +1,50 @@
+package internal_routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetIdentityJWKS_CreatesFirstKey(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	var created db.CreateSigningKeyParams
+	mockDB.On("ListSigningKeys", mock.Anything, mock.Anything).Return([]db.SigningKey{}, nil).Once()
+	mockDB.On("CreateSigningKey", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(1).(db.CreateSigningKeyParams)
+	}).Return(db.SigningKey{Kid: "new"}, nil).Once()
+
+	resp, err := http.Get(server.URL + "/internal/identity/jwks")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var jwks struct {
+		Keys []struct {
+			Kid string   `json:"kid"`
+			Kty string   `json:"kty"`
+			Alg string   `json:"alg"`
+			Use string   `json:"use"`
+			D   string   `json:"d"`
+			X5c []string `json:"x5c"`
+		} `json:"keys"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&jwks))
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "new", jwks.Keys[0].Kid)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+	assert.Empty(t, jwks.Keys[0].D, "private keys are never published")
+	assert.Len(t, jwks.Keys[0].X5c, 1)
+	assert.Greater(t, created.ExpiresAt, time.Now().Unix())
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,329 @@
+package internal_routes
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultLogLimit and maxLogLimit bound the entries returned per page.
+	defaultLogLimit = 100
+	maxLogLimit     = 1000
+	// logKeepAlive is how often idle log streams are kept alive.
+	logKeepAlive = 15 * time.Second
+	// logWriteTimeout bounds writing a single entry to a log stream.
+	logWriteTimeout = 10 * time.Second
+)
+
+// logPollInterval is how often followed logs are checked for new entries.
+var logPollInterval = time.Second
+
+var (
+	logTypes  = []string{"operation", "event", "console", "audit"}
+	logLevels = []string{"debug", "info", "warn", "error", "fatal"}
+)
+
+var logUpgrader = websocket.Upgrader{}
+
+// logQuery holds the filters of an instance log request.
+type logQuery struct {
+	instance db.Instance
+	logType  string
+	level    string
+	since    *int64
+	until    *int64
+	cursor   int64
+	limit    int64
+}
+
+// GetInstanceLogs lists the log entries of an instance, newest first, filtered by type, level
+// and time range and paginated with the returned cursor. With follow set, the latest entries
+// and every new one are streamed over Server-Sent Events, or over a WebSocket when the client
+// asks to upgrade the connection, so a boot can be followed as it happens.
+func (h Handler) GetInstanceLogs(c *gin.Context) {
+	query, ok := h.parseLogQuery(c)
+	if !ok {
+		return
+	}
+
+	if follow, _ := strconv.ParseBool(c.Query("follow")); follow {
+		if query.until != nil || query.cursor != 0 {
+			c.JSON(400, gin.H{"error": "until and cursor can't be used with follow"})
+			return
+		}
+
+		if websocket.IsWebSocketUpgrade(c.Request) {
+			h.followLogsWebSocket(c, query)
+			return
+		}
+
+		h.followLogsSSE(c, query)
+		return
+	}
+
+	entries, err := h.Database.ListInstanceLogs(c, db.ListInstanceLogsParams{
+		InstanceID: query.instance.ID,
+		LogType:    query.logType,
+		Level:      query.level,
+		Since:      query.since,
+		Until:      query.until,
+		BeforeID:   query.cursor,
+		// Fetch one more entry to know whether there is a next page
+		Limit: query.limit + 1,
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list instance logs")
+		c.JSON(500, gin.H{"error": "Failed to list instance logs"})
+		return
+	}
+
+	page := types.InstanceLogs{Logs: []types.InstanceLog{}}
+	if int64(len(entries)) > query.limit {
+		entries = entries[:query.limit]
+		page.NextCursor = strconv.FormatInt(entries[len(entries)-1].ID, 10)
+	}
+
+	for _, entry := range entries {
+		page.Logs = append(page.Logs, toInstanceLog(entry))
+	}
+
+	c.JSON(200, page)
+}
+
+// parseLogQuery validates the filters of a log request and looks up the instance,
+// answering the request itself when they are invalid.
+func (h Handler) parseLogQuery(c *gin.Context) (logQuery, bool) {
+	project, name := c.Param("project"), c.Param("name")
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return logQuery{}, false
+	}
+
+	query := logQuery{logType: c.Query("type"), level: c.Query("level"), limit: defaultLogLimit}
+	if query.logType != "" && !slices.Contains(logTypes, query.logType) {
+		c.JSON(400, gin.H{"error": "Invalid log type, must be one of operation, event, console or audit"})
+		return logQuery{}, false
+	}
+
+	if query.level != "" && !slices.Contains(logLevels, query.level) {
+		c.JSON(400, gin.H{"error": "Invalid log level, must be one of debug, info, warn, error or fatal"})
+		return logQuery{}, false
+	}
+
+	for param, target := range map[string]**int64{"since": &query.since, "until": &query.until} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid " + param + ", must be an RFC 3339 time"})
+			return logQuery{}, false
+		}
+
+		unix := t.Unix()
+		*target = &unix
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxLogLimit {
+			c.JSON(400, gin.H{"error": "Invalid limit, must be between 1 and " + strconv.Itoa(maxLogLimit)})
+			return logQuery{}, false
+		}
+
+		query.limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || cursor < 1 {
+			c.JSON(400, gin.H{"error": "Invalid cursor"})
+			return logQuery{}, false
+		}
+
+		query.cursor = cursor
+	}
+
+	remote := c.DefaultQuery("remote", h.Config.Incus.Name)
+	instance, err := h.Database.GetInstance(c, db.GetInstanceParams{Remote: remote, Name: name, Project: project})
+	if err == sql.ErrNoRows {
+		c.JSON(404, gin.H{"error": "Instance not found"})
+		return logQuery{}, false
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve instance")
+		c.JSON(500, gin.H{"error": "Failed to retrieve instance"})
+		return logQuery{}, false
+	}
+
+	query.instance = instance
+	return query, true
+}
+
+// followLogsSSE streams log entries as Server-Sent Events. Each event carries the entry ID,
+// so a reconnecting client resumes after the last entry it received with Last-Event-ID.
+func (h Handler) followLogsSSE(c *gin.Context, query logQuery) {
+	var after int64
+	if value := c.GetHeader("Last-Event-ID"); value != "" {
+		after, _ = strconv.ParseInt(value, 10, 64)
+	}
+
+	// The stream outlives the write timeout of the listener
+	controller := http.NewResponseController(c.Writer)
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(200)
+	c.Writer.Flush()
+
+	send := func(entry *types.InstanceLog) error {
+		_ = controller.SetWriteDeadline(time.Now().Add(logWriteTimeout))
+		defer controller.SetWriteDeadline(time.Time{})
+
+		if entry == nil {
+			_, err := c.Writer.WriteString(": keep-alive\n\n")
+			c.Writer.Flush()
+			return err
+		}
+
+		c.Render(-1, sse.Event{Event: "log", Id: strconv.FormatInt(entry.ID, 10), Data: entry})
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	}
+
+	if err := h.followLogs(c.Request.Context(), query, after, send); err != nil && c.Request.Context().Err() == nil {
+		logs.FromContext(c).Warn().Err(err).Msg("Instance log stream ended")
+	}
+}
+
+// followLogsWebSocket streams log entries as JSON WebSocket messages until the client closes the connection.
+func (h Handler) followLogsWebSocket(c *gin.Context, query logQuery) {
+	conn, err := logUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader already answered the request
+		logs.FromContext(c).Warn().Err(err).Msg("Failed to upgrade instance log stream")
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// Clients only send control messages, reading handles them and notices when the client goes away
+	_ = conn.SetReadDeadline(time.Time{})
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(entry *types.InstanceLog) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(logWriteTimeout))
+		if entry == nil {
+			return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(logWriteTimeout))
+		}
+
+		return conn.WriteJSON(entry)
+	}
+
+	if err := h.followLogs(ctx, query, 0, send); err != nil && ctx.Err() == nil {
+		logs.FromContext(c).Warn().Err(err).Msg("Instance log stream ended")
+	}
+
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+}
+
+// followLogs sends the latest entries matching the query, or those after the given entry ID,
+// and then every new entry until ctx is done or sending fails. send is called with nil to keep
+// idle streams alive.
+func (h Handler) followLogs(ctx context.Context, query logQuery, after int64, send func(*types.InstanceLog) error) error {
+	if after == 0 {
+		latest, err := h.Database.ListInstanceLogs(ctx, db.ListInstanceLogsParams{
+			InstanceID: query.instance.ID,
+			LogType:    query.logType,
+			Level:      query.level,
+			Since:      query.since,
+			Limit:      query.limit,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Entries are sent oldest first, like tail -f
+		for i := len(latest) - 1; i >= 0; i-- {
+			entry := toInstanceLog(latest[i])
+			if err := send(&entry); err != nil {
+				return err
+			}
+			after = entry.ID
+		}
+	}
+
+	poll := time.NewTicker(logPollInterval)
+	defer poll.Stop()
+
+	lastSent := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+		}
+
+		entries, err := h.Database.ListInstanceLogsAfter(ctx, db.ListInstanceLogsAfterParams{
+			InstanceID: query.instance.ID,
+			LogType:    query.logType,
+			Level:      query.level,
+			AfterID:    after,
+			Limit:      maxLogLimit,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range entries {
+			entry := toInstanceLog(row)
+			if err := send(&entry); err != nil {
+				return err
+			}
+			after, lastSent = entry.ID, time.Now()
+		}
+
+		if time.Since(lastSent) >= logKeepAlive {
+			if err := send(nil); err != nil {
+				return err
+			}
+			lastSent = time.Now()
+		}
+	}
+}
+
+func toInstanceLog(row db.InstanceLog) types.InstanceLog {
+	entry := types.InstanceLog{ID: row.ID, Type: row.LogType, Level: row.Level, Message: row.Message}
+	if row.CreatedAt != nil {
+		entry.CreatedAt = *row.CreatedAt
+	}
+
+	return entry
+}
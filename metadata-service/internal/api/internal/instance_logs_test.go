@@ -0,0 +1,172 @@
+package internal_routes
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	localtls "github.com/lxc/incus/shared/tls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var testInstance = db.Instance{ID: 7, Name: "c1", Project: "default", Remote: "local"}
+
+// setupLogsServer serves the admin routes to a reader restricted to the default project
+func setupLogsServer(t *testing.T, mockDB *mocks.MockQuerier) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	cert := testClientCertificate(t)
+
+	router := gin.New()
+	RegisterInternalRoutes(router, &config.Config{Incus: &config.IncusConfig{Name: "local"}}, mockDB, &trust.Store{Database: mockDB}, identity.NewKeyring(mockDB, &config.IdentityConfig{KeyRotation: time.Hour}, nil), nil)
+
+	mockDB.On("GetCertificate", mock.Anything, localtls.CertFingerprint(cert)).Return(db.Certificate{
+		Name:       "reader",
+		Role:       "reader",
+		Restricted: true,
+		Projects:   []byte(`["default"]`),
+	}, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func logEntry(id int64, level string, message string) db.InstanceLog {
+	createdAt := time.Unix(1700000000+id, 0).UTC()
+	return db.InstanceLog{ID: id, InstanceID: 7, LogType: "event", Level: level, Message: message, CreatedAt: &createdAt}
+}
+
+func TestGetInstanceLogs_FiltersAndPaginates(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	since, until := int64(1700000000), int64(1700003600)
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(testInstance, nil)
+	mockDB.On("ListInstanceLogs", mock.Anything, db.ListInstanceLogsParams{
+		InstanceID: 7,
+		LogType:    "event",
+		Level:      "error",
+		Since:      &since,
+		Until:      &until,
+		BeforeID:   40,
+		Limit:      3,
+	}).Return([]db.InstanceLog{logEntry(30, "error", "c"), logEntry(20, "error", "b"), logEntry(10, "error", "a")}, nil)
+
+	resp, err := http.Get(server.URL + "/internal/instances/default/c1/logs?type=event&level=error&since=2023-11-14T22:13:20Z&until=2023-11-14T23:13:20Z&limit=2&cursor=40")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var page types.InstanceLogs
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	require.Len(t, page.Logs, 2)
+	assert.Equal(t, int64(30), page.Logs[0].ID)
+	assert.Equal(t, "c", page.Logs[0].Message)
+	assert.Equal(t, "20", page.NextCursor, "the cursor points at the oldest entry returned")
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetInstanceLogs_RejectsInvalidRequests(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	tests := map[string]int{
+		"/internal/instances/other/c1/logs":                     http.StatusForbidden,
+		"/internal/instances/default/c1/logs?type=kernel":       http.StatusBadRequest,
+		"/internal/instances/default/c1/logs?level=verbose":     http.StatusBadRequest,
+		"/internal/instances/default/c1/logs?since=today":       http.StatusBadRequest,
+		"/internal/instances/default/c1/logs?limit=5000":        http.StatusBadRequest,
+		"/internal/instances/default/c1/logs?cursor=abc":        http.StatusBadRequest,
+		"/internal/instances/default/c1/logs?follow=1&cursor=5": http.StatusBadRequest,
+	}
+
+	mockDB.On("GetInstance", mock.Anything, mock.Anything).Return(testInstance, nil).Maybe()
+
+	for path, status := range tests {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+
+	mockDB.AssertNotCalled(t, "ListInstanceLogs", mock.Anything, mock.Anything)
+}
+
+func TestGetInstanceLogs_FollowSSE(t *testing.T) {
+	logPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { logPollInterval = time.Second })
+
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	mockDB.On("GetInstance", mock.Anything, mock.Anything).Return(testInstance, nil)
+	mockDB.On("ListInstanceLogs", mock.Anything, mock.Anything).Return([]db.InstanceLog{logEntry(2, "info", "b"), logEntry(1, "info", "a")}, nil)
+	mockDB.On("ListInstanceLogsAfter", mock.Anything, mock.MatchedBy(func(arg db.ListInstanceLogsAfterParams) bool { return arg.AfterID == 2 })).
+		Return([]db.InstanceLog{logEntry(3, "error", "c")}, nil).Once()
+	mockDB.On("ListInstanceLogsAfter", mock.Anything, mock.Anything).Return([]db.InstanceLog(nil), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", server.URL+"/internal/instances/default/c1/logs?follow=true", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var ids []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(ids) < 3 && scanner.Scan() {
+		if id, ok := strings.CutPrefix(scanner.Text(), "id:"); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	assert.Equal(t, []string{"1", "2", "3"}, ids, "the latest entries are sent oldest first, then new ones")
+}
+
+func TestGetInstanceLogs_FollowWebSocket(t *testing.T) {
+	logPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { logPollInterval = time.Second })
+
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	mockDB.On("GetInstance", mock.Anything, mock.Anything).Return(testInstance, nil)
+	mockDB.On("ListInstanceLogs", mock.Anything, mock.Anything).Return([]db.InstanceLog{logEntry(1, "info", "a")}, nil)
+	mockDB.On("ListInstanceLogsAfter", mock.Anything, mock.MatchedBy(func(arg db.ListInstanceLogsAfterParams) bool { return arg.AfterID == 1 })).
+		Return([]db.InstanceLog{logEntry(2, "error", "b")}, nil).Once()
+	mockDB.On("ListInstanceLogsAfter", mock.Anything, mock.Anything).Return([]db.InstanceLog(nil), nil)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/internal/instances/default/c1/logs?follow=true", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, expected := range []string{"a", "b"} {
+		var entry types.InstanceLog
+		require.NoError(t, conn.ReadJSON(&entry))
+		assert.Equal(t, expected, entry.Message)
+	}
+}
@@ -0,0 +1,134 @@
+package internal_routes
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// maxStatusWait bounds how long a status request waits for instances.
+const maxStatusWait = time.Hour
+
+// statusPollInterval is how often a status wait checks the reported statuses.
+var statusPollInterval = time.Second
+
+// GetInstanceStatuses lists the instances of a project that reported a status, config-done by
+// default. With count and timeout set, the request waits until count instances reported it, so
+// a rollout can proceed once its instances are configured. Only reports made since the given
+// time are considered, to ignore those of a previous rollout. A wait that times out still answers
+// 200, with done false and the instances that reported so far: the request itself succeeded.
+func (h Handler) GetInstanceStatuses(c *gin.Context) {
+	project := c.DefaultQuery("project", "default")
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return
+	}
+
+	status := c.DefaultQuery("status", types.StatusConfigDone)
+	switch status {
+	case types.StatusBooting, types.StatusConfigDone, types.StatusFailed:
+	default:
+		c.JSON(400, gin.H{"error": "Invalid status, must be one of booting, config-done or failed"})
+		return
+	}
+
+	params := db.ListInstanceStatesParams{
+		Remote:  c.DefaultQuery("remote", h.Config.Incus.Name),
+		Project: project,
+	}
+
+	if value := c.Query("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid since, must be an RFC 3339 time"})
+			return
+		}
+
+		unix := since.Unix()
+		params.Since = &unix
+	}
+
+	var count int
+	if value := c.Query("count"); value != "" {
+		var err error
+		count, err = strconv.Atoi(value)
+		if err != nil || count < 1 {
+			c.JSON(400, gin.H{"error": "Invalid count, must be a positive number"})
+			return
+		}
+	}
+
+	var timeout time.Duration
+	if value := c.Query("timeout"); value != "" {
+		var err error
+		timeout, err = time.ParseDuration(value)
+		if err != nil || timeout < 0 || timeout > maxStatusWait {
+			c.JSON(400, gin.H{"error": "Invalid timeout, must be a duration of at most " + maxStatusWait.String()})
+			return
+		}
+	}
+
+	if timeout > 0 {
+		// The wait outlives the write timeout of the listener
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout + logWriteTimeout))
+	}
+
+	ctx := c.Request.Context()
+	deadline := time.Now().Add(timeout)
+
+	poll := time.NewTicker(statusPollInterval)
+	defer poll.Stop()
+
+	for {
+		rows, err := h.Database.ListInstanceStates(ctx, params)
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to list instance statuses")
+			c.JSON(500, gin.H{"error": "Failed to list instance statuses"})
+			return
+		}
+
+		statuses := toInstanceStatuses(rows, status)
+		statuses.Done = count == 0 || statuses.Count >= count
+
+		if statuses.Done || !time.Now().Before(deadline) {
+			c.JSON(200, statuses)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		}
+	}
+}
+
+func toInstanceStatuses(rows []db.ListInstanceStatesRow, status string) types.InstanceStatuses {
+	statuses := types.InstanceStatuses{Instances: []types.InstanceStatus{}, Failed: []types.InstanceStatus{}}
+
+	for _, row := range rows {
+		instance := types.InstanceStatus{Name: row.Name, Project: row.Project, Status: row.Status}
+		if row.UpdatedAt != nil {
+			instance.UpdatedAt = *row.UpdatedAt
+		}
+
+		if row.Status == status {
+			statuses.Instances = append(statuses.Instances, instance)
+		}
+
+		if row.Status == types.StatusFailed && status != types.StatusFailed {
+			statuses.Failed = append(statuses.Failed, instance)
+		}
+	}
+
+	statuses.Count = len(statuses.Instances)
+	return statuses
+}
@@ -0,0 +1,113 @@
+package internal_routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func stateRow(name string, status string) db.ListInstanceStatesRow {
+	updatedAt := time.Unix(1700000000, 0).UTC()
+	return db.ListInstanceStatesRow{Name: name, Project: "default", Status: status, UpdatedAt: &updatedAt}
+}
+
+func getStatuses(t *testing.T, url string) (int, types.InstanceStatuses) {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var statuses types.InstanceStatuses
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&statuses))
+
+	return resp.StatusCode, statuses
+}
+
+func TestGetInstanceStatuses_WaitsForCount(t *testing.T) {
+	statusPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { statusPollInterval = time.Second })
+
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	since := int64(1700000000)
+	params := db.ListInstanceStatesParams{Remote: "local", Project: "default", Since: &since}
+	mockDB.On("ListInstanceStates", mock.Anything, params).Return([]db.ListInstanceStatesRow{
+		stateRow("c1", "config-done"), stateRow("c2", "booting"), stateRow("c3", "failed"),
+	}, nil).Twice()
+	mockDB.On("ListInstanceStates", mock.Anything, params).Return([]db.ListInstanceStatesRow{
+		stateRow("c1", "config-done"), stateRow("c2", "config-done"), stateRow("c3", "failed"),
+	}, nil)
+
+	code, statuses := getStatuses(t, server.URL+"/internal/status?count=2&timeout=5s&since=2023-11-14T22:13:20Z")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, statuses.Done)
+	assert.Equal(t, 2, statuses.Count)
+	require.Len(t, statuses.Instances, 2)
+	assert.Equal(t, "c2", statuses.Instances[1].Name)
+	require.Len(t, statuses.Failed, 1)
+	assert.Equal(t, "c3", statuses.Failed[0].Name)
+	mockDB.AssertNumberOfCalls(t, "ListInstanceStates", 3)
+}
+
+func TestGetInstanceStatuses_TimesOut(t *testing.T) {
+	statusPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { statusPollInterval = time.Second })
+
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	mockDB.On("ListInstanceStates", mock.Anything, db.ListInstanceStatesParams{Remote: "local", Project: "default"}).
+		Return([]db.ListInstanceStatesRow{stateRow("c1", "booting")}, nil)
+
+	code, statuses := getStatuses(t, server.URL+"/internal/status?count=1&timeout=50ms")
+
+	// The wait timing out isn't an error of the request, done tells the caller the count wasn't reached
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, statuses.Done)
+	assert.Empty(t, statuses.Instances)
+}
+
+func TestGetInstanceStatuses_ListsWithoutWaiting(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	mockDB.On("ListInstanceStates", mock.Anything, db.ListInstanceStatesParams{Remote: "local", Project: "default"}).
+		Return([]db.ListInstanceStatesRow{stateRow("c1", "booting"), stateRow("c2", "config-done")}, nil).Once()
+
+	code, statuses := getStatuses(t, server.URL+"/internal/status?status=booting")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, statuses.Done)
+	require.Len(t, statuses.Instances, 1)
+	assert.Equal(t, "c1", statuses.Instances[0].Name)
+}
+
+func TestGetInstanceStatuses_RejectsInvalidRequests(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	for query, code := range map[string]int{
+		"?project=other":  http.StatusForbidden,
+		"?status=running": http.StatusBadRequest,
+		"?count=0":        http.StatusBadRequest,
+		"?timeout=2h":     http.StatusBadRequest,
+		"?since=today":    http.StatusBadRequest,
+	} {
+		resp, err := http.Get(server.URL + "/internal/status" + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, code, resp.StatusCode, query)
+	}
+
+	mockDB.AssertNotCalled(t, "ListInstanceStates", mock.Anything, mock.Anything)
+}
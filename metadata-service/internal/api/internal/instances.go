@@ -0,0 +1,86 @@
+package internal_routes
+
+import (
+	"strings"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// tagFilter selects instances with a tag, of any value unless one is given.
+type tagFilter struct {
+	key      string
+	value    string
+	anyValue bool
+}
+
+func (f tagFilter) matches(tags map[string]string) bool {
+	value, ok := tags[f.key]
+	return ok && (f.anyValue || value == f.value)
+}
+
+// ListInstances lists the cached instances of a project with their tags. Each tag query
+// parameter, key=value or key for any value, narrows the list down to instances having it.
+func (h Handler) ListInstances(c *gin.Context) {
+	project := c.DefaultQuery("project", "default")
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return
+	}
+
+	var filters []tagFilter
+	for _, tag := range c.QueryArray("tag") {
+		key, value, hasValue := strings.Cut(tag, "=")
+		if key == "" {
+			c.JSON(400, gin.H{"error": "Invalid tag " + tag + ": must be key=value or key"})
+			return
+		}
+
+		filters = append(filters, tagFilter{key: key, value: value, anyValue: !hasValue})
+	}
+
+	remote := c.DefaultQuery("remote", h.Config.Incus.Name)
+	rows, err := h.Database.ListProjectInstanceTags(c, db.ListProjectInstanceTagsParams{Remote: remote, Project: project})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list instance tags")
+		c.JSON(500, gin.H{"error": "Failed to list instances"})
+		return
+	}
+
+	// Rows are ordered by instance, with a row per tag or a single one without a tag
+	instances := []types.Instance{}
+	var current *types.Instance
+	var currentID int64
+	for _, row := range rows {
+		if current == nil || row.InstanceID != currentID {
+			instances = append(instances, types.Instance{Name: row.Name, Project: project, Remote: remote, Tags: map[string]string{}})
+			current, currentID = &instances[len(instances)-1], row.InstanceID
+			if row.Uuid != nil {
+				current.UUID = *row.Uuid
+			}
+		}
+
+		if row.Key != nil && row.Value != nil {
+			current.Tags[*row.Key] = *row.Value
+		}
+	}
+
+	matching := []types.Instance{}
+	for _, instance := range instances {
+		matches := true
+		for _, filter := range filters {
+			matches = matches && filter.matches(instance.Tags)
+		}
+
+		if matches {
+			matching = append(matching, instance)
+		}
+	}
+
+	c.JSON(200, gin.H{"data": matching})
+}
@@ -0,0 +1,100 @@
+package internal_routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func mockInstanceTags(mockDB *mocks.MockQuerier) {
+	tag := func(s string) *string { return &s }
+	uuid := "2c1a6f3e-0b1e-4f5e-9a57-3c2f0d1e4b6a"
+
+	mockDB.On("ListProjectInstanceTags", mock.Anything, db.ListProjectInstanceTagsParams{Remote: "local", Project: "default"}).Return([]db.ListProjectInstanceTagsRow{
+		{InstanceID: 1, Name: "c1", Uuid: &uuid, Key: tag("env"), Value: tag("prod")},
+		{InstanceID: 1, Name: "c1", Uuid: &uuid, Key: tag("team"), Value: tag("payments")},
+		{InstanceID: 2, Name: "c2", Key: tag("env"), Value: tag("staging")},
+		{InstanceID: 3, Name: "c3"},
+	}, nil)
+}
+
+func listInstances(t *testing.T, url string) []types.Instance {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data []types.Instance `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body.Data
+}
+
+func TestListInstances_IncludesTags(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+	mockInstanceTags(mockDB)
+
+	instances := listInstances(t, server.URL+"/internal/instances")
+	require.Len(t, instances, 3)
+	assert.Equal(t, types.Instance{
+		Name:    "c1",
+		Project: "default",
+		Remote:  "local",
+		UUID:    "2c1a6f3e-0b1e-4f5e-9a57-3c2f0d1e4b6a",
+		Tags:    map[string]string{"env": "prod", "team": "payments"},
+	}, instances[0])
+	assert.Equal(t, map[string]string{"env": "staging"}, instances[1].Tags)
+	assert.Empty(t, instances[2].Tags)
+}
+
+func TestListInstances_FiltersByTag(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+	mockInstanceTags(mockDB)
+
+	names := func(instances []types.Instance) []string {
+		names := []string{}
+		for _, instance := range instances {
+			names = append(names, instance.Name)
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"c1", "c2"}, names(listInstances(t, server.URL+"/internal/instances?tag=env")))
+	assert.Equal(t, []string{"c2"}, names(listInstances(t, server.URL+"/internal/instances?tag=env=staging")))
+	assert.Equal(t, []string{"c1"}, names(listInstances(t, server.URL+"/internal/instances?tag=env&tag=team=payments")))
+	assert.Equal(t, []string{}, names(listInstances(t, server.URL+"/internal/instances?tag=team=billing")))
+}
+
+func TestListInstances_RejectsInvalidTag(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	resp, err := http.Get(server.URL + "/internal/instances?tag==prod")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	mockDB.AssertNotCalled(t, "ListProjectInstanceTags", mock.Anything, mock.Anything)
+}
+
+func TestListInstances_ChecksProjectAccess(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupLogsServer(t, mockDB)
+
+	resp, err := http.Get(server.URL + "/internal/instances?project=other")
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	mockDB.AssertNotCalled(t, "ListProjectInstanceTags", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,141 @@
+package internal_routes
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// GetInstancePassword returns the password generated for an instance, encrypted to the public
+// key of the operators, in the manner of EC2's get-password-data. The encrypted password is
+// only returned once, later requests show who retrieved it.
+func (h Handler) GetInstancePassword(c *gin.Context) {
+	instance, ok := h.lookupInstance(c)
+	if !ok {
+		return
+	}
+
+	row, err := h.Database.GetInstancePassword(c, instance.ID)
+	if err == sql.ErrNoRows {
+		c.JSON(404, gin.H{"error": "No password was generated for this instance"})
+		return
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve password")
+		c.JSON(500, gin.H{"error": "Failed to retrieve password"})
+		return
+	}
+
+	password := types.InstancePassword{
+		Instance:       instance.Name,
+		Project:        instance.Project,
+		KeyFingerprint: row.KeyFingerprint,
+		RetrievedAt:    row.RetrievedAt,
+	}
+
+	if row.CreatedAt != nil {
+		password.CreatedAt = *row.CreatedAt
+	}
+
+	if row.RetrievedBy != nil {
+		password.RetrievedBy = *row.RetrievedBy
+	}
+
+	if row.RetrievedAt != nil {
+		c.JSON(410, gin.H{"error": "Password already retrieved, reset it to generate a new one", "data": password})
+		return
+	}
+
+	identity := trust.IdentityFromContext(c)
+
+	// The password must not leave without a trace, so the retrieval is audited first
+	_, err = h.Database.CreateInstanceLog(c, db.CreateInstanceLogParams{
+		InstanceID: instance.ID,
+		LogType:    "audit",
+		Level:      "info",
+		Message:    "Password retrieved by " + identity.Name,
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to audit password retrieval")
+		c.JSON(500, gin.H{"error": "Failed to audit password retrieval"})
+		return
+	}
+
+	// Claiming the password is atomic, so concurrent requests can't both retrieve it
+	claimed, err := h.Database.ClaimInstancePassword(c, db.ClaimInstancePasswordParams{RetrievedBy: &identity.Name, ID: row.ID})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to claim password")
+		c.JSON(500, gin.H{"error": "Failed to retrieve password"})
+		return
+	}
+
+	if claimed == 0 {
+		c.JSON(410, gin.H{"error": "Password already retrieved, reset it to generate a new one"})
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	password.EncryptedPassword = row.EncryptedPassword
+	password.RetrievedAt = &now
+	password.RetrievedBy = identity.Name
+
+	logs.FromContext(c).Info().Str("instance", instance.Name).Str("project", instance.Project).Str("retrieved_by", identity.Name).Msg("Password retrieved")
+	c.JSON(200, gin.H{"data": password})
+}
+
+// DeleteInstancePassword forgets the password of an instance, so a new one is generated the
+// next time the instance fetches its user-data.
+func (h Handler) DeleteInstancePassword(c *gin.Context) {
+	instance, ok := h.lookupInstance(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.Database.DeleteInstancePassword(c, instance.ID)
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to reset password")
+		c.JSON(500, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if deleted == 0 {
+		c.JSON(404, gin.H{"error": "No password was generated for this instance"})
+		return
+	}
+
+	logs.FromContext(c).Info().Str("instance", instance.Name).Str("project", instance.Project).Str("reset_by", trust.IdentityFromContext(c).Name).Msg("Password reset")
+	c.JSON(200, gin.H{"message": "Password reset, a new one is generated on the next user-data request"})
+}
+
+// lookupInstance loads the instance named by the project and name of the path, checking the
+// caller can access its project, and answers the request itself otherwise.
+func (h Handler) lookupInstance(c *gin.Context) (db.Instance, bool) {
+	project, name := c.Param("project"), c.Param("name")
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return db.Instance{}, false
+	}
+
+	remote := c.DefaultQuery("remote", h.Config.Incus.Name)
+	instance, err := h.Database.GetInstance(c, db.GetInstanceParams{Remote: remote, Name: name, Project: project})
+	if err == sql.ErrNoRows {
+		c.JSON(404, gin.H{"error": "Instance not found"})
+		return db.Instance{}, false
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve instance")
+		c.JSON(500, gin.H{"error": "Failed to retrieve instance"})
+		return db.Instance{}, false
+	}
+
+	return instance, true
+}
@@ -0,0 +1,115 @@
+package internal_routes
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetInstancePassword_ReturnsEncryptedPasswordOnce(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+
+	createdAt := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(testInstance, nil)
+	mockDB.On("GetInstancePassword", mock.Anything, int64(7)).Return(db.InstancePassword{
+		ID:                3,
+		InstanceID:        7,
+		KeyFingerprint:    "0f1e",
+		EncryptedPassword: []byte("encrypted"),
+		CreatedAt:         &createdAt,
+	}, nil).Once()
+	audit := mockDB.On("CreateInstanceLog", mock.Anything, db.CreateInstanceLogParams{
+		InstanceID: 7,
+		LogType:    "audit",
+		Level:      "info",
+		Message:    "Password retrieved by operator",
+	}).Return(db.InstanceLog{}, nil).Once()
+	retrievedBy := "operator"
+	mockDB.On("ClaimInstancePassword", mock.Anything, db.ClaimInstancePasswordParams{RetrievedBy: &retrievedBy, ID: 3}).Return(int64(1), nil).Once().NotBefore(audit)
+
+	resp := sendJSON(t, http.MethodGet, server.URL+"/internal/instances/default/c1/password", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data types.InstancePassword `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, []byte("encrypted"), body.Data.EncryptedPassword)
+	assert.Equal(t, "0f1e", body.Data.KeyFingerprint)
+	assert.Equal(t, "operator", body.Data.RetrievedBy)
+
+	// Once retrieved, only who retrieved it is shown
+	retrievedAt := createdAt.Add(time.Hour)
+	mockDB.On("GetInstancePassword", mock.Anything, int64(7)).Return(db.InstancePassword{
+		ID:             3,
+		InstanceID:     7,
+		KeyFingerprint: "0f1e",
+		CreatedAt:      &createdAt,
+		RetrievedAt:    &retrievedAt,
+		RetrievedBy:    &retrievedBy,
+	}, nil).Once()
+
+	resp = sendJSON(t, http.MethodGet, server.URL+"/internal/instances/default/c1/password", nil)
+	require.Equal(t, http.StatusGone, resp.StatusCode)
+
+	var gone struct {
+		Data types.InstancePassword `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&gone))
+	assert.Empty(t, gone.Data.EncryptedPassword)
+	assert.Equal(t, "operator", gone.Data.RetrievedBy)
+	mockDB.AssertExpectations(t)
+}
+
+func TestGetInstancePassword_LostClaim(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(testInstance, nil)
+	mockDB.On("GetInstancePassword", mock.Anything, int64(7)).Return(db.InstancePassword{ID: 3, InstanceID: 7, EncryptedPassword: []byte("encrypted")}, nil)
+	mockDB.On("CreateInstanceLog", mock.Anything, mock.Anything).Return(db.InstanceLog{}, nil)
+	mockDB.On("ClaimInstancePassword", mock.Anything, mock.Anything).Return(int64(0), nil)
+
+	resp := sendJSON(t, http.MethodGet, server.URL+"/internal/instances/default/c1/password", nil)
+	assert.Equal(t, http.StatusGone, resp.StatusCode, "a concurrent request retrieved the password first")
+}
+
+func TestInstancePassword_NotFoundAndForbidden(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(testInstance, nil)
+	mockDB.On("GetInstancePassword", mock.Anything, int64(7)).Return(db.InstancePassword{}, sql.ErrNoRows)
+	mockDB.On("DeleteInstancePassword", mock.Anything, int64(7)).Return(int64(0), nil)
+
+	resp := sendJSON(t, http.MethodGet, server.URL+"/internal/instances/default/c1/password", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = sendJSON(t, http.MethodDelete, server.URL+"/internal/instances/default/c1/password", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = sendJSON(t, http.MethodGet, server.URL+"/internal/instances/other/c1/password", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestDeleteInstancePassword_Resets(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(testInstance, nil)
+	mockDB.On("DeleteInstancePassword", mock.Anything, int64(7)).Return(int64(1), nil).Once()
+
+	resp := sendJSON(t, http.MethodDelete, server.URL+"/internal/instances/default/c1/password", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockDB.AssertExpectations(t)
+}
@@ -2,19 +2,79 @@ package internal_routes
 
 import (
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterInternalRoutes(router *gin.Engine, cfg *config.Config, db db.Querier) {
+// RegisterInternalRoutes registers the admin API routes. Every route is authenticated
+// against the trust store using the client certificate of the mutual TLS connection.
+func RegisterInternalRoutes(router gin.IRouter, cfg *config.Config, db db.Querier, store *trust.Store, keyring *identity.Keyring, envelope *secrets.Envelope) {
 	// Register internal routes here
 
 	handler := Handler{
 		Config:   cfg,
 		Database: db,
+		Trust:    store,
+		Identity: keyring,
+		Secrets:  envelope,
 	}
 
-	router.PUT("/internal/vendor/:vendor_name/data", handler.UpdateVendorData)
-	router.GET("/internal/vendor/:vendor_name/data", handler.GetVendorData)
-	router.POST("/internal/vendor", handler.CreateVendorData)
+	internalGroup := router.Group("/internal", trust.Authenticate(store))
+
+	reader := trust.RequireRole(trust.RoleReader)
+	operator := trust.RequireRole(trust.RoleOperator)
+	admin := trust.RequireRole(trust.RoleAdmin)
+
+	internalGroup.PUT("/vendor/:vendor_name/data", operator, handler.UpdateVendorData)
+	internalGroup.GET("/vendor/:vendor_name/data", reader, handler.GetVendorData)
+	internalGroup.POST("/vendor", operator, handler.CreateVendorData)
+
+	// Cached instances of a project with their tags, optionally selected by tag
+	internalGroup.GET("/instances", reader, handler.ListInstances)
+
+	// Instance logs, e.g. cloud-init events, optionally followed as they are written
+	internalGroup.GET("/instances/:project/:name/logs", reader, handler.GetInstanceLogs)
+
+	// Configuration status reported by instances, optionally waiting for a number of them
+	internalGroup.GET("/status", reader, handler.GetInstanceStatuses)
+
+	// SSH host keys published by instances, as history or as a known_hosts file
+	internalGroup.GET("/instances/:project/:name/host-keys", reader, handler.GetInstanceHostKeys)
+	internalGroup.GET("/known_hosts", reader, handler.GetKnownHosts)
+
+	// SSH keys authorized on the instances of a project, profile or instance
+	internalGroup.GET("/ssh-keys", reader, handler.ListSSHKeys)
+	internalGroup.POST("/ssh-keys", operator, handler.CreateSSHKey)
+	internalGroup.GET("/ssh-keys/:id", reader, handler.GetSSHKey)
+	internalGroup.PUT("/ssh-keys/:id", operator, handler.UpdateSSHKey)
+	internalGroup.DELETE("/ssh-keys/:id", operator, handler.DeleteSSHKey)
+
+	// Short-lived SSH keys pushed to an instance for just-in-time access
+	internalGroup.POST("/instances/:project/:name/ssh-keys", operator, handler.PushEphemeralSSHKey)
+
+	// Passwords generated for instances, encrypted to the operators' public key and returned once
+	internalGroup.GET("/instances/:project/:name/password", operator, handler.GetInstancePassword)
+	internalGroup.DELETE("/instances/:project/:name/password", operator, handler.DeleteInstancePassword)
+
+	// Public keys of instance identity documents, for services verifying them offline
+	internalGroup.GET("/identity/jwks", reader, handler.GetIdentityJWKS)
+
+	// Secrets delivered to the instances of a project, profile or instance. Values are write-only
+	internalGroup.GET("/secrets", reader, handler.ListSecrets)
+	internalGroup.POST("/secrets", operator, handler.CreateSecret)
+	internalGroup.GET("/secrets/:id", reader, handler.GetSecret)
+	internalGroup.PUT("/secrets/:id", operator, handler.UpdateSecret)
+	internalGroup.DELETE("/secrets/:id", operator, handler.DeleteSecret)
+
+	// Trust store management, similar to `incus config trust`.
+	// Adding a certificate is also reachable by untrusted clients holding a join token.
+	internalGroup.GET("/certificates", admin, handler.ListCertificates)
+	internalGroup.POST("/certificates", handler.CreateCertificate)
+	internalGroup.GET("/certificates/:fingerprint", admin, handler.GetCertificate)
+	internalGroup.PUT("/certificates/:fingerprint", admin, handler.UpdateCertificate)
+	internalGroup.DELETE("/certificates/:fingerprint", admin, handler.DeleteCertificate)
+	internalGroup.POST("/certificates/tokens", admin, handler.CreateCertificateToken)
 }
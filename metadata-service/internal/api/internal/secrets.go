@@ -0,0 +1,278 @@
+package internal_routes
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+)
+
+// maxSecretSize bounds the value of a secret.
+const maxSecretSize = 64 << 10
+
+// secretNamePattern matches secret names, which are used as the last element of guest paths.
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+
+func toSecret(row db.Secret) types.Secret {
+	secret := types.Secret{
+		ID:        row.ID,
+		Name:      row.Name,
+		Project:   row.Project,
+		Remote:    row.Remote,
+		Scope:     row.Scope,
+		Target:    row.Target,
+		OneTime:   row.OneTime,
+		CreatedBy: row.CreatedBy,
+	}
+
+	if row.CreatedAt != nil {
+		secret.CreatedAt = *row.CreatedAt
+	}
+
+	if row.UpdatedAt != nil {
+		secret.UpdatedAt = *row.UpdatedAt
+	}
+
+	return secret
+}
+
+// ListSecrets lists the secrets of a project, without their values.
+func (h Handler) ListSecrets(c *gin.Context) {
+	project := c.DefaultQuery("project", "default")
+	remote := c.DefaultQuery("remote", h.Config.Incus.Name)
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return
+	}
+
+	rows, err := h.Database.ListSecrets(c, db.ListSecretsParams{Remote: remote, Project: project})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list secrets")
+		c.JSON(500, gin.H{"error": "Failed to list secrets"})
+		return
+	}
+
+	list := make([]types.Secret, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, toSecret(row))
+	}
+
+	c.JSON(200, gin.H{"data": list})
+}
+
+func (h Handler) GetSecret(c *gin.Context) {
+	row, ok := h.lookupSecret(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, gin.H{"data": toSecret(row)})
+}
+
+// CreateSecret encrypts and stores a secret. Instances in its scope read it with a session
+// token from their next request on.
+func (h Handler) CreateSecret(c *gin.Context) {
+	if !h.secretsEnabled(c) {
+		return
+	}
+
+	var req types.SecretsPost
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if req.Project == "" {
+		req.Project = "default"
+	}
+
+	if req.Remote == "" {
+		req.Remote = h.Config.Incus.Name
+	}
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(req.Project) {
+		c.JSON(403, gin.H{"error": "Access to project " + req.Project + " is not allowed"})
+		return
+	}
+
+	if !secretNamePattern.MatchString(req.Name) {
+		c.JSON(400, gin.H{"error": "Invalid secret: name must be letters, digits, dots, dashes and underscores"})
+		return
+	}
+
+	if len(req.Value) > maxSecretSize {
+		c.JSON(400, gin.H{"error": "Invalid secret: value must not exceed 64 KiB"})
+		return
+	}
+
+	scope, err := parseScope("secrets", req.Scope, req.Target)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid secret: " + err.Error()})
+		return
+	}
+
+	if _, ok := h.Config.Remote(req.Remote); !ok {
+		c.JSON(400, gin.H{"error": "Unknown remote " + req.Remote})
+		return
+	}
+
+	existing, err := h.Database.ListSecrets(c, db.ListSecretsParams{Remote: req.Remote, Project: req.Project})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list secrets")
+		c.JSON(500, gin.H{"error": "Failed to list secrets"})
+		return
+	}
+
+	for _, row := range existing {
+		if row.Name == req.Name && row.Scope == scope && row.Target == req.Target {
+			c.JSON(409, gin.H{"error": "Secret " + req.Name + " already exists for this scope"})
+			return
+		}
+	}
+
+	sealed, err := h.Secrets.Seal([]byte(req.Value), secrets.AdditionalData(req.Project, scope, req.Target, req.Name))
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to encrypt secret")
+		c.JSON(500, gin.H{"error": "Failed to encrypt secret"})
+		return
+	}
+
+	row, err := h.Database.CreateSecret(c, db.CreateSecretParams{
+		Name:       req.Name,
+		Project:    req.Project,
+		Remote:     req.Remote,
+		Scope:      scope,
+		Target:     req.Target,
+		KeyID:      sealed.KeyID,
+		WrappedKey: sealed.WrappedKey,
+		Ciphertext: sealed.Ciphertext,
+		OneTime:    req.OneTime,
+		CreatedBy:  identity.Name,
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to store secret")
+		c.JSON(500, gin.H{"error": "Failed to store secret"})
+		return
+	}
+
+	logs.FromContext(c).Info().Int64("id", row.ID).Str("name", row.Name).Str("project", row.Project).Str("scope", row.Scope).Str("created_by", identity.Name).Msg("Secret stored")
+	c.JSON(201, gin.H{"data": toSecret(row)})
+}
+
+// UpdateSecret replaces the value of a secret, e.g. to rotate a password. Instances that read
+// a one-time secret can read the new value once more.
+func (h Handler) UpdateSecret(c *gin.Context) {
+	if !h.secretsEnabled(c) {
+		return
+	}
+
+	var req types.SecretPut
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	current, ok := h.lookupSecret(c)
+	if !ok {
+		return
+	}
+
+	if len(req.Value) > maxSecretSize {
+		c.JSON(400, gin.H{"error": "Invalid secret: value must not exceed 64 KiB"})
+		return
+	}
+
+	sealed, err := h.Secrets.Seal([]byte(req.Value), secrets.AdditionalData(current.Project, current.Scope, current.Target, current.Name))
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to encrypt secret")
+		c.JSON(500, gin.H{"error": "Failed to encrypt secret"})
+		return
+	}
+
+	row, err := h.Database.UpdateSecret(c, db.UpdateSecretParams{
+		KeyID:      sealed.KeyID,
+		WrappedKey: sealed.WrappedKey,
+		Ciphertext: sealed.Ciphertext,
+		OneTime:    req.OneTime,
+		ID:         current.ID,
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to update secret")
+		c.JSON(500, gin.H{"error": "Failed to update secret"})
+		return
+	}
+
+	if err := h.Database.DeleteSecretReads(c, row.ID); err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to reset secret reads")
+		c.JSON(500, gin.H{"error": "Failed to update secret"})
+		return
+	}
+
+	logs.FromContext(c).Info().Int64("id", row.ID).Str("name", row.Name).Str("updated_by", trust.IdentityFromContext(c).Name).Msg("Secret updated")
+	c.JSON(200, gin.H{"data": toSecret(row)})
+}
+
+func (h Handler) DeleteSecret(c *gin.Context) {
+	current, ok := h.lookupSecret(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.Database.DeleteSecret(c, current.ID); err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to delete secret")
+		c.JSON(500, gin.H{"error": "Failed to delete secret"})
+		return
+	}
+
+	logs.FromContext(c).Info().Int64("id", current.ID).Str("name", current.Name).Str("deleted_by", trust.IdentityFromContext(c).Name).Msg("Secret deleted")
+	c.JSON(200, gin.H{"message": "Secret deleted successfully"})
+}
+
+// secretsEnabled answers the request itself when no key encryption key is configured.
+func (h Handler) secretsEnabled(c *gin.Context) bool {
+	if h.Secrets == nil {
+		c.JSON(501, gin.H{"error": "Secrets are disabled, set SECRETS_CONFIG_KEY_FILE or SECRETS_CONFIG_KEY to enable them"})
+		return false
+	}
+
+	return true
+}
+
+// lookupSecret loads the secret of the request and checks the caller can access its project,
+// answering the request itself otherwise.
+func (h Handler) lookupSecret(c *gin.Context) (db.Secret, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "Secret not found"})
+		return db.Secret{}, false
+	}
+
+	row, err := h.Database.GetSecret(c, id)
+	if err == sql.ErrNoRows {
+		c.JSON(404, gin.H{"error": "Secret not found"})
+		return db.Secret{}, false
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve secret")
+		c.JSON(500, gin.H{"error": "Failed to retrieve secret"})
+		return db.Secret{}, false
+	}
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(row.Project) {
+		c.JSON(403, gin.H{"error": "Access to project " + row.Project + " is not allowed"})
+		return db.Secret{}, false
+	}
+
+	return row, true
+}
@@ -0,0 +1,114 @@
+package internal_routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testEnvelope(t *testing.T) *secrets.Envelope {
+	envelope, err := secrets.New(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+
+	return envelope
+}
+
+func TestCreateSecret_EncryptsValue(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	envelope := testEnvelope(t)
+	server := setupOperatorServerWithSecrets(t, mockDB, envelope)
+
+	var stored db.CreateSecretParams
+	mockDB.On("ListSecrets", mock.Anything, db.ListSecretsParams{Remote: "local", Project: "default"}).Return([]db.Secret{}, nil)
+	mockDB.On("CreateSecret", mock.Anything, mock.MatchedBy(func(arg db.CreateSecretParams) bool {
+		stored = arg
+		return true
+	})).Return(db.Secret{ID: 1, Name: "db_password", Project: "default", Scope: "profile", Target: "web", OneTime: true, CreatedBy: "operator"}, nil)
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/secrets", map[string]any{
+		"name":     "db_password",
+		"value":    "hunter2",
+		"scope":    "profile",
+		"target":   "web",
+		"one_time": true,
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "hunter2")
+
+	var created struct {
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &created))
+	assert.Equal(t, "db_password", created.Data["name"])
+	assert.NotContains(t, created.Data, "value")
+
+	assert.Equal(t, "operator", stored.CreatedBy)
+	assert.Equal(t, "local", stored.Remote)
+	assert.True(t, stored.OneTime)
+	assert.NotContains(t, string(stored.Ciphertext), "hunter2")
+
+	value, err := envelope.Open(secrets.Sealed{KeyID: stored.KeyID, WrappedKey: stored.WrappedKey, Ciphertext: stored.Ciphertext}, secrets.AdditionalData("default", "profile", "web", "db_password"))
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(value))
+}
+
+func TestCreateSecret_RejectsInvalidRequests(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServerWithSecrets(t, mockDB, testEnvelope(t))
+
+	mockDB.On("ListSecrets", mock.Anything, db.ListSecretsParams{Remote: "local", Project: "default"}).Return([]db.Secret{{ID: 1, Name: "db_password", Project: "default", Scope: "project"}}, nil)
+
+	for name, body := range map[string]map[string]any{
+		"missing value":       {"name": "token"},
+		"path in name":        {"name": "../token", "value": "x"},
+		"instance w/o target": {"name": "token", "value": "x", "scope": "instance"},
+		"too large":           {"name": "token", "value": string(bytes.Repeat([]byte{'x'}, 64<<10+1))},
+		"unknown remote":      {"name": "token", "value": "x", "remote": "dc2"},
+	} {
+		resp := sendJSON(t, http.MethodPost, server.URL+"/internal/secrets", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, name)
+	}
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/secrets", map[string]any{"name": "db_password", "value": "x"})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = sendJSON(t, http.MethodPost, server.URL+"/internal/secrets", map[string]any{"name": "token", "value": "x", "project": "other"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	mockDB.AssertNotCalled(t, "CreateSecret", mock.Anything, mock.Anything)
+}
+
+func TestUpdateSecret_ResetsReads(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServerWithSecrets(t, mockDB, testEnvelope(t))
+
+	mockDB.On("GetSecret", mock.Anything, int64(3)).Return(db.Secret{ID: 3, Name: "join_token", Project: "default", Scope: "project", OneTime: true}, nil)
+	mockDB.On("UpdateSecret", mock.Anything, mock.MatchedBy(func(arg db.UpdateSecretParams) bool {
+		return arg.ID == 3 && arg.OneTime && len(arg.Ciphertext) > 0
+	})).Return(db.Secret{ID: 3, Name: "join_token", Project: "default", Scope: "project", OneTime: true}, nil)
+	mockDB.On("DeleteSecretReads", mock.Anything, int64(3)).Return(nil).Once()
+
+	resp := sendJSON(t, http.MethodPut, server.URL+"/internal/secrets/3", map[string]any{"value": "rotated", "one_time": true})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockDB.AssertExpectations(t)
+}
+
+func TestSecrets_DisabledWithoutKey(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/secrets", map[string]any{"name": "token", "value": "x"})
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+}
@@ -0,0 +1,322 @@
+package internal_routes
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+// sshKey is a validated SSH key request.
+type sshKey struct {
+	keyType     string
+	publicKey   string
+	fingerprint string
+	comment     string
+	scope       string
+	target      string
+	expiresAt   *int64
+}
+
+// parseSSHKey validates an SSH key request and computes the SHA256 fingerprint of the key.
+func parseSSHKey(publicKey string, comment string, scope string, target string, expiresAt *time.Time) (sshKey, error) {
+	parsed, keyComment, options, rest, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return sshKey{}, errors.New("public key must be in authorized_keys format")
+	}
+
+	if len(options) > 0 || len(strings.TrimSpace(string(rest))) > 0 {
+		return sshKey{}, errors.New("public key must be a single key without options")
+	}
+
+	scope, err = parseScope("keys", scope, target)
+	if err != nil {
+		return sshKey{}, err
+	}
+
+	key := sshKey{
+		keyType:     parsed.Type(),
+		publicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(parsed))),
+		fingerprint: ssh.FingerprintSHA256(parsed),
+		comment:     comment,
+		scope:       scope,
+		target:      target,
+	}
+
+	if key.comment == "" {
+		key.comment = keyComment
+	}
+
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return sshKey{}, errors.New("expiry must be in the future")
+		}
+
+		unix := expiresAt.Unix()
+		key.expiresAt = &unix
+	}
+
+	return key, nil
+}
+
+// parseScope validates the scope and target of keys or secrets, defaulting to the project scope.
+func parseScope(kind string, scope string, target string) (string, error) {
+	if scope == "" {
+		scope = "project"
+	}
+
+	switch scope {
+	case "project":
+		if target != "" {
+			return "", errors.New(kind + " scoped to a project don't have a target")
+		}
+	case "profile", "instance":
+		if target == "" {
+			return "", errors.New(kind + " scoped to a " + scope + " need the " + scope + " name as target")
+		}
+	default:
+		return "", errors.New("scope must be one of project, profile or instance")
+	}
+
+	return scope, nil
+}
+
+func toSSHKey(row db.SshKey) types.SSHKey {
+	key := types.SSHKey{
+		ID:          row.ID,
+		Owner:       row.Owner,
+		Comment:     row.Comment,
+		Type:        row.KeyType,
+		PublicKey:   row.PublicKey,
+		Fingerprint: row.Fingerprint,
+		Project:     row.Project,
+		Remote:      row.Remote,
+		Scope:       row.Scope,
+		Target:      row.Target,
+		ExpiresAt:   row.ExpiresAt,
+	}
+
+	if row.CreatedAt != nil {
+		key.CreatedAt = *row.CreatedAt
+	}
+
+	if row.UpdatedAt != nil {
+		key.UpdatedAt = *row.UpdatedAt
+	}
+
+	return key
+}
+
+// ListSSHKeys lists the SSH keys registered in a project, optionally only those of an owner.
+func (h Handler) ListSSHKeys(c *gin.Context) {
+	project := c.DefaultQuery("project", "default")
+	remote := c.DefaultQuery("remote", h.Config.Incus.Name)
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(project) {
+		c.JSON(403, gin.H{"error": "Access to project " + project + " is not allowed"})
+		return
+	}
+
+	rows, err := h.Database.ListSSHKeys(c, db.ListSSHKeysParams{Remote: remote, Project: project, Owner: c.Query("owner")})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list SSH keys")
+		c.JSON(500, gin.H{"error": "Failed to list SSH keys"})
+		return
+	}
+
+	keys := make([]types.SSHKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, toSSHKey(row))
+	}
+
+	c.JSON(200, gin.H{"data": keys})
+}
+
+func (h Handler) GetSSHKey(c *gin.Context) {
+	row, ok := h.lookupSSHKey(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, gin.H{"data": toSSHKey(row)})
+}
+
+// CreateSSHKey registers an SSH key. It is served to the instances in its scope from their
+// next metadata or user-data request.
+func (h Handler) CreateSSHKey(c *gin.Context) {
+	var req types.SSHKeysPost
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	if req.Project == "" {
+		req.Project = "default"
+	}
+
+	if req.Remote == "" {
+		req.Remote = h.Config.Incus.Name
+	}
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(req.Project) {
+		c.JSON(403, gin.H{"error": "Access to project " + req.Project + " is not allowed"})
+		return
+	}
+
+	key, err := parseSSHKey(req.PublicKey, req.Comment, req.Scope, req.Target, req.ExpiresAt)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid SSH key: " + err.Error()})
+		return
+	}
+
+	if _, ok := h.Config.Remote(req.Remote); !ok {
+		c.JSON(400, gin.H{"error": "Unknown remote " + req.Remote})
+		return
+	}
+
+	if !h.checkDuplicateSSHKey(c, req.Remote, req.Project, key, 0) {
+		return
+	}
+
+	row, err := h.Database.CreateSSHKey(c, db.CreateSSHKeyParams{
+		Owner:       req.Owner,
+		Comment:     key.comment,
+		KeyType:     key.keyType,
+		PublicKey:   key.publicKey,
+		Fingerprint: key.fingerprint,
+		Project:     req.Project,
+		Remote:      req.Remote,
+		Scope:       key.scope,
+		Target:      key.target,
+		ExpiresAt:   key.expiresAt,
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to register SSH key")
+		c.JSON(500, gin.H{"error": "Failed to register SSH key"})
+		return
+	}
+
+	logs.FromContext(c).Info().Str("fingerprint", row.Fingerprint).Str("owner", row.Owner).Str("project", row.Project).Str("added_by", identity.Name).Msg("SSH key registered")
+	c.JSON(201, gin.H{"data": toSSHKey(row)})
+}
+
+// UpdateSSHKey replaces an SSH key, its owner, scope or expiry. Rotating a key in place
+// takes effect on every instance in its scope without recreating them.
+func (h Handler) UpdateSSHKey(c *gin.Context) {
+	var req types.SSHKeyPut
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	current, ok := h.lookupSSHKey(c)
+	if !ok {
+		return
+	}
+
+	key, err := parseSSHKey(req.PublicKey, req.Comment, req.Scope, req.Target, req.ExpiresAt)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid SSH key: " + err.Error()})
+		return
+	}
+
+	if !h.checkDuplicateSSHKey(c, current.Remote, current.Project, key, current.ID) {
+		return
+	}
+
+	row, err := h.Database.UpdateSSHKey(c, db.UpdateSSHKeyParams{
+		Owner:       req.Owner,
+		Comment:     key.comment,
+		KeyType:     key.keyType,
+		PublicKey:   key.publicKey,
+		Fingerprint: key.fingerprint,
+		Scope:       key.scope,
+		Target:      key.target,
+		ExpiresAt:   key.expiresAt,
+		ID:          current.ID,
+	})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to update SSH key")
+		c.JSON(500, gin.H{"error": "Failed to update SSH key"})
+		return
+	}
+
+	logs.FromContext(c).Info().Int64("id", row.ID).Str("previous_fingerprint", current.Fingerprint).Str("fingerprint", row.Fingerprint).Str("updated_by", trust.IdentityFromContext(c).Name).Msg("SSH key updated")
+	c.JSON(200, gin.H{"data": toSSHKey(row)})
+}
+
+func (h Handler) DeleteSSHKey(c *gin.Context) {
+	current, ok := h.lookupSSHKey(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.Database.DeleteSSHKey(c, current.ID); err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to delete SSH key")
+		c.JSON(500, gin.H{"error": "Failed to delete SSH key"})
+		return
+	}
+
+	logs.FromContext(c).Info().Str("fingerprint", current.Fingerprint).Str("deleted_by", trust.IdentityFromContext(c).Name).Msg("SSH key deleted")
+	c.JSON(200, gin.H{"message": "SSH key deleted successfully"})
+}
+
+// checkDuplicateSSHKey checks no other key of the project authorizes the same key in its
+// scope, answering the request itself otherwise. Updates pass the ID of the key they replace.
+func (h Handler) checkDuplicateSSHKey(c *gin.Context, remote string, project string, key sshKey, id int64) bool {
+	existing, err := h.Database.ListSSHKeys(c, db.ListSSHKeysParams{Remote: remote, Project: project})
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to list SSH keys")
+		c.JSON(500, gin.H{"error": "Failed to list SSH keys"})
+		return false
+	}
+
+	for _, row := range existing {
+		if row.ID != id && row.Fingerprint == key.fingerprint && row.Scope == key.scope && row.Target == key.target {
+			c.JSON(409, gin.H{"error": "SSH key is already registered for this scope"})
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookupSSHKey loads the key of the request and checks the caller can access its project,
+// answering the request itself otherwise.
+func (h Handler) lookupSSHKey(c *gin.Context) (db.SshKey, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "SSH key not found"})
+		return db.SshKey{}, false
+	}
+
+	row, err := h.Database.GetSSHKey(c, id)
+	if err == sql.ErrNoRows {
+		c.JSON(404, gin.H{"error": "SSH key not found"})
+		return db.SshKey{}, false
+	}
+
+	if err != nil {
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve SSH key")
+		c.JSON(500, gin.H{"error": "Failed to retrieve SSH key"})
+		return db.SshKey{}, false
+	}
+
+	identity := trust.IdentityFromContext(c)
+	if identity == nil || !identity.CanAccessProject(row.Project) {
+		c.JSON(403, gin.H{"error": "Access to project " + row.Project + " is not allowed"})
+		return db.SshKey{}, false
+	}
+
+	return row, true
+}
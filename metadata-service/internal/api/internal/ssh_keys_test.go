@@ -0,0 +1,226 @@
+package internal_routes
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
+	"github.com/gin-gonic/gin"
+	localtls "github.com/lxc/incus/shared/tls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// setupOperatorServer serves the admin routes to an operator restricted to the default project
+func setupOperatorServer(t *testing.T, mockDB *mocks.MockQuerier) *httptest.Server {
+	return setupOperatorServerWithSecrets(t, mockDB, nil)
+}
+
+// setupOperatorServerWithSecrets is setupOperatorServer with secrets encrypted by envelope.
+func setupOperatorServerWithSecrets(t *testing.T, mockDB *mocks.MockQuerier, envelope *secrets.Envelope) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	cert := testClientCertificate(t)
+
+	router := gin.New()
+	RegisterInternalRoutes(router, &config.Config{Incus: &config.IncusConfig{Name: "local"}}, mockDB, &trust.Store{Database: mockDB}, nil, envelope)
+
+	mockDB.On("GetCertificate", mock.Anything, localtls.CertFingerprint(cert)).Return(db.Certificate{
+		Name:       "operator",
+		Role:       "operator",
+		Restricted: true,
+		Projects:   []byte(`["default"]`),
+	}, nil)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func testSSHKey(t *testing.T) ssh.PublicKey {
+	public, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	key, err := ssh.NewPublicKey(public)
+	require.NoError(t, err)
+
+	return key
+}
+
+func sendJSON(t *testing.T, method string, url string, body any) *http.Response {
+	payload, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+func TestCreateSSHKey_ValidatesAndFingerprints(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+
+	key := testSSHKey(t)
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	expiresAt := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	expiresUnix := expiresAt.Unix()
+
+	mockDB.On("ListSSHKeys", mock.Anything, db.ListSSHKeysParams{Remote: "local", Project: "default"}).Return([]db.SshKey{}, nil)
+	mockDB.On("CreateSSHKey", mock.Anything, db.CreateSSHKeyParams{
+		Owner:       "alice",
+		Comment:     "alice@laptop",
+		KeyType:     "ssh-ed25519",
+		PublicKey:   authorizedKey,
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Project:     "default",
+		Remote:      "local",
+		Scope:       "profile",
+		Target:      "web",
+		ExpiresAt:   &expiresUnix,
+	}).Return(db.SshKey{ID: 1, Owner: "alice", KeyType: "ssh-ed25519", PublicKey: authorizedKey, Fingerprint: ssh.FingerprintSHA256(key), Project: "default", Scope: "profile", Target: "web"}, nil)
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/ssh-keys", map[string]any{
+		"owner":      "alice",
+		"public_key": authorizedKey + " alice@laptop",
+		"scope":      "profile",
+		"target":     "web",
+		"expires_at": expiresAt,
+	})
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	mockDB.AssertExpectations(t)
+}
+
+func TestCreateSSHKey_RejectsInvalidKeys(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(testSSHKey(t))))
+
+	for name, body := range map[string]map[string]any{
+		"malformed key":       {"owner": "alice", "public_key": "ssh-ed25519 not-base64"},
+		"key with options":    {"owner": "alice", "public_key": `command="/bin/true" ` + authorizedKey},
+		"missing owner":       {"public_key": authorizedKey},
+		"unknown scope":       {"owner": "alice", "public_key": authorizedKey, "scope": "cluster"},
+		"instance w/o target": {"owner": "alice", "public_key": authorizedKey, "scope": "instance"},
+		"project with target": {"owner": "alice", "public_key": authorizedKey, "target": "c1"},
+		"expired":             {"owner": "alice", "public_key": authorizedKey, "expires_at": time.Now().Add(-time.Hour)},
+		"unknown remote":      {"owner": "alice", "public_key": authorizedKey, "remote": "dc2"},
+	} {
+		resp := sendJSON(t, http.MethodPost, server.URL+"/internal/ssh-keys", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, name)
+	}
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/ssh-keys", map[string]any{"owner": "alice", "public_key": authorizedKey, "project": "other"})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	mockDB.AssertNotCalled(t, "CreateSSHKey", mock.Anything, mock.Anything)
+}
+
+func TestCreateSSHKey_RejectsDuplicates(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+	key := testSSHKey(t)
+
+	mockDB.On("ListSSHKeys", mock.Anything, db.ListSSHKeysParams{Remote: "local", Project: "default"}).Return([]db.SshKey{
+		{ID: 1, Fingerprint: ssh.FingerprintSHA256(key), Project: "default", Scope: "project"},
+	}, nil)
+
+	resp := sendJSON(t, http.MethodPost, server.URL+"/internal/ssh-keys", map[string]any{
+		"owner":      "alice",
+		"public_key": string(ssh.MarshalAuthorizedKey(key)),
+	})
+
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	mockDB.AssertNotCalled(t, "CreateSSHKey", mock.Anything, mock.Anything)
+}
+
+func TestUpdateSSHKey_RotatesInPlace(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+	rotated := testSSHKey(t)
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(rotated)))
+
+	current := db.SshKey{ID: 5, Owner: "ci", Fingerprint: "SHA256:old", Project: "default", Remote: "local", Scope: "project"}
+	mockDB.On("GetSSHKey", mock.Anything, int64(5)).Return(current, nil)
+	mockDB.On("ListSSHKeys", mock.Anything, db.ListSSHKeysParams{Remote: "local", Project: "default"}).Return([]db.SshKey{current}, nil)
+	mockDB.On("UpdateSSHKey", mock.Anything, db.UpdateSSHKeyParams{
+		Owner:       "ci",
+		Comment:     "deploy",
+		KeyType:     "ssh-ed25519",
+		PublicKey:   authorizedKey,
+		Fingerprint: ssh.FingerprintSHA256(rotated),
+		Scope:       "project",
+		ID:          5,
+	}).Return(db.SshKey{ID: 5, Owner: "ci", Fingerprint: ssh.FingerprintSHA256(rotated), Project: "default", Scope: "project"}, nil)
+
+	resp := sendJSON(t, http.MethodPut, server.URL+"/internal/ssh-keys/5", map[string]any{
+		"owner":      "ci",
+		"public_key": authorizedKey,
+		"comment":    "deploy",
+	})
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUpdateSSHKey_RejectsDuplicates(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+	key := testSSHKey(t)
+
+	mockDB.On("GetSSHKey", mock.Anything, int64(5)).Return(db.SshKey{ID: 5, Owner: "ci", Fingerprint: ssh.FingerprintSHA256(key), Project: "default", Remote: "local", Scope: "profile", Target: "web"}, nil)
+	mockDB.On("ListSSHKeys", mock.Anything, db.ListSSHKeysParams{Remote: "local", Project: "default"}).Return([]db.SshKey{
+		{ID: 1, Fingerprint: ssh.FingerprintSHA256(key), Project: "default", Remote: "local", Scope: "project"},
+		{ID: 5, Fingerprint: ssh.FingerprintSHA256(key), Project: "default", Remote: "local", Scope: "profile", Target: "web"},
+	}, nil)
+
+	// Moving the key to the project scope, where it is already registered
+	resp := sendJSON(t, http.MethodPut, server.URL+"/internal/ssh-keys/5", map[string]any{
+		"owner":      "ci",
+		"public_key": string(ssh.MarshalAuthorizedKey(key)),
+		"scope":      "project",
+	})
+
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	mockDB.AssertNotCalled(t, "UpdateSSHKey", mock.Anything, mock.Anything)
+}
+
+func TestSSHKeys_ChecksProjectOfExistingKeys(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	server := setupOperatorServer(t, mockDB)
+
+	mockDB.On("GetSSHKey", mock.Anything, int64(6)).Return(db.SshKey{ID: 6, Project: "other", Scope: "project"}, nil)
+	mockDB.On("GetSSHKey", mock.Anything, int64(7)).Return(db.SshKey{}, sql.ErrNoRows)
+
+	resp := sendJSON(t, http.MethodDelete, server.URL+"/internal/ssh-keys/6", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp = sendJSON(t, http.MethodDelete, server.URL+"/internal/ssh-keys/7", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	mockDB.AssertNotCalled(t, "DeleteSSHKey", mock.Anything, mock.Anything)
+}
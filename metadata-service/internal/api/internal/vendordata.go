@@ -19,18 +19,18 @@ func (h Handler) UpdateVendorData(c *gin.Context) {
 		return
 	}
 
-	logs.Logger.Info().Str("vendor_name", vendorName).Msg("Updating vendor data")
+	logs.FromContext(c).Info().Str("vendor_name", vendorName).Msg("Updating vendor data")
 
 	var req AddVendorDataKeyRequest
 	if err := c.BindJSON(&req); err != nil {
-		logs.Logger.Error().Err(err).Msg("Failed to bind request payload")
+		logs.FromContext(c).Error().Err(err).Msg("Failed to bind request payload")
 		c.JSON(400, gin.H{"error": "Invalid request payload"})
 		return
 	}
 
 	vendorData, err := h.Database.GetVendorData(c, vendorName)
 	if err != nil {
-		logs.Logger.Error().Err(err).Msg("Failed to retrieve vendor data")
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve vendor data")
 		c.JSON(500, gin.H{"error": "Failed to retrieve vendor data"})
 		return
 	}
@@ -48,7 +48,7 @@ func (h Handler) UpdateVendorData(c *gin.Context) {
 
 	// Update the vendor data in the database
 	if _, err := h.Database.UpdateVendorData(c, update); err != nil {
-		logs.Logger.Error().Err(err).Msg("Failed to update vendor data")
+		logs.FromContext(c).Error().Err(err).Msg("Failed to update vendor data")
 		c.JSON(500, gin.H{"error": "Failed to update vendor data"})
 		return
 	}
@@ -71,13 +71,13 @@ func (h Handler) CreateVendorData(c *gin.Context) {
 
 	_, err := h.Database.GetVendorData(c, req.VendorName)
 	if err != nil && err != sql.ErrNoRows {
-		logs.Logger.Error().Err(err).Msg("Failed to check existing vendor data")
+		logs.FromContext(c).Error().Err(err).Msg("Failed to check existing vendor data")
 		c.JSON(500, gin.H{"error": "Failed to check existing vendor data"})
 		return
 	}
 
 	if err == nil {
-		logs.Logger.Warn().Str("vendor_name", req.VendorName).Msg("Vendor data already exists")
+		logs.FromContext(c).Warn().Str("vendor_name", req.VendorName).Msg("Vendor data already exists")
 		c.JSON(400, gin.H{"error": "Vendor data already exists"})
 		return
 	}
@@ -97,7 +97,7 @@ func (h Handler) CreateVendorData(c *gin.Context) {
 
 	// Insert the new vendor data into the database
 	if _, err := h.Database.CreateVendorData(c, newData); err != nil {
-		logs.Logger.Error().Err(err).Msg("Failed to create vendor data")
+		logs.FromContext(c).Error().Err(err).Msg("Failed to create vendor data")
 		c.JSON(500, gin.H{"error": "Failed to create vendor data"})
 		return
 	}
@@ -107,7 +107,7 @@ func (h Handler) CreateVendorData(c *gin.Context) {
 
 func (h Handler) GetVendorData(c *gin.Context) {
 	vendorName := c.Param("vendor_name")
-	logs.Logger.Info().Str("vendor_name", vendorName).Msg("Retrieving vendor data")
+	logs.FromContext(c).Info().Str("vendor_name", vendorName).Msg("Retrieving vendor data")
 	if vendorName == "" {
 		c.JSON(400, gin.H{"error": "Vendor name is required"})
 		return
@@ -119,14 +119,14 @@ func (h Handler) GetVendorData(c *gin.Context) {
 			c.JSON(404, gin.H{"error": "Vendor data not found"})
 			return
 		}
-		logs.Logger.Error().Err(err).Msg("Failed to retrieve vendor data")
+		logs.FromContext(c).Error().Err(err).Msg("Failed to retrieve vendor data")
 		c.JSON(500, gin.H{"error": "Failed to retrieve vendor data"})
 		return
 	}
 
 	var data map[string]any
 	if err := db.ToJSONB(vendorData.Data, &data); err != nil {
-		logs.Logger.Error().Err(err).Msg("Failed to parse vendor data")
+		logs.FromContext(c).Error().Err(err).Msg("Failed to parse vendor data")
 		c.JSON(500, gin.H{"error": "Failed to parse vendor data"})
 		return
 	}
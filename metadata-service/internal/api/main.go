@@ -4,28 +4,66 @@ import (
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/api/configs"
 	internal_routes "github.com/focadecombate/incus-metadata-service/metadata-service/internal/api/internal"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/health"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/metrics"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/trust"
 	"github.com/gin-gonic/gin"
 	incus "github.com/lxc/incus/client"
+	"github.com/zitadel/oidc/v2/pkg/oidc"
 )
 
 type App struct {
 	Config   *config.Config
 	Router   *gin.Engine
-	Database *db.Queries
+	Admin    *gin.Engine
+	Health   *gin.Engine
+	Database db.Querier
 	Incus    incus.InstanceServer
+	Trust    *trust.Store
+	Resolver resolver.Resolver
+	// Identity signs the instance identity documents and workload identity tokens served to guests.
+	Identity *identity.Keyring
+	// Secrets decrypts the secrets delivered to guests, nil when no key encryption key is configured.
+	Secrets *secrets.Envelope
+	// Passwords encrypts the passwords generated for guests, nil when no public key is configured.
+	Passwords *secrets.PasswordKey
+	// Liveness and Readiness hold the dependency checks served on /livez and /readyz.
+	Liveness  *health.Registry
+	Readiness *health.Registry
 }
 
-// SetupRouter initializes the Gin router with the necessary routes for the metadata service.
+// SetupRouter initializes the Gin routers with the necessary routes for the metadata service.
+// Each router is served by its own listener: guest facing routes by Router, the admin API
+// by Admin over mutual TLS and health checks by Health, so guests can never reach admin routes.
 func SetupRouter(app *App) *gin.Engine {
 	// Define a simple health check endpoint
-	app.Router.GET("/health", HealthCheck)
+	app.Health.GET("/health", HealthCheck)
+	if app.Liveness != nil {
+		app.Health.GET("/livez", app.Liveness.Handler)
+	}
+	if app.Readiness != nil {
+		app.Health.GET("/readyz", app.Readiness.Handler)
+	}
+	app.Health.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Relying parties of workload identity tokens discover the keys verifying them without
+	// credentials, so the OIDC routes live on the health listener rather than the admin API.
+	// The listener also serves the routes above, a proxy exposing the issuer should only
+	// forward the /.well-known routes.
+	if issuer := app.Config.Identity.Issuer; issuer != "" {
+		app.Health.GET(identity.IssuerPath(issuer, oidc.DiscoveryEndpoint), OpenIDConfiguration(issuer))
+		app.Health.GET(identity.IssuerPath(issuer, identity.JWKSPath), WorkloadJWKS(app.Identity))
+	}
 
 	// Register config API routes
-	configs.RegisterConfigRoutes(app.Router, app.Config, app.Database)
+	configs.RegisterConfigRoutes(app.Router, app.Config, app.Database, app.Resolver, app.Identity, app.Secrets, app.Passwords)
 
 	// Register internal API routes
-	internal_routes.RegisterInternalRoutes(app.Router, app.Config, app.Database)
+	internal_routes.RegisterInternalRoutes(app.Admin, app.Config, app.Database, app.Trust, app.Identity, app.Secrets)
 
 	return app.Router
 }
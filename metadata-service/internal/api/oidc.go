@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenIDConfiguration serves the OpenID Provider metadata of the workload identity token issuer.
+func OpenIDConfiguration(issuer string) gin.HandlerFunc {
+	discovery := identity.Discovery(issuer)
+
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, discovery)
+	}
+}
+
+// WorkloadJWKS serves the public keys verifying workload identity tokens.
+func WorkloadJWKS(keyring *identity.Keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwks, err := keyring.JWKS(c.Request.Context())
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to load signing keys")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load signing keys"})
+			return
+		}
+
+		c.JSON(http.StatusOK, jwks)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/metrics"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// NewGuestRouter returns the router for the guest facing metadata API.
+// Proxy headers are never trusted, since guests are identified by their source address,
+// and neither is trace context or request IDs sent by guests, so every guest request
+// starts a new trace.
+func NewGuestRouter(accessLog *logs.AccessLog) *gin.Engine {
+	router := gin.New()
+	router.Use(
+		otelgin.Middleware(tracing.ServiceName, otelgin.WithPropagators(propagation.NewCompositeTextMapPropagator())),
+		accessLog.Middleware("guest", false),
+		metrics.Middleware("guest"),
+		logs.Recovery(),
+	)
+	_ = router.SetTrustedProxies(nil)
+
+	return router
+}
+
+// NewProxyRouter returns the router of the per-network metadata proxy. Like the guest router,
+// it trusts nothing sent by guests, the proxy itself names the guest to the service.
+func NewProxyRouter(accessLog *logs.AccessLog) *gin.Engine {
+	router := gin.New()
+	router.Use(
+		otelgin.Middleware(tracing.ServiceName, otelgin.WithPropagators(propagation.NewCompositeTextMapPropagator())),
+		accessLog.Middleware("proxy", false),
+		metrics.Middleware("proxy"),
+		logs.Recovery(),
+	)
+	_ = router.SetTrustedProxies(nil)
+
+	return router
+}
+
+// NewAdminRouter returns the router for the admin API.
+func NewAdminRouter(accessLog *logs.AccessLog) *gin.Engine {
+	router := gin.New()
+	router.Use(
+		otelgin.Middleware(tracing.ServiceName),
+		accessLog.Middleware("admin", true),
+		metrics.Middleware("admin"),
+		logs.Recovery(),
+	)
+
+	return router
+}
+
+// NewHealthRouter returns the router for health checks. Probes are not access logged.
+func NewHealthRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(logs.Recovery())
+
+	return router
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sethvargo/go-envconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(envconfig.MapLookuper(map[string]string{}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{":8080"}, cfg.Guest.Addresses)
+	assert.Equal(t, "", cfg.Incus.TLSServerCert)
+	assert.Equal(t, zerolog.InfoLevel, cfg.LogLevel)
+}
+
+func TestLoad_YAMLFileUnderEnv(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+log_level: debug
+incus:
+  server_url: https://incus.example:8443
+guest:
+  addresses:
+    - 169.254.169.254:80
+    - "[fd00:ec2::254]:80"
+  read_timeout: 3s
+access_log:
+  sample_rate: 50
+`)
+
+	cfg, err := load(envconfig.MapLookuper(map[string]string{
+		FileEnv:                  path,
+		"ACCESS_LOG_SAMPLE_RATE": "5",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, zerolog.DebugLevel, cfg.LogLevel)
+	assert.Equal(t, "https://incus.example:8443", cfg.Incus.ServerURL)
+	assert.Equal(t, []string{"169.254.169.254:80", "[fd00:ec2::254]:80"}, cfg.Guest.Addresses)
+	assert.Equal(t, 3*time.Second, cfg.Guest.ReadTimeout)
+	assert.Equal(t, uint32(5), cfg.AccessLog.SampleRate, "environment variables take precedence over the file")
+	assert.Equal(t, 30*time.Second, cfg.Guest.WriteTimeout, "unset settings keep their default")
+}
+
+func TestLoad_TOMLFile(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+port = "9090"
+
+[health]
+check_timeout = "500ms"
+
+[tracing]
+sample_ratio = 0.25
+`)
+
+	cfg, err := load(envconfig.MapLookuper(map[string]string{FileEnv: path}))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{":9090"}, cfg.Guest.Addresses)
+	assert.Equal(t, 500*time.Millisecond, cfg.Health.CheckTimeout)
+	assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+}
+
+func TestLoad_RejectsUnknownSettings(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+incus:
+  server_ulr: https://incus.example:8443
+`)
+
+	_, err := load(envconfig.MapLookuper(map[string]string{FileEnv: path}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown setting "incus.server_ulr"`)
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	_, err := load(envconfig.MapLookuper(map[string]string{
+		"INCUS_CONFIG_SERVER_URL":      "incus.example:8443",
+		"INCUS_CONFIG_TLS_SERVER_CERT": "/does/not/exist.crt",
+		"GUEST_CONFIG_TLS_CERT":        "guest.crt",
+		"TRACING_CONFIG_SAMPLE_RATIO":  "2",
+		"IDENTITY_CONFIG_ISSUER":       "metadata.example.com",
+		"SECRETS_CONFIG_KEY":           "c2hvcnQ=",
+		"PASSWORDS_CONFIG_LENGTH":      "8",
+		"TAGS_CONFIG_DENY":             "web=",
+	}))
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), `INCUS_CONFIG_SERVER_URL (incus.server_url): "incus.example:8443" must be an https:// or unix:// URL`)
+	assert.Contains(t, err.Error(), "INCUS_CONFIG_TLS_SERVER_CERT (incus.tls_server_cert): stat /does/not/exist.crt: no such file or directory")
+	assert.Contains(t, err.Error(), "GUEST_CONFIG_TLS_KEY (guest.tls_key): must be set together with GUEST_CONFIG_TLS_CERT (guest.tls_cert)")
+	assert.Contains(t, err.Error(), "TRACING_CONFIG_SAMPLE_RATIO (tracing.sample_ratio): 2 must be between 0 and 1")
+	assert.Contains(t, err.Error(), `IDENTITY_CONFIG_ISSUER (identity.issuer): "metadata.example.com" must be an http:// or https:// URL without query or fragment`)
+	assert.Contains(t, err.Error(), "SECRETS_CONFIG_KEY (secrets.key): key must be 32 bytes encoded in base64")
+	assert.Contains(t, err.Error(), "PASSWORDS_CONFIG_LENGTH (passwords.length): 8 must be between 12 and 128")
+	assert.Contains(t, err.Error(), `TAGS_CONFIG_DENY (tags.deny): "web=" must be a pattern or project=pattern`)
+}
+
+func TestValidate_IssuerRequiresKEK(t *testing.T) {
+	_, err := load(envconfig.MapLookuper(map[string]string{"IDENTITY_CONFIG_ISSUER": "https://metadata.example.com"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "IDENTITY_CONFIG_ISSUER (identity.issuer): requires SECRETS_CONFIG_KEY_FILE or SECRETS_CONFIG_KEY")
+
+	_, err = load(envconfig.MapLookuper(map[string]string{
+		"IDENTITY_CONFIG_ISSUER": "https://metadata.example.com",
+		"SECRETS_CONFIG_KEY":     "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=",
+	}))
+	assert.NoError(t, err)
+}
+
+func TestTagsConfig_Allowed(t *testing.T) {
+	tags := &TagsConfig{
+		Allow: []string{"payments=team", "payments=env-*"},
+		Deny:  []string{"*password*", "web=internal-*"},
+	}
+
+	assert.True(t, tags.Allowed("web", "team"), "no allow pattern applies to the project")
+	assert.False(t, tags.Allowed("web", "db_PASSWORD"))
+	assert.False(t, tags.Allowed("web", "internal-cost-center"))
+	assert.True(t, tags.Allowed("staging", "internal-cost-center"))
+
+	assert.True(t, tags.Allowed("payments", "team"))
+	assert.True(t, tags.Allowed("payments", "env-tier"))
+	assert.False(t, tags.Allowed("payments", "owner"))
+}
+
+func TestTagsConfig_AllowedKeysWithSlashes(t *testing.T) {
+	tags := &TagsConfig{
+		Allow: []string{"web=app/*", "web=env-?"},
+		Deny:  []string{"*password*", "*/internal"},
+	}
+
+	assert.False(t, tags.Allowed("web", "app/db-password"))
+	assert.False(t, tags.Allowed("staging", "team/billing/Password"))
+	assert.False(t, tags.Allowed("web", "app/billing/internal"))
+	assert.True(t, tags.Allowed("web", "app/billing/owner"))
+	assert.True(t, tags.Allowed("web", "env-a"))
+	assert.False(t, tags.Allowed("web", "env-ab"))
+	assert.False(t, tags.Allowed("web", "team"))
+}
+
+func TestValidate_IncusConnectionModes(t *testing.T) {
+	cfg, err := load(envconfig.MapLookuper(map[string]string{"INCUS_CONFIG_SERVER_URL": "unix://"}))
+	require.NoError(t, err)
+	assert.Equal(t, "unix://", cfg.Incus.ServerURL)
+
+	_, err = load(envconfig.MapLookuper(map[string]string{"INCUS_CONFIG_SERVER_URL": "unix:///var/lib/incus/unix.socket"}))
+	require.NoError(t, err)
+
+	_, err = load(envconfig.MapLookuper(map[string]string{"INCUS_CONFIG_TRUST_TOKEN": "not-a-token"}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "INCUS_CONFIG_TLS_SERVER_CERT (incus.tls_server_cert): must be set to save the server certificate when a trust token is used")
+	assert.Contains(t, err.Error(), "INCUS_CONFIG_TRUST_TOKEN (incus.trust_token): ")
+}
+
+func TestLoad_Remotes(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+incus:
+  server_url: unix://
+remotes:
+  dc2:
+    server_url: https://dc2.example:8443
+    guest_addresses: ["10.2.0.1:80"]
+  dc3-b:
+    server_url: https://dc3.example:8443
+    guest_addresses: ["10.3.0.1:80"]
+`)
+
+	cfg, err := load(envconfig.MapLookuper(map[string]string{
+		FileEnv:                         path,
+		"INCUS_REMOTE_DC3_B_SERVER_URL": "https://dc3-b.example:8443",
+	}))
+	require.NoError(t, err)
+
+	require.Len(t, cfg.IncusRemotes(), 3)
+	assert.Equal(t, "local", cfg.Incus.Name)
+
+	dc2, ok := cfg.Remote("dc2")
+	require.True(t, ok)
+	assert.Equal(t, "https://dc2.example:8443", dc2.ServerURL)
+	assert.Equal(t, []string{"10.2.0.1:80"}, dc2.GuestAddresses)
+	assert.Equal(t, "/etc/incus/client.crt", dc2.TLSClientCert, "unset settings keep their default")
+
+	dc3, ok := cfg.Remote("dc3-b")
+	require.True(t, ok)
+	assert.Equal(t, "https://dc3-b.example:8443", dc3.ServerURL, "environment variables take precedence over the file")
+
+	// Removing a remote needs a restart
+	next := cfg.clone()
+	next.Remotes = next.Remotes[:1]
+	assert.Contains(t, Diff(cfg, next), Change{Setting: "INCUS_REMOTE_DC3_B_SERVER_URL (remotes.dc3-b.server_url)", Old: "https://dc3-b.example:8443"})
+}
+
+func TestValidate_Remotes(t *testing.T) {
+	_, err := load(envconfig.MapLookuper(map[string]string{
+		"INCUS_REMOTES":                 "dc2,local,DC4",
+		"INCUS_REMOTE_DC2_SERVER_URL":   "dc2.example:8443",
+		"INCUS_REMOTE_LOCAL_SERVER_URL": "https://local.example:8443",
+	}))
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), `INCUS_REMOTE_DC2_SERVER_URL (remotes.dc2.server_url): "dc2.example:8443" must be an https:// or unix:// URL`)
+	assert.Contains(t, err.Error(), "INCUS_REMOTE_DC2_GUEST_ADDRESSES (remotes.dc2.guest_addresses): must be set to tell the instances of this remote apart")
+	assert.Contains(t, err.Error(), `remote "local" is defined more than once`)
+	assert.Contains(t, err.Error(), `"DC4" must only contain lower case letters, digits and dashes`)
+}
+
+func TestDiffAndReload(t *testing.T) {
+	current, err := load(envconfig.MapLookuper(map[string]string{}))
+	require.NoError(t, err)
+
+	next, err := load(envconfig.MapLookuper(map[string]string{
+		"LOG_LEVEL":             "debug",
+		"HEALTH_CONFIG_ADDRESS": ":9091",
+		"PROXY_CONFIG_SECRET":   "0123456789abcdef",
+	}))
+	require.NoError(t, err)
+
+	changes := Diff(current, next)
+	assert.ElementsMatch(t, []Change{
+		{Setting: "LOG_LEVEL (log_level)", Old: "info", New: "debug", Reloadable: true},
+		{Setting: "HEALTH_CONFIG_ADDRESS (health.address)", Old: ":8081", New: ":9091"},
+		{Setting: "PROXY_CONFIG_SECRET (proxy.secret)", Old: "", New: "<redacted>"},
+	}, changes)
+
+	reloaded := Reload(current, next)
+	assert.Equal(t, zerolog.DebugLevel, reloaded.LogLevel)
+	assert.Equal(t, ":8081", reloaded.Health.Address, "settings that need a restart are not applied")
+	assert.Equal(t, zerolog.InfoLevel, current.LogLevel, "the current configuration is not modified")
+}
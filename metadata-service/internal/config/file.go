@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv is the environment variable pointing at the optional configuration file.
+const FileEnv = "CONFIG_FILE"
+
+// readFile loads a YAML or TOML configuration file, chosen by extension, and
+// flattens it into the environment variables it stands for. Unknown keys are errors.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	document := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &document)
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).Decode(&document)
+	default:
+		return nil, fmt.Errorf("configuration file %s must have a .yaml, .yml or .toml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse configuration file %s: %w", path, err)
+	}
+
+	keys := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		keys[s.Key] = s
+	}
+
+	for _, s := range incusSettings() {
+		keys[remotesSection+".*."+strings.ToLower(strings.TrimPrefix(s.Env, incusPrefix))] = s
+	}
+
+	values := map[string]string{}
+	var remotes []string
+	var errs []error
+	flatten(document, "", func(key string, value any) {
+		// Remotes are tables named after the remote, e.g. remotes.dc2.server_url
+		if rest, ok := strings.CutPrefix(key, remotesSection+"."); ok {
+			name, field, _ := strings.Cut(rest, ".")
+			if s, ok := keys[remotesSection+".*."+field]; ok && remoteNamePattern.MatchString(name) {
+				if !slices.Contains(remotes, name) {
+					remotes = append(remotes, name)
+				}
+
+				values[remoteSetting(name, s).Env] = formatFileValue(value)
+				return
+			}
+		}
+
+		s, ok := keys[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %q", path, key))
+			return
+		}
+
+		values[s.Env] = formatFileValue(value)
+	})
+
+	// Remotes defined in the file are enabled unless the file lists them explicitly
+	if _, ok := values[remotesEnv]; !ok && len(remotes) > 0 {
+		sort.Strings(remotes)
+		values[remotesEnv] = strings.Join(remotes, ",")
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return values, errors.Join(errs...)
+}
+
+func flatten(document map[string]any, prefix string, visit func(key string, value any)) {
+	for key, value := range document {
+		if nested, ok := value.(map[string]any); ok {
+			flatten(nested, prefix+key+".", visit)
+			continue
+		}
+
+		visit(prefix+key, value)
+	}
+}
+
+func formatFileValue(value any) string {
+	if list, ok := value.([]any); ok {
+		items := make([]string, len(list))
+		for i, item := range list {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
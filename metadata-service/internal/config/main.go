@@ -2,13 +2,26 @@ package config
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/sethvargo/go-envconfig"
 )
 
 type IncusConfig struct {
-	// ServerURL is the URL of the Incus server.
+	// Name identifies the remote in the instance cache and in logs. Additional remotes
+	// are named by INCUS_REMOTES instead.
+	Name string `env:"NAME,default=local"`
+	// ServerURL is the URL of the Incus server, either https://host:port or unix:// for the
+	// local unix socket (unix:///path/to/unix.socket for a non-default path).
 	ServerURL string `env:"SERVER_URL,default=https://localhost:8443"`
 	// TLSConfig holds the TLS configuration for connecting to the Incus server.
 	TLSClientCert string `env:"TLS_CLIENT_CERT,default=/etc/incus/client.crt"`
@@ -16,6 +29,14 @@ type IncusConfig struct {
 	TLSClientKey string `env:"TLS_CLIENT_KEY,default=/etc/incus/client.key"`
 	TLSServerCert string `env:"TLS_SERVER_CERT,default="` // Optional, can be left empty to use default server certificate handling
 	TLSInsecureSkipVerify bool `env:"TLS_INSECURE_SKIP_VERIFY,default=false"` // Skip certificate verification for self-signed certs
+	// TrustToken enrolls the client certificate on first run, see `incus config trust add`.
+	TrustToken string `env:"TRUST_TOKEN" secret:"true"`
+	// ClusterMember is the name of the cluster member running on this host, whose instances can
+	// be identified by vsock context ID and PID. Defaults to the member serving the unix socket.
+	ClusterMember string `env:"CLUSTER_MEMBER"`
+	// GuestAddresses are the guest listener addresses that this remote's instances connect to.
+	// They tell remotes apart when their instances reuse the same private addresses.
+	GuestAddresses []string `env:"GUEST_ADDRESSES"`
 }
 
 type DatabaseConfig struct {
@@ -25,22 +46,373 @@ type DatabaseConfig struct {
 	DBSource string `env:"DB_SOURCE,default=metadata.db"`
 }
 
+// TimeoutConfig holds the timeouts of an HTTP listener.
+type TimeoutConfig struct {
+	// ReadHeaderTimeout is how long the listener waits for request headers.
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT,default=5s"`
+	// ReadTimeout is the maximum duration for reading an entire request.
+	ReadTimeout time.Duration `env:"READ_TIMEOUT,default=10s"`
+	// WriteTimeout is the maximum duration before timing out writes of the response.
+	WriteTimeout time.Duration `env:"WRITE_TIMEOUT,default=30s"`
+	// IdleTimeout is how long keep-alive connections are kept open between requests.
+	IdleTimeout time.Duration `env:"IDLE_TIMEOUT,default=60s"`
+}
+
+// GuestConfig holds the configuration for the guest facing metadata listener.
+type GuestConfig struct {
+	// Addresses are the addresses the guest metadata API listens on, such as
+	// 169.254.169.254:80 and [fd00:ec2::254]:80. Defaults to all interfaces on Port.
+	Addresses []string `env:"ADDRESSES"`
+	// VsockPort enables serving the guest API over vsock on this port, for virtual
+	// machines without guest networking. Zero disables the vsock listener.
+	VsockPort uint32 `env:"VSOCK_PORT,default=0"`
+	// UnixSocket enables serving the guest API on a unix socket at this path, meant to
+	// be bind-mounted into system containers. Empty disables the unix socket listener.
+	UnixSocket string `env:"UNIX_SOCKET"`
+	// Networks maps where guest requests arrive to the Incus network they come from, written
+	// as network=host:port for a listener address or network=vlan:<id> for a VLAN, e.g.
+	// ovn-web=10.10.0.1:80,lan=vlan:100. Needed when networks reuse the same addresses.
+	Networks []string `env:"NETWORKS"`
+	// TrustedProxies are the CIDRs of metadata proxies allowed to name the network and guest
+	// of a request with the X-Instance-Network and X-Forwarded-For headers.
+	TrustedProxies []string `env:"TRUSTED_PROXIES"`
+	// TLSCert and TLSKey enable HTTPS on the guest listener when both are set.
+	TLSCert string `env:"TLS_CERT"`
+	TLSKey  string `env:"TLS_KEY"`
+	TimeoutConfig
+}
+
+// HealthConfig holds the configuration for the health check listener.
+type HealthConfig struct {
+	// Address is the address the health endpoints listen on.
+	Address string `env:"ADDRESS,default=:8081"`
+	// CheckTimeout bounds how long a single dependency check may take.
+	CheckTimeout time.Duration `env:"CHECK_TIMEOUT,default=2s" reload:"true"`
+	// CheckCacheTTL is how long check results are reused, so probes don't hammer dependencies.
+	CheckCacheTTL time.Duration `env:"CHECK_CACHE_TTL,default=5s" reload:"true"`
+	// TLSCert and TLSKey enable HTTPS on the health listener when both are set.
+	TLSCert string `env:"TLS_CERT"`
+	TLSKey  string `env:"TLS_KEY"`
+	TimeoutConfig
+}
+
+// AdminConfig holds the configuration for the admin API listener.
+type AdminConfig struct {
+	// Address is the address the admin API listens on.
+	Address string `env:"ADDRESS,default=:8443"`
+	// TLSCert is the path to the admin listener certificate. It is generated on first start if missing.
+	TLSCert string `env:"TLS_CERT,default=server.crt"`
+	// TLSKey is the path to the admin listener private key.
+	TLSKey string `env:"TLS_KEY,default=server.key"`
+	// TokenExpiry is how long a join token stays valid after being issued.
+	TokenExpiry time.Duration `env:"TOKEN_EXPIRY,default=24h"`
+	TimeoutConfig
+}
+
+// ProxyConfig holds the configuration of the per-network metadata proxy, see `metadata-service proxy`.
+type ProxyConfig struct {
+	// Address is the address the proxy listens on inside the network namespace.
+	Address string `env:"ADDRESS,default=169.254.169.254:80"`
+	// Network is the Incus network whose guests the proxy serves.
+	Network string `env:"NETWORK"`
+	// Upstream is the URL of the central service's guest listener, e.g. http://10.0.0.1:8080.
+	Upstream string `env:"UPSTREAM"`
+	// Secret is shared by the proxies and the central service, which only trusts the network
+	// and guest address named by a proxy when they are signed with it.
+	Secret string `env:"SECRET" secret:"true"`
+	TimeoutConfig
+}
+
+// LogRetentionConfig bounds the entries kept of one log type.
+type LogRetentionConfig struct {
+	// MaxAge removes entries older than this. Zero keeps entries regardless of their age.
+	MaxAge time.Duration `env:"MAX_AGE,default=720h"`
+	// MaxRows keeps at most this many of the newest entries per instance. Zero keeps every entry.
+	MaxRows int64 `env:"MAX_ROWS,default=10000"`
+}
+
+// RetentionConfig controls the worker removing old instance logs and soft-deleted rows.
+type RetentionConfig struct {
+	// Interval is how often the worker runs. Zero disables it.
+	Interval time.Duration `env:"INTERVAL,default=1h"`
+	// BatchSize bounds the rows removed per statement, so request traffic isn't blocked for long.
+	BatchSize int64 `env:"BATCH_SIZE,default=500"`
+	// DeletedGracePeriod is how long soft-deleted instances, profiles and vendor data are kept.
+	DeletedGracePeriod time.Duration `env:"DELETED_GRACE_PERIOD,default=168h"`
+	// Vacuum returns free pages to the file system after each run: incremental, full or off.
+	Vacuum string `env:"VACUUM,default=incremental"`
+	// Operation, Event, Console and Audit bound the entries kept of each log type.
+	Operation LogRetentionConfig `env:",prefix=OPERATION_"`
+	Event     LogRetentionConfig `env:",prefix=EVENT_"`
+	Console   LogRetentionConfig `env:",prefix=CONSOLE_"`
+	Audit     LogRetentionConfig `env:",prefix=AUDIT_"`
+}
+
+// IdentityConfig controls the keys instance identity documents are signed with.
+type IdentityConfig struct {
+	// KeyRotation is how long a signing key is used before a new one is created.
+	KeyRotation time.Duration `env:"KEY_ROTATION,default=720h"`
+	// KeyGracePeriod is how long a replaced key stays published, so documents it signed still verify.
+	KeyGracePeriod time.Duration `env:"KEY_GRACE_PERIOD,default=720h"`
+	// Issuer is the URL external systems reach the health listener's /.well-known routes at, e.g.
+	// https://metadata.example.com, which are served under its path. It enables workload identity
+	// tokens, whose iss claim it is.
+	Issuer string `env:"ISSUER"`
+	// TokenLifetime is how long workload identity tokens are valid.
+	TokenLifetime time.Duration `env:"TOKEN_LIFETIME,default=10m"`
+}
+
+// SecretsConfig holds the key encryption key protecting the secrets delivered to instances. The
+// key is a base64 encoded 32 byte key, read from KeyFile or given in Key. Secrets and session
+// tokens are disabled when neither is set.
+type SecretsConfig struct {
+	// KeyFile is the path to a file holding the key, e.g. mounted from a secrets manager.
+	KeyFile string `env:"KEY_FILE"`
+	// Key is the key itself, for deployments passing it in the environment.
+	Key string `env:"KEY" secret:"true"`
+}
+
+// PasswordsConfig controls the passwords generated for the users of instances, which are
+// disabled without a public key.
+type PasswordsConfig struct {
+	// PublicKeyFile is the path to the PEM encoded RSA public key passwords are encrypted to. The
+	// operators hold the private key, the service only keeps encrypted passwords.
+	PublicKeyFile string `env:"PUBLIC_KEY_FILE"`
+	// Projects limits passwords to the instances of these projects. Empty means every project.
+	Projects []string `env:"PROJECTS"`
+	// Length is the number of characters of generated passwords.
+	Length int `env:"LENGTH,default=20"`
+}
+
+// TagsConfig controls which tags, the user.* keys of instances, are served to guests. Entries
+// are glob patterns matching tag keys, e.g. team or app/*, that apply to every project or,
+// written as project=pattern, to a single project.
+type TagsConfig struct {
+	// Allow limits the tags served to those matching a pattern, when any applies to the project.
+	Allow []string `env:"ALLOW"`
+	// Deny hides the tags matching a pattern, even when they are allowed.
+	Deny []string `env:"DENY,default=*password*,*secret*,*token*"`
+}
+
+// TracingConfig configures OpenTelemetry tracing.
+type TracingConfig struct {
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://collector:4318. Empty disables tracing.
+	Endpoint string `env:"ENDPOINT"`
+	// SampleRatio is the fraction of new traces that are recorded.
+	SampleRatio float64 `env:"SAMPLE_RATIO,default=1"`
+}
+
+// AccessLogConfig controls the access log written for guest and admin requests.
+type AccessLogConfig struct {
+	// SampledRoutes are hot route templates whose successful requests are only logged once every SampleRate requests.
+	SampledRoutes []string `env:"SAMPLED_ROUTES,default=/configs/meta-data,/configs/meta-data/:key,/configs/user-data,/configs/vendor-data,/configs/network-config,/configs/reporting" reload:"true"`
+	// SampleRate logs one in every SampleRate successful requests to SampledRoutes. 1 logs every request.
+	SampleRate uint32 `env:"SAMPLE_RATE,default=10" reload:"true"`
+}
+
 // Config holds the configuration for the metadata service.
 type Config struct {
-	// Port is the port on which the metadata service will run.
+	// Port is the port on which the guest metadata API runs when no guest addresses are configured.
 	Port string `env:"PORT,default=8080"`
 	// LogLevel sets the logging level for the service.
-	LogLevel zerolog.Level `env:"LOG_LEVEL,default=info"`
+	LogLevel zerolog.Level `env:"LOG_LEVEL,default=info" reload:"true"`
 	// Incus contains the configuration for connecting to the Incus server.
 	Incus *IncusConfig `env:",prefix=INCUS_CONFIG_"`
+	// RemoteNames lists additional Incus remotes, each configured like Incus with the
+	// INCUS_REMOTE_<NAME>_ prefix, e.g. INCUS_REMOTE_DC2_SERVER_URL.
+	RemoteNames []string `env:"INCUS_REMOTES"`
+	// Remotes holds the configuration of the remotes listed in RemoteNames.
+	Remotes []*IncusConfig
 	// Database contains the configuration for connecting to the database.
 	Database *DatabaseConfig `env:",prefix=DATABASE_CONFIG_"`
+	// Guest contains the configuration for the guest facing metadata listener.
+	Guest *GuestConfig `env:",prefix=GUEST_CONFIG_"`
+	// Health contains the configuration for the health check listener.
+	Health *HealthConfig `env:",prefix=HEALTH_CONFIG_"`
+	// Admin contains the configuration for the mutual TLS admin API.
+	Admin *AdminConfig `env:",prefix=ADMIN_CONFIG_"`
+	// Proxy contains the configuration of the per-network metadata proxy.
+	Proxy *ProxyConfig `env:",prefix=PROXY_CONFIG_"`
+	// Retention controls how long instance logs and soft-deleted rows are kept.
+	Retention *RetentionConfig `env:",prefix=RETENTION_CONFIG_"`
+	// Identity controls the signing keys of instance identity documents.
+	Identity *IdentityConfig `env:",prefix=IDENTITY_CONFIG_"`
+	// Secrets holds the key encryption key of the secrets delivered to instances.
+	Secrets *SecretsConfig `env:",prefix=SECRETS_CONFIG_"`
+	// Passwords controls the passwords generated for the users of instances.
+	Passwords *PasswordsConfig `env:",prefix=PASSWORDS_CONFIG_"`
+	// Tags controls which instance tags are served to guests.
+	Tags *TagsConfig `env:",prefix=TAGS_CONFIG_"`
+	// Tracing contains the OpenTelemetry tracing configuration.
+	Tracing *TracingConfig `env:",prefix=TRACING_CONFIG_"`
+	// AccessLog controls the access log of the guest and admin listeners.
+	AccessLog *AccessLogConfig `env:",prefix=ACCESS_LOG_"`
 }
 
+// LoadConfig loads the configuration from environment variables, layered over the
+// configuration file named by CONFIG_FILE if set, and validates it.
 func LoadConfig() (*Config, error) {
+	return load(envconfig.OsLookuper())
+}
+
+func load(env envconfig.Lookuper) (*Config, error) {
+	lookuper := env
+	if path, ok := env.Lookup(FileEnv); ok && path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Environment variables take precedence over the file
+		lookuper = envconfig.MultiLookuper(env, envconfig.MapLookuper(values))
+	}
+
 	var cfg Config
-	if err := envconfig.Process(context.Background(), &cfg); err != nil {
+	if err := envconfig.ProcessWith(context.Background(), &envconfig.Config{Target: &cfg, Lookuper: lookuper}); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if err := cfg.loadRemotes(lookuper); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Guest.Addresses) == 0 {
+		cfg.Guest.Addresses = []string{":" + cfg.Port}
+	}
+
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
+
 	return &cfg, nil
 }
+
+// KEK returns the key encryption key of the secrets, nil when secrets are disabled.
+func (c *SecretsConfig) KEK() ([]byte, error) {
+	encoded := c.Key
+	if c.KeyFile != "" {
+		content, err := os.ReadFile(c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded = string(content)
+	}
+
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("key must be 32 bytes encoded in base64, e.g. from `openssl rand -base64 32`")
+	}
+
+	return key, nil
+}
+
+// PublicKey returns the key passwords are encrypted to, nil when passwords are disabled.
+func (c *PasswordsConfig) PublicKey() (*rsa.PublicKey, error) {
+	if c.PublicKeyFile == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(c.PublicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key found, e.g. from `openssl rsa -in key.pem -pubout`")
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q, a public key is required", block.Type)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok || key.N.BitLen() < 2048 {
+		return nil, errors.New("an RSA key of at least 2048 bits is required")
+	}
+
+	return key, nil
+}
+
+// Allowed reports whether the tag key of an instance in project is served to guests.
+func (c *TagsConfig) Allowed(project, key string) bool {
+	for _, entry := range c.Deny {
+		if pattern, ok := tagPattern(entry, project); ok && matchTag(pattern, key) {
+			return false
+		}
+	}
+
+	restricted := false
+	for _, entry := range c.Allow {
+		pattern, ok := tagPattern(entry, project)
+		if !ok {
+			continue
+		}
+
+		if matchTag(pattern, key) {
+			return true
+		}
+		restricted = true
+	}
+
+	return !restricted
+}
+
+// tagPattern returns the pattern of a tag entry when it applies to project.
+func tagPattern(entry string, project string) (string, bool) {
+	scope, pattern, scoped := strings.Cut(entry, "=")
+	if !scoped {
+		return entry, true
+	}
+
+	return pattern, scope == project
+}
+
+// matchTag matches tag keys case-insensitively, so a denied password key can't slip through as PASSWORD.
+// A * matches any run of characters, / included, as tag keys such as app/db-password aren't paths,
+// and a ? matches a single character.
+func matchTag(pattern string, key string) bool {
+	p := []rune(strings.ToLower(pattern))
+	k := []rune(strings.ToLower(key))
+
+	// On a mismatch, the last * swallows one more character of the key and matching resumes after it
+	star, resume := -1, 0
+	i, j := 0, 0
+	for j < len(k) {
+		switch {
+		case i < len(p) && (p[i] == '?' || p[i] == k[j]):
+			i++
+			j++
+		case i < len(p) && p[i] == '*':
+			star, resume = i, j
+			i++
+		case star >= 0:
+			resume++
+			i, j = star+1, resume
+		default:
+			return false
+		}
+	}
+
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+
+	return i == len(p)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+)
+
+// Change is a setting whose value differs between two configurations.
+type Change struct {
+	Setting string
+	Old     string
+	New     string
+	// Reloadable is set when the change is applied on reload; other changes need a restart.
+	Reloadable bool
+}
+
+// Diff lists the settings that differ between old and new.
+func Diff(old *Config, new *Config) []Change {
+	var changes []Change
+	for _, s := range settings {
+		before, after := s.format(old), s.format(new)
+		if before == after {
+			continue
+		}
+
+		changes = append(changes, Change{Setting: s.String(), Old: redact(s, before), New: redact(s, after), Reloadable: s.Reload})
+	}
+
+	// Remotes are compared by name, so adding one lists all of its settings
+	for _, name := range remoteNames(old, new) {
+		before, _ := old.Remote(name)
+		after, _ := new.Remote(name)
+		for _, s := range incusSettings() {
+			if s.Env == incusPrefix+"NAME" {
+				continue
+			}
+
+			previous, next := s.format(remoteView(before)), s.format(remoteView(after))
+			if previous != next {
+				changes = append(changes, Change{Setting: remoteSetting(name, s).String(), Old: redact(s, previous), New: redact(s, next)})
+			}
+		}
+	}
+
+	return changes
+}
+
+// redact hides the value of secret settings, so changes can be logged.
+func redact(s setting, value string) string {
+	if s.Secret && value != "" {
+		return "<redacted>"
+	}
+
+	return value
+}
+
+// remoteNames lists the additional remotes of both configurations.
+func remoteNames(old *Config, new *Config) []string {
+	var names []string
+	for _, cfg := range []*Config{old, new} {
+		for _, remote := range cfg.Remotes {
+			if !slices.Contains(names, remote.Name) {
+				names = append(names, remote.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+// Reload returns a copy of current with the reloadable settings taken from next.
+// Other settings keep their current value until the service is restarted.
+func Reload(current *Config, next *Config) *Config {
+	reloaded := current.clone()
+	for _, s := range settings {
+		if !s.Reload {
+			continue
+		}
+
+		value, ok := s.field(next, false)
+		if !ok {
+			continue
+		}
+
+		target, _ := s.field(reloaded, true)
+		target.Set(value)
+	}
+
+	return reloaded
+}
+
+// clone copies the configuration, including the nested sections.
+func (cfg *Config) clone() *Config {
+	copied := *cfg
+	value := reflect.ValueOf(&copied).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		if field.Kind() == reflect.Pointer && !field.IsNil() && field.Elem().Kind() == reflect.Struct {
+			section := reflect.New(field.Type().Elem())
+			section.Elem().Set(field.Elem())
+			field.Set(section)
+		}
+	}
+
+	return &copied
+}
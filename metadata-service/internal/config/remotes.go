@@ -0,0 +1,94 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sethvargo/go-envconfig"
+)
+
+const (
+	// remotesEnv lists the names of the additional Incus remotes.
+	remotesEnv = "INCUS_REMOTES"
+	// remotesSection is the section of the configuration file holding one table per remote.
+	remotesSection = "remotes"
+	// incusPrefix is the prefix of the primary remote's settings, which each remote repeats.
+	incusPrefix = "INCUS_CONFIG_"
+)
+
+// remoteNamePattern restricts remote names to what can be used in both an environment variable and a file key.
+var remoteNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// remotePrefix returns the environment variable prefix of a remote's settings,
+// e.g. INCUS_REMOTE_DC2_ for the remote dc2.
+func remotePrefix(name string) string {
+	return "INCUS_REMOTE_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
+
+// remoteSetting returns the setting of a remote that corresponds to a setting of the
+// primary remote, e.g. INCUS_REMOTE_DC2_SERVER_URL (remotes.dc2.server_url) for INCUS_CONFIG_SERVER_URL.
+func remoteSetting(name string, primary setting) setting {
+	suffix := strings.TrimPrefix(primary.Env, incusPrefix)
+	return setting{
+		Env:    remotePrefix(name) + suffix,
+		Key:    remotesSection + "." + name + "." + strings.ToLower(suffix),
+		Secret: primary.Secret,
+		index:  primary.index,
+	}
+}
+
+// incusSettings lists the settings of the primary remote, which every remote shares.
+func incusSettings() []setting {
+	var result []setting
+	for _, s := range settings {
+		if strings.HasPrefix(s.Env, incusPrefix) {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
+
+// loadRemotes reads the settings of every remote named in INCUS_REMOTES.
+func (cfg *Config) loadRemotes(lookuper envconfig.Lookuper) error {
+	cfg.Remotes = nil
+	for _, name := range cfg.RemoteNames {
+		remote := &IncusConfig{}
+		err := envconfig.ProcessWith(context.Background(), &envconfig.Config{
+			Target:   remote,
+			Lookuper: envconfig.PrefixLookuper(remotePrefix(name), lookuper),
+		})
+		if err != nil {
+			return fmt.Errorf("invalid configuration of Incus remote %s: %w", name, err)
+		}
+
+		remote.Name = name
+		cfg.Remotes = append(cfg.Remotes, remote)
+	}
+
+	return nil
+}
+
+// remoteView returns a configuration holding only the given remote as its Incus section,
+// so the settings of the primary remote can be used to read the remote's values.
+func remoteView(remote *IncusConfig) *Config {
+	return &Config{Incus: remote}
+}
+
+// IncusRemotes returns the primary Incus remote followed by the additional remotes.
+func (cfg *Config) IncusRemotes() []*IncusConfig {
+	return append([]*IncusConfig{cfg.Incus}, cfg.Remotes...)
+}
+
+// Remote returns the configuration of the remote with the given name.
+func (cfg *Config) Remote(name string) (*IncusConfig, bool) {
+	for _, remote := range cfg.IncusRemotes() {
+		if remote.Name == name {
+			return remote, true
+		}
+	}
+
+	return nil, false
+}
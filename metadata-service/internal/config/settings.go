@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// setting describes a single configuration value, which can be set through its
+// environment variable or its key in the configuration file.
+type setting struct {
+	// Env is the environment variable, e.g. INCUS_CONFIG_SERVER_URL.
+	Env string
+	// Key is the dotted key in the configuration file, e.g. incus.server_url.
+	Key string
+	// Reload is set for settings that are applied on SIGHUP without a restart.
+	Reload bool
+	// Secret is set for settings whose value must not be logged.
+	Secret bool
+
+	index []int
+}
+
+// String names the setting in error messages by both of the ways it can be set.
+func (s setting) String() string {
+	return fmt.Sprintf("%s (%s)", s.Env, s.Key)
+}
+
+// settings lists every setting of Config, derived from the env struct tags. Nested
+// structs with a prefix become a section of the file named after the prefix, so
+// INCUS_CONFIG_SERVER_URL is incus.server_url and ACCESS_LOG_SAMPLE_RATE is access_log.sample_rate.
+var settings = collectSettings(reflect.TypeOf(Config{}), "", "", nil)
+
+func collectSettings(t reflect.Type, envPrefix string, keyPrefix string, index []int) []setting {
+	var result []setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		name, options, _ := strings.Cut(field.Tag.Get("env"), ",")
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if name == "" && fieldType.Kind() == reflect.Struct {
+			prefix := ""
+			if value, ok := strings.CutPrefix(options, "prefix="); ok {
+				prefix = value
+			}
+
+			section := keyPrefix
+			if prefix != "" {
+				section += strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(prefix, "_"), "_CONFIG")) + "."
+			}
+
+			result = append(result, collectSettings(fieldType, envPrefix+prefix, section, fieldIndex)...)
+			continue
+		}
+
+		if name == "" {
+			continue
+		}
+
+		result = append(result, setting{
+			Env:    envPrefix + name,
+			Key:    keyPrefix + strings.ToLower(name),
+			Reload: field.Tag.Get("reload") == "true",
+			Secret: field.Tag.Get("secret") == "true",
+			index:  fieldIndex,
+		})
+	}
+
+	return result
+}
+
+// lookupSetting finds a setting by environment variable.
+func lookupSetting(env string) setting {
+	for _, s := range settings {
+		if s.Env == env {
+			return s
+		}
+	}
+
+	return setting{Env: env, Key: strings.ToLower(env)}
+}
+
+// field returns the value of a setting in cfg, allocating nested structs when allocate is set.
+func (s setting) field(cfg *Config, allocate bool) (reflect.Value, bool) {
+	value := reflect.ValueOf(cfg).Elem()
+	for _, i := range s.index {
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				if !allocate {
+					return reflect.Value{}, false
+				}
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(i)
+	}
+
+	return value, true
+}
+
+// format renders a setting value the way it would be written in an environment variable.
+func (s setting) format(cfg *Config) string {
+	value, ok := s.field(cfg, false)
+	if !ok {
+		return ""
+	}
+
+	if value.Kind() == reflect.Slice {
+		items := make([]string, value.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(value.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	}
+
+	return fmt.Sprint(value.Interface())
+}
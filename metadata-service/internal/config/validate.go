@@ -0,0 +1,295 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	localtls "github.com/lxc/incus/shared/tls"
+)
+
+// Validate checks the configuration for values that would only fail later, such as
+// malformed addresses or half-configured TLS, and reports every problem at once.
+func (cfg *Config) Validate() error {
+	var errs []error
+	report := func(s setting, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", s, fmt.Sprintf(format, args...)))
+	}
+	invalid := func(env string, format string, args ...any) {
+		report(lookupSetting(env), format, args...)
+	}
+
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		invalid("PORT", "%q is not a valid port", cfg.Port)
+	}
+
+	if !remoteNamePattern.MatchString(cfg.Incus.Name) {
+		invalid("INCUS_CONFIG_NAME", "%q must only contain lower case letters, digits and dashes", cfg.Incus.Name)
+	}
+
+	validateIncus(cfg.Incus, func(s setting) setting { return s }, report)
+
+	seen := map[string]bool{cfg.Incus.Name: true}
+	for _, remote := range cfg.Remotes {
+		if !remoteNamePattern.MatchString(remote.Name) {
+			invalid("INCUS_REMOTES", "%q must only contain lower case letters, digits and dashes", remote.Name)
+			continue
+		}
+
+		if seen[remote.Name] {
+			invalid("INCUS_REMOTES", "remote %q is defined more than once", remote.Name)
+			continue
+		}
+		seen[remote.Name] = true
+
+		validateIncus(remote, func(s setting) setting { return remoteSetting(remote.Name, s) }, report)
+
+		if len(remote.GuestAddresses) == 0 {
+			report(remoteSetting(remote.Name, lookupSetting("INCUS_CONFIG_GUEST_ADDRESSES")), "must be set to tell the instances of this remote apart")
+		}
+	}
+
+	if cfg.Database.DBSource == "" {
+		invalid("DATABASE_CONFIG_DB_SOURCE", "must not be empty")
+	}
+
+	for _, address := range cfg.Guest.Addresses {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			invalid("GUEST_CONFIG_ADDRESSES", "%q is not a host:port address", address)
+		}
+	}
+
+	for _, mapping := range cfg.Guest.Networks {
+		name, source, ok := strings.Cut(mapping, "=")
+		if vlan, isVLAN := strings.CutPrefix(source, "vlan:"); ok && isVLAN {
+			if id, err := strconv.Atoi(vlan); err != nil || id < 1 || id > 4094 {
+				invalid("GUEST_CONFIG_NETWORKS", "%q has an invalid VLAN ID", mapping)
+			}
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(source); !ok || name == "" || err != nil {
+			invalid("GUEST_CONFIG_NETWORKS", "%q must be network=host:port or network=vlan:<id>", mapping)
+		}
+	}
+
+	for _, proxy := range cfg.Guest.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil {
+			invalid("GUEST_CONFIG_TRUSTED_PROXIES", "%q is not a CIDR", proxy)
+		}
+	}
+
+	if cfg.Guest.UnixSocket != "" && !filepath.IsAbs(cfg.Guest.UnixSocket) {
+		invalid("GUEST_CONFIG_UNIX_SOCKET", "%q must be an absolute path", cfg.Guest.UnixSocket)
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Health.Address); err != nil {
+		invalid("HEALTH_CONFIG_ADDRESS", "%q is not a host:port address", cfg.Health.Address)
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Admin.Address); err != nil {
+		invalid("ADMIN_CONFIG_ADDRESS", "%q is not a host:port address", cfg.Admin.Address)
+	}
+
+	for _, pair := range [][2]string{{"GUEST_CONFIG_TLS_CERT", "GUEST_CONFIG_TLS_KEY"}, {"HEALTH_CONFIG_TLS_CERT", "HEALTH_CONFIG_TLS_KEY"}, {"ADMIN_CONFIG_TLS_CERT", "ADMIN_CONFIG_TLS_KEY"}} {
+		cert, key := lookupSetting(pair[0]).format(cfg), lookupSetting(pair[1]).format(cfg)
+		if (cert == "") != (key == "") {
+			invalid(pair[1], "must be set together with %s", lookupSetting(pair[0]))
+		}
+	}
+
+	if cfg.Admin.TLSCert == "" {
+		invalid("ADMIN_CONFIG_TLS_CERT", "must not be empty, the admin API always uses TLS")
+	}
+
+	if cfg.Admin.TokenExpiry <= 0 {
+		invalid("ADMIN_CONFIG_TOKEN_EXPIRY", "must be positive")
+	}
+
+	if _, _, err := net.SplitHostPort(cfg.Proxy.Address); err != nil {
+		invalid("PROXY_CONFIG_ADDRESS", "%q is not a host:port address", cfg.Proxy.Address)
+	}
+
+	if cfg.Proxy.Upstream != "" {
+		upstream, err := url.Parse(cfg.Proxy.Upstream)
+		if err != nil || (upstream.Scheme != "http" && upstream.Scheme != "https") || upstream.Host == "" {
+			invalid("PROXY_CONFIG_UPSTREAM", "%q must be an http:// or https:// URL", cfg.Proxy.Upstream)
+		}
+	}
+
+	if cfg.Proxy.Secret != "" && len(cfg.Proxy.Secret) < 16 {
+		invalid("PROXY_CONFIG_SECRET", "must be at least 16 characters")
+	}
+
+	for _, prefix := range []string{"GUEST_CONFIG_", "HEALTH_CONFIG_", "ADMIN_CONFIG_", "PROXY_CONFIG_"} {
+		for _, name := range []string{"READ_HEADER_TIMEOUT", "READ_TIMEOUT", "WRITE_TIMEOUT", "IDLE_TIMEOUT"} {
+			if value, _ := lookupSetting(prefix+name).field(cfg, false); value.IsValid() && value.Int() < 0 {
+				invalid(prefix+name, "must not be negative")
+			}
+		}
+	}
+
+	if cfg.Health.CheckTimeout <= 0 {
+		invalid("HEALTH_CONFIG_CHECK_TIMEOUT", "must be positive")
+	}
+
+	if cfg.Health.CheckCacheTTL < 0 {
+		invalid("HEALTH_CONFIG_CHECK_CACHE_TTL", "must not be negative")
+	}
+
+	if cfg.Retention.Interval < 0 {
+		invalid("RETENTION_CONFIG_INTERVAL", "must not be negative")
+	}
+
+	if cfg.Retention.BatchSize < 1 {
+		invalid("RETENTION_CONFIG_BATCH_SIZE", "must be at least 1")
+	}
+
+	if cfg.Retention.DeletedGracePeriod < 0 {
+		invalid("RETENTION_CONFIG_DELETED_GRACE_PERIOD", "must not be negative")
+	}
+
+	if !slices.Contains([]string{"incremental", "full", "off"}, cfg.Retention.Vacuum) {
+		invalid("RETENTION_CONFIG_VACUUM", "%q must be incremental, full or off", cfg.Retention.Vacuum)
+	}
+
+	for _, prefix := range []string{"RETENTION_CONFIG_OPERATION_", "RETENTION_CONFIG_EVENT_", "RETENTION_CONFIG_CONSOLE_", "RETENTION_CONFIG_AUDIT_"} {
+		for _, name := range []string{"MAX_AGE", "MAX_ROWS"} {
+			if value, _ := lookupSetting(prefix+name).field(cfg, false); value.IsValid() && value.Int() < 0 {
+				invalid(prefix+name, "must not be negative")
+			}
+		}
+	}
+
+	if cfg.Identity.KeyRotation <= 0 {
+		invalid("IDENTITY_CONFIG_KEY_ROTATION", "must be positive")
+	}
+
+	if cfg.Identity.KeyGracePeriod < 0 {
+		invalid("IDENTITY_CONFIG_KEY_GRACE_PERIOD", "must not be negative")
+	}
+
+	if cfg.Identity.Issuer != "" {
+		issuer, err := url.Parse(cfg.Identity.Issuer)
+		if err != nil || (issuer.Scheme != "http" && issuer.Scheme != "https") || issuer.Host == "" || issuer.RawQuery != "" || issuer.Fragment != "" {
+			invalid("IDENTITY_CONFIG_ISSUER", "%q must be an http:// or https:// URL without query or fragment", cfg.Identity.Issuer)
+		}
+
+		// Tokens reach external systems, a copy of the database must not let anyone forge them
+		if cfg.Secrets.Key == "" && cfg.Secrets.KeyFile == "" {
+			invalid("IDENTITY_CONFIG_ISSUER", "requires SECRETS_CONFIG_KEY_FILE or SECRETS_CONFIG_KEY, which seals the signing keys")
+		}
+
+		// Tokens must expire before the key that signed them is unpublished
+		if cfg.Identity.TokenLifetime > cfg.Identity.KeyGracePeriod {
+			invalid("IDENTITY_CONFIG_TOKEN_LIFETIME", "%s must not exceed IDENTITY_CONFIG_KEY_GRACE_PERIOD", cfg.Identity.TokenLifetime)
+		}
+	}
+
+	if cfg.Identity.TokenLifetime <= 0 {
+		invalid("IDENTITY_CONFIG_TOKEN_LIFETIME", "must be positive")
+	}
+
+	if cfg.Secrets.Key != "" && cfg.Secrets.KeyFile != "" {
+		invalid("SECRETS_CONFIG_KEY", "must not be set together with SECRETS_CONFIG_KEY_FILE")
+	} else if _, err := cfg.Secrets.KEK(); err != nil {
+		env := "SECRETS_CONFIG_KEY"
+		if cfg.Secrets.KeyFile != "" {
+			env = "SECRETS_CONFIG_KEY_FILE"
+		}
+
+		invalid(env, "%v", err)
+	}
+
+	if _, err := cfg.Passwords.PublicKey(); err != nil {
+		invalid("PASSWORDS_CONFIG_PUBLIC_KEY_FILE", "%v", err)
+	}
+
+	if cfg.Passwords.Length < 12 || cfg.Passwords.Length > 128 {
+		invalid("PASSWORDS_CONFIG_LENGTH", "%d must be between 12 and 128", cfg.Passwords.Length)
+	}
+
+	for _, list := range []struct {
+		env     string
+		entries []string
+	}{{"TAGS_CONFIG_ALLOW", cfg.Tags.Allow}, {"TAGS_CONFIG_DENY", cfg.Tags.Deny}} {
+		for _, entry := range list.entries {
+			project, pattern, scoped := strings.Cut(entry, "=")
+			if !scoped {
+				pattern = project
+			}
+
+			if pattern == "" || (scoped && project == "") {
+				invalid(list.env, "%q must be a pattern or project=pattern", entry)
+			}
+		}
+	}
+
+	if cfg.Tracing.Endpoint != "" {
+		endpoint, err := url.Parse(cfg.Tracing.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			invalid("TRACING_CONFIG_ENDPOINT", "%q must be an http:// or https:// URL", cfg.Tracing.Endpoint)
+		}
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		invalid("TRACING_CONFIG_SAMPLE_RATIO", "%v must be between 0 and 1", cfg.Tracing.SampleRatio)
+	}
+
+	if cfg.AccessLog.SampleRate < 1 {
+		invalid("ACCESS_LOG_SAMPLE_RATE", "must be at least 1")
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateIncus checks the connection settings of an Incus remote. name maps a setting of the
+// primary remote to the same setting of the remote being checked.
+func validateIncus(remote *IncusConfig, name func(setting) setting, report func(setting, string, ...any)) {
+	invalid := func(suffix string, format string, args ...any) {
+		report(name(lookupSetting(incusPrefix+suffix)), format, args...)
+	}
+
+	serverURL, err := url.Parse(remote.ServerURL)
+	switch {
+	case err != nil:
+		invalid("SERVER_URL", "%q must be an https:// or unix:// URL", remote.ServerURL)
+	case serverURL.Scheme == "unix":
+		if serverURL.Host != "" {
+			invalid("SERVER_URL", "%q must be unix:// or unix:///path/to/unix.socket", remote.ServerURL)
+		}
+
+		if remote.TrustToken != "" {
+			invalid("TRUST_TOKEN", "is only used with an https:// server URL")
+		}
+	case serverURL.Scheme != "https" || serverURL.Host == "":
+		invalid("SERVER_URL", "%q must be an https:// or unix:// URL", remote.ServerURL)
+	}
+
+	if remote.TrustToken != "" {
+		// The token pins the server certificate, which has to be kept for the next start
+		if remote.TLSServerCert == "" {
+			invalid("TLS_SERVER_CERT", "must be set to save the server certificate when a trust token is used")
+		}
+
+		if _, err := localtls.CertificateTokenDecode(remote.TrustToken); err != nil {
+			invalid("TRUST_TOKEN", "%v", err)
+		}
+	} else if remote.TLSServerCert != "" {
+		if _, err := os.Stat(remote.TLSServerCert); err != nil {
+			invalid("TLS_SERVER_CERT", "%v", err)
+		}
+	}
+
+	for _, address := range remote.GuestAddresses {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			invalid("GUEST_ADDRESSES", "%q is not a host:port address", address)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path"
+	"sync/atomic"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/incus"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/cache"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/tracing"
+	"github.com/lxc/incus/shared/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// invalidatingActions are the lifecycle events after which a cached instance may
+// no longer hold the same address, vsock ID, name or configuration.
+var invalidatingActions = map[string]bool{
+	api.EventLifecycleInstanceDeleted:   true,
+	api.EventLifecycleInstanceRenamed:   true,
+	api.EventLifecycleInstanceStarted:   true,
+	api.EventLifecycleInstanceStopped:   true,
+	api.EventLifecycleInstanceRestarted: true,
+	api.EventLifecycleInstanceShutdown:  true,
+	api.EventLifecycleInstanceUpdated:   true,
+}
+
+// Cache keeps the instance cache of one Incus remote in line with its event stream. It
+// marks cached instances stale when Incus reports a change, so the next request from the
+// instance resolves it against Incus again. Stale instances stay available to the admin
+// API; only deleted instances, and those a sync no longer finds, are removed.
+type Cache struct {
+	// Remote is the name of the Incus remote whose instances are cached.
+	Remote    string
+	Instances incus.InstanceLister
+	Database  db.Store
+
+	synced atomic.Bool
+}
+
+// Synced reports whether the cache has been synced with Incus at least once.
+func (c *Cache) Synced() bool {
+	return c.synced.Load()
+}
+
+// Sync caches every instance Incus knows about and drops cached instances that no longer exist.
+func (c *Cache) Sync(ctx context.Context) error {
+	ctx, span := tracing.Tracer.Start(ctx, "incus.sync", trace.WithAttributes(attribute.String("incus.remote", c.Remote)))
+	defer span.End()
+
+	instances, err := incus.ListInstances(ctx, c.Instances, api.InstanceTypeAny)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[db.GetInstanceParams]bool, len(instances))
+	for _, instance := range instances {
+		if _, err := cache.StoreInstance(ctx, c.Database, c.Remote, instance); err != nil {
+			return err
+		}
+
+		current[db.GetInstanceParams{Name: instance.Name, Project: instance.Project}] = true
+	}
+
+	cached, err := c.Database.ListInstancesByRemote(ctx, c.Remote)
+	if err != nil {
+		return fmt.Errorf("failed to list cached instances: %w", err)
+	}
+
+	removed := 0
+	for _, instance := range cached {
+		if current[db.GetInstanceParams{Name: instance.Name, Project: instance.Project}] {
+			continue
+		}
+
+		if err := c.Database.DeleteInstance(ctx, instance.ID); err != nil {
+			return fmt.Errorf("failed to remove cached instance %s: %w", instance.Name, err)
+		}
+
+		if err := c.revokeHostKeys(ctx, instance); err != nil {
+			return fmt.Errorf("failed to revoke host keys of instance %s: %w", instance.Name, err)
+		}
+		removed++
+	}
+
+	c.synced.Store(true)
+	logs.Logger.Info().Ctx(ctx).Str("remote", c.Remote).Int("instances", len(instances)).Int("removed", removed).Msg("Synced instance cache with Incus")
+
+	return nil
+}
+
+// Handle invalidates the cached instance a lifecycle event refers to.
+func (c *Cache) Handle(ctx context.Context, event api.Event, lifecycle api.EventLifecycle) {
+	if !invalidatingActions[lifecycle.Action] {
+		return
+	}
+
+	name, project := instanceFromEvent(event, lifecycle)
+	if name == "" {
+		return
+	}
+
+	c.invalidate(ctx, name, project, lifecycle.Action, lifecycle.Action == api.EventLifecycleInstanceDeleted)
+
+	// Renames are reported against the new name, the old one no longer exists in Incus
+	if oldName, ok := lifecycle.Context["old_name"].(string); ok && oldName != "" {
+		c.invalidate(ctx, oldName, project, lifecycle.Action, true)
+	}
+}
+
+// invalidate marks a cached instance stale, or removes it when it no longer exists in Incus.
+func (c *Cache) invalidate(ctx context.Context, name string, project string, action string, removed bool) {
+	instance, err := c.Database.GetInstance(ctx, db.GetInstanceParams{Remote: c.Remote, Name: name, Project: project})
+	if err == sql.ErrNoRows {
+		return
+	}
+
+	if err != nil {
+		logs.Logger.Error().Ctx(ctx).Err(err).Str("remote", c.Remote).Str("instance", name).Str("project", project).Msg("Failed to look up cached instance")
+		return
+	}
+
+	if !removed {
+		if err := c.Database.InvalidateInstance(ctx, instance.ID); err != nil {
+			logs.Logger.Error().Ctx(ctx).Err(err).Str("remote", c.Remote).Str("instance", name).Str("project", project).Msg("Failed to invalidate cached instance")
+			return
+		}
+
+		logs.Logger.Debug().Ctx(ctx).Str("remote", c.Remote).Str("instance", name).Str("project", project).Str("action", action).Msg("Invalidated cached instance")
+		return
+	}
+
+	if err := c.Database.DeleteInstance(ctx, instance.ID); err != nil {
+		logs.Logger.Error().Ctx(ctx).Err(err).Str("remote", c.Remote).Str("instance", name).Str("project", project).Msg("Failed to remove cached instance")
+		return
+	}
+
+	// A renamed instance is still the same machine, its host keys stay valid
+	if action == api.EventLifecycleInstanceDeleted {
+		if err := c.revokeHostKeys(ctx, instance); err != nil {
+			logs.Logger.Error().Ctx(ctx).Err(err).Str("remote", c.Remote).Str("instance", name).Str("project", project).Msg("Failed to revoke host keys of deleted instance")
+		}
+	}
+
+	logs.Logger.Debug().Ctx(ctx).Str("remote", c.Remote).Str("instance", name).Str("project", project).Str("action", action).Msg("Removed cached instance")
+}
+
+// revokeHostKeys revokes the SSH host keys of a deleted instance, so known_hosts files no
+// longer trust them when the name or address is reused.
+func (c *Cache) revokeHostKeys(ctx context.Context, instance db.Instance) error {
+	_, err := c.Database.RevokeInstanceHostKeys(ctx, db.RevokeInstanceHostKeysParams{RevokedReason: "deleted", InstanceID: instance.ID})
+	return err
+}
+
+// instanceFromEvent returns the instance and project an event refers to. Older Incus
+// servers only report the instance through the source URL, e.g. /1.0/instances/c1?project=web.
+func instanceFromEvent(event api.Event, lifecycle api.EventLifecycle) (string, string) {
+	name := lifecycle.Name
+	project := lifecycle.Project
+	if project == "" {
+		project = event.Project
+	}
+
+	source, err := url.Parse(lifecycle.Source)
+	if err == nil {
+		if name == "" && path.Dir(source.Path) == "/1.0/instances" {
+			name = path.Base(source.Path)
+		}
+
+		if project == "" {
+			project = source.Query().Get("project")
+		}
+	}
+
+	if project == "" {
+		project = api.ProjectDefaultName
+	}
+
+	return name, project
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/lxc/incus/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func handle(cache *Cache, lifecycle api.EventLifecycle) {
+	cache.Handle(context.Background(), api.Event{Type: api.EventTypeLifecycle}, lifecycle)
+}
+
+func TestHandle_InvalidatesDeletedInstance(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	cache := &Cache{Remote: "local", Database: mockDB}
+
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "web"}).Return(db.Instance{ID: 4, Name: "c1", Project: "web"}, nil)
+	mockDB.On("DeleteInstance", mock.Anything, int64(4)).Return(nil)
+	mockDB.On("RevokeInstanceHostKeys", mock.Anything, db.RevokeInstanceHostKeysParams{RevokedReason: "deleted", InstanceID: 4}).Return(int64(2), nil)
+
+	handle(cache, api.EventLifecycle{
+		Action: api.EventLifecycleInstanceDeleted,
+		Source: "/1.0/instances/c1?project=web",
+	})
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandle_KeepsChangedInstances(t *testing.T) {
+	actions := []string{
+		api.EventLifecycleInstanceStarted,
+		api.EventLifecycleInstanceStopped,
+		api.EventLifecycleInstanceRestarted,
+		api.EventLifecycleInstanceShutdown,
+		api.EventLifecycleInstanceUpdated,
+	}
+
+	for _, action := range actions {
+		t.Run(action, func(t *testing.T) {
+			mockDB := &mocks.MockQuerier{}
+			cache := &Cache{Remote: "local", Database: mockDB}
+
+			mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "default"}).Return(db.Instance{ID: 4, Name: "c1", Project: "default"}, nil)
+			mockDB.On("InvalidateInstance", mock.Anything, int64(4)).Return(nil)
+
+			handle(cache, api.EventLifecycle{
+				Action: action,
+				Source: "/1.0/instances/c1",
+			})
+
+			// A soft-deleted instance would be purged by the retention worker, with its history
+			mockDB.AssertExpectations(t)
+			mockDB.AssertNotCalled(t, "DeleteInstance", mock.Anything, mock.Anything)
+			mockDB.AssertNotCalled(t, "RevokeInstanceHostKeys", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandle_InvalidatesOldNameOnRename(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	cache := &Cache{Remote: "local", Database: mockDB}
+
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "new", Project: "default"}).Return(db.Instance{}, sql.ErrNoRows)
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "old", Project: "default"}).Return(db.Instance{ID: 9}, nil)
+	mockDB.On("DeleteInstance", mock.Anything, int64(9)).Return(nil)
+
+	handle(cache, api.EventLifecycle{
+		Action:  api.EventLifecycleInstanceRenamed,
+		Source:  "/1.0/instances/new",
+		Name:    "new",
+		Context: map[string]any{"old_name": "old"},
+	})
+
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "RevokeInstanceHostKeys", mock.Anything, mock.Anything)
+}
+
+func TestHandle_IgnoresUnrelatedEvents(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	cache := &Cache{Remote: "local", Database: mockDB}
+
+	handle(cache, api.EventLifecycle{
+		Action: api.EventLifecycleInstanceExec,
+		Source: "/1.0/instances/c1",
+	})
+	handle(cache, api.EventLifecycle{
+		Action: api.EventLifecycleInstanceDeleted,
+		Source: "/1.0/storage-pools/default/volumes/custom/v1",
+	})
+
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "GetInstance", mock.Anything, mock.Anything)
+}
+
+// fakeIncus implements incus.InstanceLister with a fixed set of instances
+type fakeIncus struct {
+	instances []api.InstanceFull
+}
+
+func (f *fakeIncus) GetInstancesFullAllProjects(instanceType api.InstanceType) ([]api.InstanceFull, error) {
+	return f.instances, nil
+}
+
+func TestSync_ReconcilesCacheWithIncus(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	cache := &Cache{
+		Remote:   "local",
+		Database: mockDB,
+		Instances: &fakeIncus{instances: []api.InstanceFull{
+			{Instance: api.Instance{Name: "c1", Project: "default", InstancePut: api.InstancePut{Profiles: []string{"default", "web"}, Config: map[string]string{"volatile.uuid": "6f1c"}}}},
+		}},
+	}
+
+	uuid := "6f1c"
+	mockDB.On("UpsertInstance", mock.Anything, db.UpsertInstanceParams{Remote: "local", Name: "c1", Project: "default", Uuid: &uuid}).Return(db.Instance{ID: 1, Name: "c1", Project: "default", Uuid: &uuid}, nil)
+	mockDB.On("RevokeStaleInstanceHostKeys", mock.Anything, db.RevokeStaleInstanceHostKeysParams{InstanceID: 1, InstanceUuid: "6f1c"}).Return(int64(0), nil)
+	mockDB.On("DeleteInstanceProfiles", mock.Anything, int64(1)).Return(nil)
+	mockDB.On("CreateInstanceProfile", mock.Anything, db.CreateInstanceProfileParams{InstanceID: 1, Profile: "default"}).Return(nil)
+	mockDB.On("CreateInstanceProfile", mock.Anything, db.CreateInstanceProfileParams{InstanceID: 1, Profile: "web"}).Return(nil)
+	mockDB.On("ListInstancesByRemote", mock.Anything, "local").Return([]db.Instance{
+		{ID: 1, Name: "c1", Project: "default"},
+		{ID: 2, Name: "gone", Project: "default"},
+	}, nil)
+	mockDB.On("DeleteInstance", mock.Anything, int64(2)).Return(nil)
+	mockDB.On("RevokeInstanceHostKeys", mock.Anything, db.RevokeInstanceHostKeysParams{RevokedReason: "deleted", InstanceID: 2}).Return(int64(0), nil)
+
+	assert.False(t, cache.Synced())
+	assert.NoError(t, cache.Sync(context.Background()))
+	assert.True(t, cache.Synced())
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/metrics"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/tracing"
+	incusclient "github.com/lxc/incus/client"
+	"github.com/lxc/incus/shared/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ReconnectDelay is how long the listener waits before reconnecting a dropped event stream.
+const ReconnectDelay = 5 * time.Second
+
+// EventSource is the subset of the Incus client used to follow the event stream.
+type EventSource interface {
+	GetEventsAllProjects() (*incusclient.EventListener, error)
+}
+
+// Handler reacts to the lifecycle events a Listener follows.
+type Handler interface {
+	// Sync is called every time the stream is (re)connected, since events may have
+	// been missed while disconnected.
+	Sync(ctx context.Context) error
+	// Handle is called for every lifecycle event.
+	Handle(ctx context.Context, event api.Event, lifecycle api.EventLifecycle)
+}
+
+// Listener follows the Incus lifecycle event stream across all projects, records how far
+// behind the stream is and passes every lifecycle event to its handler. Each Incus remote
+// has its own listener.
+type Listener struct {
+	// Remote is the name of the Incus remote the listener follows.
+	Remote  string
+	Incus   EventSource
+	Handler Handler
+
+	connected atomic.Bool
+}
+
+// Connected reports whether the event stream is currently established.
+func (l *Listener) Connected() bool {
+	return l.connected.Load()
+}
+
+// Run follows the event stream until ctx is cancelled, reconnecting whenever it drops.
+func (l *Listener) Run(ctx context.Context) {
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		logs.Logger.Warn().Err(err).Str("remote", l.Remote).Dur("retry_in", ReconnectDelay).Msg("Incus event stream unavailable")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ReconnectDelay):
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	listener, err := l.Incus.GetEventsAllProjects()
+	if err != nil {
+		metrics.IncusAPIErrors.WithLabelValues("GetEventsAllProjects").Inc()
+		return fmt.Errorf("failed to connect to event stream: %w", err)
+	}
+	defer listener.Disconnect()
+
+	_, err = listener.AddHandler([]string{api.EventTypeLifecycle}, func(event api.Event) {
+		l.Handle(ctx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add event handler: %w", err)
+	}
+
+	l.connected.Store(true)
+	defer l.connected.Store(false)
+	logs.Logger.Info().Str("remote", l.Remote).Msg("Connected to Incus event stream")
+
+	// Subscribe before syncing so no change between the two is missed
+	if l.Handler != nil {
+		if err := l.Handler.Sync(ctx); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- listener.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-done:
+		if err == nil {
+			err = errors.New("event stream closed")
+		}
+		metrics.IncusAPIErrors.WithLabelValues("GetEventsAllProjects").Inc()
+		return err
+	}
+}
+
+// Handle processes a single lifecycle event.
+func (l *Listener) Handle(ctx context.Context, event api.Event) {
+	ctx, span := tracing.Tracer.Start(ctx, "incus.event", trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("incus.remote", l.Remote)))
+	defer span.End()
+
+	if !event.Timestamp.IsZero() {
+		metrics.EventLag.Observe(time.Since(event.Timestamp).Seconds())
+	}
+
+	var lifecycle api.EventLifecycle
+	if err := json.Unmarshal(event.Metadata, &lifecycle); err != nil {
+		logs.Logger.Warn().Ctx(ctx).Err(err).Msg("Failed to decode lifecycle event")
+		return
+	}
+
+	span.SetAttributes(attribute.String("incus.event.action", lifecycle.Action))
+	if l.Handler != nil {
+		l.Handler.Handle(ctx, event, lifecycle)
+	}
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lxc/incus/shared/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func lifecycleEvent(t *testing.T, lifecycle api.EventLifecycle) api.Event {
+	metadata, err := json.Marshal(lifecycle)
+	assert.NoError(t, err)
+
+	return api.Event{Type: api.EventTypeLifecycle, Timestamp: time.Now(), Metadata: metadata}
+}
+
+// recordingHandler records the lifecycle events passed to it
+type recordingHandler struct {
+	actions []string
+}
+
+func (h *recordingHandler) Sync(ctx context.Context) error {
+	return nil
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, event api.Event, lifecycle api.EventLifecycle) {
+	h.actions = append(h.actions, lifecycle.Action)
+}
+
+func TestHandle_PassesLifecycleEventsToHandler(t *testing.T) {
+	handler := &recordingHandler{}
+	listener := &Listener{Remote: "local", Handler: handler}
+
+	listener.Handle(context.Background(), lifecycleEvent(t, api.EventLifecycle{
+		Action: api.EventLifecycleInstanceStarted,
+		Source: "/1.0/instances/c1",
+	}))
+	listener.Handle(context.Background(), api.Event{Type: api.EventTypeLifecycle, Metadata: json.RawMessage("not json")})
+
+	assert.Equal(t, []string{api.EventLifecycleInstanceStarted}, handler.actions)
+}
+
+func TestHandle_WithoutHandler(t *testing.T) {
+	listener := &Listener{Remote: "local"}
+
+	assert.NotPanics(t, func() {
+		listener.Handle(context.Background(), lifecycleEvent(t, api.EventLifecycle{Action: api.EventLifecycleInstanceDeleted}))
+	})
+}
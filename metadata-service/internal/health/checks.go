@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lxc/incus/shared/api"
+)
+
+// Pinger is implemented by database handles that can check their connection.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ServerGetter is the subset of the Incus client used to check the Incus API.
+type ServerGetter interface {
+	GetServer() (*api.Server, string, error)
+}
+
+// Database checks that the database answers a ping.
+func Database(database Pinger) Checker {
+	return CheckerFunc(database.Ping)
+}
+
+// Incus checks that the Incus API answers. The client call does not take a
+// context, so the registry timeout bounds it instead.
+func Incus(client ServerGetter) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		_, _, err := client.GetServer()
+		return err
+	})
+}
+
+// Condition checks a boolean reported by another component, such as whether the
+// Incus event stream is connected.
+func Condition(condition func() bool, failure string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		if !condition() {
+			return errors.New(failure)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,178 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Checker reports whether a dependency of the service is usable.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the outcome of every check in a Registry.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Registry runs a set of named checks. Each check is bounded by Timeout and the
+// report is reused for CacheTTL, so frequent probes don't hammer dependencies.
+type Registry struct {
+	Timeout  time.Duration
+	CacheTTL time.Duration
+
+	mu       sync.Mutex
+	checks   map[string]Checker
+	report   Report
+	cachedAt time.Time
+	// generation changes whenever the checks or their configuration do.
+	generation int
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry(timeout time.Duration, cacheTTL time.Duration) *Registry {
+	return &Registry{Timeout: timeout, CacheTTL: cacheTTL, checks: map[string]Checker{}}
+}
+
+// Configure changes the check timeout and cache TTL, e.g. when the configuration is reloaded.
+func (r *Registry) Configure(timeout time.Duration, cacheTTL time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Timeout = timeout
+	r.CacheTTL = cacheTTL
+	r.cachedAt = time.Time{}
+	r.generation++
+}
+
+// Register adds a check, replacing any check with the same name.
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks[name] = checker
+	r.cachedAt = time.Time{}
+	r.generation++
+}
+
+// Run returns the cached report, running every check concurrently once it has expired.
+// The checks run outside the lock, so a slow check doesn't block registering checks
+// or the configuration being changed.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	if !r.cachedAt.IsZero() && time.Since(r.cachedAt) < r.CacheTTL {
+		report := r.report
+		r.mu.Unlock()
+		return report
+	}
+
+	names := make([]string, 0, len(r.checks))
+	checks := make([]Checker, 0, len(r.checks))
+	for name := range r.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checks = append(checks, r.checks[name])
+	}
+
+	timeout := r.Timeout
+	generation := r.generation
+	r.mu.Unlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, checker := range checks {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			results[i] = run(ctx, checker, timeout)
+		}(i, checker)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(names))}
+	for i, name := range names {
+		report.Checks[name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFailed
+		}
+	}
+
+	// Don't cache a report for checks that were replaced while it ran
+	r.mu.Lock()
+	if r.generation == generation {
+		r.report = report
+		r.cachedAt = time.Now()
+	}
+	r.mu.Unlock()
+
+	return report
+}
+
+// run executes a single check, giving up after timeout even if the check ignores
+// its context, as some Incus client calls do.
+func run(ctx context.Context, checker Checker, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %s", timeout)
+	}
+
+	result := Result{Status: StatusOK, Duration: time.Since(start).String(), CheckedAt: start.UTC()}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Handler serves the report as JSON, with 503 when any check fails.
+func (r *Registry) Handler(c *gin.Context) {
+	report := r.Run(c.Request.Context())
+
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
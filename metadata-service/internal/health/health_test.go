@@ -0,0 +1,121 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_ReportsEachCheck(t *testing.T) {
+	registry := NewRegistry(time.Second, 0)
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error { return nil }))
+	registry.Register("incus", CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+
+	report := registry.Run(context.Background())
+
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, StatusFailed, report.Checks["incus"].Status)
+	assert.Equal(t, "connection refused", report.Checks["incus"].Error)
+}
+
+func TestRegistry_TimesOutChecksIgnoringTheirContext(t *testing.T) {
+	registry := NewRegistry(20*time.Millisecond, 0)
+	release := make(chan struct{})
+	defer close(release)
+	registry.Register("incus", CheckerFunc(func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusFailed, report.Status)
+	assert.Contains(t, report.Checks["incus"].Error, "timed out")
+}
+
+func TestRegistry_CachesResults(t *testing.T) {
+	var calls atomic.Int32
+	registry := NewRegistry(time.Second, time.Minute)
+	registry.Register("database", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+
+	registry.Run(context.Background())
+	registry.Run(context.Background())
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Registering a check invalidates the cached report
+	registry.Register("sync", Condition(func() bool { return true }, "not synced"))
+	registry.Run(context.Background())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestRegistry_RunsChecksOutsideTheLock(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	registry := NewRegistry(time.Second, time.Minute)
+	registry.Register("incus", CheckerFunc(func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+
+	done := make(chan Report)
+	go func() {
+		done <- registry.Run(context.Background())
+	}()
+	<-started
+
+	// A slow check must not block registering checks while it runs
+	registered := make(chan struct{})
+	go func() {
+		registry.Register("sync", Condition(func() bool { return true }, "not synced"))
+		close(registered)
+	}()
+
+	select {
+	case <-registered:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Register blocked while a check was running")
+	}
+
+	close(release)
+	<-done
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	synced := false
+	registry := NewRegistry(time.Second, 0)
+	registry.Register("sync", Condition(func() bool { return synced }, "initial sync with Incus has not completed"))
+
+	router := gin.New()
+	router.GET("/readyz", registry.Handler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(t, "initial sync with Incus has not completed", report.Checks["sync"].Error)
+
+	synced = true
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
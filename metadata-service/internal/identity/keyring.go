@@ -0,0 +1,330 @@
+// Package identity signs instance identity documents with rotating keys kept in the database,
+// so every replica of the service signs with, and publishes, the same keys.
+package identity
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/secrets"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	jose "gopkg.in/go-jose/go-jose.v2"
+)
+
+// refreshInterval is how long loaded keys are used before checking the database for keys
+// created by other replicas.
+const refreshInterval = time.Minute
+
+// sealedKeyType is the PEM type of private keys sealed with the key encryption key of the
+// secrets. The ID of the KEK and the wrapped data key are stored as PEM headers.
+const sealedKeyType = "SEALED PRIVATE KEY"
+
+// Key is a signing key along with the self-signed certificate embedded in PKCS #7 signatures.
+type Key struct {
+	ID          string
+	PrivateKey  *rsa.PrivateKey
+	Certificate *x509.Certificate
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Keyring hands out the current signing key, creating a new one every KeyRotation. Replaced
+// keys stay published for KeyGracePeriod. Private keys are sealed with Secrets when it is set,
+// so a copy of the database doesn't let anyone sign documents or tokens.
+type Keyring struct {
+	Database db.Querier
+	Config   *config.IdentityConfig
+	Secrets  *secrets.Envelope
+
+	mu       sync.Mutex
+	keys     []Key
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+// NewKeyring returns a keyring storing its keys in database, sealed with envelope unless it is nil.
+func NewKeyring(database db.Querier, cfg *config.IdentityConfig, envelope *secrets.Envelope) *Keyring {
+	return &Keyring{Database: database, Config: cfg, Secrets: envelope}
+}
+
+// Current returns the key to sign with, rotating it when it is older than KeyRotation.
+func (k *Keyring) Current(ctx context.Context) (Key, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.load(ctx); err != nil {
+		return Key{}, err
+	}
+
+	now := k.clock()
+	if len(k.keys) > 0 && now.Sub(k.keys[0].CreatedAt) < k.Config.KeyRotation {
+		return k.keys[0], nil
+	}
+
+	key, err := k.create(ctx, now)
+	if err != nil {
+		return Key{}, err
+	}
+
+	k.keys = append([]Key{key}, k.keys...)
+	logs.Logger.Info().Ctx(ctx).Str("kid", key.ID).Time("expires_at", key.ExpiresAt).Msg("Created instance identity signing key")
+	return key, nil
+}
+
+// Keys returns the published keys, newest first.
+func (k *Keyring) Keys(ctx context.Context) ([]Key, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if err := k.load(ctx); err != nil {
+		return nil, err
+	}
+
+	return k.keys, nil
+}
+
+// JWKS returns the public part of the published keys. The current key is created if needed,
+// so verifiers can fetch it before the first document is signed.
+func (k *Keyring) JWKS(ctx context.Context) (jose.JSONWebKeySet, error) {
+	if _, err := k.Current(ctx); err != nil {
+		return jose.JSONWebKeySet{}, err
+	}
+
+	keys, err := k.Keys(ctx)
+	if err != nil {
+		return jose.JSONWebKeySet{}, err
+	}
+
+	set := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:          key.PrivateKey.Public(),
+			KeyID:        key.ID,
+			Algorithm:    string(jose.RS256),
+			Use:          "sig",
+			Certificates: []*x509.Certificate{key.Certificate},
+		})
+	}
+
+	return set, nil
+}
+
+func (k *Keyring) clock() time.Time {
+	if k.now != nil {
+		return k.now()
+	}
+
+	return time.Now()
+}
+
+// load reads the published keys from the database unless they were read recently.
+func (k *Keyring) load(ctx context.Context) error {
+	now := k.clock()
+	if !k.loadedAt.IsZero() && now.Sub(k.loadedAt) < refreshInterval {
+		return nil
+	}
+
+	rows, err := k.Database.ListSigningKeys(ctx, now.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to list signing keys: %w", err)
+	}
+
+	keys := make([]Key, 0, len(rows))
+	for _, row := range rows {
+		key, sealed, err := k.parseKey(row)
+		if err != nil {
+			logs.Logger.Error().Ctx(ctx).Err(err).Str("kid", row.Kid).Msg("Ignoring invalid signing key")
+			continue
+		}
+
+		// Keys created before a KEK was configured are sealed in place
+		if !sealed && k.Secrets != nil {
+			k.seal(ctx, row.ID, key)
+		}
+
+		keys = append(keys, key)
+	}
+
+	k.keys = keys
+	k.loadedAt = now
+	return nil
+}
+
+// create generates a key and stores it, valid for the rotation period and the grace period after it.
+func (k *Keyring) create(ctx context.Context, now time.Time) (Key, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	kid, err := thumbprint(privateKey.Public())
+	if err != nil {
+		return Key{}, err
+	}
+
+	expiresAt := now.Add(k.Config.KeyRotation + k.Config.KeyGracePeriod).Truncate(time.Second)
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return Key{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Instance identity " + kid},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     expiresAt,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to create signing certificate: %w", err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return Key{}, err
+	}
+
+	encoded, err := k.encodePrivateKey(kid, privateKey)
+	if err != nil {
+		return Key{}, err
+	}
+
+	row, err := k.Database.CreateSigningKey(ctx, db.CreateSigningKeyParams{
+		Kid:         kid,
+		Algorithm:   string(jose.RS256),
+		PrivateKey:  encoded,
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		ExpiresAt:   expiresAt.Unix(),
+	})
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to store signing key: %w", err)
+	}
+
+	return Key{ID: row.Kid, PrivateKey: privateKey, Certificate: certificate, CreatedAt: now, ExpiresAt: expiresAt}, nil
+}
+
+// seal replaces the plaintext private key of a stored key with a sealed one. Failures leave the
+// key usable, it is sealed again on the next load.
+func (k *Keyring) seal(ctx context.Context, id int64, key Key) {
+	encoded, err := k.encodePrivateKey(key.ID, key.PrivateKey)
+	if err == nil {
+		err = k.Database.UpdateSigningKey(ctx, db.UpdateSigningKeyParams{PrivateKey: encoded, ID: id})
+	}
+
+	if err != nil {
+		logs.Logger.Warn().Ctx(ctx).Err(err).Str("kid", key.ID).Msg("Failed to seal signing key")
+		return
+	}
+
+	logs.Logger.Info().Ctx(ctx).Str("kid", key.ID).Msg("Sealed signing key")
+}
+
+// encodePrivateKey returns the PEM encoded PKCS #8 private key, sealed when a KEK is configured.
+func (k *Keyring) encodePrivateKey(kid string, privateKey *rsa.PrivateKey) (string, error) {
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	if k.Secrets == nil {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})), nil
+	}
+
+	sealed, err := k.Secrets.Seal(pkcs8, additionalData(kid))
+	if err != nil {
+		return "", fmt.Errorf("failed to seal signing key: %w", err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type: sealedKeyType,
+		Headers: map[string]string{
+			"Key-Id":      sealed.KeyID,
+			"Wrapped-Key": base64.StdEncoding.EncodeToString(sealed.WrappedKey),
+		},
+		Bytes: sealed.Ciphertext,
+	})), nil
+}
+
+// parseKey decodes a stored key, reporting whether its private key was sealed.
+func (k *Keyring) parseKey(row db.SigningKey) (Key, bool, error) {
+	block, _ := pem.Decode([]byte(row.PrivateKey))
+	if block == nil {
+		return Key{}, false, errors.New("private key is not PEM encoded")
+	}
+
+	pkcs8 := block.Bytes
+	sealed := block.Type == sealedKeyType
+	if sealed {
+		if k.Secrets == nil {
+			return Key{}, true, errors.New("private key is sealed but no key encryption key is configured")
+		}
+
+		wrappedKey, err := base64.StdEncoding.DecodeString(block.Headers["Wrapped-Key"])
+		if err != nil {
+			return Key{}, true, fmt.Errorf("malformed wrapped key: %w", err)
+		}
+
+		pkcs8, err = k.Secrets.Open(secrets.Sealed{KeyID: block.Headers["Key-Id"], WrappedKey: wrappedKey, Ciphertext: block.Bytes}, additionalData(row.Kid))
+		if err != nil {
+			return Key{}, true, err
+		}
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(pkcs8)
+	if err != nil {
+		return Key{}, sealed, err
+	}
+
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return Key{}, sealed, errors.New("private key is not an RSA key")
+	}
+
+	block, _ = pem.Decode([]byte(row.Certificate))
+	if block == nil {
+		return Key{}, sealed, errors.New("certificate is not PEM encoded")
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return Key{}, sealed, err
+	}
+
+	key := Key{ID: row.Kid, PrivateKey: privateKey, Certificate: certificate, ExpiresAt: row.ExpiresAt}
+	if row.CreatedAt != nil {
+		key.CreatedAt = *row.CreatedAt
+	}
+
+	return key, sealed, nil
+}
+
+// additionalData binds a sealed private key to its key ID, so it can't be swapped with another.
+func additionalData(kid string) []byte {
+	return []byte("signing key\x00" + kid)
+}
+
+// thumbprint identifies a public key by its RFC 7638 JWK thumbprint.
+func thumbprint(key crypto.PublicKey) (string, error) {
+	jwk := jose.JSONWebKey{Key: key}
+	sum, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(sum), nil
+}
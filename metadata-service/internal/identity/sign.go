@@ -0,0 +1,85 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	pkgidentity "github.com/focadecombate/incus-metadata-service/metadata-service/pkg/identity"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	jose "gopkg.in/go-jose/go-jose.v2"
+)
+
+// Document returns the identity document of an instance, for the nonce the guest asked for if
+// any. addresses are the instance's current addresses, the first of which is its private IP.
+// The bytes served are the bytes signed, so they are never re-encoded afterwards.
+func Document(instance db.Instance, addresses []string, nonce string) ([]byte, error) {
+	document := types.InstanceIdentityDocument{
+		InstanceID: instance.Name,
+		Project:    instance.Project,
+		Remote:     instance.Remote,
+		Nonce:      nonce,
+		Version:    types.InstanceIdentityDocumentVersion,
+	}
+
+	if instance.Uuid != nil {
+		document.InstanceUUID = *instance.Uuid
+	}
+
+	if len(addresses) > 0 {
+		document.PrivateIP = addresses[0]
+	}
+
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// Signature returns the base64 encoded RSA SHA-256 signature of document.
+func (k Key) Signature(document []byte) (string, error) {
+	digest := sha256.Sum256(document)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// PKCS7 returns the PKCS #7 signature of document, base64 encoded in lines of 64 characters
+// without PEM armor, like EC2 serves it.
+func (k Key) PKCS7(document []byte, detached bool) (string, error) {
+	der, err := pkgidentity.SignPKCS7(document, k.Certificate, k.PrivateKey, detached)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(der)
+
+	var lines strings.Builder
+	for len(encoded) > 64 {
+		lines.WriteString(encoded[:64] + "\n")
+		encoded = encoded[64:]
+	}
+	lines.WriteString(encoded)
+
+	return lines.String(), nil
+}
+
+// JWS returns document as the payload of a compact JWS naming the key that signed it.
+func (k Key) JWS(document []byte) (string, error) {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: k.PrivateKey, KeyID: k.ID}}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := signer.Sign(document)
+	if err != nil {
+		return "", err
+	}
+
+	return signed.CompactSerialize()
+}
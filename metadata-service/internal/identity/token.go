@@ -0,0 +1,106 @@
+package identity
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/zitadel/oidc/v2/pkg/oidc"
+	jose "gopkg.in/go-jose/go-jose.v2"
+)
+
+// JWKSPath is where the health listener serves the keys verifying workload identity tokens,
+// next to oidc.DiscoveryEndpoint, both under the path of the issuer, see IssuerPath.
+const JWKSPath = "/.well-known/jwks.json"
+
+// Discovery returns the OpenID Provider metadata of issuer, letting relying parties such as
+// Vault find the keys verifying its tokens. Only ID tokens are issued, by the guest API rather
+// than through OAuth flows, so no other endpoint is advertised.
+func Discovery(issuer string) oidc.DiscoveryConfiguration {
+	return oidc.DiscoveryConfiguration{
+		Issuer:                           issuer,
+		JwksURI:                          strings.TrimSuffix(issuer, "/") + JWKSPath,
+		ResponseTypesSupported:           []string{"id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{string(jose.RS256)},
+		ClaimsSupported:                  []string{"iss", "sub", "aud", "iat", "nbf", "exp", "jti", "instance", "instance_uuid", "project", "profiles", "remote"},
+	}
+}
+
+// IssuerPath returns the path the health listener serves route at for issuer. Relying parties
+// look the /.well-known routes up under the path of the issuer, so an issuer such as
+// https://example.com/metadata has its keys at /metadata/.well-known/jwks.json.
+func IssuerPath(issuer, route string) string {
+	parsed, err := url.Parse(issuer)
+	if err != nil {
+		return route
+	}
+
+	return strings.TrimSuffix(parsed.Path, "/") + route
+}
+
+// Subject returns the sub claim of the tokens of an instance.
+func Subject(instance db.Instance) string {
+	return fmt.Sprintf("instance:%s:%s:%s", instance.Remote, instance.Project, instance.Name)
+}
+
+// WorkloadClaims returns the claims of a workload identity token issued to instance for
+// audience at now, valid for lifetime.
+func WorkloadClaims(instance db.Instance, profiles []string, issuer, audience string, now time.Time, lifetime time.Duration) (types.WorkloadIdentityClaims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return types.WorkloadIdentityClaims{}, err
+	}
+
+	if profiles == nil {
+		profiles = []string{}
+	}
+
+	claims := types.WorkloadIdentityClaims{
+		Issuer:    issuer,
+		Subject:   Subject(instance),
+		Audience:  audience,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		Expiry:    now.Add(lifetime).Unix(),
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		Instance:  instance.Name,
+		Project:   instance.Project,
+		Profiles:  profiles,
+		Remote:    instance.Remote,
+	}
+
+	if instance.Uuid != nil {
+		claims.InstanceUUID = *instance.Uuid
+	}
+
+	return claims, nil
+}
+
+// Token returns claims as a JWT signed with RS256, whose key ID names the key of the JWKS
+// that verifies it.
+func (k Key) Token(claims types.WorkloadIdentityClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	options := (&jose.SignerOptions{}).WithType("JWT")
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: k.PrivateKey, KeyID: k.ID}}, options)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return signed.CompactSerialize()
+}
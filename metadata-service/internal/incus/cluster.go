@@ -0,0 +1,230 @@
+package incus
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	incus "github.com/lxc/incus/client"
+	"github.com/lxc/incus/shared/api"
+	"golang.org/x/sync/singleflight"
+)
+
+// stateConcurrency bounds the instance state requests made at once while listing a cluster.
+const stateConcurrency = 16
+
+// ListingTTL is how long a cluster listing is reused. Listing a cluster reads the state of
+// every instance from its member, so lookups and syncs arriving together share one listing.
+const ListingTTL = 2 * time.Second
+
+// Cluster lists instances through a connection to every member of an Incus cluster, so the
+// state of each instance is read from the member that hosts it instead of being gathered by
+// the member the service is connected to. On a standalone server it passes calls through.
+type Cluster struct {
+	client  incus.InstanceServer
+	members map[string]incus.InstanceServer
+	local   string
+
+	listings singleflight.Group
+	mu       sync.Mutex
+	listed   map[string]listing
+	now      func() time.Time
+}
+
+// listing is a cluster listing and when it was made.
+type listing struct {
+	instances []api.InstanceFull
+	at        time.Time
+}
+
+// ConnectCluster discovers the members of the cluster the client is connected to and connects
+// to each of them with the same credentials. Over the unix socket, requests are targeted at
+// members through the local server instead, since members are only reachable over TLS.
+func ConnectCluster(config *config.IncusConfig, client incus.InstanceServer) (*Cluster, error) {
+	server, _, err := client.GetServer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Incus server: %w", err)
+	}
+
+	cluster := NewCluster(client, map[string]incus.InstanceServer{}, config.ClusterMember)
+	if !server.Environment.ServerClustered {
+		return cluster, nil
+	}
+
+	serverURL, err := url.Parse(config.ServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Incus server URL: %w", err)
+	}
+
+	// The unix socket is always served by the member on this host
+	unix := serverURL.Scheme == "unix"
+	if unix && cluster.local == "" {
+		cluster.local = server.Environment.ServerName
+	}
+
+	var args *incus.ConnectionArgs
+	if !unix {
+		args, err = connectionArgs(config)
+		if err != nil {
+			return nil, err
+		}
+
+		// Members that are down must not prevent the service from starting
+		args.SkipGetServer = true
+	}
+
+	members, err := client.GetClusterMembers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster members: %w", err)
+	}
+
+	for _, member := range members {
+		if unix {
+			cluster.members[member.ServerName] = client.UseTarget(member.ServerName)
+			continue
+		}
+
+		memberClient, err := incus.ConnectIncus(member.URL, args)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to cluster member %s: %w", member.ServerName, err)
+		}
+
+		cluster.members[member.ServerName] = memberClient
+	}
+
+	if cluster.local == "" {
+		logs.Logger.Warn().Str("remote", config.Name).Msg("Cluster member is not set, vsock and unix socket lookups consider instances on every cluster member")
+	}
+
+	logs.Logger.Info().Str("remote", config.Name).Int("members", len(cluster.members)).Str("local_member", cluster.local).Msg("Connected to Incus cluster")
+
+	return cluster, nil
+}
+
+// NewCluster returns a Cluster for a client connected to a standalone server or, with
+// members, to a cluster. It is mostly useful for tests, see ConnectCluster.
+func NewCluster(client incus.InstanceServer, members map[string]incus.InstanceServer, local string) *Cluster {
+	return &Cluster{client: client, members: members, local: local, listed: map[string]listing{}, now: time.Now}
+}
+
+// GetInstancesFullAllProjects lists instances across all projects together with their state.
+func (c *Cluster) GetInstancesFullAllProjects(instanceType api.InstanceType) ([]api.InstanceFull, error) {
+	return c.list(instanceType, "")
+}
+
+// Local returns a lister limited to the instances hosted by the local cluster member, for
+// identifiers that are only unique per host such as vsock context IDs and PIDs.
+func (c *Cluster) Local() InstanceLister {
+	return localLister{cluster: c}
+}
+
+type localLister struct {
+	cluster *Cluster
+}
+
+func (l localLister) GetInstancesFullAllProjects(instanceType api.InstanceType) ([]api.InstanceFull, error) {
+	return l.cluster.list(instanceType, l.cluster.local)
+}
+
+// list lists instances, only those hosted by the given member when location is set. A listing
+// made less than ListingTTL ago is reused, and concurrent callers wait for the same listing.
+func (c *Cluster) list(instanceType api.InstanceType, location string) ([]api.InstanceFull, error) {
+	if len(c.members) == 0 {
+		return c.client.GetInstancesFullAllProjects(instanceType)
+	}
+
+	key := string(instanceType) + "|" + location
+
+	c.mu.Lock()
+	cached, ok := c.listed[key]
+	c.mu.Unlock()
+	if ok && c.now().Sub(cached.at) < ListingTTL {
+		return slices.Clone(cached.instances), nil
+	}
+
+	listed, err, _ := c.listings.Do(key, func() (any, error) {
+		instances, err := c.listMembers(instanceType, location)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.listed[key] = listing{instances: instances, at: c.now()}
+		c.mu.Unlock()
+
+		return instances, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Clone(listed.([]api.InstanceFull)), nil
+}
+
+// listMembers lists instances with the state read from the member hosting each of them.
+func (c *Cluster) listMembers(instanceType api.InstanceType, location string) ([]api.InstanceFull, error) {
+	// Listing without state is answered from the cluster database and doesn't reach every member
+	instances, err := c.client.GetInstancesAllProjects(instanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	full := make([]api.InstanceFull, 0, len(instances))
+	for _, instance := range instances {
+		if location != "" && instance.Location != location {
+			continue
+		}
+
+		// Context IDs are only unique per host, so those of other members are never cached as
+		// identifying a caller over the local vsock
+		if c.local != "" && instance.Location != c.local {
+			if _, ok := instance.Config["volatile.vsock_id"]; ok {
+				instance.Config = maps.Clone(instance.Config)
+				delete(instance.Config, "volatile.vsock_id")
+			}
+		}
+
+		full = append(full, api.InstanceFull{Instance: instance})
+	}
+
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, stateConcurrency)
+	for i := range full {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(instance *api.InstanceFull) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+
+			state, _, err := c.member(instance.Location).UseProject(instance.Project).GetInstanceState(instance.Name)
+			if err != nil {
+				// An unreachable member only hides its own instances
+				logs.Logger.Warn().Err(err).Str("instance", instance.Name).Str("project", instance.Project).
+					Str("member", instance.Location).Msg("Failed to get instance state from cluster member")
+				return
+			}
+
+			instance.State = state
+		}(&full[i])
+	}
+	wg.Wait()
+
+	return full, nil
+}
+
+// member returns the connection to the given cluster member, or the one to the server the
+// service is connected to for members that joined after startup.
+func (c *Cluster) member(name string) incus.InstanceServer {
+	if member, ok := c.members[name]; ok {
+		return member
+	}
+
+	return c.client.UseTarget(name)
+}
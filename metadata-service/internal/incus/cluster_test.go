@@ -0,0 +1,160 @@
+package incus
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	incus "github.com/lxc/incus/client"
+	"github.com/lxc/incus/shared/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer implements the calls the cluster lister makes, calling any other method panics.
+type fakeServer struct {
+	incus.InstanceServer
+
+	name      string
+	project   string
+	instances []api.Instance
+	states    map[string]*api.InstanceState
+
+	mu    *sync.Mutex
+	calls *[]string
+}
+
+func newFakeServer(name string, calls *[]string, mu *sync.Mutex) *fakeServer {
+	return &fakeServer{name: name, states: map[string]*api.InstanceState{}, calls: calls, mu: mu}
+}
+
+func (s *fakeServer) GetInstancesAllProjects(api.InstanceType) ([]api.Instance, error) {
+	return s.instances, nil
+}
+
+func (s *fakeServer) UseProject(name string) incus.InstanceServer {
+	project := *s
+	project.project = name
+	return &project
+}
+
+func (s *fakeServer) UseTarget(name string) incus.InstanceServer {
+	return newFakeServer("target:"+name, s.calls, s.mu)
+}
+
+func (s *fakeServer) GetInstanceState(name string) (*api.InstanceState, string, error) {
+	s.mu.Lock()
+	*s.calls = append(*s.calls, fmt.Sprintf("%s %s/%s", s.name, s.project, name))
+	s.mu.Unlock()
+
+	state, ok := s.states[s.project+"/"+name]
+	if !ok {
+		return nil, "", fmt.Errorf("not found")
+	}
+
+	return state, "", nil
+}
+
+func TestCluster_ReadsStateFromHostingMember(t *testing.T) {
+	var calls []string
+	var mu sync.Mutex
+
+	primary := newFakeServer("primary", &calls, &mu)
+	primary.instances = []api.Instance{
+		{Name: "vm1", Project: "default", Location: "node1", InstancePut: api.InstancePut{Config: map[string]string{"volatile.vsock_id": "7"}}},
+		{Name: "vm2", Project: "staging", Location: "node2", InstancePut: api.InstancePut{Config: map[string]string{"volatile.vsock_id": "7"}}},
+	}
+
+	node1 := newFakeServer("node1", &calls, &mu)
+	node1.states["default/vm1"] = &api.InstanceState{Status: "Running"}
+	node2 := newFakeServer("node2", &calls, &mu)
+	node2.states["staging/vm2"] = &api.InstanceState{Status: "Running"}
+
+	cluster := NewCluster(primary, map[string]incus.InstanceServer{"node1": node1, "node2": node2}, "node2")
+
+	instances, err := cluster.GetInstancesFullAllProjects(api.InstanceTypeAny)
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, "Running", instances[0].State.Status)
+	assert.Equal(t, "Running", instances[1].State.Status)
+	assert.ElementsMatch(t, []string{"node1 default/vm1", "node2 staging/vm2"}, calls)
+
+	// Only the local VM keeps its context ID
+	_, ok := VsockID(instances[0])
+	assert.False(t, ok)
+	id, ok := VsockID(instances[1])
+	assert.True(t, ok)
+	assert.Equal(t, uint32(7), id)
+	assert.Equal(t, "7", primary.instances[0].Config["volatile.vsock_id"])
+
+	calls = nil
+	local, err := cluster.Local().GetInstancesFullAllProjects(api.InstanceTypeVM)
+	require.NoError(t, err)
+	require.Len(t, local, 1)
+	assert.Equal(t, "vm2", local[0].Name)
+	assert.Equal(t, []string{"node2 staging/vm2"}, calls)
+}
+
+func TestCluster_UnreachableMemberOnlyHidesItsInstances(t *testing.T) {
+	var calls []string
+	var mu sync.Mutex
+
+	primary := newFakeServer("primary", &calls, &mu)
+	primary.instances = []api.Instance{
+		{Name: "vm1", Project: "default", Location: "node1"},
+		{Name: "vm3", Project: "default", Location: "node3"},
+	}
+
+	node1 := newFakeServer("node1", &calls, &mu)
+	node1.states["default/vm1"] = &api.InstanceState{Status: "Running"}
+
+	cluster := NewCluster(primary, map[string]incus.InstanceServer{"node1": node1}, "")
+
+	instances, err := cluster.GetInstancesFullAllProjects(api.InstanceTypeAny)
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.NotNil(t, instances[0].State)
+	assert.Nil(t, instances[1].State)
+
+	// Members that joined after startup are reached through the primary connection
+	assert.Contains(t, calls, "target:node3 default/vm3")
+}
+
+func TestCluster_ReusesRecentListing(t *testing.T) {
+	var calls []string
+	var mu sync.Mutex
+
+	primary := newFakeServer("primary", &calls, &mu)
+	primary.instances = []api.Instance{{Name: "vm1", Project: "default", Location: "node1"}}
+
+	node1 := newFakeServer("node1", &calls, &mu)
+	node1.states["default/vm1"] = &api.InstanceState{Status: "Running"}
+
+	now := time.Now()
+	cluster := NewCluster(primary, map[string]incus.InstanceServer{"node1": node1}, "node1")
+	cluster.now = func() time.Time { return now }
+
+	// Concurrent callers share a single listing
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instances, err := cluster.GetInstancesFullAllProjects(api.InstanceTypeAny)
+			assert.NoError(t, err)
+			assert.Len(t, instances, 1)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"node1 default/vm1"}, calls)
+
+	_, err := cluster.GetInstancesFullAllProjects(api.InstanceTypeAny)
+	require.NoError(t, err)
+	assert.Len(t, calls, 1, "a recent listing is reused")
+
+	now = now.Add(ListingTTL)
+	_, err = cluster.GetInstancesFullAllProjects(api.InstanceTypeAny)
+	require.NoError(t, err)
+	assert.Len(t, calls, 2, "an expired listing is made again")
+}
@@ -1,40 +1,175 @@
 package incus
 
 import (
+	"encoding/pem"
 	"fmt"
+	"net/url"
 	"os"
+	"time"
 
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
 	incus "github.com/lxc/incus/client"
+	"github.com/lxc/incus/shared/api"
+	localtls "github.com/lxc/incus/shared/tls"
 )
 
-func ConnectToIncus(config *config.Config) (incus.InstanceServer, error) {
+// UserAgent identifies the service to Incus.
+const UserAgent = "incus-metadata-service"
+
+// ConnectToIncus connects to an Incus remote named by its SERVER_URL. A unix:// URL
+// connects over the local unix socket (the default socket when no path is given), anything else
+// over TLS with the client certificate. The server certificate is pinned when
+// TLS_SERVER_CERT is set and verified against the system CAs otherwise.
+//
+// When TRUST_TOKEN is set and the server doesn't trust the client yet, the client
+// certificate is generated if missing and enrolled with the token, and the server certificate is
+// pinned to the fingerprint carried by the token and saved for the next start.
+func ConnectToIncus(config *config.IncusConfig) (incus.InstanceServer, error) {
+	serverURL, err := url.Parse(config.ServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Incus server URL: %w", err)
+	}
+
+	if serverURL.Scheme == "unix" {
+		client, err := incus.ConnectIncusUnix(serverURL.Path, &incus.ConnectionArgs{UserAgent: UserAgent})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to Incus unix socket: %w", err)
+		}
+
+		return client, nil
+	}
+
+	if config.TrustToken != "" {
+		// Generate a client certificate on first run, there is nothing to enroll otherwise
+		err := localtls.FindOrGenCert(config.TLSClientCert, config.TLSClientKey, true, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate client certificate: %w", err)
+		}
+	}
+
+	args, err := connectionArgs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.TrustToken != "" && args.TLSServerCert == "" {
+		// The certificate is written before enrolling, so a token is never spent on an unpinned server
+		args.TLSServerCert, err = pinServerCertificate(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Connect to the Incus server with TLS configuration
+	client, err := incus.ConnectIncus(config.ServerURL, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Incus server: %w", err)
+	}
+
+	if config.TrustToken == "" {
+		return client, nil
+	}
+
+	server, _, err := client.GetServer()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Incus server: %w", err)
+	}
+
+	if server.Auth == "trusted" {
+		return client, nil
+	}
+
+	if err := enroll(client, config.TrustToken); err != nil {
+		return nil, err
+	}
+
+	// Reconnect so the client sees the server as trusted
+	client, err = incus.ConnectIncus(config.ServerURL, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Incus server: %w", err)
+	}
+
+	return client, nil
+}
+
+// connectionArgs reads the certificates used to connect to Incus over TLS.
+func connectionArgs(config *config.IncusConfig) (*incus.ConnectionArgs, error) {
 	// Read the certificate files
-	certData, err := os.ReadFile(config.Incus.TLSClientCert)
+	certData, err := os.ReadFile(config.TLSClientCert)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read client certificate file: %w", err)
 	}
 
-	keyData, err := os.ReadFile(config.Incus.TLSClientKey)
+	keyData, err := os.ReadFile(config.TLSClientKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read client key file: %w", err)
 	}
 
-	serverCertData, err := os.ReadFile(config.Incus.TLSServerCert)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read server certificate file: %w", err)
+	// The server certificate is optional, without it the system CAs are used
+	var serverCertData []byte
+	if config.TLSServerCert != "" {
+		serverCertData, err = os.ReadFile(config.TLSServerCert)
+		if err != nil && (config.TrustToken == "" || !os.IsNotExist(err)) {
+			return nil, fmt.Errorf("failed to read server certificate file: %w", err)
+		}
 	}
 
-	// Connect to the Incus server with TLS configuration
-	client, err := incus.ConnectIncus(config.Incus.ServerURL, &incus.ConnectionArgs{
+	return &incus.ConnectionArgs{
 		TLSClientCert:      string(certData),
 		TLSClientKey:       string(keyData),
-		InsecureSkipVerify: config.Incus.TLSInsecureSkipVerify,
+		InsecureSkipVerify: config.TLSInsecureSkipVerify,
 		TLSServerCert:      string(serverCertData),
+		UserAgent:          UserAgent,
+	}, nil
+}
+
+// pinServerCertificate fetches the server certificate, checks it against the fingerprint in
+// the trust token and saves it to TLS_SERVER_CERT.
+func pinServerCertificate(config *config.IncusConfig) (string, error) {
+	token, err := localtls.CertificateTokenDecode(config.TrustToken)
+	if err != nil {
+		return "", fmt.Errorf("invalid trust token: %w", err)
+	}
+
+	cert, err := localtls.GetRemoteCertificate(config.ServerURL, UserAgent)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Incus server certificate: %w", err)
+	}
+
+	if localtls.CertFingerprint(cert) != token.Fingerprint {
+		return "", fmt.Errorf("Incus server certificate fingerprint does not match the trust token")
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := os.WriteFile(config.TLSServerCert, certPEM, 0o644); err != nil {
+		return "", fmt.Errorf("failed to save server certificate: %w", err)
+	}
+
+	return string(certPEM), nil
+}
+
+// enroll adds the client certificate to the server's trust store with a trust token,
+// the same way `incus remote add <name> <token>` does.
+func enroll(client incus.InstanceServer, trustToken string) error {
+	token, err := localtls.CertificateTokenDecode(trustToken)
+	if err != nil {
+		return fmt.Errorf("invalid trust token: %w", err)
+	}
+
+	if !token.ExpiresAt.IsZero() && time.Now().After(token.ExpiresAt) {
+		return fmt.Errorf("trust token expired at %s", token.ExpiresAt)
+	}
+
+	err = client.CreateCertificate(api.CertificatesPost{
+		CertificatePut: api.CertificatePut{Name: token.ClientName, Type: api.CertificateTypeClient},
+		TrustToken:     token.Secret,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Incus server: %w", err)
+		return fmt.Errorf("failed to enroll with the trust token: %w", err)
 	}
 
-	return client, nil
-}
\ No newline at end of file
+	logs.Logger.Info().Str("client_name", token.ClientName).Msg("Enrolled with Incus using the trust token")
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package incus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/tracing"
+	"github.com/lxc/incus/shared/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstanceLister is the subset of the Incus client used to look up instances.
+type InstanceLister interface {
+	GetInstancesFullAllProjects(instanceType api.InstanceType) ([]api.InstanceFull, error)
+}
+
+// ListInstances lists instances of the given type across all projects, tracing the Incus API call.
+func ListInstances(ctx context.Context, client InstanceLister, instanceType api.InstanceType) ([]api.InstanceFull, error) {
+	_, span := tracing.Tracer.Start(ctx, "incus.GetInstancesFullAllProjects",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("incus.instance_type", string(instanceType))),
+	)
+
+	instances, err := client.GetInstancesFullAllProjects(instanceType)
+	span.SetAttributes(attribute.Int("incus.instances", len(instances)))
+	tracing.End(span, err)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	return instances, nil
+}
+
+// InstanceAddresses returns the global unicast addresses of a running instance.
+func InstanceAddresses(instance api.InstanceFull) []string {
+	if instance.State == nil {
+		return nil
+	}
+
+	var addresses []string
+	for _, network := range instance.State.Network {
+		for _, address := range network.Addresses {
+			ip := net.ParseIP(address.Address)
+			if ip == nil || !ip.IsGlobalUnicast() {
+				continue
+			}
+
+			addresses = append(addresses, ip.String())
+		}
+	}
+
+	return addresses
+}
+
+// Address is an address of an instance NIC together with the network the NIC is attached to.
+type Address struct {
+	// Network is the managed network of the NIC, or its parent bridge for unmanaged ones.
+	Network string
+	IP      string
+	MAC     string
+}
+
+// NetworkAddresses returns the global unicast addresses of a running instance, with the
+// network and MAC address of the NIC holding them. Interfaces are matched to NIC devices by
+// MAC address, interfaces that don't belong to a NIC device are skipped.
+func NetworkAddresses(instance api.InstanceFull) []Address {
+	if instance.State == nil {
+		return nil
+	}
+
+	networks := map[string]string{}
+	for name, device := range instance.ExpandedDevices {
+		if device["type"] != "nic" {
+			continue
+		}
+
+		network := device["network"]
+		if network == "" {
+			network = device["parent"]
+		}
+
+		mac := device["hwaddr"]
+		if mac == "" {
+			mac = instance.ExpandedConfig["volatile."+name+".hwaddr"]
+		}
+
+		if network != "" && mac != "" {
+			networks[strings.ToLower(mac)] = network
+		}
+	}
+
+	var addresses []Address
+	for _, iface := range instance.State.Network {
+		mac := strings.ToLower(iface.Hwaddr)
+		network, ok := networks[mac]
+		if !ok {
+			continue
+		}
+
+		for _, address := range iface.Addresses {
+			ip := net.ParseIP(address.Address)
+			if ip == nil || !ip.IsGlobalUnicast() {
+				continue
+			}
+
+			addresses = append(addresses, Address{Network: network, IP: ip.String(), MAC: mac})
+		}
+	}
+
+	return addresses
+}
+
+// VsockID returns the vsock context ID assigned to a virtual machine.
+func VsockID(instance api.InstanceFull) (uint32, bool) {
+	value, ok := instance.Config["volatile.vsock_id"]
+	if !ok {
+		return 0, false
+	}
+
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	return uint32(id), true
+}
+
+// cloudInitKeys are the user.* keys older cloud-init images read their configuration from,
+// which aren't tags.
+var cloudInitKeys = []string{"user.meta-data", "user.user-data", "user.vendor-data", "user.network-config"}
+
+// InstanceTags returns the user.* keys of the expanded configuration of an instance, including
+// those set by its profiles, keyed without their prefix.
+func InstanceTags(instance api.InstanceFull) map[string]string {
+	tags := map[string]string{}
+	for key, value := range instance.ExpandedConfig {
+		name, ok := strings.CutPrefix(key, "user.")
+		if !ok || name == "" || slices.Contains(cloudInitKeys, key) {
+			continue
+		}
+
+		tags[name] = value
+	}
+
+	return tags
+}
+
+// FindInstanceByIP looks up the instance, across all projects, that currently holds the given address.
+func FindInstanceByIP(ctx context.Context, client InstanceLister, ip string) (*api.InstanceFull, error) {
+	instances, err := ListInstances(ctx, client, api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instance := range instances {
+		for _, address := range InstanceAddresses(instance) {
+			if address == ip {
+				return &instance, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// ErrAmbiguousAddress is returned when several instances hold an address on different networks
+// and the network of the caller is unknown.
+var ErrAmbiguousAddress = errors.New("address is held by several instances")
+
+// FindInstanceByAddress looks up the instance, across all projects, holding the given address.
+// The network and MAC address narrow the search down when they are known.
+func FindInstanceByAddress(ctx context.Context, client InstanceLister, network string, ip string, mac string) (*api.InstanceFull, error) {
+	instances, err := ListInstances(ctx, client, api.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *api.InstanceFull
+	for i, instance := range instances {
+		for _, address := range NetworkAddresses(instance) {
+			if address.IP != ip || (network != "" && address.Network != network) || (mac != "" && address.MAC != strings.ToLower(mac)) {
+				continue
+			}
+
+			if found != nil && (found.Name != instance.Name || found.Project != instance.Project) {
+				return nil, ErrAmbiguousAddress
+			}
+
+			found = &instances[i]
+		}
+	}
+
+	return found, nil
+}
+
+// FindInstanceByVsockID looks up the virtual machine, across all projects, with the given vsock context ID.
+func FindInstanceByVsockID(ctx context.Context, client InstanceLister, cid uint32) (*api.InstanceFull, error) {
+	instances, err := ListInstances(ctx, client, api.InstanceTypeVM)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, instance := range instances {
+		if id, ok := VsockID(instance); ok && id == cid {
+			return &instance, nil
+		}
+	}
+
+	return nil, nil
+}
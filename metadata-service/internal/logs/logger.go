@@ -13,7 +13,14 @@ var Logger zerolog.Logger
 func InitLogger(level zerolog.Level) {
 	// Set the global logger with the specified log level and output
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-	Logger = zerolog.New(os.Stdout).Level(level)
+	SetLevel(level)
+	Logger = zerolog.New(os.Stdout)
 	
-	Logger = Logger.With().Str("service", "metadata-service").Timestamp().Logger()
-}
\ No newline at end of file
+	Logger = Logger.With().Str("service", "metadata-service").Timestamp().Logger().Hook(traceHook{})
+}
+
+// SetLevel changes the level of every logger, including request-scoped ones, e.g. when
+// the configuration is reloaded.
+func SetLevel(level zerolog.Level) {
+	zerolog.SetGlobalLevel(level)
+}
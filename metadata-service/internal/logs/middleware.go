@@ -0,0 +1,142 @@
+package logs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID of a request in responses and, on trusted listeners, in requests.
+const RequestIDHeader = "X-Request-ID"
+
+const loggerKey = "logs.logger"
+
+// AccessLog writes an access log line for every request. Successful requests to the
+// sampled routes are only logged once every SampleRate requests; failures are always
+// logged. Sampling can be reconfigured while serving, e.g. on reload.
+type AccessLog struct {
+	samplers atomic.Pointer[map[string]zerolog.Sampler]
+}
+
+// NewAccessLog creates an access log sampled according to cfg.
+func NewAccessLog(cfg *config.AccessLogConfig) *AccessLog {
+	accessLog := &AccessLog{}
+	accessLog.Configure(cfg)
+	return accessLog
+}
+
+// Configure replaces the sampling settings.
+func (a *AccessLog) Configure(cfg *config.AccessLogConfig) {
+	samplers := map[string]zerolog.Sampler{}
+	if cfg != nil && cfg.SampleRate > 1 {
+		for _, route := range cfg.SampledRoutes {
+			samplers[route] = &zerolog.BasicSampler{N: cfg.SampleRate}
+		}
+	}
+
+	a.samplers.Store(&samplers)
+}
+
+// Middleware writes the access log for a listener and stores a request-scoped logger
+// in the gin context, see FromContext. Request IDs sent by the client are only reused
+// when trustRequestID is set.
+func (a *AccessLog) Middleware(listener string, trustRequestID bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := ""
+		if trustRequestID {
+			requestID = c.GetHeader(RequestIDHeader)
+		}
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		fields := Logger.With().Str("request_id", requestID).Str("listener", listener)
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsValid() {
+			fields = fields.Str("trace_id", spanContext.TraceID().String()).Str("span_id", spanContext.SpanID().String())
+		}
+
+		logger := fields.Logger()
+		c.Set(loggerKey, &logger)
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context()))
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := c.Writer.Status()
+		if sampler, ok := (*a.samplers.Load())[route]; ok && status < http.StatusBadRequest && !sampler.Sample(zerolog.InfoLevel) {
+			return
+		}
+
+		event := logger.Info()
+		if status >= http.StatusInternalServerError {
+			event = logger.Error()
+		} else if status >= http.StatusBadRequest {
+			event = logger.Warn()
+		}
+
+		event.
+			Str("method", c.Request.Method).
+			Str("route", route).
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Dur("latency", time.Since(start)).
+			Int("bytes", max(c.Writer.Size(), 0)).
+			Str("user_agent", c.Request.UserAgent()).
+			Str("remote_addr", c.Request.RemoteAddr).
+			Msg("Request handled")
+	}
+}
+
+// Recovery turns panics in handlers into 500 responses, logging them with the request-scoped logger.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		FromContext(c).Error().Interface("panic", err).Msg("Recovered from panic in handler")
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// FromContext returns the request-scoped logger stored by Middleware, falling back to
+// the global Logger outside of a request.
+func FromContext(c *gin.Context) *zerolog.Logger {
+	if value, ok := c.Get(loggerKey); ok {
+		if logger, ok := value.(*zerolog.Logger); ok {
+			return logger
+		}
+	}
+
+	return &Logger
+}
+
+// UpdateContext adds fields to the request-scoped logger, and therefore to the access
+// log line of the request, e.g. once the calling instance has been resolved.
+func UpdateContext(c *gin.Context, update func(zerolog.Context) zerolog.Context) {
+	value, ok := c.Get(loggerKey)
+	if !ok {
+		return
+	}
+
+	if logger, ok := value.(*zerolog.Logger); ok {
+		logger.UpdateContext(update)
+	}
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,131 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAccessLog(t *testing.T, cfg *config.AccessLogConfig, trustRequestID bool) (*gin.Engine, *bytes.Buffer) {
+	gin.SetMode(gin.TestMode)
+
+	var output bytes.Buffer
+	previous := Logger
+	Logger = zerolog.New(&output)
+	t.Cleanup(func() { Logger = previous })
+
+	router := gin.New()
+	router.Use(NewAccessLog(cfg).Middleware("guest", trustRequestID), Recovery())
+	router.GET("/configs/meta-data/:key", func(c *gin.Context) {
+		UpdateContext(c, func(l zerolog.Context) zerolog.Context {
+			return l.Str("instance", "c1").Str("project", "default")
+		})
+		FromContext(c).Info().Msg("Rendering metadata")
+		c.String(http.StatusOK, "c1")
+	})
+	router.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	return router, &output
+}
+
+func logLines(t *testing.T, output *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, raw := range bytes.Split(bytes.TrimSpace(output.Bytes()), []byte("\n")) {
+		if len(raw) == 0 {
+			continue
+		}
+
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(raw, &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestMiddleware_WritesAccessLog(t *testing.T) {
+	router, output := setupAccessLog(t, &config.AccessLogConfig{}, false)
+
+	req := httptest.NewRequest("GET", "/configs/meta-data/instance-id", nil)
+	req.Header.Set("User-Agent", "Cloud-Init/24.1")
+	req.Header.Set(RequestIDHeader, "from-guest")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	lines := logLines(t, output)
+	require.Len(t, lines, 2)
+
+	handler, access := lines[0], lines[1]
+	requestID := w.Header().Get(RequestIDHeader)
+	assert.NotEqual(t, "from-guest", requestID)
+	assert.Equal(t, requestID, handler["request_id"])
+	assert.Equal(t, "c1", handler["instance"])
+
+	assert.Equal(t, requestID, access["request_id"])
+	assert.Equal(t, "c1", access["instance"])
+	assert.Equal(t, "default", access["project"])
+	assert.Equal(t, "/configs/meta-data/:key", access["route"])
+	assert.Equal(t, float64(http.StatusOK), access["status"])
+	assert.Equal(t, float64(2), access["bytes"])
+	assert.Equal(t, "Cloud-Init/24.1", access["user_agent"])
+	assert.Contains(t, access, "latency")
+}
+
+func TestMiddleware_TrustedRequestID(t *testing.T) {
+	router, _ := setupAccessLog(t, &config.AccessLogConfig{}, true)
+
+	req := httptest.NewRequest("GET", "/configs/meta-data/instance-id", nil)
+	req.Header.Set(RequestIDHeader, "from-operator")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "from-operator", w.Header().Get(RequestIDHeader))
+}
+
+func TestMiddleware_SamplesHotRoutes(t *testing.T) {
+	router, output := setupAccessLog(t, &config.AccessLogConfig{SampledRoutes: []string{"/configs/meta-data/:key"}, SampleRate: 5}, false)
+
+	for i := 0; i < 10; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/configs/meta-data/instance-id", nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/missing", nil))
+
+	accessLines := 0
+	notFound := 0
+	for _, line := range logLines(t, output) {
+		if line["message"] != "Request handled" {
+			continue
+		}
+		if line["status"] == float64(http.StatusNotFound) {
+			notFound++
+			continue
+		}
+		accessLines++
+	}
+
+	assert.Equal(t, 2, accessLines)
+	assert.Equal(t, 1, notFound)
+}
+
+func TestRecovery_LogsPanics(t *testing.T) {
+	router, output := setupAccessLog(t, &config.AccessLogConfig{}, false)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	lines := logLines(t, output)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "boom", lines[0]["panic"])
+	assert.Equal(t, "error", lines[1]["level"])
+}
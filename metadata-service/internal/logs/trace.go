@@ -0,0 +1,19 @@
+package logs
+
+import (
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceHook adds the trace and span IDs of the event's context, set with Event.Ctx,
+// so log lines can be correlated with traces.
+type traceHook struct{}
+
+func (traceHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	spanContext := trace.SpanContextFromContext(e.GetCtx())
+	if !spanContext.IsValid() {
+		return
+	}
+
+	e.Str("trace_id", spanContext.TraceID().String()).Str("span_id", spanContext.SpanID().String())
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/incus"
+	"github.com/lxc/incus/shared/api"
+)
+
+// InstanceLister counts failed calls to the wrapped Incus client.
+type InstanceLister struct {
+	incus.InstanceLister
+}
+
+// NewInstanceLister wraps an Incus client so its failures show up in IncusAPIErrors.
+func NewInstanceLister(inner incus.InstanceLister) *InstanceLister {
+	return &InstanceLister{InstanceLister: inner}
+}
+
+func (l *InstanceLister) GetInstancesFullAllProjects(instanceType api.InstanceType) ([]api.InstanceFull, error) {
+	instances, err := l.InstanceLister.GetInstancesFullAllProjects(instanceType)
+	if err != nil {
+		IncusAPIErrors.WithLabelValues("GetInstancesFullAllProjects").Inc()
+	}
+
+	return instances, err
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "metadata"
+
+// Registry holds every metric exported by the service on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts requests by listener, route template and status code.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by listener, method, route and status code.",
+	}, []string{"listener", "method", "route", "status"})
+
+	// HTTPRequestDuration observes request latency by listener, route template and status code.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by listener, method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"listener", "method", "route", "status"})
+
+	// Resolutions counts instance lookups by resolver, source and result.
+	Resolutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "instance_resolutions_total",
+		Help:      "Instance resolutions, by resolver (ip, vsock, unix), source (database, incus) and result (hit, miss).",
+	}, []string{"resolver", "source", "result"})
+
+	// DBQueryDuration observes database query latency by query name.
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query latency, by query and outcome (ok, no_rows, error).",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"query", "outcome"})
+
+	// IncusAPIErrors counts failed calls to the Incus API by operation.
+	IncusAPIErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "incus_api_errors_total",
+		Help:      "Failed Incus API calls, by operation.",
+	}, []string{"operation"})
+
+	// EventLag observes the delay between Incus emitting a lifecycle event and the service handling it.
+	EventLag = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "incus_event_lag_seconds",
+		Help:      "Delay between an Incus lifecycle event being emitted and handled.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	// RetentionDeletedRows counts rows removed by the retention worker by table and reason.
+	RetentionDeletedRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retention_deleted_rows_total",
+		Help:      "Rows removed by the retention worker, by table and reason (max_age, max_rows, deleted, orphaned).",
+	}, []string{"table", "reason"})
+
+	// RetentionRunDuration observes how long retention runs take by outcome.
+	RetentionRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "retention_run_duration_seconds",
+		Help:      "Duration of retention runs, by outcome (ok, error).",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+	}, []string{"outcome"})
+
+	// RetentionLastSuccess is the time of the last successful retention run.
+	RetentionLastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "retention_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful retention run.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		Resolutions,
+		DBQueryDuration,
+		IncusAPIErrors,
+		EventLag,
+		RetentionDeletedRows,
+		RetentionRunDuration,
+		RetentionLastSuccess,
+	)
+}
+
+// Handler serves the metrics in Registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveResolution records whether a resolver found the caller in the given source.
+func ObserveResolution(resolver string, source string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	Resolutions.WithLabelValues(resolver, source, result).Inc()
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMiddleware_LabelsRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(Middleware("test"))
+	router.GET("/configs/meta-data/:key", func(c *gin.Context) {
+		c.Status(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/configs/meta-data/a", "/configs/meta-data/b", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("test", "GET", "/configs/meta-data/:key", "404")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(HTTPRequests.WithLabelValues("test", "GET", "unmatched", "404")))
+}
+
+func TestQuerier_ObservesQueries(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	querier := NewQuerier(mockDB)
+
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Name: "c1", Project: "default"}).Return(db.Instance{ID: 1}, nil).Once()
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Name: "c2", Project: "default"}).Return(db.Instance{}, sql.ErrNoRows).Once()
+
+	instance, err := querier.GetInstance(context.Background(), db.GetInstanceParams{Name: "c1", Project: "default"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), instance.ID)
+
+	_, err = querier.GetInstance(context.Background(), db.GetInstanceParams{Name: "c2", Project: "default"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(DBQueryDuration, "metadata_db_query_duration_seconds"))
+	mockDB.AssertExpectations(t)
+}
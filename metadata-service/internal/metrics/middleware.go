@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware records request counts and latency for a listener. Requests are labelled
+// with the route template, e.g. /configs/meta-data/:key, to keep cardinality bounded;
+// requests that match no route share the "unmatched" label.
+func Middleware(listener string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		status := strconv.Itoa(c.Writer.Status())
+		HTTPRequests.WithLabelValues(listener, c.Request.Method, route, status).Inc()
+		HTTPRequestDuration.WithLabelValues(listener, c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,753 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+)
+
+// Querier records the latency of every query made through the wrapped db.Store.
+type Querier struct {
+	inner db.Store
+}
+
+var _ db.Store = (*Querier)(nil)
+
+// NewQuerier wraps a db.Store so its queries show up in DBQueryDuration.
+func NewQuerier(inner db.Store) *Querier {
+	return &Querier{inner: inner}
+}
+
+func observe(query string, start time.Time, err error) {
+	outcome := "ok"
+	if errors.Is(err, sql.ErrNoRows) {
+		outcome = "no_rows"
+	} else if err != nil {
+		outcome = "error"
+	}
+
+	DBQueryDuration.WithLabelValues(query, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (q *Querier) InTx(ctx context.Context, fn func(db.Store) error) error {
+	return q.inner.InTx(ctx, func(tx db.Store) error {
+		return fn(NewQuerier(tx))
+	})
+}
+
+func (q *Querier) ClaimCertificateToken(ctx context.Context, secret string) (db.CertificateToken, error) {
+	start := time.Now()
+	result, err := q.inner.ClaimCertificateToken(ctx, secret)
+	observe("ClaimCertificateToken", start, err)
+	return result, err
+}
+
+func (q *Querier) ClaimInstancePassword(ctx context.Context, arg db.ClaimInstancePasswordParams) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.ClaimInstancePassword(ctx, arg)
+	observe("ClaimInstancePassword", start, err)
+	return result, err
+}
+
+func (q *Querier) ClaimSecretRead(ctx context.Context, arg db.ClaimSecretReadParams) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.ClaimSecretRead(ctx, arg)
+	observe("ClaimSecretRead", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateCertificate(ctx context.Context, arg db.CreateCertificateParams) (db.Certificate, error) {
+	start := time.Now()
+	result, err := q.inner.CreateCertificate(ctx, arg)
+	observe("CreateCertificate", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateCertificateToken(ctx context.Context, arg db.CreateCertificateTokenParams) (db.CertificateToken, error) {
+	start := time.Now()
+	result, err := q.inner.CreateCertificateToken(ctx, arg)
+	observe("CreateCertificateToken", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateEphemeralSSHKey(ctx context.Context, arg db.CreateEphemeralSSHKeyParams) (db.EphemeralSshKey, error) {
+	start := time.Now()
+	result, err := q.inner.CreateEphemeralSSHKey(ctx, arg)
+	observe("CreateEphemeralSSHKey", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateInstance(ctx context.Context, arg db.CreateInstanceParams) (db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.CreateInstance(ctx, arg)
+	observe("CreateInstance", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateInstanceAddress(ctx context.Context, arg db.CreateInstanceAddressParams) error {
+	start := time.Now()
+	err := q.inner.CreateInstanceAddress(ctx, arg)
+	observe("CreateInstanceAddress", start, err)
+	return err
+}
+
+func (q *Querier) CreateInstanceHostKey(ctx context.Context, arg db.CreateInstanceHostKeyParams) (db.InstanceHostKey, error) {
+	start := time.Now()
+	result, err := q.inner.CreateInstanceHostKey(ctx, arg)
+	observe("CreateInstanceHostKey", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateInstanceLog(ctx context.Context, arg db.CreateInstanceLogParams) (db.InstanceLog, error) {
+	start := time.Now()
+	result, err := q.inner.CreateInstanceLog(ctx, arg)
+	observe("CreateInstanceLog", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateInstancePassword(ctx context.Context, arg db.CreateInstancePasswordParams) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.CreateInstancePassword(ctx, arg)
+	observe("CreateInstancePassword", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateInstanceProfile(ctx context.Context, arg db.CreateInstanceProfileParams) error {
+	start := time.Now()
+	err := q.inner.CreateInstanceProfile(ctx, arg)
+	observe("CreateInstanceProfile", start, err)
+	return err
+}
+
+func (q *Querier) CreateInstanceTag(ctx context.Context, arg db.CreateInstanceTagParams) error {
+	start := time.Now()
+	err := q.inner.CreateInstanceTag(ctx, arg)
+	observe("CreateInstanceTag", start, err)
+	return err
+}
+
+func (q *Querier) CreateOrUpdateInstanceState(ctx context.Context, arg db.CreateOrUpdateInstanceStateParams) (db.InstanceState, error) {
+	start := time.Now()
+	result, err := q.inner.CreateOrUpdateInstanceState(ctx, arg)
+	observe("CreateOrUpdateInstanceState", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateProfile(ctx context.Context, arg db.CreateProfileParams) (db.Profile, error) {
+	start := time.Now()
+	result, err := q.inner.CreateProfile(ctx, arg)
+	observe("CreateProfile", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateSSHKey(ctx context.Context, arg db.CreateSSHKeyParams) (db.SshKey, error) {
+	start := time.Now()
+	result, err := q.inner.CreateSSHKey(ctx, arg)
+	observe("CreateSSHKey", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateSecret(ctx context.Context, arg db.CreateSecretParams) (db.Secret, error) {
+	start := time.Now()
+	result, err := q.inner.CreateSecret(ctx, arg)
+	observe("CreateSecret", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateSigningKey(ctx context.Context, arg db.CreateSigningKeyParams) (db.SigningKey, error) {
+	start := time.Now()
+	result, err := q.inner.CreateSigningKey(ctx, arg)
+	observe("CreateSigningKey", start, err)
+	return result, err
+}
+
+func (q *Querier) CreateVendorData(ctx context.Context, arg db.CreateVendorDataParams) (db.VendorDatum, error) {
+	start := time.Now()
+	result, err := q.inner.CreateVendorData(ctx, arg)
+	observe("CreateVendorData", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteCertificate(ctx context.Context, fingerprint string) error {
+	start := time.Now()
+	err := q.inner.DeleteCertificate(ctx, fingerprint)
+	observe("DeleteCertificate", start, err)
+	return err
+}
+
+func (q *Querier) DeleteExcessInstanceLogs(ctx context.Context, arg db.DeleteExcessInstanceLogsParams) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteExcessInstanceLogs(ctx, arg)
+	observe("DeleteExcessInstanceLogs", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteExpiredCertificateTokens(ctx context.Context) error {
+	start := time.Now()
+	err := q.inner.DeleteExpiredCertificateTokens(ctx)
+	observe("DeleteExpiredCertificateTokens", start, err)
+	return err
+}
+
+func (q *Querier) DeleteExpiredEphemeralSSHKeys(ctx context.Context, before int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteExpiredEphemeralSSHKeys(ctx, before)
+	observe("DeleteExpiredEphemeralSSHKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteExpiredInstanceLogs(ctx context.Context, arg db.DeleteExpiredInstanceLogsParams) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteExpiredInstanceLogs(ctx, arg)
+	observe("DeleteExpiredInstanceLogs", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteExpiredSigningKeys(ctx context.Context, before int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteExpiredSigningKeys(ctx, before)
+	observe("DeleteExpiredSigningKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteInstance(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := q.inner.DeleteInstance(ctx, id)
+	observe("DeleteInstance", start, err)
+	return err
+}
+
+func (q *Querier) DeleteInstanceAddresses(ctx context.Context, instanceID int64) error {
+	start := time.Now()
+	err := q.inner.DeleteInstanceAddresses(ctx, instanceID)
+	observe("DeleteInstanceAddresses", start, err)
+	return err
+}
+
+func (q *Querier) DeleteInstanceLogs(ctx context.Context, instanceID int64) error {
+	start := time.Now()
+	err := q.inner.DeleteInstanceLogs(ctx, instanceID)
+	observe("DeleteInstanceLogs", start, err)
+	return err
+}
+
+func (q *Querier) DeleteInstancePassword(ctx context.Context, instanceID int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteInstancePassword(ctx, instanceID)
+	observe("DeleteInstancePassword", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteInstanceProfiles(ctx context.Context, instanceID int64) error {
+	start := time.Now()
+	err := q.inner.DeleteInstanceProfiles(ctx, instanceID)
+	observe("DeleteInstanceProfiles", start, err)
+	return err
+}
+
+func (q *Querier) DeleteInstanceState(ctx context.Context, instanceID int64) error {
+	start := time.Now()
+	err := q.inner.DeleteInstanceState(ctx, instanceID)
+	observe("DeleteInstanceState", start, err)
+	return err
+}
+
+func (q *Querier) DeleteInstanceTags(ctx context.Context, instanceID int64) error {
+	start := time.Now()
+	err := q.inner.DeleteInstanceTags(ctx, instanceID)
+	observe("DeleteInstanceTags", start, err)
+	return err
+}
+
+func (q *Querier) DeleteOldInstanceLogs(ctx context.Context, arg db.DeleteOldInstanceLogsParams) error {
+	start := time.Now()
+	err := q.inner.DeleteOldInstanceLogs(ctx, arg)
+	observe("DeleteOldInstanceLogs", start, err)
+	return err
+}
+
+func (q *Querier) DeleteOrphanedInstanceAddresses(ctx context.Context) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteOrphanedInstanceAddresses(ctx)
+	observe("DeleteOrphanedInstanceAddresses", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceHostKeys(ctx context.Context) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteOrphanedInstanceHostKeys(ctx)
+	observe("DeleteOrphanedInstanceHostKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceLogs(ctx context.Context, limit int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteOrphanedInstanceLogs(ctx, limit)
+	observe("DeleteOrphanedInstanceLogs", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstancePasswords(ctx context.Context) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteOrphanedInstancePasswords(ctx)
+	observe("DeleteOrphanedInstancePasswords", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceProfiles(ctx context.Context) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteOrphanedInstanceProfiles(ctx)
+	observe("DeleteOrphanedInstanceProfiles", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceStates(ctx context.Context) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteOrphanedInstanceStates(ctx)
+	observe("DeleteOrphanedInstanceStates", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceTags(ctx context.Context) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteOrphanedInstanceTags(ctx)
+	observe("DeleteOrphanedInstanceTags", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedSecretReads(ctx context.Context) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteOrphanedSecretReads(ctx)
+	observe("DeleteOrphanedSecretReads", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteProfile(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := q.inner.DeleteProfile(ctx, id)
+	observe("DeleteProfile", start, err)
+	return err
+}
+
+func (q *Querier) DeleteSSHKey(ctx context.Context, id int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteSSHKey(ctx, id)
+	observe("DeleteSSHKey", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteSecret(ctx context.Context, id int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.DeleteSecret(ctx, id)
+	observe("DeleteSecret", start, err)
+	return result, err
+}
+
+func (q *Querier) DeleteSecretReads(ctx context.Context, secretID int64) error {
+	start := time.Now()
+	err := q.inner.DeleteSecretReads(ctx, secretID)
+	observe("DeleteSecretReads", start, err)
+	return err
+}
+
+func (q *Querier) DeleteVendorData(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := q.inner.DeleteVendorData(ctx, id)
+	observe("DeleteVendorData", start, err)
+	return err
+}
+
+func (q *Querier) GetCertificate(ctx context.Context, fingerprint string) (db.Certificate, error) {
+	start := time.Now()
+	result, err := q.inner.GetCertificate(ctx, fingerprint)
+	observe("GetCertificate", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstance(ctx context.Context, arg db.GetInstanceParams) (db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstance(ctx, arg)
+	observe("GetInstance", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstanceByAddress(ctx context.Context, arg db.GetInstanceByAddressParams) (db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstanceByAddress(ctx, arg)
+	observe("GetInstanceByAddress", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstanceByID(ctx context.Context, id int64) (db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstanceByID(ctx, id)
+	observe("GetInstanceByID", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstanceByIP(ctx context.Context, arg db.GetInstanceByIPParams) (db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstanceByIP(ctx, arg)
+	observe("GetInstanceByIP", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstanceByVsockID(ctx context.Context, arg db.GetInstanceByVsockIDParams) (db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstanceByVsockID(ctx, arg)
+	observe("GetInstanceByVsockID", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstanceLogs(ctx context.Context, arg db.GetInstanceLogsParams) ([]db.InstanceLog, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstanceLogs(ctx, arg)
+	observe("GetInstanceLogs", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstanceLogsByLevel(ctx context.Context, arg db.GetInstanceLogsByLevelParams) ([]db.InstanceLog, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstanceLogsByLevel(ctx, arg)
+	observe("GetInstanceLogsByLevel", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstanceLogsByType(ctx context.Context, arg db.GetInstanceLogsByTypeParams) ([]db.InstanceLog, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstanceLogsByType(ctx, arg)
+	observe("GetInstanceLogsByType", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstancePassword(ctx context.Context, instanceID int64) (db.InstancePassword, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstancePassword(ctx, instanceID)
+	observe("GetInstancePassword", start, err)
+	return result, err
+}
+
+func (q *Querier) GetInstanceState(ctx context.Context, instanceID int64) (db.InstanceState, error) {
+	start := time.Now()
+	result, err := q.inner.GetInstanceState(ctx, instanceID)
+	observe("GetInstanceState", start, err)
+	return result, err
+}
+
+func (q *Querier) GetProfile(ctx context.Context, arg db.GetProfileParams) (db.Profile, error) {
+	start := time.Now()
+	result, err := q.inner.GetProfile(ctx, arg)
+	observe("GetProfile", start, err)
+	return result, err
+}
+
+func (q *Querier) GetSSHKey(ctx context.Context, id int64) (db.SshKey, error) {
+	start := time.Now()
+	result, err := q.inner.GetSSHKey(ctx, id)
+	observe("GetSSHKey", start, err)
+	return result, err
+}
+
+func (q *Querier) GetSecret(ctx context.Context, id int64) (db.Secret, error) {
+	start := time.Now()
+	result, err := q.inner.GetSecret(ctx, id)
+	observe("GetSecret", start, err)
+	return result, err
+}
+
+func (q *Querier) GetVendorData(ctx context.Context, name string) (db.GetVendorDataRow, error) {
+	start := time.Now()
+	result, err := q.inner.GetVendorData(ctx, name)
+	observe("GetVendorData", start, err)
+	return result, err
+}
+
+func (q *Querier) HardDeleteInstance(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := q.inner.HardDeleteInstance(ctx, id)
+	observe("HardDeleteInstance", start, err)
+	return err
+}
+
+func (q *Querier) InvalidateInstance(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := q.inner.InvalidateInstance(ctx, id)
+	observe("InvalidateInstance", start, err)
+	return err
+}
+
+func (q *Querier) ListActiveEphemeralSSHKeys(ctx context.Context, arg db.ListActiveEphemeralSSHKeysParams) ([]db.EphemeralSshKey, error) {
+	start := time.Now()
+	result, err := q.inner.ListActiveEphemeralSSHKeys(ctx, arg)
+	observe("ListActiveEphemeralSSHKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) ListCertificateTokens(ctx context.Context) ([]db.CertificateToken, error) {
+	start := time.Now()
+	result, err := q.inner.ListCertificateTokens(ctx)
+	observe("ListCertificateTokens", start, err)
+	return result, err
+}
+
+func (q *Querier) ListCertificates(ctx context.Context) ([]db.Certificate, error) {
+	start := time.Now()
+	result, err := q.inner.ListCertificates(ctx)
+	observe("ListCertificates", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceAddresses(ctx context.Context, instanceID int64) ([]string, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceAddresses(ctx, instanceID)
+	observe("ListInstanceAddresses", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceHostKeys(ctx context.Context, instanceID int64) ([]db.InstanceHostKey, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceHostKeys(ctx, instanceID)
+	observe("ListInstanceHostKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceLogs(ctx context.Context, arg db.ListInstanceLogsParams) ([]db.InstanceLog, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceLogs(ctx, arg)
+	observe("ListInstanceLogs", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceLogsAfter(ctx context.Context, arg db.ListInstanceLogsAfterParams) ([]db.InstanceLog, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceLogsAfter(ctx, arg)
+	observe("ListInstanceLogsAfter", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceProfiles(ctx context.Context, instanceID int64) ([]string, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceProfiles(ctx, instanceID)
+	observe("ListInstanceProfiles", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceSSHKeys(ctx context.Context, arg db.ListInstanceSSHKeysParams) ([]db.SshKey, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceSSHKeys(ctx, arg)
+	observe("ListInstanceSSHKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceSecrets(ctx context.Context, arg db.ListInstanceSecretsParams) ([]db.ListInstanceSecretsRow, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceSecrets(ctx, arg)
+	observe("ListInstanceSecrets", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceStates(ctx context.Context, arg db.ListInstanceStatesParams) ([]db.ListInstanceStatesRow, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceStates(ctx, arg)
+	observe("ListInstanceStates", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstanceTags(ctx context.Context, instanceID int64) ([]db.InstanceTag, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstanceTags(ctx, instanceID)
+	observe("ListInstanceTags", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstances(ctx context.Context) ([]db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstances(ctx)
+	observe("ListInstances", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstancesByAddressIP(ctx context.Context, arg db.ListInstancesByAddressIPParams) ([]db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstancesByAddressIP(ctx, arg)
+	observe("ListInstancesByAddressIP", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstancesByProject(ctx context.Context, project string) ([]db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstancesByProject(ctx, project)
+	observe("ListInstancesByProject", start, err)
+	return result, err
+}
+
+func (q *Querier) ListInstancesByRemote(ctx context.Context, remote string) ([]db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.ListInstancesByRemote(ctx, remote)
+	observe("ListInstancesByRemote", start, err)
+	return result, err
+}
+
+func (q *Querier) ListProfiles(ctx context.Context) ([]db.Profile, error) {
+	start := time.Now()
+	result, err := q.inner.ListProfiles(ctx)
+	observe("ListProfiles", start, err)
+	return result, err
+}
+
+func (q *Querier) ListProfilesByProject(ctx context.Context, project string) ([]db.Profile, error) {
+	start := time.Now()
+	result, err := q.inner.ListProfilesByProject(ctx, project)
+	observe("ListProfilesByProject", start, err)
+	return result, err
+}
+
+func (q *Querier) ListProjectHostKeys(ctx context.Context, arg db.ListProjectHostKeysParams) ([]db.ListProjectHostKeysRow, error) {
+	start := time.Now()
+	result, err := q.inner.ListProjectHostKeys(ctx, arg)
+	observe("ListProjectHostKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) ListProjectInstanceAddresses(ctx context.Context, arg db.ListProjectInstanceAddressesParams) ([]db.ListProjectInstanceAddressesRow, error) {
+	start := time.Now()
+	result, err := q.inner.ListProjectInstanceAddresses(ctx, arg)
+	observe("ListProjectInstanceAddresses", start, err)
+	return result, err
+}
+
+func (q *Querier) ListProjectInstanceTags(ctx context.Context, arg db.ListProjectInstanceTagsParams) ([]db.ListProjectInstanceTagsRow, error) {
+	start := time.Now()
+	result, err := q.inner.ListProjectInstanceTags(ctx, arg)
+	observe("ListProjectInstanceTags", start, err)
+	return result, err
+}
+
+func (q *Querier) ListSSHKeys(ctx context.Context, arg db.ListSSHKeysParams) ([]db.SshKey, error) {
+	start := time.Now()
+	result, err := q.inner.ListSSHKeys(ctx, arg)
+	observe("ListSSHKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) ListSecrets(ctx context.Context, arg db.ListSecretsParams) ([]db.Secret, error) {
+	start := time.Now()
+	result, err := q.inner.ListSecrets(ctx, arg)
+	observe("ListSecrets", start, err)
+	return result, err
+}
+
+func (q *Querier) ListSigningKeys(ctx context.Context, now int64) ([]db.SigningKey, error) {
+	start := time.Now()
+	result, err := q.inner.ListSigningKeys(ctx, now)
+	observe("ListSigningKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) PurgeDeletedInstances(ctx context.Context, before int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.PurgeDeletedInstances(ctx, before)
+	observe("PurgeDeletedInstances", start, err)
+	return result, err
+}
+
+func (q *Querier) PurgeDeletedProfiles(ctx context.Context, before int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.PurgeDeletedProfiles(ctx, before)
+	observe("PurgeDeletedProfiles", start, err)
+	return result, err
+}
+
+func (q *Querier) PurgeDeletedVendorData(ctx context.Context, before int64) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.PurgeDeletedVendorData(ctx, before)
+	observe("PurgeDeletedVendorData", start, err)
+	return result, err
+}
+
+func (q *Querier) RevokeInstanceHostKeys(ctx context.Context, arg db.RevokeInstanceHostKeysParams) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.RevokeInstanceHostKeys(ctx, arg)
+	observe("RevokeInstanceHostKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) RevokeStaleInstanceHostKeys(ctx context.Context, arg db.RevokeStaleInstanceHostKeysParams) (int64, error) {
+	start := time.Now()
+	result, err := q.inner.RevokeStaleInstanceHostKeys(ctx, arg)
+	observe("RevokeStaleInstanceHostKeys", start, err)
+	return result, err
+}
+
+func (q *Querier) TouchInstanceHostKey(ctx context.Context, id int64) error {
+	start := time.Now()
+	err := q.inner.TouchInstanceHostKey(ctx, id)
+	observe("TouchInstanceHostKey", start, err)
+	return err
+}
+
+func (q *Querier) UpdateCertificate(ctx context.Context, arg db.UpdateCertificateParams) (db.Certificate, error) {
+	start := time.Now()
+	result, err := q.inner.UpdateCertificate(ctx, arg)
+	observe("UpdateCertificate", start, err)
+	return result, err
+}
+
+func (q *Querier) UpdateInstance(ctx context.Context, arg db.UpdateInstanceParams) (db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.UpdateInstance(ctx, arg)
+	observe("UpdateInstance", start, err)
+	return result, err
+}
+
+func (q *Querier) UpdateInstanceIP(ctx context.Context, arg db.UpdateInstanceIPParams) error {
+	start := time.Now()
+	err := q.inner.UpdateInstanceIP(ctx, arg)
+	observe("UpdateInstanceIP", start, err)
+	return err
+}
+
+func (q *Querier) UpdateProfile(ctx context.Context, id int64) (db.Profile, error) {
+	start := time.Now()
+	result, err := q.inner.UpdateProfile(ctx, id)
+	observe("UpdateProfile", start, err)
+	return result, err
+}
+
+func (q *Querier) UpdateSSHKey(ctx context.Context, arg db.UpdateSSHKeyParams) (db.SshKey, error) {
+	start := time.Now()
+	result, err := q.inner.UpdateSSHKey(ctx, arg)
+	observe("UpdateSSHKey", start, err)
+	return result, err
+}
+
+func (q *Querier) UpdateSecret(ctx context.Context, arg db.UpdateSecretParams) (db.Secret, error) {
+	start := time.Now()
+	result, err := q.inner.UpdateSecret(ctx, arg)
+	observe("UpdateSecret", start, err)
+	return result, err
+}
+
+func (q *Querier) UpdateSigningKey(ctx context.Context, arg db.UpdateSigningKeyParams) error {
+	start := time.Now()
+	err := q.inner.UpdateSigningKey(ctx, arg)
+	observe("UpdateSigningKey", start, err)
+	return err
+}
+
+func (q *Querier) UpdateVendorData(ctx context.Context, arg db.UpdateVendorDataParams) (db.VendorDatum, error) {
+	start := time.Now()
+	result, err := q.inner.UpdateVendorData(ctx, arg)
+	observe("UpdateVendorData", start, err)
+	return result, err
+}
+
+func (q *Querier) UpsertInstance(ctx context.Context, arg db.UpsertInstanceParams) (db.Instance, error) {
+	start := time.Now()
+	result, err := q.inner.UpsertInstance(ctx, arg)
+	observe("UpsertInstance", start, err)
+	return result, err
+}
@@ -0,0 +1,90 @@
+// Package proxy implements the per-network metadata proxy. It runs inside the network
+// namespace of an Incus network, such as an OVN network, and forwards guest requests to
+// the central service, stamped with the network and guest address they came from. This
+// mirrors the OpenStack neutron-metadata-agent design.
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/gin-gonic/gin"
+)
+
+// Proxy forwards guest requests of one network to the central service.
+type Proxy struct {
+	// Network is the Incus network whose guests the proxy serves.
+	Network string
+	// Upstream is the URL of the central service's guest listener.
+	Upstream *url.URL
+	// Secret signs the network and guest address of forwarded requests.
+	Secret []byte
+
+	reverse *httputil.ReverseProxy
+	now     func() time.Time
+}
+
+// New creates a proxy from its configuration.
+func New(cfg *config.ProxyConfig) (*Proxy, error) {
+	if cfg.Network == "" {
+		return nil, fmt.Errorf("the network of the proxy must be set")
+	}
+
+	if cfg.Upstream == "" {
+		return nil, fmt.Errorf("the upstream metadata service must be set")
+	}
+
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("the secret shared with the metadata service must be set")
+	}
+
+	upstream, err := url.Parse(cfg.Upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream URL: %w", err)
+	}
+
+	p := &Proxy{Network: cfg.Network, Upstream: upstream, Secret: []byte(cfg.Secret), now: time.Now}
+	p.reverse = &httputil.ReverseProxy{Rewrite: p.rewrite, ErrorHandler: p.fail}
+
+	return p, nil
+}
+
+// Register forwards every request received by router.
+func (p *Proxy) Register(router *gin.Engine) {
+	router.Any("/*path", func(c *gin.Context) {
+		p.reverse.ServeHTTP(c.Writer, c.Request)
+	})
+}
+
+// rewrite points a guest request at the upstream service. Forwarding headers sent by the
+// guest are dropped, only the ones signed by the proxy reach the service.
+func (p *Proxy) rewrite(r *httputil.ProxyRequest) {
+	r.SetURL(p.Upstream)
+
+	r.Out.Header.Del(resolver.NetworkHeader)
+	r.Out.Header.Del(resolver.ForwardedForHeader)
+	r.Out.Header.Del(resolver.TimestampHeader)
+	r.Out.Header.Del(resolver.SignatureHeader)
+
+	host, _, err := net.SplitHostPort(r.In.RemoteAddr)
+	if err != nil {
+		return
+	}
+
+	resolver.SignOrigin(r.Out, p.Secret, p.Network, host, p.now())
+}
+
+func (p *Proxy) fail(w http.ResponseWriter, r *http.Request, err error) {
+	logs.Logger.Error().Ctx(r.Context()).Err(err).Str("upstream", p.Upstream.String()).Msg("Failed to reach the metadata service")
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadGateway)
+	_, _ = w.Write([]byte(`{"error":"Metadata service unavailable"}`))
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/resolver"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxy_ForwardsSignedOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	networks := &resolver.Networks{ProxySecret: []byte("0123456789abcdef")}
+	var origin resolver.Origin
+	var path string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin, _ = networks.Origin(r)
+		path = r.URL.Path
+		_, _ = w.Write([]byte("instance-id: c1"))
+	}))
+	defer upstream.Close()
+
+	p, err := New(&config.ProxyConfig{Network: "ovn-web", Upstream: upstream.URL, Secret: "0123456789abcdef"})
+	require.NoError(t, err)
+
+	router := gin.New()
+	p.Register(router)
+
+	guest := httptest.NewServer(router)
+	defer guest.Close()
+
+	req, err := http.NewRequest(http.MethodGet, guest.URL+"/configs/meta-data", nil)
+	require.NoError(t, err)
+	// Guests can't name another network or address
+	req.Header.Set(resolver.NetworkHeader, "ovn-db")
+	req.Header.Set(resolver.ForwardedForHeader, "192.168.1.6")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "instance-id: c1", string(body))
+	assert.Equal(t, "/configs/meta-data", path)
+	assert.Equal(t, resolver.Origin{Network: "ovn-web", IP: "127.0.0.1"}, origin)
+}
+
+func TestProxy_UpstreamUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	p, err := New(&config.ProxyConfig{Network: "ovn-web", Upstream: upstream.URL, Secret: "0123456789abcdef"})
+	require.NoError(t, err)
+
+	router := gin.New()
+	p.Register(router)
+
+	guest := httptest.NewServer(router)
+	defer guest.Close()
+
+	resp, err := http.Get(guest.URL + "/configs/meta-data")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
+
+func TestNew_RequiresNetworkUpstreamAndSecret(t *testing.T) {
+	_, err := New(&config.ProxyConfig{Upstream: "http://10.0.0.1:8080", Secret: "0123456789abcdef"})
+	assert.Error(t, err)
+
+	_, err = New(&config.ProxyConfig{Network: "ovn-web", Secret: "0123456789abcdef"})
+	assert.Error(t, err)
+
+	_, err = New(&config.ProxyConfig{Network: "ovn-web", Upstream: "http://10.0.0.1:8080"})
+	assert.Error(t, err)
+}
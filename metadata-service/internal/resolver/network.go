@@ -0,0 +1,273 @@
+package resolver
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+)
+
+const (
+	// NetworkHeader names the Incus network of a request forwarded by a metadata proxy.
+	NetworkHeader = "X-Instance-Network"
+	// ForwardedForHeader carries the guest address of a request forwarded by a metadata proxy.
+	ForwardedForHeader = "X-Forwarded-For"
+)
+
+// Origin is where a guest request came from.
+type Origin struct {
+	// Network is the Incus network the request arrived from, empty when unknown.
+	Network string
+	// IP is the guest address.
+	IP string
+	// MAC is the guest MAC address, only known for guests on a directly attached network.
+	MAC string
+}
+
+// Networks determines the Incus network guest requests arrive from, since the same
+// private addresses are reused across projects and OVN networks. The network is taken,
+// in order, from the headers of a trusted or signing metadata proxy, the listener address the
+// request was received on or the VLAN of the interface it arrived on.
+type Networks struct {
+	// Listeners maps listener addresses to the network whose guests connect to them.
+	Listeners map[string]string
+	// VLANs maps VLAN IDs to the network carried by the VLAN.
+	VLANs map[int]string
+	// TrustedProxies are the metadata proxies allowed to set NetworkHeader and ForwardedForHeader.
+	TrustedProxies []*net.IPNet
+	// ProxySecret is shared with the metadata proxies. When set, NetworkHeader and
+	// ForwardedForHeader are only trusted when signed with it, see SignOrigin.
+	ProxySecret []byte
+	// ProcPath is the procfs mount point, defaults to /proc.
+	ProcPath string
+
+	// vlanOf returns the VLAN ID of the interface holding a local address.
+	vlanOf func(ip net.IP) (int, bool)
+}
+
+// ParseNetworks parses network mappings written as network=host:port for a listener
+// address or network=vlan:<id> for a VLAN, and the CIDRs of trusted metadata proxies.
+func ParseNetworks(mappings []string, trustedProxies []string) (*Networks, error) {
+	networks := &Networks{Listeners: map[string]string{}, VLANs: map[int]string{}}
+	for _, mapping := range mappings {
+		name, source, ok := strings.Cut(mapping, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("network mapping %q must be network=host:port or network=vlan:<id>", mapping)
+		}
+
+		if vlan, ok := strings.CutPrefix(source, "vlan:"); ok {
+			id, err := strconv.Atoi(vlan)
+			if err != nil || id < 1 || id > 4094 {
+				return nil, fmt.Errorf("network mapping %q has an invalid VLAN ID", mapping)
+			}
+
+			networks.VLANs[id] = name
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(source); err != nil {
+			return nil, fmt.Errorf("network mapping %q must be network=host:port or network=vlan:<id>", mapping)
+		}
+
+		networks.Listeners[source] = name
+	}
+
+	for _, proxy := range trustedProxies {
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q is not a CIDR: %w", proxy, err)
+		}
+
+		networks.TrustedProxies = append(networks.TrustedProxies, cidr)
+	}
+
+	return networks, nil
+}
+
+// Origin returns the network and guest address a request came from. Without Networks, only the
+// source address of the request is known.
+func (n *Networks) Origin(r *http.Request) (Origin, bool) {
+	ip, ok := sourceIP(r.RemoteAddr)
+	if !ok {
+		return Origin{}, false
+	}
+
+	if n == nil {
+		return Origin{IP: ip.String()}, true
+	}
+
+	// Proxied requests come from the proxy, the guest is named by the headers
+	if network := r.Header.Get(NetworkHeader); network != "" && n.proxied(r, ip) {
+		forwarded, _, _ := strings.Cut(r.Header.Get(ForwardedForHeader), ",")
+		guest, ok := sourceIP(net.JoinHostPort(strings.TrimSpace(forwarded), "0"))
+		if !ok {
+			return Origin{}, false
+		}
+
+		return Origin{Network: network, IP: guest.String()}, true
+	}
+
+	origin := Origin{IP: ip.String(), MAC: n.neighbour(ip)}
+	for address, network := range n.Listeners {
+		if receivedOn(r, []string{address}) {
+			origin.Network = network
+			return origin, true
+		}
+	}
+
+	if len(n.VLANs) > 0 {
+		if local, ok := r.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr); ok {
+			if id, ok := n.lookupVLAN(local.IP); ok {
+				origin.Network = n.VLANs[id]
+			}
+		}
+	}
+
+	return origin, true
+}
+
+// proxied reports whether a request comes from a metadata proxy whose headers can be trusted,
+// which requires its address to be trusted, its headers to be signed, or both when both are configured.
+func (n *Networks) proxied(r *http.Request, ip net.IP) bool {
+	if len(n.TrustedProxies) == 0 && len(n.ProxySecret) == 0 {
+		return false
+	}
+
+	if len(n.TrustedProxies) > 0 && !n.trusted(ip) {
+		return false
+	}
+
+	if len(n.ProxySecret) > 0 {
+		if err := verifyOrigin(r, n.ProxySecret, time.Now()); err != nil {
+			logs.Logger.Warn().Ctx(r.Context()).Err(err).Str("remote_addr", r.RemoteAddr).Msg("Ignoring unsigned metadata proxy headers")
+			return false
+		}
+	}
+
+	return true
+}
+
+func (n *Networks) trusted(ip net.IP) bool {
+	for _, proxy := range n.TrustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *Networks) procPath() string {
+	if n.ProcPath == "" {
+		return "/proc"
+	}
+
+	return n.ProcPath
+}
+
+// neighbour returns the MAC address of a directly attached IPv4 guest from the ARP table.
+// Routed guests have no entry of their own, so their MAC address stays unknown.
+func (n *Networks) neighbour(ip net.IP) string {
+	if ip.To4() == nil {
+		return ""
+	}
+
+	file, err := os.Open(filepath.Join(n.procPath(), "net", "arp"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	// IP address, HW type, Flags, HW address, Mask, Device
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[0] != ip.String() || fields[2] == "0x0" {
+			continue
+		}
+
+		return strings.ToLower(fields[3])
+	}
+
+	return ""
+}
+
+func (n *Networks) lookupVLAN(ip net.IP) (int, bool) {
+	if n.vlanOf != nil {
+		return n.vlanOf(ip)
+	}
+
+	iface, ok := interfaceOf(ip)
+	if !ok {
+		return 0, false
+	}
+
+	// The kernel lists VLAN interfaces as "eth0.100 | 100 | eth0"
+	file, err := os.Open(filepath.Join(n.procPath(), "net", "vlan", "config"))
+	if err != nil {
+		return 0, false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) != 3 || strings.TrimSpace(fields[0]) != iface {
+			continue
+		}
+
+		id, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		return id, err == nil
+	}
+
+	return 0, false
+}
+
+// interfaceOf returns the name of the local interface holding an address.
+func interfaceOf(ip net.IP) (string, bool) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", false
+	}
+
+	for _, iface := range ifaces {
+		addresses, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, address := range addresses {
+			if prefix, ok := address.(*net.IPNet); ok && prefix.IP.Equal(ip) {
+				return iface.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// sourceIP parses the address of a request, mapping IPv4-mapped IPv6 addresses to IPv4.
+func sourceIP(address string) (net.IP, bool) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, false
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, false
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, true
+	}
+
+	return ip, true
+}
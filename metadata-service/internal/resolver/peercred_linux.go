@@ -0,0 +1,34 @@
+//go:build linux
+
+package resolver
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerPID returns the PID of the process on the other end of a unix socket,
+// as seen from the service's PID namespace.
+func peerPID(conn *net.UnixConn) (int32, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	var ucred *unix.Ucred
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		ucred, sockErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if sockErr != nil {
+		return 0, fmt.Errorf("failed to read peer credentials: %w", sockErr)
+	}
+
+	return ucred.Pid, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package resolver
+
+import (
+	"errors"
+	"net"
+)
+
+// peerPID is only supported on Linux, where containers run.
+func peerPID(conn *net.UnixConn) (int32, error) {
+	return 0, errors.New("peer credentials are only supported on Linux")
+}
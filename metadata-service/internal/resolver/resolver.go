@@ -0,0 +1,334 @@
+package resolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/incus"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/metrics"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/server"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/cache"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/gin-gonic/gin"
+	"github.com/mdlayher/vsock"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrNotFound is returned when no instance matches the caller of a request.
+	ErrNotFound = errors.New("no instance matches the caller")
+	// ErrNotApplicable is returned by a resolver that cannot identify callers on this kind of connection.
+	ErrNotApplicable = errors.New("resolver does not apply to this connection")
+)
+
+// Resolver maps the caller of a guest request to an Incus instance.
+type Resolver interface {
+	Resolve(r *http.Request) (db.Instance, error)
+}
+
+// Chain tries each resolver in order and returns the result of the first one that applies.
+type Chain []Resolver
+
+func (chain Chain) Resolve(r *http.Request) (db.Instance, error) {
+	for _, resolver := range chain {
+		instance, err := resolver.Resolve(r)
+		if errors.Is(err, ErrNotApplicable) {
+			continue
+		}
+
+		return instance, err
+	}
+
+	return db.Instance{}, ErrNotFound
+}
+
+// IPResolver identifies callers by their source address, on the network they arrived
+// from when it is known. Instances are looked up in the database first and fall back to
+// Incus, caching the result.
+type IPResolver struct {
+	Database db.Store
+	Incus    incus.InstanceLister
+	// Remote is the name of the Incus remote the instances belong to.
+	Remote string
+	// Addresses limits the resolver to requests received on these listener addresses, so
+	// remotes whose instances reuse the same private addresses are told apart. Empty
+	// matches every listener.
+	Addresses []string
+	// Networks determines the network requests arrive from. Without it, an address held
+	// by instances on several networks can't be resolved.
+	Networks *Networks
+
+	// lookups shares one Incus lookup between concurrent requests from the same address,
+	// and misses remembers the addresses Incus recently knew nothing about.
+	lookups singleflight.Group
+	mu      sync.Mutex
+	misses  map[string]time.Time
+}
+
+// MissTTL is how long the IP resolver answers 404 for an address Incus knew nothing
+// about without asking Incus again, as each lookup lists every instance. Instances
+// cached by the event listener meanwhile are found in the database regardless.
+const MissTTL = 5 * time.Second
+
+func (res *IPResolver) Resolve(r *http.Request) (db.Instance, error) {
+	if _, ok := peerVsockAddr(r.Context()); ok {
+		return db.Instance{}, ErrNotApplicable
+	}
+
+	if len(res.Addresses) > 0 && !receivedOn(r, res.Addresses) {
+		return db.Instance{}, ErrNotApplicable
+	}
+
+	origin, ok := res.Networks.Origin(r)
+	if !ok {
+		return db.Instance{}, ErrNotApplicable
+	}
+
+	instance, err := res.lookup(r.Context(), origin)
+	if err == nil {
+		metrics.ObserveResolution("ip", "database", true)
+		return instance, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return db.Instance{}, err
+	}
+
+	metrics.ObserveResolution("ip", "database", false)
+
+	key := res.Remote + "|" + origin.Network + "|" + origin.IP + "|" + origin.MAC
+	if res.missed(key) {
+		return db.Instance{}, ErrNotFound
+	}
+
+	result, err, _ := res.lookups.Do(key, func() (any, error) {
+		// The lookup is shared, so it must not fail when the request that started it goes away
+		instance, err := res.find(context.WithoutCancel(r.Context()), origin)
+		if errors.Is(err, ErrNotFound) {
+			res.miss(key)
+		}
+
+		return instance, err
+	})
+	if err != nil {
+		return db.Instance{}, err
+	}
+
+	return result.(db.Instance), nil
+}
+
+// find looks up the instance holding an address in Incus and caches it.
+func (res *IPResolver) find(ctx context.Context, origin Origin) (db.Instance, error) {
+	found, err := incus.FindInstanceByAddress(ctx, res.Incus, origin.Network, origin.IP, origin.MAC)
+	if errors.Is(err, incus.ErrAmbiguousAddress) {
+		logs.Logger.Warn().Ctx(ctx).Str("ip", origin.IP).Str("remote", res.Remote).
+			Msg("Address is held by instances on several networks, configure how to tell the networks apart")
+		return db.Instance{}, ErrNotFound
+	}
+
+	if err != nil {
+		return db.Instance{}, err
+	}
+
+	metrics.ObserveResolution("ip", "incus", found != nil)
+	if found == nil {
+		return db.Instance{}, ErrNotFound
+	}
+
+	return cache.StoreInstance(ctx, res.Database, res.Remote, *found)
+}
+
+// missed reports whether Incus knew nothing about an address less than MissTTL ago.
+func (res *IPResolver) missed(key string) bool {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	at, ok := res.misses[key]
+	if ok && time.Since(at) >= MissTTL {
+		delete(res.misses, key)
+		return false
+	}
+
+	return ok
+}
+
+// miss records that Incus knew nothing about an address, dropping expired entries so
+// scans from many addresses don't grow the map without bound.
+func (res *IPResolver) miss(key string) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	if res.misses == nil {
+		res.misses = map[string]time.Time{}
+	}
+
+	now := time.Now()
+	for other, at := range res.misses {
+		if now.Sub(at) >= MissTTL {
+			delete(res.misses, other)
+		}
+	}
+
+	res.misses[key] = now
+}
+
+// lookup finds a cached instance by address, returning sql.ErrNoRows when there is none.
+func (res *IPResolver) lookup(ctx context.Context, origin Origin) (db.Instance, error) {
+	if origin.Network != "" {
+		instance, err := res.Database.GetInstanceByAddress(ctx, db.GetInstanceByAddressParams{
+			Remote:     res.Remote,
+			Network:    origin.Network,
+			IpAddress:  origin.IP,
+			MacAddress: origin.MAC,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			return db.Instance{}, fmt.Errorf("failed to look up instance by address: %w", err)
+		}
+
+		return instance, err
+	}
+
+	instances, err := res.Database.ListInstancesByAddressIP(ctx, db.ListInstancesByAddressIPParams{
+		Remote:     res.Remote,
+		IpAddress:  origin.IP,
+		MacAddress: origin.MAC,
+	})
+	if err != nil {
+		return db.Instance{}, fmt.Errorf("failed to look up instance by IP: %w", err)
+	}
+
+	// Ambiguous addresses are resolved against Incus, which reports them as such
+	if len(instances) != 1 {
+		return db.Instance{}, sql.ErrNoRows
+	}
+
+	return instances[0], nil
+}
+
+// receivedOn reports whether a request was received on one of the given listener
+// addresses. Addresses without a host, such as ":80", match every local IP on that port.
+func receivedOn(r *http.Request, addresses []string) bool {
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+
+	localHost, localPort, err := net.SplitHostPort(local.String())
+	if err != nil {
+		return false
+	}
+
+	localIP := net.ParseIP(localHost)
+	for _, address := range addresses {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || port != localPort {
+			continue
+		}
+
+		ip := net.ParseIP(host)
+		if host == "" || (ip != nil && ip.IsUnspecified()) || (ip != nil && ip.Equal(localIP)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// VsockResolver identifies virtual machines connecting over vsock by their
+// context ID, which Incus records in volatile.vsock_id. It works even when
+// the guest has no network configured.
+type VsockResolver struct {
+	Database db.Store
+	Incus    incus.InstanceLister
+	// Remote is the name of the Incus remote running on this host.
+	Remote string
+}
+
+func (res *VsockResolver) Resolve(r *http.Request) (db.Instance, error) {
+	addr, ok := peerVsockAddr(r.Context())
+	if !ok {
+		return db.Instance{}, ErrNotApplicable
+	}
+
+	cid := int64(addr.ContextID)
+	instance, err := res.Database.GetInstanceByVsockID(r.Context(), db.GetInstanceByVsockIDParams{Remote: res.Remote, VsockID: &cid})
+	if err == nil {
+		metrics.ObserveResolution("vsock", "database", true)
+		return instance, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return db.Instance{}, fmt.Errorf("failed to look up instance by vsock ID: %w", err)
+	}
+
+	metrics.ObserveResolution("vsock", "database", false)
+
+	found, err := incus.FindInstanceByVsockID(r.Context(), res.Incus, addr.ContextID)
+	if err != nil {
+		return db.Instance{}, err
+	}
+
+	metrics.ObserveResolution("vsock", "incus", found != nil)
+	if found == nil {
+		return db.Instance{}, ErrNotFound
+	}
+
+	return cache.StoreInstance(r.Context(), res.Database, res.Remote, *found)
+}
+
+func peerVsockAddr(ctx context.Context) (*vsock.Addr, bool) {
+	conn := server.PeerConn(ctx)
+	if conn == nil {
+		return nil, false
+	}
+
+	addr, ok := conn.RemoteAddr().(*vsock.Addr)
+	return addr, ok
+}
+
+const instanceKey = "resolver.instance"
+
+// Middleware resolves the instance making the request and stores it in the gin context.
+// Requests from unknown callers are rejected with 404.
+func Middleware(resolver Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		instance, err := resolver.Resolve(c.Request)
+		if errors.Is(err, ErrNotFound) {
+			logs.FromContext(c).Warn().Str("remote_addr", c.Request.RemoteAddr).Msg("No instance matches the caller")
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+			return
+		}
+
+		if err != nil {
+			logs.FromContext(c).Error().Err(err).Str("remote_addr", c.Request.RemoteAddr).Msg("Failed to resolve instance")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve instance"})
+			return
+		}
+
+		logs.UpdateContext(c, func(l zerolog.Context) zerolog.Context {
+			return l.Str("instance", instance.Name).Str("project", instance.Project).Str("remote", instance.Remote)
+		})
+
+		c.Set(instanceKey, instance)
+		c.Next()
+	}
+}
+
+// InstanceFromContext returns the instance resolved by Middleware.
+func InstanceFromContext(c *gin.Context) (db.Instance, bool) {
+	value, ok := c.Get(instanceKey)
+	if !ok {
+		return db.Instance{}, false
+	}
+
+	instance, ok := value.(db.Instance)
+	return instance, ok
+}
@@ -0,0 +1,468 @@
+package resolver
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/server"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/lxc/incus/shared/api"
+	"github.com/mdlayher/vsock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIncus implements incus.InstanceLister with a fixed set of instances
+type fakeIncus struct {
+	instances []api.InstanceFull
+	calls     atomic.Int32
+}
+
+func (f *fakeIncus) GetInstancesFullAllProjects(instanceType api.InstanceType) ([]api.InstanceFull, error) {
+	f.calls.Add(1)
+	var instances []api.InstanceFull
+	for _, instance := range f.instances {
+		if instanceType == api.InstanceTypeAny || instance.Type == string(instanceType) {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+// vsockConn is one end of an in-memory pipe that reports a vsock peer address
+type vsockConn struct {
+	net.Conn
+	cid uint32
+}
+
+func (c *vsockConn) RemoteAddr() net.Addr {
+	return &vsock.Addr{ContextID: c.cid, Port: 1024}
+}
+
+// fakeVsockListener hands out in-memory connections as if they were accepted over vsock
+type fakeVsockListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+}
+
+func newFakeVsockListener() *fakeVsockListener {
+	return &fakeVsockListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *fakeVsockListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *fakeVsockListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *fakeVsockListener) Addr() net.Addr {
+	return &vsock.Addr{ContextID: 2, Port: 80}
+}
+
+// client returns an HTTP client whose connections arrive at the listener from the given CID
+func (l *fakeVsockListener) client(cid uint32) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				guest, host := net.Pipe()
+				l.conns <- &vsockConn{Conn: host, cid: cid}
+				return guest, nil
+			},
+		},
+	}
+}
+
+func setupVsockServer(t *testing.T, mockDB *mocks.MockQuerier, incusClient *fakeIncus) *fakeVsockListener {
+	gin.SetMode(gin.TestMode)
+
+	instanceResolver := Chain{
+		&VsockResolver{Database: mockDB, Incus: incusClient, Remote: "local"},
+		&IPResolver{Database: mockDB, Incus: incusClient, Remote: "local"},
+	}
+
+	router := gin.New()
+	router.GET("/configs/meta-data", Middleware(instanceResolver), func(c *gin.Context) {
+		instance, _ := InstanceFromContext(c)
+		c.String(http.StatusOK, instance.Project+"/"+instance.Name)
+	})
+
+	listener := newFakeVsockListener()
+	guest := server.New("guest", nil, router, config.TimeoutConfig{}, nil)
+	guest.Attach(listener)
+
+	go guest.Serve()
+	t.Cleanup(func() { guest.Shutdown(context.Background()) })
+
+	return listener
+}
+
+func get(t *testing.T, client *http.Client) (int, string) {
+	resp, err := client.Get("http://169.254.169.254/configs/meta-data")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestVsockResolver_CachedInstance(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	listener := setupVsockServer(t, mockDB, &fakeIncus{})
+
+	cid := int64(42)
+	mockDB.On("GetInstanceByVsockID", mock.Anything, db.GetInstanceByVsockIDParams{Remote: "local", VsockID: &cid}).Return(db.Instance{ID: 1, Name: "vm1", Project: "default", VsockID: &cid}, nil)
+
+	status, body := get(t, listener.client(42))
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "default/vm1", body)
+	mockDB.AssertNotCalled(t, "GetInstanceByIP", mock.Anything, mock.Anything)
+	mockDB.AssertExpectations(t)
+}
+
+func TestVsockResolver_FallsBackToIncus(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	incusClient := &fakeIncus{instances: []api.InstanceFull{
+		{Instance: api.Instance{Name: "other", Project: "default", Type: "virtual-machine", InstancePut: api.InstancePut{Config: map[string]string{"volatile.vsock_id": "7"}}}},
+		{Instance: api.Instance{Name: "no-network", Project: "staging", Type: "virtual-machine", InstancePut: api.InstancePut{Config: map[string]string{"volatile.vsock_id": "43"}}}},
+		{Instance: api.Instance{Name: "container", Project: "default", Type: "container", InstancePut: api.InstancePut{Config: map[string]string{"volatile.vsock_id": "43"}}}},
+	}}
+	listener := setupVsockServer(t, mockDB, incusClient)
+
+	cid := int64(43)
+	mockDB.On("GetInstanceByVsockID", mock.Anything, db.GetInstanceByVsockIDParams{Remote: "local", VsockID: &cid}).Return(db.Instance{}, sql.ErrNoRows)
+	mockDB.On("UpsertInstance", mock.Anything, db.UpsertInstanceParams{Remote: "local", Name: "no-network", Project: "staging", VsockID: &cid}).
+		Return(db.Instance{ID: 2, Name: "no-network", Project: "staging", VsockID: &cid}, nil)
+
+	status, body := get(t, listener.client(43))
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "staging/no-network", body)
+	mockDB.AssertExpectations(t)
+}
+
+func TestVsockResolver_UnknownCID(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	listener := setupVsockServer(t, mockDB, &fakeIncus{})
+
+	cid := int64(99)
+	mockDB.On("GetInstanceByVsockID", mock.Anything, db.GetInstanceByVsockIDParams{Remote: "local", VsockID: &cid}).Return(db.Instance{}, sql.ErrNoRows)
+
+	status, _ := get(t, listener.client(99))
+
+	assert.Equal(t, http.StatusNotFound, status)
+	mockDB.AssertExpectations(t)
+}
+
+func TestIPResolver(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	incusClient := &fakeIncus{instances: []api.InstanceFull{
+		{
+			Instance: api.Instance{Name: "web", Project: "default", Type: "container", ExpandedDevices: map[string]map[string]string{
+				"eth0": {"type": "nic", "network": "incusbr0", "hwaddr": "00:16:3e:00:00:05"},
+			}},
+			State: &api.InstanceState{Network: map[string]api.InstanceStateNetwork{
+				"lo":   {Hwaddr: "", Addresses: []api.InstanceStateNetworkAddress{{Family: "inet", Address: "127.0.0.1"}}},
+				"eth0": {Hwaddr: "00:16:3e:00:00:05", Addresses: []api.InstanceStateNetworkAddress{{Family: "inet", Address: "10.0.0.5"}}},
+			}},
+		},
+	}}
+	res := &IPResolver{Database: mockDB, Incus: incusClient, Remote: "local"}
+
+	mockDB.On("ListInstancesByAddressIP", mock.Anything, db.ListInstancesByAddressIPParams{Remote: "local", IpAddress: "10.0.0.4"}).
+		Return([]db.Instance{{Name: "db"}}, nil)
+
+	mockDB.On("ListInstancesByAddressIP", mock.Anything, db.ListInstancesByAddressIPParams{Remote: "local", IpAddress: "10.0.0.5"}).
+		Return([]db.Instance(nil), nil)
+	mockDB.On("UpsertInstance", mock.Anything, db.UpsertInstanceParams{Remote: "local", Name: "web", Project: "default", IpAddress: ptr("10.0.0.5"), AddressesKnown: true}).
+		Return(db.Instance{ID: 5, Name: "web"}, nil)
+	mockDB.On("DeleteInstanceAddresses", mock.Anything, int64(5)).Return(nil)
+	mockDB.On("CreateInstanceAddress", mock.Anything, db.CreateInstanceAddressParams{InstanceID: 5, Network: "incusbr0", IpAddress: "10.0.0.5", MacAddress: "00:16:3e:00:00:05"}).
+		Return(nil)
+
+	mockDB.On("ListInstancesByAddressIP", mock.Anything, db.ListInstancesByAddressIPParams{Remote: "local", IpAddress: "10.0.0.6"}).
+		Return([]db.Instance(nil), nil)
+
+	req := httptest.NewRequest("GET", "/configs/meta-data", nil)
+
+	req.RemoteAddr = "10.0.0.4:40000"
+	instance, err := res.Resolve(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "db", instance.Name)
+
+	req.RemoteAddr = "[::ffff:10.0.0.5]:40000"
+	instance, err = res.Resolve(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "web", instance.Name)
+
+	req.RemoteAddr = "10.0.0.6:40000"
+	_, err = res.Resolve(req)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestIPResolver_RemembersMisses(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	incusClient := &fakeIncus{}
+	res := &IPResolver{Database: mockDB, Incus: incusClient, Remote: "local"}
+
+	mockDB.On("ListInstancesByAddressIP", mock.Anything, db.ListInstancesByAddressIPParams{Remote: "local", IpAddress: "10.0.0.6"}).
+		Return([]db.Instance(nil), nil)
+
+	req := httptest.NewRequest("GET", "/configs/meta-data", nil)
+	req.RemoteAddr = "10.0.0.6:40000"
+	for range 3 {
+		_, err := res.Resolve(req)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+
+	// Only the first miss lists the instances of the remote
+	assert.Equal(t, int32(1), incusClient.calls.Load())
+	mockDB.AssertNumberOfCalls(t, "ListInstancesByAddressIP", 3)
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// requestOn returns a guest request from remote as if received on the given listener address
+func requestOn(remote string, local string) *http.Request {
+	req := httptest.NewRequest("GET", "/configs/meta-data", nil)
+	req.RemoteAddr = remote
+
+	addr, _ := net.ResolveTCPAddr("tcp", local)
+	return req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, addr))
+}
+
+func TestIPResolver_ScopedPerRemoteListener(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	chain := Chain{
+		&IPResolver{Database: mockDB, Incus: &fakeIncus{}, Remote: "dc2", Addresses: []string{"10.2.0.1:80"}},
+		&IPResolver{Database: mockDB, Incus: &fakeIncus{}, Remote: "local"},
+	}
+
+	mockDB.On("ListInstancesByAddressIP", mock.Anything, db.ListInstancesByAddressIPParams{Remote: "dc2", IpAddress: "10.0.0.5"}).
+		Return([]db.Instance{{Name: "web", Remote: "dc2"}}, nil)
+	mockDB.On("ListInstancesByAddressIP", mock.Anything, db.ListInstancesByAddressIPParams{Remote: "local", IpAddress: "10.0.0.5"}).
+		Return([]db.Instance{{Name: "db", Remote: "local"}}, nil)
+
+	resolve := func(local string) db.Instance {
+		instance, err := chain.Resolve(requestOn("10.0.0.5:40000", local))
+		require.NoError(t, err)
+		return instance
+	}
+
+	// The same address belongs to a different instance depending on the listener it reached
+	assert.Equal(t, "web", resolve("10.2.0.1:80").Name)
+	assert.Equal(t, "db", resolve("10.1.0.1:80").Name)
+	mockDB.AssertExpectations(t)
+}
+
+func TestIPResolver_NetworkScopedLookup(t *testing.T) {
+	networks, err := ParseNetworks([]string{"ovn-web=10.10.0.1:80", "lan=vlan:100"}, []string{"192.0.2.0/24"})
+	require.NoError(t, err)
+	networks.ProcPath = t.TempDir()
+	networks.vlanOf = func(ip net.IP) (int, bool) { return 100, ip.Equal(net.ParseIP("10.20.0.1")) }
+
+	mockDB := &mocks.MockQuerier{}
+	res := &IPResolver{Database: mockDB, Incus: &fakeIncus{}, Remote: "local", Networks: networks}
+
+	for _, network := range []string{"ovn-web", "lan", "ovn-db"} {
+		mockDB.On("GetInstanceByAddress", mock.Anything, db.GetInstanceByAddressParams{Remote: "local", Network: network, IpAddress: "10.0.0.5"}).
+			Return(db.Instance{Name: "on-" + network}, nil)
+	}
+
+	// By listener address
+	instance, err := res.Resolve(requestOn("10.0.0.5:40000", "10.10.0.1:80"))
+	require.NoError(t, err)
+	assert.Equal(t, "on-ovn-web", instance.Name)
+
+	// By the VLAN of the interface the request arrived on
+	instance, err = res.Resolve(requestOn("10.0.0.5:40000", "10.20.0.1:80"))
+	require.NoError(t, err)
+	assert.Equal(t, "on-lan", instance.Name)
+
+	// By the headers of a trusted metadata proxy
+	req := requestOn("192.0.2.10:40000", "10.30.0.1:80")
+	req.Header.Set(NetworkHeader, "ovn-db")
+	req.Header.Set(ForwardedForHeader, "10.0.0.5")
+	instance, err = res.Resolve(req)
+	require.NoError(t, err)
+	assert.Equal(t, "on-ovn-db", instance.Name)
+
+	// Headers from anyone else are ignored
+	mockDB.On("ListInstancesByAddressIP", mock.Anything, db.ListInstancesByAddressIPParams{Remote: "local", IpAddress: "10.0.0.7"}).
+		Return([]db.Instance{{Name: "a"}, {Name: "b"}}, nil)
+	req = requestOn("10.0.0.7:40000", "10.30.0.1:80")
+	req.Header.Set(NetworkHeader, "ovn-db")
+	req.Header.Set(ForwardedForHeader, "10.0.0.5")
+	_, err = res.Resolve(req)
+	assert.ErrorIs(t, err, ErrNotFound, "an address on several networks is ambiguous without the network")
+
+	mockDB.AssertExpectations(t)
+}
+
+func TestNetworks_NeighbourMAC(t *testing.T) {
+	networks := &Networks{ProcPath: t.TempDir()}
+	require.NoError(t, os.MkdirAll(filepath.Join(networks.ProcPath, "net"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(networks.ProcPath, "net", "arp"), []byte(
+		"IP address       HW type     Flags       HW address            Mask     Device\n"+
+			"10.0.0.5         0x1         0x2         00:16:3E:AA:BB:CC     *        incusbr0\n"+
+			"10.0.0.6         0x1         0x0         00:00:00:00:00:00     *        incusbr0\n"), 0o644))
+
+	origin, ok := networks.Origin(requestOn("10.0.0.5:40000", "10.0.0.1:80"))
+	require.True(t, ok)
+	assert.Equal(t, Origin{IP: "10.0.0.5", MAC: "00:16:3e:aa:bb:cc"}, origin)
+
+	origin, _ = networks.Origin(requestOn("10.0.0.6:40000", "10.0.0.1:80"))
+	assert.Equal(t, "", origin.MAC, "incomplete entries are ignored")
+}
+
+func TestNetworks_SignedProxyHeaders(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	networks := &Networks{ProxySecret: secret}
+
+	req := requestOn("10.0.0.2:40000", "10.0.0.1:80")
+	SignOrigin(req, secret, "ovn-web", "192.168.1.5", time.Now())
+
+	origin, ok := networks.Origin(req)
+	require.True(t, ok)
+	assert.Equal(t, Origin{Network: "ovn-web", IP: "192.168.1.5"}, origin)
+
+	// Changing the signed network or address invalidates the signature
+	req.Header.Set(NetworkHeader, "ovn-db")
+	origin, _ = networks.Origin(req)
+	assert.Equal(t, Origin{IP: "10.0.0.2"}, origin, "forged headers are ignored")
+
+	// The signature only holds for the method and path it was made for
+	replayed := requestOn("10.0.0.2:40000", "10.0.0.1:80")
+	SignOrigin(replayed, secret, "ovn-web", "192.168.1.5", time.Now())
+	replayed.URL.Path = "/latest/api/token"
+	origin, _ = networks.Origin(replayed)
+	assert.Equal(t, Origin{IP: "10.0.0.2"}, origin, "signatures replayed on another path are ignored")
+
+	replayed.URL.Path = "/configs/meta-data"
+	replayed.Method = http.MethodPut
+	origin, _ = networks.Origin(replayed)
+	assert.Equal(t, Origin{IP: "10.0.0.2"}, origin, "signatures replayed with another method are ignored")
+
+	stale := requestOn("10.0.0.2:40000", "10.0.0.1:80")
+	SignOrigin(stale, secret, "ovn-web", "192.168.1.5", time.Now().Add(-time.Hour))
+	origin, _ = networks.Origin(stale)
+	assert.Equal(t, Origin{IP: "10.0.0.2"}, origin, "stale signatures are ignored")
+
+	// With trusted proxies too, the headers must also come from one of them
+	_, cidr, _ := net.ParseCIDR("10.1.0.0/16")
+	networks.TrustedProxies = []*net.IPNet{cidr}
+	signed := requestOn("10.0.0.2:40000", "10.0.0.1:80")
+	SignOrigin(signed, secret, "ovn-web", "192.168.1.5", time.Now())
+	origin, _ = networks.Origin(signed)
+	assert.Equal(t, "", origin.Network)
+}
+
+func setupUnixServer(t *testing.T, mockDB *mocks.MockQuerier, incusClient *fakeIncus) *http.Client {
+	gin.SetMode(gin.TestMode)
+
+	instanceResolver := Chain{
+		&UnixResolver{Database: mockDB, Incus: incusClient, Remote: "local"},
+		&IPResolver{Database: mockDB, Incus: incusClient, Remote: "local"},
+	}
+
+	router := gin.New()
+	router.GET("/configs/meta-data", Middleware(instanceResolver), func(c *gin.Context) {
+		instance, _ := InstanceFromContext(c)
+		c.String(http.StatusOK, instance.Project+"/"+instance.Name)
+	})
+
+	path := filepath.Join(t.TempDir(), "metadata.sock")
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	guest := server.New("guest", nil, router, config.TimeoutConfig{}, nil)
+	guest.Attach(ln)
+
+	go guest.Serve()
+	t.Cleanup(func() { guest.Shutdown(context.Background()) })
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+func TestUnixResolver_MatchesContainerPIDNamespace(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only supported on Linux")
+	}
+
+	// The test process plays the container: its init PID shares our PID namespace.
+	pid := int64(os.Getpid())
+
+	mockDB := &mocks.MockQuerier{}
+	incusClient := &fakeIncus{instances: []api.InstanceFull{
+		{Instance: api.Instance{Name: "stopped", Project: "default", Type: "container"}, State: &api.InstanceState{Pid: 0}},
+		{Instance: api.Instance{Name: "vm", Project: "default", Type: "virtual-machine"}, State: &api.InstanceState{Pid: pid}},
+		{Instance: api.Instance{Name: "c1", Project: "staging", Type: "container"}, State: &api.InstanceState{Pid: pid}},
+	}}
+	client := setupUnixServer(t, mockDB, incusClient)
+
+	mockDB.On("UpsertInstance", mock.Anything, db.UpsertInstanceParams{Remote: "local", Name: "c1", Project: "staging", AddressesKnown: true}).
+		Return(db.Instance{ID: 3, Name: "c1", Project: "staging"}, nil).Once()
+	mockDB.On("DeleteInstanceAddresses", mock.Anything, int64(3)).Return(nil).Once()
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Remote: "local", Name: "c1", Project: "staging"}).
+		Return(db.Instance{ID: 3, Name: "c1", Project: "staging"}, nil).Once()
+
+	status, body := get(t, client)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "staging/c1", body)
+
+	// The namespace is cached, so the second request is answered from the database.
+	incusClient.instances = nil
+	client.CloseIdleConnections()
+
+	status, body = get(t, client)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "staging/c1", body)
+	mockDB.AssertExpectations(t)
+}
+
+func TestUnixResolver_UnknownNamespace(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only supported on Linux")
+	}
+
+	mockDB := &mocks.MockQuerier{}
+	client := setupUnixServer(t, mockDB, &fakeIncus{})
+
+	status, _ := get(t, client)
+
+	assert.Equal(t, http.StatusNotFound, status)
+	mockDB.AssertExpectations(t)
+}
@@ -0,0 +1,71 @@
+package resolver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of the network, guest address, timestamp, method
+	// and path of a request forwarded by a metadata proxy, keyed with the secret shared with the proxy.
+	SignatureHeader = "X-Instance-Network-Signature"
+	// TimestampHeader carries the Unix time a metadata proxy signed a request at.
+	TimestampHeader = "X-Instance-Network-Timestamp"
+
+	// signatureMaxAge bounds the clock skew between proxies and the service, and how long
+	// a captured signature can be replayed.
+	signatureMaxAge = time.Minute
+)
+
+// ErrInvalidSignature is returned when the headers of a proxied request are not signed with the shared secret.
+var ErrInvalidSignature = errors.New("invalid metadata proxy signature")
+
+// SignOrigin stamps a request forwarded by a metadata proxy with the network and address
+// of the guest, signed with the secret shared with the service. The method and path are
+// signed too, so a captured signature can't be replayed against another endpoint.
+func SignOrigin(r *http.Request, secret []byte, network string, ip string, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	r.Header.Set(NetworkHeader, network)
+	r.Header.Set(ForwardedForHeader, ip)
+	r.Header.Set(TimestampHeader, timestamp)
+	r.Header.Set(SignatureHeader, signature(secret, network, ip, timestamp, r.Method, r.URL.Path))
+}
+
+// verifyOrigin checks that the network and guest address of a proxied request were signed
+// for its method and path with the shared secret within signatureMaxAge.
+func verifyOrigin(r *http.Request, secret []byte, now time.Time) error {
+	header := r.Header
+	network, timestamp := header.Get(NetworkHeader), header.Get(TimestampHeader)
+	forwarded, _, _ := strings.Cut(header.Get(ForwardedForHeader), ",")
+
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if age := now.Sub(time.Unix(signedAt, 0)); age > signatureMaxAge || age < -signatureMaxAge {
+		return ErrInvalidSignature
+	}
+
+	expected := signature(secret, network, strings.TrimSpace(forwarded), timestamp, r.Method, r.URL.Path)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(SignatureHeader))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// signature keys the signed fields with the secret. The path comes last, as it is the only
+// field that can hold a newline.
+func signature(secret []byte, network string, ip string, timestamp string, method string, path string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(network + "\n" + ip + "\n" + timestamp + "\n" + method + "\n" + path))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,138 @@
+package resolver
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/incus"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/metrics"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/server"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/cache"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/lxc/incus/shared/api"
+)
+
+// UnixResolver identifies system containers connecting over a unix socket that is
+// bind-mounted into them, like Incus' own devIncus socket. The peer PID is read with
+// SO_PEERCRED and its PID namespace is matched against the init process of each container.
+type UnixResolver struct {
+	Database db.Store
+	Incus    incus.InstanceLister
+	// Remote is the name of the Incus remote running on this host.
+	Remote string
+	// ProcPath is the procfs mount point, defaults to /proc.
+	ProcPath string
+
+	mu sync.Mutex
+	// namespaces caches PID namespaces to the container owning them.
+	namespaces map[string]namespaceOwner
+}
+
+type namespaceOwner struct {
+	name    string
+	project string
+	initPID int64
+}
+
+func (res *UnixResolver) Resolve(r *http.Request) (db.Instance, error) {
+	conn, ok := server.PeerConn(r.Context()).(*net.UnixConn)
+	if !ok {
+		return db.Instance{}, ErrNotApplicable
+	}
+
+	pid, err := peerPID(conn)
+	if err != nil {
+		return db.Instance{}, err
+	}
+
+	namespace, err := res.pidNamespace(int64(pid))
+	if err != nil {
+		return db.Instance{}, fmt.Errorf("failed to read PID namespace of peer %d: %w", pid, err)
+	}
+
+	if owner, ok := res.cachedOwner(namespace); ok {
+		instance, err := res.Database.GetInstance(r.Context(), db.GetInstanceParams{Remote: res.Remote, Name: owner.name, Project: owner.project})
+		if err == nil && instance.InvalidatedAt == nil {
+			metrics.ObserveResolution("unix", "database", true)
+			return instance, nil
+		}
+
+		if err != sql.ErrNoRows {
+			return db.Instance{}, fmt.Errorf("failed to look up instance: %w", err)
+		}
+	}
+
+	metrics.ObserveResolution("unix", "database", false)
+
+	instances, err := incus.ListInstances(r.Context(), res.Incus, api.InstanceTypeContainer)
+	if err != nil {
+		return db.Instance{}, err
+	}
+
+	for _, instance := range instances {
+		if instance.State == nil || instance.State.Pid <= 0 {
+			continue
+		}
+
+		initNamespace, err := res.pidNamespace(instance.State.Pid)
+		if err != nil || initNamespace != namespace {
+			continue
+		}
+
+		metrics.ObserveResolution("unix", "incus", true)
+		res.cacheOwner(namespace, namespaceOwner{name: instance.Name, project: instance.Project, initPID: instance.State.Pid})
+		return cache.StoreInstance(r.Context(), res.Database, res.Remote, instance)
+	}
+
+	metrics.ObserveResolution("unix", "incus", false)
+	return db.Instance{}, ErrNotFound
+}
+
+// pidNamespace returns the PID namespace identifier of a process, e.g. "pid:[4026532198]".
+func (res *UnixResolver) pidNamespace(pid int64) (string, error) {
+	procPath := res.ProcPath
+	if procPath == "" {
+		procPath = "/proc"
+	}
+
+	return os.Readlink(filepath.Join(procPath, strconv.FormatInt(pid, 10), "ns", "pid"))
+}
+
+// cachedOwner returns the container owning a namespace, as long as its init process
+// still lives in that namespace. Namespace identifiers are reused once released.
+func (res *UnixResolver) cachedOwner(namespace string) (namespaceOwner, bool) {
+	res.mu.Lock()
+	owner, ok := res.namespaces[namespace]
+	res.mu.Unlock()
+
+	if !ok {
+		return namespaceOwner{}, false
+	}
+
+	current, err := res.pidNamespace(owner.initPID)
+	if err != nil || current != namespace {
+		res.mu.Lock()
+		delete(res.namespaces, namespace)
+		res.mu.Unlock()
+		return namespaceOwner{}, false
+	}
+
+	return owner, true
+}
+
+func (res *UnixResolver) cacheOwner(namespace string, owner namespaceOwner) {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	if res.namespaces == nil {
+		res.namespaces = map[string]namespaceOwner{}
+	}
+
+	res.namespaces[namespace] = owner
+}
@@ -0,0 +1,177 @@
+// Package retention removes old instance logs and soft-deleted rows from the database.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/metrics"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+)
+
+// vacuumPages bounds the pages an incremental vacuum releases per run.
+const vacuumPages = 4096
+
+// Vacuumer returns the free pages of the database to the file system, see db.Queries.Vacuum.
+type Vacuumer interface {
+	Vacuum(ctx context.Context, full bool, pages int) error
+}
+
+// Worker periodically applies the retention policy. Rows are removed in batches of
+// BatchSize, so each statement only holds the database lock briefly and the worker can
+// run alongside request traffic.
+type Worker struct {
+	Database db.Querier
+	// Vacuumer releases free pages after each run, nil skips vacuuming.
+	Vacuumer Vacuumer
+	Config   *config.RetentionConfig
+
+	now func() time.Time
+}
+
+// Run applies the retention policy every Interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	if w.Config.Interval == 0 {
+		logs.Logger.Info().Msg("Retention worker disabled")
+		return
+	}
+
+	ticker := time.NewTicker(w.Config.Interval)
+	defer ticker.Stop()
+
+	for {
+		start := time.Now()
+		if err := w.RunOnce(ctx); err != nil {
+			metrics.RetentionRunDuration.WithLabelValues("error").Observe(time.Since(start).Seconds())
+			logs.Logger.Error().Err(err).Msg("Retention run failed")
+		} else {
+			metrics.RetentionRunDuration.WithLabelValues("ok").Observe(time.Since(start).Seconds())
+			metrics.RetentionLastSuccess.SetToCurrentTime()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce removes expired and excess logs of every log type, purges rows soft-deleted for
+// longer than the grace period along with the rows that belonged to them, removes expired
+// ephemeral SSH keys and signing keys, forgets reads of deleted secrets, and vacuums the
+// database. Every step runs even when an earlier one fails.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	now := time.Now()
+	if w.now != nil {
+		now = w.now()
+	}
+
+	var errs []error
+	for _, policy := range w.policies() {
+		if policy.MaxAge > 0 {
+			before := now.Add(-policy.MaxAge).Unix()
+			errs = append(errs, w.deleteBatches(ctx, "instance_logs", "max_age", func(limit int64) (int64, error) {
+				return w.Database.DeleteExpiredInstanceLogs(ctx, db.DeleteExpiredInstanceLogsParams{LogType: policy.logType, Before: before, Limit: limit})
+			}))
+		}
+
+		if policy.MaxRows > 0 {
+			errs = append(errs, w.deleteBatches(ctx, "instance_logs", "max_rows", func(limit int64) (int64, error) {
+				return w.Database.DeleteExcessInstanceLogs(ctx, db.DeleteExcessInstanceLogsParams{LogType: policy.logType, MaxRows: policy.MaxRows, Limit: limit})
+			}))
+		}
+	}
+
+	// Instances are only soft-deleted once deleted in Incus, or missing from a sync. Stopped
+	// instances are kept however long they stay stopped, along with their logs and passwords.
+	before := now.Add(-w.Config.DeletedGracePeriod).Unix()
+	errs = append(errs,
+		w.delete(ctx, "instances", "deleted", func() (int64, error) { return w.Database.PurgeDeletedInstances(ctx, before) }),
+		w.delete(ctx, "profiles", "deleted", func() (int64, error) { return w.Database.PurgeDeletedProfiles(ctx, before) }),
+		w.delete(ctx, "vendor_data", "deleted", func() (int64, error) { return w.Database.PurgeDeletedVendorData(ctx, before) }),
+		// Pushed SSH keys are only served until they expire, pushes stay in the audit log
+		w.delete(ctx, "ephemeral_ssh_keys", "expired", func() (int64, error) { return w.Database.DeleteExpiredEphemeralSSHKeys(ctx, now.Unix()) }),
+		w.delete(ctx, "signing_keys", "expired", func() (int64, error) { return w.Database.DeleteExpiredSigningKeys(ctx, now.Unix()) }),
+		// Foreign keys aren't enforced, so rows of purged instances are removed explicitly
+		w.delete(ctx, "instance_addresses", "orphaned", func() (int64, error) { return w.Database.DeleteOrphanedInstanceAddresses(ctx) }),
+		w.delete(ctx, "instance_state", "orphaned", func() (int64, error) { return w.Database.DeleteOrphanedInstanceStates(ctx) }),
+		w.delete(ctx, "instance_host_keys", "orphaned", func() (int64, error) { return w.Database.DeleteOrphanedInstanceHostKeys(ctx) }),
+		w.delete(ctx, "instance_passwords", "orphaned", func() (int64, error) { return w.Database.DeleteOrphanedInstancePasswords(ctx) }),
+		w.delete(ctx, "instance_profiles", "orphaned", func() (int64, error) { return w.Database.DeleteOrphanedInstanceProfiles(ctx) }),
+		w.delete(ctx, "instance_tags", "orphaned", func() (int64, error) { return w.Database.DeleteOrphanedInstanceTags(ctx) }),
+		w.delete(ctx, "secret_reads", "orphaned", func() (int64, error) { return w.Database.DeleteOrphanedSecretReads(ctx) }),
+		w.deleteBatches(ctx, "instance_logs", "orphaned", func(limit int64) (int64, error) {
+			return w.Database.DeleteOrphanedInstanceLogs(ctx, limit)
+		}),
+	)
+
+	if w.Vacuumer != nil && w.Config.Vacuum != "off" {
+		if err := w.Vacuumer.Vacuum(ctx, w.Config.Vacuum == "full", vacuumPages); err != nil {
+			errs = append(errs, fmt.Errorf("failed to vacuum the database: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type policy struct {
+	logType string
+	config.LogRetentionConfig
+}
+
+func (w *Worker) policies() []policy {
+	return []policy{
+		{"operation", w.Config.Operation},
+		{"event", w.Config.Event},
+		{"console", w.Config.Console},
+		{"audit", w.Config.Audit},
+	}
+}
+
+// deleteBatches calls remove with the batch size until it removes less than a full batch.
+func (w *Worker) deleteBatches(ctx context.Context, table string, reason string, remove func(limit int64) (int64, error)) error {
+	var total int64
+	defer func() { w.record(table, reason, total) }()
+
+	for ctx.Err() == nil {
+		removed, err := remove(w.Config.BatchSize)
+		total += removed
+		if err != nil {
+			return fmt.Errorf("failed to remove %s rows (%s): %w", table, reason, err)
+		}
+
+		if removed < w.Config.BatchSize {
+			return nil
+		}
+	}
+
+	return ctx.Err()
+}
+
+func (w *Worker) delete(ctx context.Context, table string, reason string, remove func() (int64, error)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	removed, err := remove()
+	w.record(table, reason, removed)
+	if err != nil {
+		return fmt.Errorf("failed to remove %s rows (%s): %w", table, reason, err)
+	}
+
+	return nil
+}
+
+func (w *Worker) record(table string, reason string, removed int64) {
+	if removed == 0 {
+		return
+	}
+
+	metrics.RetentionDeletedRows.WithLabelValues(table, reason).Add(float64(removed))
+	logs.Logger.Info().Str("table", table).Str("reason", reason).Int64("rows", removed).Msg("Retention removed rows")
+}
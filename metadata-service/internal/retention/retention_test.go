@@ -0,0 +1,95 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type fakeVacuumer struct {
+	calls []bool
+}
+
+func (v *fakeVacuumer) Vacuum(_ context.Context, full bool, _ int) error {
+	v.calls = append(v.calls, full)
+	return nil
+}
+
+func TestRunOnce_AppliesPolicyInBatches(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	mockDB := &mocks.MockQuerier{}
+	vacuumer := &fakeVacuumer{}
+	worker := &Worker{
+		Database: mockDB,
+		Vacuumer: vacuumer,
+		Config: &config.RetentionConfig{
+			BatchSize:          100,
+			DeletedGracePeriod: 24 * time.Hour,
+			Vacuum:             "incremental",
+			Event:              config.LogRetentionConfig{MaxAge: time.Hour, MaxRows: 50},
+		},
+		now: func() time.Time { return now },
+	}
+
+	// Full batches are followed by another batch until one comes back short
+	expired := db.DeleteExpiredInstanceLogsParams{LogType: "event", Before: now.Add(-time.Hour).Unix(), Limit: 100}
+	mockDB.On("DeleteExpiredInstanceLogs", mock.Anything, expired).Return(int64(100), nil).Twice()
+	mockDB.On("DeleteExpiredInstanceLogs", mock.Anything, expired).Return(int64(7), nil).Once()
+	mockDB.On("DeleteExcessInstanceLogs", mock.Anything, db.DeleteExcessInstanceLogsParams{LogType: "event", MaxRows: 50, Limit: 100}).Return(int64(0), nil).Once()
+
+	before := now.Add(-24 * time.Hour).Unix()
+	mockDB.On("PurgeDeletedInstances", mock.Anything, before).Return(int64(2), nil).Once()
+	mockDB.On("PurgeDeletedProfiles", mock.Anything, before).Return(int64(0), nil).Once()
+	mockDB.On("PurgeDeletedVendorData", mock.Anything, before).Return(int64(1), nil).Once()
+	mockDB.On("DeleteExpiredEphemeralSSHKeys", mock.Anything, now.Unix()).Return(int64(2), nil).Once()
+	mockDB.On("DeleteExpiredSigningKeys", mock.Anything, now.Unix()).Return(int64(1), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceAddresses", mock.Anything).Return(int64(3), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceStates", mock.Anything).Return(int64(2), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceHostKeys", mock.Anything).Return(int64(1), nil).Once()
+	mockDB.On("DeleteOrphanedInstancePasswords", mock.Anything).Return(int64(1), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceProfiles", mock.Anything).Return(int64(4), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceTags", mock.Anything).Return(int64(5), nil).Once()
+	mockDB.On("DeleteOrphanedSecretReads", mock.Anything).Return(int64(2), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceLogs", mock.Anything, int64(100)).Return(int64(40), nil).Once()
+
+	assert.NoError(t, worker.RunOnce(context.Background()))
+	assert.Equal(t, []bool{false}, vacuumer.calls)
+	mockDB.AssertExpectations(t)
+}
+
+func TestRunOnce_ContinuesAfterFailures(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	vacuumer := &fakeVacuumer{}
+	worker := &Worker{
+		Database: mockDB,
+		Vacuumer: vacuumer,
+		Config:   &config.RetentionConfig{BatchSize: 100, Vacuum: "off"},
+	}
+
+	mockDB.On("PurgeDeletedInstances", mock.Anything, mock.Anything).Return(int64(0), errors.New("database is locked")).Once()
+	mockDB.On("PurgeDeletedProfiles", mock.Anything, mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("PurgeDeletedVendorData", mock.Anything, mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteExpiredEphemeralSSHKeys", mock.Anything, mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteExpiredSigningKeys", mock.Anything, mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceAddresses", mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceStates", mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceHostKeys", mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteOrphanedInstancePasswords", mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceProfiles", mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceTags", mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteOrphanedSecretReads", mock.Anything).Return(int64(0), nil).Once()
+	mockDB.On("DeleteOrphanedInstanceLogs", mock.Anything, int64(100)).Return(int64(0), nil).Once()
+
+	err := worker.RunOnce(context.Background())
+	assert.ErrorContains(t, err, "database is locked")
+	assert.Empty(t, vacuumer.calls, "vacuum is disabled")
+	mockDB.AssertNotCalled(t, "DeleteExpiredInstanceLogs", mock.Anything, mock.Anything)
+	mockDB.AssertExpectations(t)
+}
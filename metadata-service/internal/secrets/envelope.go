@@ -0,0 +1,141 @@
+// Package secrets encrypts the secrets delivered to instances with envelope encryption: every
+// value is encrypted with its own data key, which is encrypted with the key encryption key
+// (KEK) from the configuration. The database never holds the KEK, so a copy of it doesn't
+// reveal any secret.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownKey is returned for values whose data key was wrapped with another KEK.
+var ErrUnknownKey = errors.New("secret encrypted with another key encryption key")
+
+// Sealed is an encrypted value along with its wrapped data key. WrappedKey and Ciphertext are
+// prefixed with the nonce they were encrypted with.
+type Sealed struct {
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+}
+
+// Envelope encrypts and decrypts values with a KEK.
+type Envelope struct {
+	kek cipher.AEAD
+	// keyID identifies the KEK without revealing it, so values wrapped with a replaced KEK are told apart.
+	keyID string
+	// sessionKey authenticates session tokens, see SessionToken.
+	sessionKey []byte
+}
+
+// New returns an envelope using kek, a 32 byte AES-256 key.
+func New(kek []byte) (*Envelope, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	id := sha256.Sum256(kek)
+	sessionKey, err := hkdf.Key(sha256.New, kek, nil, "metadata-service session token", 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{kek: aead, keyID: hex.EncodeToString(id[:8]), sessionKey: sessionKey}, nil
+}
+
+// KeyID identifies the KEK, it is stored with every value.
+func (e *Envelope) KeyID() string {
+	return e.keyID
+}
+
+// Seal encrypts plaintext with a new data key. The additional data isn't stored, it has to be
+// given again to Open, which binds the value to what it describes.
+func (e *Envelope) Seal(plaintext []byte, additionalData []byte) (Sealed, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return Sealed{}, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	ciphertext, err := seal(aead, plaintext, additionalData)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	wrappedKey, err := seal(e.kek, dataKey, additionalData)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	return Sealed{KeyID: e.keyID, WrappedKey: wrappedKey, Ciphertext: ciphertext}, nil
+}
+
+// Open decrypts a value sealed with the same KEK and additional data.
+func (e *Envelope) Open(sealed Sealed, additionalData []byte) ([]byte, error) {
+	if sealed.KeyID != e.keyID {
+		return nil, ErrUnknownKey
+	}
+
+	dataKey, err := open(e.kek, sealed.WrappedKey, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(aead, sealed.Ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// AdditionalData binds a secret value to the project, scope, target and name of its row, so a
+// value copied to another row of the database doesn't decrypt.
+func AdditionalData(project, scope, target, name string) []byte {
+	return []byte(strings.Join([]string{"secret", project, scope, target, name}, "\x00"))
+}
@@ -0,0 +1,66 @@
+package secrets
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEnvelope(t *testing.T, seed byte) *Envelope {
+	envelope, err := New(bytes.Repeat([]byte{seed}, 32))
+	require.NoError(t, err)
+
+	return envelope
+}
+
+func TestSealAndOpen(t *testing.T) {
+	envelope := newTestEnvelope(t, 1)
+	additionalData := AdditionalData("default", "instance", "c1", "db_password")
+
+	sealed, err := envelope.Seal([]byte("hunter2"), additionalData)
+	require.NoError(t, err)
+	assert.Equal(t, envelope.KeyID(), sealed.KeyID)
+	assert.NotContains(t, string(sealed.Ciphertext), "hunter2")
+
+	value, err := envelope.Open(sealed, additionalData)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(value))
+
+	_, err = envelope.Open(sealed, AdditionalData("default", "instance", "c2", "db_password"))
+	assert.Error(t, err, "a value copied to another row doesn't decrypt")
+
+	_, err = newTestEnvelope(t, 2).Open(sealed, additionalData)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	other, err := envelope.Seal([]byte("hunter2"), additionalData)
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed.WrappedKey, other.WrappedKey, "every value has its own data key")
+}
+
+func TestSessionToken(t *testing.T) {
+	envelope := newTestEnvelope(t, 1)
+	uuid := "5c1f2f4e-8a8e-4c57-9d3e-2a1b0c9d8e7f"
+	instance := db.Instance{ID: 42, Remote: "local", Project: "default", Name: "c1", Uuid: &uuid}
+	now := time.Now()
+
+	token := envelope.SessionToken(instance, now.Add(time.Minute))
+	assert.True(t, envelope.VerifySessionToken(token, instance, now))
+	assert.False(t, envelope.VerifySessionToken(token, instance, now.Add(time.Minute)), "expired")
+
+	other := instance
+	other.Name = "c2"
+	assert.False(t, envelope.VerifySessionToken(token, other, now), "issued to another instance")
+
+	recreated := instance
+	recreatedUUID := "0b6a3f1e-2c4d-4e5f-8a9b-0c1d2e3f4a5b"
+	recreated.Uuid = &recreatedUUID
+	assert.False(t, envelope.VerifySessionToken(token, recreated, now), "issued before the instance was recreated")
+
+	assert.False(t, newTestEnvelope(t, 2).VerifySessionToken(token, instance, now))
+	assert.False(t, envelope.VerifySessionToken("", instance, now))
+	assert.False(t, envelope.VerifySessionToken("9999999999."+token[len("9999999999."):], instance, now), "expiry can't be extended")
+}
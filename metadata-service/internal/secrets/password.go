@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"math/big"
+)
+
+// passwordAlphabet leaves out characters that are easily confused when typed on a console.
+const passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789-_.+!%"
+
+// GeneratePassword returns a random password of length characters.
+func GeneratePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	password := make([]byte, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+
+	return string(password), nil
+}
+
+// PasswordKey encrypts generated passwords to the public key of the operators, in the manner
+// of the password data of EC2 Windows instances. Only the private key decrypts them.
+type PasswordKey struct {
+	key *rsa.PublicKey
+	// fingerprint identifies the key, so operators know which private key decrypts a password.
+	fingerprint string
+}
+
+// NewPasswordKey returns a password key encrypting to key.
+func NewPasswordKey(key *rsa.PublicKey) (*PasswordKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(der)
+	return &PasswordKey{key: key, fingerprint: hex.EncodeToString(sum[:])}, nil
+}
+
+// Fingerprint is the SHA256 of the DER encoded public key, as printed by
+// `openssl pkey -pubin -outform DER | sha256sum`.
+func (k *PasswordKey) Fingerprint() string {
+	return k.fingerprint
+}
+
+// Encrypt encrypts password with RSA-OAEP and SHA-256.
+func (k *PasswordKey) Encrypt(password string) ([]byte, error) {
+	return rsa.EncryptOAEP(sha256.New(), rand.Reader, k.key, []byte(password), nil)
+}
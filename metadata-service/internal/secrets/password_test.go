@@ -0,0 +1,43 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	password, err := GeneratePassword(20)
+	require.NoError(t, err)
+	assert.Len(t, password, 20)
+
+	for _, r := range password {
+		assert.True(t, strings.ContainsRune(passwordAlphabet, r), "unexpected character %q", r)
+	}
+
+	other, err := GeneratePassword(20)
+	require.NoError(t, err)
+	assert.NotEqual(t, password, other)
+}
+
+func TestPasswordKey_Encrypt(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	key, err := NewPasswordKey(&private.PublicKey)
+	require.NoError(t, err)
+	assert.Len(t, key.Fingerprint(), 64)
+
+	encrypted, err := key.Encrypt("hunter2")
+	require.NoError(t, err)
+	assert.NotContains(t, string(encrypted), "hunter2")
+
+	password, err := rsa.DecryptOAEP(sha256.New(), nil, private, encrypted, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(password))
+}
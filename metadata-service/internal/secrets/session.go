@@ -0,0 +1,56 @@
+package secrets
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+)
+
+// MaxSessionTTL is the longest a session token can be valid, as with IMDSv2.
+const MaxSessionTTL = 6 * time.Hour
+
+// SessionToken returns a token proving a PUT request was made by instance, which stays valid
+// until expiresAt. Tokens aren't stored: they carry their expiry and a MAC binding it to the
+// instance, so every replica sharing the KEK accepts them.
+func (e *Envelope) SessionToken(instance db.Instance, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + base64.RawURLEncoding.EncodeToString(e.sessionMAC(instance, expiry))
+}
+
+// VerifySessionToken reports whether token was issued to instance and is still valid at now.
+func (e *Envelope) VerifySessionToken(token string, instance db.Instance, now time.Time) bool {
+	expiry, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return false
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(mac, e.sessionMAC(instance, expiry))
+}
+
+// sessionMAC authenticates an expiry for an instance. The UUID invalidates the tokens of an
+// instance recreated under the same name.
+func (e *Envelope) sessionMAC(instance db.Instance, expiry string) []byte {
+	uuid := ""
+	if instance.Uuid != nil {
+		uuid = *instance.Uuid
+	}
+
+	mac := hmac.New(sha256.New, e.sessionKey)
+	mac.Write([]byte(strings.Join([]string{instance.Remote, instance.Project, instance.Name, uuid, expiry}, "\x00")))
+	return mac.Sum(nil)
+}
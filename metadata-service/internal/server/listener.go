@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+)
+
+// ShutdownTimeout is how long in-flight requests get to finish when the service stops.
+const ShutdownTimeout = 10 * time.Second
+
+// Listener is an HTTP server bound to one or more addresses, with its own
+// handler chain, timeouts and TLS settings.
+type Listener struct {
+	// Name identifies the listener in logs, e.g. "guest" or "admin".
+	Name string
+	// Addresses are the TCP addresses the listener binds to.
+	Addresses []string
+
+	server    *http.Server
+	tlsConfig *tls.Config
+	listeners []net.Listener
+}
+
+// New creates a listener serving handler on the given addresses. When tlsConfig is
+// nil the listener serves plain HTTP.
+func New(name string, addresses []string, handler http.Handler, timeouts config.TimeoutConfig, tlsConfig *tls.Config) *Listener {
+	return &Listener{
+		Name:      name,
+		Addresses: addresses,
+		tlsConfig: tlsConfig,
+		server: &http.Server{
+			Handler:           handler,
+			TLSConfig:         tlsConfig,
+			ReadHeaderTimeout: timeouts.ReadHeaderTimeout,
+			ReadTimeout:       timeouts.ReadTimeout,
+			WriteTimeout:      timeouts.WriteTimeout,
+			IdleTimeout:       timeouts.IdleTimeout,
+			ConnContext:       withPeerConn,
+		},
+	}
+}
+
+// Listen binds every configured address. It is separate from Serve so that an
+// address conflict fails startup before any listener starts accepting requests.
+// When an address fails to bind, the addresses bound before it are released.
+func (l *Listener) Listen() error {
+	bound := make([]net.Listener, 0, len(l.Addresses))
+	for _, address := range l.Addresses {
+		ln, err := net.Listen("tcp", address)
+		if err != nil {
+			for _, ln := range bound {
+				ln.Close()
+			}
+			return fmt.Errorf("%s listener failed to bind %s: %w", l.Name, address, err)
+		}
+
+		bound = append(bound, ln)
+	}
+
+	for _, ln := range bound {
+		l.Attach(ln)
+	}
+
+	return nil
+}
+
+// Attach adds an already bound listener, such as a vsock or unix socket listener.
+func (l *Listener) Attach(ln net.Listener) {
+	if l.tlsConfig != nil {
+		ln = tls.NewListener(ln, l.tlsConfig)
+	}
+
+	l.listeners = append(l.listeners, ln)
+}
+
+// Serve accepts connections on every bound listener and blocks until one of them
+// fails or the listener is shut down.
+func (l *Listener) Serve() error {
+	if len(l.listeners) == 0 {
+		return fmt.Errorf("%s listener has no bound addresses", l.Name)
+	}
+
+	errs := make(chan error, len(l.listeners))
+	for _, ln := range l.listeners {
+		logs.Logger.Info().Str("listener", l.Name).Str("address", ln.Addr().String()).Bool("tls", l.tlsConfig != nil).Msg("Listener started")
+
+		go func(ln net.Listener) {
+			errs <- l.server.Serve(ln)
+		}(ln)
+	}
+
+	for range l.listeners {
+		if err := <-errs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("%s listener stopped: %w", l.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown gracefully stops the listener, waiting for in-flight requests.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	return l.server.Shutdown(ctx)
+}
+
+// close releases the bound listeners of a listener that never started serving.
+func (l *Listener) close() {
+	for _, ln := range l.listeners {
+		ln.Close()
+	}
+	l.listeners = nil
+}
+
+// Run binds and serves all listeners until ctx is cancelled or one of them fails,
+// then shuts every listener down.
+func Run(ctx context.Context, listeners ...*Listener) error {
+	if err := checkDistinct(listeners); err != nil {
+		return err
+	}
+
+	for i, l := range listeners {
+		if err := l.Listen(); err != nil {
+			// Nothing serves yet, release what the other listeners bound so a retry can bind it again
+			for _, l := range listeners[:i] {
+				l.close()
+			}
+			return err
+		}
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, l := range listeners {
+		go func(l *Listener) {
+			errs <- l.Serve()
+		}(l)
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logs.Logger.Info().Msg("Shutting down listeners")
+	case runErr = <-errs:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, l := range listeners {
+		wg.Add(1)
+		go func(l *Listener) {
+			defer wg.Done()
+			if err := l.Shutdown(shutdownCtx); err != nil {
+				logs.Logger.Error().Err(err).Str("listener", l.Name).Msg("Failed to shut down listener")
+			}
+		}(l)
+	}
+	wg.Wait()
+
+	return runErr
+}
+
+// checkDistinct makes sure no two listeners share an address, so guest traffic
+// can never end up on the admin or health handler chains. Addresses are compared
+// by what they bind, e.g. :80 and 0.0.0.0:80 are the same address and overlap
+// 10.0.0.1:80.
+func checkDistinct(listeners []*Listener) error {
+	type owned struct {
+		address string
+		owner   string
+	}
+
+	var seen []owned
+	for _, l := range listeners {
+		for _, address := range l.Addresses {
+			for _, other := range seen {
+				if other.owner != l.Name && overlaps(other.address, address) {
+					if other.address == address {
+						return fmt.Errorf("address %s is configured for both the %s and %s listeners", address, other.owner, l.Name)
+					}
+					return fmt.Errorf("address %s of the %s listener overlaps %s of the %s listener", address, l.Name, other.address, other.owner)
+				}
+			}
+			seen = append(seen, owned{address: address, owner: l.Name})
+		}
+	}
+
+	return nil
+}
+
+// overlaps reports whether two TCP addresses bind the same port on a common interface.
+// Port 0 picks a free port on every bind, so it never overlaps.
+func overlaps(a string, b string) bool {
+	hostA, portA := normalizeAddress(a)
+	hostB, portB := normalizeAddress(b)
+	if portA != portB || portA == "0" {
+		return false
+	}
+
+	return hostA == hostB || hostA == "" || hostB == ""
+}
+
+// normalizeAddress returns the host and port of an address in a comparable form, the
+// host being empty for addresses that bind every interface.
+func normalizeAddress(address string) (string, string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address, ""
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+		if ip.IsUnspecified() {
+			host = ""
+		}
+	}
+
+	if number, err := net.LookupPort("tcp", port); err == nil {
+		port = strconv.Itoa(number)
+	}
+
+	return host, port
+}
+
+// ListenUnix binds a unix socket at path, replacing a stale socket left behind by a
+// previous run. The socket is world writable because callers are identified by their
+// peer credentials rather than by file permissions.
+func ListenUnix(path string) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind unix socket %s: %w", path, err)
+	}
+
+	if err := os.Chmod(path, 0o666); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set permissions on unix socket %s: %w", path, err)
+	}
+
+	return ln, nil
+}
+
+// LoadTLSConfig loads a key pair for a listener. It returns nil when neither
+// file is configured, meaning the listener serves plain HTTP.
+func LoadTLSConfig(certFile string, keyFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{keyPair},
+	}, nil
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_RejectsSharedAddresses(t *testing.T) {
+	handler := http.NewServeMux()
+
+	err := Run(context.Background(),
+		New("guest", []string{"127.0.0.1:8080"}, handler, config.TimeoutConfig{}, nil),
+		New("admin", []string{"127.0.0.1:8080"}, handler, config.TimeoutConfig{}, nil),
+	)
+
+	assert.ErrorContains(t, err, "configured for both the guest and admin listeners")
+}
+
+func TestCheckDistinct_ComparesBoundAddresses(t *testing.T) {
+	handler := http.NewServeMux()
+	listeners := func(guest string, admin string) []*Listener {
+		return []*Listener{
+			New("guest", []string{guest}, handler, config.TimeoutConfig{}, nil),
+			New("admin", []string{admin}, handler, config.TimeoutConfig{}, nil),
+		}
+	}
+
+	assert.ErrorContains(t, checkDistinct(listeners(":80", "0.0.0.0:80")), "address 0.0.0.0:80 of the admin listener overlaps :80 of the guest listener")
+	assert.Error(t, checkDistinct(listeners("[::]:8080", "10.0.0.1:8080")))
+	assert.Error(t, checkDistinct(listeners("[fd00:ec2:0::254]:80", "[fd00:ec2::254]:http")))
+	assert.NoError(t, checkDistinct(listeners("10.0.0.1:80", "10.0.0.2:80")))
+	assert.NoError(t, checkDistinct(listeners(":80", ":8443")))
+	assert.NoError(t, checkDistinct(listeners("127.0.0.1:0", "127.0.0.1:0")))
+}
+
+// freeAddress returns a loopback address nothing listens on.
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	return address
+}
+
+func TestRun_ReleasesBoundAddressesOnFailure(t *testing.T) {
+	handler := http.NewServeMux()
+
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer taken.Close()
+
+	guestAddress, adminAddress := freeAddress(t), freeAddress(t)
+	err = Run(context.Background(),
+		New("guest", []string{guestAddress}, handler, config.TimeoutConfig{}, nil),
+		New("admin", []string{adminAddress, taken.Addr().String()}, handler, config.TimeoutConfig{}, nil),
+	)
+	require.ErrorContains(t, err, "admin listener failed to bind")
+
+	// Both the addresses of the failed listener and those of the listeners before it are free again
+	for _, address := range []string{guestAddress, adminAddress} {
+		ln, err := net.Listen("tcp", address)
+		require.NoError(t, err, address)
+		ln.Close()
+	}
+}
+
+func TestListener_ServesEveryAddressWithItsOwnHandler(t *testing.T) {
+	guestHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "guest") })
+	adminHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "admin") })
+
+	guest := New("guest", []string{"127.0.0.1:0", "127.0.0.1:0"}, guestHandler, config.TimeoutConfig{ReadTimeout: time.Second}, nil)
+	admin := New("admin", []string{"127.0.0.1:0"}, adminHandler, config.TimeoutConfig{}, nil)
+	require.NoError(t, guest.Listen())
+	require.NoError(t, admin.Listen())
+
+	go guest.Serve()
+	go admin.Serve()
+	defer guest.Shutdown(context.Background())
+	defer admin.Shutdown(context.Background())
+
+	get := func(l *Listener, index int) string {
+		resp, err := http.Get("http://" + l.listeners[index].Addr().String() + "/internal/certificates")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body)
+	}
+
+	assert.Equal(t, "guest", get(guest, 0))
+	assert.Equal(t, "guest", get(guest, 1))
+	assert.Equal(t, "admin", get(admin, 0))
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+)
+
+type peerConnKey struct{}
+
+// withPeerConn stores the accepted connection in the request context, so that
+// handlers can identify callers by more than their address, e.g. the vsock CID.
+func withPeerConn(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, peerConnKey{}, conn)
+}
+
+// PeerConn returns the underlying connection a request arrived on, unwrapping TLS.
+func PeerConn(ctx context.Context) net.Conn {
+	conn, _ := ctx.Value(peerConnKey{}).(net.Conn)
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		return tlsConn.NetConn()
+	}
+
+	return conn
+}
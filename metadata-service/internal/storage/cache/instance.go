@@ -0,0 +1,121 @@
+// Package cache stores the instances found in Incus in the database, shared by the
+// resolvers caching the instances they look up and the event listener keeping them in sync.
+package cache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/incus"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/lxc/incus/shared/api"
+)
+
+// StoreInstance caches an instance found in an Incus remote, with its profiles, tags and
+// addresses, so later requests are answered from the database. Resolvers and the event
+// listener store instances concurrently, so everything is written in one transaction and
+// readers never see an instance with part of its profiles, tags or addresses.
+func StoreInstance(ctx context.Context, database db.Store, remote string, instance api.InstanceFull) (db.Instance, error) {
+	var cached db.Instance
+	err := database.InTx(ctx, func(tx db.Store) error {
+		var err error
+		cached, err = storeInstance(ctx, tx, remote, instance)
+		return err
+	})
+	if err != nil {
+		return db.Instance{}, err
+	}
+
+	return cached, nil
+}
+
+func storeInstance(ctx context.Context, database db.Querier, remote string, instance api.InstanceFull) (db.Instance, error) {
+	params := db.UpsertInstanceParams{
+		Remote:  remote,
+		Name:    instance.Name,
+		Project: instance.Project,
+		// Without a state Incus says nothing about the addresses, the last known one is kept
+		AddressesKnown: instance.State != nil,
+	}
+
+	if addresses := incus.InstanceAddresses(instance); len(addresses) > 0 {
+		params.IpAddress = &addresses[0]
+	}
+
+	if id, ok := incus.VsockID(instance); ok {
+		vsockID := int64(id)
+		params.VsockID = &vsockID
+	}
+
+	if uuid := instance.Config["volatile.uuid"]; uuid != "" {
+		params.Uuid = &uuid
+	}
+
+	cached, err := database.UpsertInstance(ctx, params)
+	if err != nil {
+		return db.Instance{}, fmt.Errorf("failed to cache instance: %w", err)
+	}
+
+	// A new UUID means the instance was rebuilt or recreated under the same name,
+	// the host keys published before belong to another machine
+	if params.Uuid != nil {
+		revoked, err := database.RevokeStaleInstanceHostKeys(ctx, db.RevokeStaleInstanceHostKeysParams{InstanceID: cached.ID, InstanceUuid: *params.Uuid})
+		if err != nil {
+			return db.Instance{}, fmt.Errorf("failed to revoke stale host keys: %w", err)
+		}
+
+		if revoked > 0 {
+			logs.Logger.Info().Ctx(ctx).Str("remote", remote).Str("instance", instance.Name).Str("project", instance.Project).Int64("keys", revoked).Msg("Revoked host keys of rebuilt instance")
+		}
+	}
+
+	// Incus always lists the profiles of an instance, even when there are none
+	if instance.Profiles != nil {
+		if err := database.DeleteInstanceProfiles(ctx, cached.ID); err != nil {
+			return db.Instance{}, fmt.Errorf("failed to cache instance profiles: %w", err)
+		}
+
+		for _, profile := range instance.Profiles {
+			if err := database.CreateInstanceProfile(ctx, db.CreateInstanceProfileParams{InstanceID: cached.ID, Profile: profile}); err != nil {
+				return db.Instance{}, fmt.Errorf("failed to cache instance profiles: %w", err)
+			}
+		}
+	}
+
+	// Incus always returns the expanded configuration of an instance, even when it is empty
+	if instance.ExpandedConfig != nil {
+		if err := database.DeleteInstanceTags(ctx, cached.ID); err != nil {
+			return db.Instance{}, fmt.Errorf("failed to cache instance tags: %w", err)
+		}
+
+		for key, value := range incus.InstanceTags(instance) {
+			if err := database.CreateInstanceTag(ctx, db.CreateInstanceTagParams{InstanceID: cached.ID, Key: key, Value: value}); err != nil {
+				return db.Instance{}, fmt.Errorf("failed to cache instance tags: %w", err)
+			}
+		}
+	}
+
+	// Addresses are only known while the instance runs, keep the last known ones otherwise
+	if instance.State == nil {
+		return cached, nil
+	}
+
+	if err := database.DeleteInstanceAddresses(ctx, cached.ID); err != nil {
+		return db.Instance{}, fmt.Errorf("failed to cache instance addresses: %w", err)
+	}
+
+	for _, address := range incus.NetworkAddresses(instance) {
+		err := database.CreateInstanceAddress(ctx, db.CreateInstanceAddressParams{
+			InstanceID: cached.ID,
+			Network:    address.Network,
+			IpAddress:  address.IP,
+			MacAddress: address.MAC,
+		})
+		if err != nil {
+			return db.Instance{}, fmt.Errorf("failed to cache instance addresses: %w", err)
+		}
+	}
+
+	return cached, nil
+}
@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/lxc/incus/shared/api"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreInstance_SyncsTags(t *testing.T) {
+	mockDB := &mocks.MockQuerier{}
+	instance := api.InstanceFull{Instance: api.Instance{Name: "c1", Project: "web", ExpandedConfig: map[string]string{
+		"user.team":      "payments",
+		"user.env":       "staging",
+		"user.user-data": "#cloud-config",
+		"limits.cpu":     "2",
+	}}}
+
+	mockDB.On("UpsertInstance", mock.Anything, db.UpsertInstanceParams{Remote: "local", Name: "c1", Project: "web"}).Return(db.Instance{ID: 3, Name: "c1", Project: "web"}, nil)
+	mockDB.On("DeleteInstanceTags", mock.Anything, int64(3)).Return(nil).Once()
+	mockDB.On("CreateInstanceTag", mock.Anything, db.CreateInstanceTagParams{InstanceID: 3, Key: "team", Value: "payments"}).Return(nil).Once()
+	mockDB.On("CreateInstanceTag", mock.Anything, db.CreateInstanceTagParams{InstanceID: 3, Key: "env", Value: "staging"}).Return(nil).Once()
+
+	_, err := StoreInstance(context.Background(), mockDB, "local", instance)
+	require.NoError(t, err)
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNumberOfCalls(t, "CreateInstanceTag", 2)
+}
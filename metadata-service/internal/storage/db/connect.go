@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"fmt"
 
 	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
 	_ "modernc.org/sqlite"
@@ -19,6 +20,17 @@ func ConnectDB(cfg *config.Config) (*Queries, error) {
 		return nil, err
 	}
 
+	// Let the retention worker return free pages to the file system without a full VACUUM.
+	// This only applies to new databases, existing ones are converted by their first vacuum.
+	if _, err := db.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return nil, err
+	}
+
+	// Existing tables are brought up to date first, as the schema only creates what is missing
+	if err := migrate(ctx, db, cfg); err != nil {
+		return nil, err
+	}
+
 	if _, err := db.ExecContext(ctx, ddl); err != nil {
 		return nil, err
 	}
@@ -33,3 +45,96 @@ func ConnectDB(cfg *config.Config) (*Queries, error) {
 
 	return queries, nil
 }
+
+// Store is a Querier that can also run a group of queries in a single transaction.
+type Store interface {
+	Querier
+	// InTx runs fn with a Store bound to one transaction, which is committed when fn
+	// returns nil and rolled back otherwise. Nested calls join the outer transaction.
+	InTx(ctx context.Context, fn func(Store) error) error
+}
+
+var _ Store = (*Queries)(nil)
+
+// InTx implements Store using WithTx.
+func (q *Queries) InTx(ctx context.Context, fn func(Store) error) error {
+	database, ok := q.db.(*sql.DB)
+	if q.tx != nil || !ok {
+		return fn(q)
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Ping checks that the database is still reachable.
+func (q *Queries) Ping(ctx context.Context) error {
+	pinger, ok := q.db.(interface{ PingContext(context.Context) error })
+	if !ok {
+		return nil
+	}
+
+	return pinger.PingContext(ctx)
+}
+
+// autoVacuumIncremental is the value of PRAGMA auto_vacuum for incremental vacuum mode.
+const autoVacuumIncremental = 2
+
+// Vacuum returns the free pages of the database to the file system. A full vacuum rebuilds the
+// database, blocking writers while it runs. Otherwise at most pages free pages are released
+// incrementally, converting the database to incremental auto-vacuum with a one-time full vacuum
+// first if needed.
+func (q *Queries) Vacuum(ctx context.Context, full bool, pages int) error {
+	database, ok := q.db.(*sql.DB)
+	if !ok {
+		return nil
+	}
+
+	// auto_vacuum only applies to the connection it is set on
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if full {
+		_, err := conn.ExecContext(ctx, "VACUUM")
+		return err
+	}
+
+	var mode int
+	if err := conn.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return err
+	}
+
+	if mode != autoVacuumIncremental {
+		if _, err := conn.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+			return err
+		}
+
+		if _, err := conn.ExecContext(ctx, "VACUUM"); err != nil {
+			return err
+		}
+	}
+
+	// Each step of the pragma releases a page, so read it to completion
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("PRAGMA incremental_vacuum(%d)", pages))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+	}
+
+	return rows.Err()
+}
@@ -24,48 +24,162 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.claimCertificateTokenStmt, err = db.PrepareContext(ctx, claimCertificateToken); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimCertificateToken: %w", err)
+	}
+	if q.claimInstancePasswordStmt, err = db.PrepareContext(ctx, claimInstancePassword); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimInstancePassword: %w", err)
+	}
+	if q.claimSecretReadStmt, err = db.PrepareContext(ctx, claimSecretRead); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimSecretRead: %w", err)
+	}
+	if q.createCertificateStmt, err = db.PrepareContext(ctx, createCertificate); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCertificate: %w", err)
+	}
+	if q.createCertificateTokenStmt, err = db.PrepareContext(ctx, createCertificateToken); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCertificateToken: %w", err)
+	}
+	if q.createEphemeralSSHKeyStmt, err = db.PrepareContext(ctx, createEphemeralSSHKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateEphemeralSSHKey: %w", err)
+	}
 	if q.createInstanceStmt, err = db.PrepareContext(ctx, createInstance); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateInstance: %w", err)
 	}
+	if q.createInstanceAddressStmt, err = db.PrepareContext(ctx, createInstanceAddress); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateInstanceAddress: %w", err)
+	}
+	if q.createInstanceHostKeyStmt, err = db.PrepareContext(ctx, createInstanceHostKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateInstanceHostKey: %w", err)
+	}
 	if q.createInstanceLogStmt, err = db.PrepareContext(ctx, createInstanceLog); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateInstanceLog: %w", err)
 	}
+	if q.createInstancePasswordStmt, err = db.PrepareContext(ctx, createInstancePassword); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateInstancePassword: %w", err)
+	}
+	if q.createInstanceProfileStmt, err = db.PrepareContext(ctx, createInstanceProfile); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateInstanceProfile: %w", err)
+	}
+	if q.createInstanceTagStmt, err = db.PrepareContext(ctx, createInstanceTag); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateInstanceTag: %w", err)
+	}
 	if q.createOrUpdateInstanceStateStmt, err = db.PrepareContext(ctx, createOrUpdateInstanceState); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateOrUpdateInstanceState: %w", err)
 	}
 	if q.createProfileStmt, err = db.PrepareContext(ctx, createProfile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateProfile: %w", err)
 	}
+	if q.createSSHKeyStmt, err = db.PrepareContext(ctx, createSSHKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSSHKey: %w", err)
+	}
+	if q.createSecretStmt, err = db.PrepareContext(ctx, createSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSecret: %w", err)
+	}
+	if q.createSigningKeyStmt, err = db.PrepareContext(ctx, createSigningKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSigningKey: %w", err)
+	}
 	if q.createVendorDataStmt, err = db.PrepareContext(ctx, createVendorData); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateVendorData: %w", err)
 	}
+	if q.deleteCertificateStmt, err = db.PrepareContext(ctx, deleteCertificate); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCertificate: %w", err)
+	}
+	if q.deleteExcessInstanceLogsStmt, err = db.PrepareContext(ctx, deleteExcessInstanceLogs); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExcessInstanceLogs: %w", err)
+	}
+	if q.deleteExpiredCertificateTokensStmt, err = db.PrepareContext(ctx, deleteExpiredCertificateTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredCertificateTokens: %w", err)
+	}
+	if q.deleteExpiredEphemeralSSHKeysStmt, err = db.PrepareContext(ctx, deleteExpiredEphemeralSSHKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredEphemeralSSHKeys: %w", err)
+	}
+	if q.deleteExpiredInstanceLogsStmt, err = db.PrepareContext(ctx, deleteExpiredInstanceLogs); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredInstanceLogs: %w", err)
+	}
+	if q.deleteExpiredSigningKeysStmt, err = db.PrepareContext(ctx, deleteExpiredSigningKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredSigningKeys: %w", err)
+	}
 	if q.deleteInstanceStmt, err = db.PrepareContext(ctx, deleteInstance); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteInstance: %w", err)
 	}
+	if q.deleteInstanceAddressesStmt, err = db.PrepareContext(ctx, deleteInstanceAddresses); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteInstanceAddresses: %w", err)
+	}
 	if q.deleteInstanceLogsStmt, err = db.PrepareContext(ctx, deleteInstanceLogs); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteInstanceLogs: %w", err)
 	}
+	if q.deleteInstancePasswordStmt, err = db.PrepareContext(ctx, deleteInstancePassword); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteInstancePassword: %w", err)
+	}
+	if q.deleteInstanceProfilesStmt, err = db.PrepareContext(ctx, deleteInstanceProfiles); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteInstanceProfiles: %w", err)
+	}
 	if q.deleteInstanceStateStmt, err = db.PrepareContext(ctx, deleteInstanceState); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteInstanceState: %w", err)
 	}
+	if q.deleteInstanceTagsStmt, err = db.PrepareContext(ctx, deleteInstanceTags); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteInstanceTags: %w", err)
+	}
 	if q.deleteOldInstanceLogsStmt, err = db.PrepareContext(ctx, deleteOldInstanceLogs); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteOldInstanceLogs: %w", err)
 	}
+	if q.deleteOrphanedInstanceAddressesStmt, err = db.PrepareContext(ctx, deleteOrphanedInstanceAddresses); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedInstanceAddresses: %w", err)
+	}
+	if q.deleteOrphanedInstanceHostKeysStmt, err = db.PrepareContext(ctx, deleteOrphanedInstanceHostKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedInstanceHostKeys: %w", err)
+	}
+	if q.deleteOrphanedInstanceLogsStmt, err = db.PrepareContext(ctx, deleteOrphanedInstanceLogs); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedInstanceLogs: %w", err)
+	}
+	if q.deleteOrphanedInstancePasswordsStmt, err = db.PrepareContext(ctx, deleteOrphanedInstancePasswords); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedInstancePasswords: %w", err)
+	}
+	if q.deleteOrphanedInstanceProfilesStmt, err = db.PrepareContext(ctx, deleteOrphanedInstanceProfiles); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedInstanceProfiles: %w", err)
+	}
+	if q.deleteOrphanedInstanceStatesStmt, err = db.PrepareContext(ctx, deleteOrphanedInstanceStates); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedInstanceStates: %w", err)
+	}
+	if q.deleteOrphanedInstanceTagsStmt, err = db.PrepareContext(ctx, deleteOrphanedInstanceTags); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedInstanceTags: %w", err)
+	}
+	if q.deleteOrphanedSecretReadsStmt, err = db.PrepareContext(ctx, deleteOrphanedSecretReads); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteOrphanedSecretReads: %w", err)
+	}
 	if q.deleteProfileStmt, err = db.PrepareContext(ctx, deleteProfile); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteProfile: %w", err)
 	}
+	if q.deleteSSHKeyStmt, err = db.PrepareContext(ctx, deleteSSHKey); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSSHKey: %w", err)
+	}
+	if q.deleteSecretStmt, err = db.PrepareContext(ctx, deleteSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSecret: %w", err)
+	}
+	if q.deleteSecretReadsStmt, err = db.PrepareContext(ctx, deleteSecretReads); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteSecretReads: %w", err)
+	}
 	if q.deleteVendorDataStmt, err = db.PrepareContext(ctx, deleteVendorData); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteVendorData: %w", err)
 	}
+	if q.getCertificateStmt, err = db.PrepareContext(ctx, getCertificate); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCertificate: %w", err)
+	}
 	if q.getInstanceStmt, err = db.PrepareContext(ctx, getInstance); err != nil {
 		return nil, fmt.Errorf("error preparing query GetInstance: %w", err)
 	}
+	if q.getInstanceByAddressStmt, err = db.PrepareContext(ctx, getInstanceByAddress); err != nil {
+		return nil, fmt.Errorf("error preparing query GetInstanceByAddress: %w", err)
+	}
 	if q.getInstanceByIDStmt, err = db.PrepareContext(ctx, getInstanceByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetInstanceByID: %w", err)
 	}
 	if q.getInstanceByIPStmt, err = db.PrepareContext(ctx, getInstanceByIP); err != nil {
 		return nil, fmt.Errorf("error preparing query GetInstanceByIP: %w", err)
 	}
+	if q.getInstanceByVsockIDStmt, err = db.PrepareContext(ctx, getInstanceByVsockID); err != nil {
+		return nil, fmt.Errorf("error preparing query GetInstanceByVsockID: %w", err)
+	}
 	if q.getInstanceLogsStmt, err = db.PrepareContext(ctx, getInstanceLogs); err != nil {
 		return nil, fmt.Errorf("error preparing query GetInstanceLogs: %w", err)
 	}
@@ -75,30 +189,123 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getInstanceLogsByTypeStmt, err = db.PrepareContext(ctx, getInstanceLogsByType); err != nil {
 		return nil, fmt.Errorf("error preparing query GetInstanceLogsByType: %w", err)
 	}
+	if q.getInstancePasswordStmt, err = db.PrepareContext(ctx, getInstancePassword); err != nil {
+		return nil, fmt.Errorf("error preparing query GetInstancePassword: %w", err)
+	}
 	if q.getInstanceStateStmt, err = db.PrepareContext(ctx, getInstanceState); err != nil {
 		return nil, fmt.Errorf("error preparing query GetInstanceState: %w", err)
 	}
 	if q.getProfileStmt, err = db.PrepareContext(ctx, getProfile); err != nil {
 		return nil, fmt.Errorf("error preparing query GetProfile: %w", err)
 	}
+	if q.getSSHKeyStmt, err = db.PrepareContext(ctx, getSSHKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSSHKey: %w", err)
+	}
+	if q.getSecretStmt, err = db.PrepareContext(ctx, getSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSecret: %w", err)
+	}
 	if q.getVendorDataStmt, err = db.PrepareContext(ctx, getVendorData); err != nil {
 		return nil, fmt.Errorf("error preparing query GetVendorData: %w", err)
 	}
 	if q.hardDeleteInstanceStmt, err = db.PrepareContext(ctx, hardDeleteInstance); err != nil {
 		return nil, fmt.Errorf("error preparing query HardDeleteInstance: %w", err)
 	}
+	if q.invalidateInstanceStmt, err = db.PrepareContext(ctx, invalidateInstance); err != nil {
+		return nil, fmt.Errorf("error preparing query InvalidateInstance: %w", err)
+	}
+	if q.listActiveEphemeralSSHKeysStmt, err = db.PrepareContext(ctx, listActiveEphemeralSSHKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query ListActiveEphemeralSSHKeys: %w", err)
+	}
+	if q.listCertificateTokensStmt, err = db.PrepareContext(ctx, listCertificateTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCertificateTokens: %w", err)
+	}
+	if q.listCertificatesStmt, err = db.PrepareContext(ctx, listCertificates); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCertificates: %w", err)
+	}
+	if q.listInstanceAddressesStmt, err = db.PrepareContext(ctx, listInstanceAddresses); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceAddresses: %w", err)
+	}
+	if q.listInstanceHostKeysStmt, err = db.PrepareContext(ctx, listInstanceHostKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceHostKeys: %w", err)
+	}
+	if q.listInstanceLogsStmt, err = db.PrepareContext(ctx, listInstanceLogs); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceLogs: %w", err)
+	}
+	if q.listInstanceLogsAfterStmt, err = db.PrepareContext(ctx, listInstanceLogsAfter); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceLogsAfter: %w", err)
+	}
+	if q.listInstanceProfilesStmt, err = db.PrepareContext(ctx, listInstanceProfiles); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceProfiles: %w", err)
+	}
+	if q.listInstanceSSHKeysStmt, err = db.PrepareContext(ctx, listInstanceSSHKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceSSHKeys: %w", err)
+	}
+	if q.listInstanceSecretsStmt, err = db.PrepareContext(ctx, listInstanceSecrets); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceSecrets: %w", err)
+	}
+	if q.listInstanceStatesStmt, err = db.PrepareContext(ctx, listInstanceStates); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceStates: %w", err)
+	}
+	if q.listInstanceTagsStmt, err = db.PrepareContext(ctx, listInstanceTags); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstanceTags: %w", err)
+	}
 	if q.listInstancesStmt, err = db.PrepareContext(ctx, listInstances); err != nil {
 		return nil, fmt.Errorf("error preparing query ListInstances: %w", err)
 	}
+	if q.listInstancesByAddressIPStmt, err = db.PrepareContext(ctx, listInstancesByAddressIP); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstancesByAddressIP: %w", err)
+	}
 	if q.listInstancesByProjectStmt, err = db.PrepareContext(ctx, listInstancesByProject); err != nil {
 		return nil, fmt.Errorf("error preparing query ListInstancesByProject: %w", err)
 	}
+	if q.listInstancesByRemoteStmt, err = db.PrepareContext(ctx, listInstancesByRemote); err != nil {
+		return nil, fmt.Errorf("error preparing query ListInstancesByRemote: %w", err)
+	}
 	if q.listProfilesStmt, err = db.PrepareContext(ctx, listProfiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListProfiles: %w", err)
 	}
 	if q.listProfilesByProjectStmt, err = db.PrepareContext(ctx, listProfilesByProject); err != nil {
 		return nil, fmt.Errorf("error preparing query ListProfilesByProject: %w", err)
 	}
+	if q.listProjectHostKeysStmt, err = db.PrepareContext(ctx, listProjectHostKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query ListProjectHostKeys: %w", err)
+	}
+	if q.listProjectInstanceAddressesStmt, err = db.PrepareContext(ctx, listProjectInstanceAddresses); err != nil {
+		return nil, fmt.Errorf("error preparing query ListProjectInstanceAddresses: %w", err)
+	}
+	if q.listProjectInstanceTagsStmt, err = db.PrepareContext(ctx, listProjectInstanceTags); err != nil {
+		return nil, fmt.Errorf("error preparing query ListProjectInstanceTags: %w", err)
+	}
+	if q.listSSHKeysStmt, err = db.PrepareContext(ctx, listSSHKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSSHKeys: %w", err)
+	}
+	if q.listSecretsStmt, err = db.PrepareContext(ctx, listSecrets); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSecrets: %w", err)
+	}
+	if q.listSigningKeysStmt, err = db.PrepareContext(ctx, listSigningKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query ListSigningKeys: %w", err)
+	}
+	if q.purgeDeletedInstancesStmt, err = db.PrepareContext(ctx, purgeDeletedInstances); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeDeletedInstances: %w", err)
+	}
+	if q.purgeDeletedProfilesStmt, err = db.PrepareContext(ctx, purgeDeletedProfiles); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeDeletedProfiles: %w", err)
+	}
+	if q.purgeDeletedVendorDataStmt, err = db.PrepareContext(ctx, purgeDeletedVendorData); err != nil {
+		return nil, fmt.Errorf("error preparing query PurgeDeletedVendorData: %w", err)
+	}
+	if q.revokeInstanceHostKeysStmt, err = db.PrepareContext(ctx, revokeInstanceHostKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeInstanceHostKeys: %w", err)
+	}
+	if q.revokeStaleInstanceHostKeysStmt, err = db.PrepareContext(ctx, revokeStaleInstanceHostKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeStaleInstanceHostKeys: %w", err)
+	}
+	if q.touchInstanceHostKeyStmt, err = db.PrepareContext(ctx, touchInstanceHostKey); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchInstanceHostKey: %w", err)
+	}
+	if q.updateCertificateStmt, err = db.PrepareContext(ctx, updateCertificate); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateCertificate: %w", err)
+	}
 	if q.updateInstanceStmt, err = db.PrepareContext(ctx, updateInstance); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateInstance: %w", err)
 	}
@@ -108,24 +315,91 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.updateProfileStmt, err = db.PrepareContext(ctx, updateProfile); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateProfile: %w", err)
 	}
+	if q.updateSSHKeyStmt, err = db.PrepareContext(ctx, updateSSHKey); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSSHKey: %w", err)
+	}
+	if q.updateSecretStmt, err = db.PrepareContext(ctx, updateSecret); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSecret: %w", err)
+	}
+	if q.updateSigningKeyStmt, err = db.PrepareContext(ctx, updateSigningKey); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateSigningKey: %w", err)
+	}
 	if q.updateVendorDataStmt, err = db.PrepareContext(ctx, updateVendorData); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateVendorData: %w", err)
 	}
+	if q.upsertInstanceStmt, err = db.PrepareContext(ctx, upsertInstance); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertInstance: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.claimCertificateTokenStmt != nil {
+		if cerr := q.claimCertificateTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimCertificateTokenStmt: %w", cerr)
+		}
+	}
+	if q.claimInstancePasswordStmt != nil {
+		if cerr := q.claimInstancePasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimInstancePasswordStmt: %w", cerr)
+		}
+	}
+	if q.claimSecretReadStmt != nil {
+		if cerr := q.claimSecretReadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimSecretReadStmt: %w", cerr)
+		}
+	}
+	if q.createCertificateStmt != nil {
+		if cerr := q.createCertificateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCertificateStmt: %w", cerr)
+		}
+	}
+	if q.createCertificateTokenStmt != nil {
+		if cerr := q.createCertificateTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCertificateTokenStmt: %w", cerr)
+		}
+	}
+	if q.createEphemeralSSHKeyStmt != nil {
+		if cerr := q.createEphemeralSSHKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createEphemeralSSHKeyStmt: %w", cerr)
+		}
+	}
 	if q.createInstanceStmt != nil {
 		if cerr := q.createInstanceStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createInstanceStmt: %w", cerr)
 		}
 	}
+	if q.createInstanceAddressStmt != nil {
+		if cerr := q.createInstanceAddressStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createInstanceAddressStmt: %w", cerr)
+		}
+	}
+	if q.createInstanceHostKeyStmt != nil {
+		if cerr := q.createInstanceHostKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createInstanceHostKeyStmt: %w", cerr)
+		}
+	}
 	if q.createInstanceLogStmt != nil {
 		if cerr := q.createInstanceLogStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createInstanceLogStmt: %w", cerr)
 		}
 	}
+	if q.createInstancePasswordStmt != nil {
+		if cerr := q.createInstancePasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createInstancePasswordStmt: %w", cerr)
+		}
+	}
+	if q.createInstanceProfileStmt != nil {
+		if cerr := q.createInstanceProfileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createInstanceProfileStmt: %w", cerr)
+		}
+	}
+	if q.createInstanceTagStmt != nil {
+		if cerr := q.createInstanceTagStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createInstanceTagStmt: %w", cerr)
+		}
+	}
 	if q.createOrUpdateInstanceStateStmt != nil {
 		if cerr := q.createOrUpdateInstanceStateStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createOrUpdateInstanceStateStmt: %w", cerr)
@@ -136,46 +410,176 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createProfileStmt: %w", cerr)
 		}
 	}
+	if q.createSSHKeyStmt != nil {
+		if cerr := q.createSSHKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSSHKeyStmt: %w", cerr)
+		}
+	}
+	if q.createSecretStmt != nil {
+		if cerr := q.createSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSecretStmt: %w", cerr)
+		}
+	}
+	if q.createSigningKeyStmt != nil {
+		if cerr := q.createSigningKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSigningKeyStmt: %w", cerr)
+		}
+	}
 	if q.createVendorDataStmt != nil {
 		if cerr := q.createVendorDataStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createVendorDataStmt: %w", cerr)
 		}
 	}
+	if q.deleteCertificateStmt != nil {
+		if cerr := q.deleteCertificateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCertificateStmt: %w", cerr)
+		}
+	}
+	if q.deleteExcessInstanceLogsStmt != nil {
+		if cerr := q.deleteExcessInstanceLogsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExcessInstanceLogsStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredCertificateTokensStmt != nil {
+		if cerr := q.deleteExpiredCertificateTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredCertificateTokensStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredEphemeralSSHKeysStmt != nil {
+		if cerr := q.deleteExpiredEphemeralSSHKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredEphemeralSSHKeysStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredInstanceLogsStmt != nil {
+		if cerr := q.deleteExpiredInstanceLogsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredInstanceLogsStmt: %w", cerr)
+		}
+	}
+	if q.deleteExpiredSigningKeysStmt != nil {
+		if cerr := q.deleteExpiredSigningKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredSigningKeysStmt: %w", cerr)
+		}
+	}
 	if q.deleteInstanceStmt != nil {
 		if cerr := q.deleteInstanceStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteInstanceStmt: %w", cerr)
 		}
 	}
+	if q.deleteInstanceAddressesStmt != nil {
+		if cerr := q.deleteInstanceAddressesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteInstanceAddressesStmt: %w", cerr)
+		}
+	}
 	if q.deleteInstanceLogsStmt != nil {
 		if cerr := q.deleteInstanceLogsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteInstanceLogsStmt: %w", cerr)
 		}
 	}
+	if q.deleteInstancePasswordStmt != nil {
+		if cerr := q.deleteInstancePasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteInstancePasswordStmt: %w", cerr)
+		}
+	}
+	if q.deleteInstanceProfilesStmt != nil {
+		if cerr := q.deleteInstanceProfilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteInstanceProfilesStmt: %w", cerr)
+		}
+	}
 	if q.deleteInstanceStateStmt != nil {
 		if cerr := q.deleteInstanceStateStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteInstanceStateStmt: %w", cerr)
 		}
 	}
+	if q.deleteInstanceTagsStmt != nil {
+		if cerr := q.deleteInstanceTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteInstanceTagsStmt: %w", cerr)
+		}
+	}
 	if q.deleteOldInstanceLogsStmt != nil {
 		if cerr := q.deleteOldInstanceLogsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteOldInstanceLogsStmt: %w", cerr)
 		}
 	}
+	if q.deleteOrphanedInstanceAddressesStmt != nil {
+		if cerr := q.deleteOrphanedInstanceAddressesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedInstanceAddressesStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanedInstanceHostKeysStmt != nil {
+		if cerr := q.deleteOrphanedInstanceHostKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedInstanceHostKeysStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanedInstanceLogsStmt != nil {
+		if cerr := q.deleteOrphanedInstanceLogsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedInstanceLogsStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanedInstancePasswordsStmt != nil {
+		if cerr := q.deleteOrphanedInstancePasswordsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedInstancePasswordsStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanedInstanceProfilesStmt != nil {
+		if cerr := q.deleteOrphanedInstanceProfilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedInstanceProfilesStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanedInstanceStatesStmt != nil {
+		if cerr := q.deleteOrphanedInstanceStatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedInstanceStatesStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanedInstanceTagsStmt != nil {
+		if cerr := q.deleteOrphanedInstanceTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedInstanceTagsStmt: %w", cerr)
+		}
+	}
+	if q.deleteOrphanedSecretReadsStmt != nil {
+		if cerr := q.deleteOrphanedSecretReadsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteOrphanedSecretReadsStmt: %w", cerr)
+		}
+	}
 	if q.deleteProfileStmt != nil {
 		if cerr := q.deleteProfileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteProfileStmt: %w", cerr)
 		}
 	}
+	if q.deleteSSHKeyStmt != nil {
+		if cerr := q.deleteSSHKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSSHKeyStmt: %w", cerr)
+		}
+	}
+	if q.deleteSecretStmt != nil {
+		if cerr := q.deleteSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSecretStmt: %w", cerr)
+		}
+	}
+	if q.deleteSecretReadsStmt != nil {
+		if cerr := q.deleteSecretReadsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteSecretReadsStmt: %w", cerr)
+		}
+	}
 	if q.deleteVendorDataStmt != nil {
 		if cerr := q.deleteVendorDataStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteVendorDataStmt: %w", cerr)
 		}
 	}
+	if q.getCertificateStmt != nil {
+		if cerr := q.getCertificateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCertificateStmt: %w", cerr)
+		}
+	}
 	if q.getInstanceStmt != nil {
 		if cerr := q.getInstanceStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getInstanceStmt: %w", cerr)
 		}
 	}
+	if q.getInstanceByAddressStmt != nil {
+		if cerr := q.getInstanceByAddressStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getInstanceByAddressStmt: %w", cerr)
+		}
+	}
 	if q.getInstanceByIDStmt != nil {
 		if cerr := q.getInstanceByIDStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getInstanceByIDStmt: %w", cerr)
@@ -186,6 +590,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getInstanceByIPStmt: %w", cerr)
 		}
 	}
+	if q.getInstanceByVsockIDStmt != nil {
+		if cerr := q.getInstanceByVsockIDStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getInstanceByVsockIDStmt: %w", cerr)
+		}
+	}
 	if q.getInstanceLogsStmt != nil {
 		if cerr := q.getInstanceLogsStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getInstanceLogsStmt: %w", cerr)
@@ -201,6 +610,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getInstanceLogsByTypeStmt: %w", cerr)
 		}
 	}
+	if q.getInstancePasswordStmt != nil {
+		if cerr := q.getInstancePasswordStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getInstancePasswordStmt: %w", cerr)
+		}
+	}
 	if q.getInstanceStateStmt != nil {
 		if cerr := q.getInstanceStateStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getInstanceStateStmt: %w", cerr)
@@ -211,6 +625,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getProfileStmt: %w", cerr)
 		}
 	}
+	if q.getSSHKeyStmt != nil {
+		if cerr := q.getSSHKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSSHKeyStmt: %w", cerr)
+		}
+	}
+	if q.getSecretStmt != nil {
+		if cerr := q.getSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSecretStmt: %w", cerr)
+		}
+	}
 	if q.getVendorDataStmt != nil {
 		if cerr := q.getVendorDataStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getVendorDataStmt: %w", cerr)
@@ -221,16 +645,91 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing hardDeleteInstanceStmt: %w", cerr)
 		}
 	}
+	if q.invalidateInstanceStmt != nil {
+		if cerr := q.invalidateInstanceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing invalidateInstanceStmt: %w", cerr)
+		}
+	}
+	if q.listActiveEphemeralSSHKeysStmt != nil {
+		if cerr := q.listActiveEphemeralSSHKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listActiveEphemeralSSHKeysStmt: %w", cerr)
+		}
+	}
+	if q.listCertificateTokensStmt != nil {
+		if cerr := q.listCertificateTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCertificateTokensStmt: %w", cerr)
+		}
+	}
+	if q.listCertificatesStmt != nil {
+		if cerr := q.listCertificatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCertificatesStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceAddressesStmt != nil {
+		if cerr := q.listInstanceAddressesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceAddressesStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceHostKeysStmt != nil {
+		if cerr := q.listInstanceHostKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceHostKeysStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceLogsStmt != nil {
+		if cerr := q.listInstanceLogsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceLogsStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceLogsAfterStmt != nil {
+		if cerr := q.listInstanceLogsAfterStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceLogsAfterStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceProfilesStmt != nil {
+		if cerr := q.listInstanceProfilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceProfilesStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceSSHKeysStmt != nil {
+		if cerr := q.listInstanceSSHKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceSSHKeysStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceSecretsStmt != nil {
+		if cerr := q.listInstanceSecretsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceSecretsStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceStatesStmt != nil {
+		if cerr := q.listInstanceStatesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceStatesStmt: %w", cerr)
+		}
+	}
+	if q.listInstanceTagsStmt != nil {
+		if cerr := q.listInstanceTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstanceTagsStmt: %w", cerr)
+		}
+	}
 	if q.listInstancesStmt != nil {
 		if cerr := q.listInstancesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listInstancesStmt: %w", cerr)
 		}
 	}
+	if q.listInstancesByAddressIPStmt != nil {
+		if cerr := q.listInstancesByAddressIPStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstancesByAddressIPStmt: %w", cerr)
+		}
+	}
 	if q.listInstancesByProjectStmt != nil {
 		if cerr := q.listInstancesByProjectStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listInstancesByProjectStmt: %w", cerr)
 		}
 	}
+	if q.listInstancesByRemoteStmt != nil {
+		if cerr := q.listInstancesByRemoteStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listInstancesByRemoteStmt: %w", cerr)
+		}
+	}
 	if q.listProfilesStmt != nil {
 		if cerr := q.listProfilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listProfilesStmt: %w", cerr)
@@ -241,6 +740,71 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing listProfilesByProjectStmt: %w", cerr)
 		}
 	}
+	if q.listProjectHostKeysStmt != nil {
+		if cerr := q.listProjectHostKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listProjectHostKeysStmt: %w", cerr)
+		}
+	}
+	if q.listProjectInstanceAddressesStmt != nil {
+		if cerr := q.listProjectInstanceAddressesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listProjectInstanceAddressesStmt: %w", cerr)
+		}
+	}
+	if q.listProjectInstanceTagsStmt != nil {
+		if cerr := q.listProjectInstanceTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listProjectInstanceTagsStmt: %w", cerr)
+		}
+	}
+	if q.listSSHKeysStmt != nil {
+		if cerr := q.listSSHKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSSHKeysStmt: %w", cerr)
+		}
+	}
+	if q.listSecretsStmt != nil {
+		if cerr := q.listSecretsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSecretsStmt: %w", cerr)
+		}
+	}
+	if q.listSigningKeysStmt != nil {
+		if cerr := q.listSigningKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listSigningKeysStmt: %w", cerr)
+		}
+	}
+	if q.purgeDeletedInstancesStmt != nil {
+		if cerr := q.purgeDeletedInstancesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeDeletedInstancesStmt: %w", cerr)
+		}
+	}
+	if q.purgeDeletedProfilesStmt != nil {
+		if cerr := q.purgeDeletedProfilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeDeletedProfilesStmt: %w", cerr)
+		}
+	}
+	if q.purgeDeletedVendorDataStmt != nil {
+		if cerr := q.purgeDeletedVendorDataStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing purgeDeletedVendorDataStmt: %w", cerr)
+		}
+	}
+	if q.revokeInstanceHostKeysStmt != nil {
+		if cerr := q.revokeInstanceHostKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeInstanceHostKeysStmt: %w", cerr)
+		}
+	}
+	if q.revokeStaleInstanceHostKeysStmt != nil {
+		if cerr := q.revokeStaleInstanceHostKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeStaleInstanceHostKeysStmt: %w", cerr)
+		}
+	}
+	if q.touchInstanceHostKeyStmt != nil {
+		if cerr := q.touchInstanceHostKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchInstanceHostKeyStmt: %w", cerr)
+		}
+	}
+	if q.updateCertificateStmt != nil {
+		if cerr := q.updateCertificateStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateCertificateStmt: %w", cerr)
+		}
+	}
 	if q.updateInstanceStmt != nil {
 		if cerr := q.updateInstanceStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateInstanceStmt: %w", cerr)
@@ -256,11 +820,31 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateProfileStmt: %w", cerr)
 		}
 	}
+	if q.updateSSHKeyStmt != nil {
+		if cerr := q.updateSSHKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSSHKeyStmt: %w", cerr)
+		}
+	}
+	if q.updateSecretStmt != nil {
+		if cerr := q.updateSecretStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSecretStmt: %w", cerr)
+		}
+	}
+	if q.updateSigningKeyStmt != nil {
+		if cerr := q.updateSigningKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateSigningKeyStmt: %w", cerr)
+		}
+	}
 	if q.updateVendorDataStmt != nil {
 		if cerr := q.updateVendorDataStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateVendorDataStmt: %w", cerr)
 		}
 	}
+	if q.upsertInstanceStmt != nil {
+		if cerr := q.upsertInstanceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertInstanceStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -298,71 +882,217 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                              DBTX
-	tx                              *sql.Tx
-	createInstanceStmt              *sql.Stmt
-	createInstanceLogStmt           *sql.Stmt
-	createOrUpdateInstanceStateStmt *sql.Stmt
-	createProfileStmt               *sql.Stmt
-	createVendorDataStmt            *sql.Stmt
-	deleteInstanceStmt              *sql.Stmt
-	deleteInstanceLogsStmt          *sql.Stmt
-	deleteInstanceStateStmt         *sql.Stmt
-	deleteOldInstanceLogsStmt       *sql.Stmt
-	deleteProfileStmt               *sql.Stmt
-	deleteVendorDataStmt            *sql.Stmt
-	getInstanceStmt                 *sql.Stmt
-	getInstanceByIDStmt             *sql.Stmt
-	getInstanceByIPStmt             *sql.Stmt
-	getInstanceLogsStmt             *sql.Stmt
-	getInstanceLogsByLevelStmt      *sql.Stmt
-	getInstanceLogsByTypeStmt       *sql.Stmt
-	getInstanceStateStmt            *sql.Stmt
-	getProfileStmt                  *sql.Stmt
-	getVendorDataStmt               *sql.Stmt
-	hardDeleteInstanceStmt          *sql.Stmt
-	listInstancesStmt               *sql.Stmt
-	listInstancesByProjectStmt      *sql.Stmt
-	listProfilesStmt                *sql.Stmt
-	listProfilesByProjectStmt       *sql.Stmt
-	updateInstanceStmt              *sql.Stmt
-	updateInstanceIPStmt            *sql.Stmt
-	updateProfileStmt               *sql.Stmt
-	updateVendorDataStmt            *sql.Stmt
+	db                                  DBTX
+	tx                                  *sql.Tx
+	claimCertificateTokenStmt           *sql.Stmt
+	claimInstancePasswordStmt           *sql.Stmt
+	claimSecretReadStmt                 *sql.Stmt
+	createCertificateStmt               *sql.Stmt
+	createCertificateTokenStmt          *sql.Stmt
+	createEphemeralSSHKeyStmt           *sql.Stmt
+	createInstanceStmt                  *sql.Stmt
+	createInstanceAddressStmt           *sql.Stmt
+	createInstanceHostKeyStmt           *sql.Stmt
+	createInstanceLogStmt               *sql.Stmt
+	createInstancePasswordStmt          *sql.Stmt
+	createInstanceProfileStmt           *sql.Stmt
+	createInstanceTagStmt               *sql.Stmt
+	createOrUpdateInstanceStateStmt     *sql.Stmt
+	createProfileStmt                   *sql.Stmt
+	createSSHKeyStmt                    *sql.Stmt
+	createSecretStmt                    *sql.Stmt
+	createSigningKeyStmt                *sql.Stmt
+	createVendorDataStmt                *sql.Stmt
+	deleteCertificateStmt               *sql.Stmt
+	deleteExcessInstanceLogsStmt        *sql.Stmt
+	deleteExpiredCertificateTokensStmt  *sql.Stmt
+	deleteExpiredEphemeralSSHKeysStmt   *sql.Stmt
+	deleteExpiredInstanceLogsStmt       *sql.Stmt
+	deleteExpiredSigningKeysStmt        *sql.Stmt
+	deleteInstanceStmt                  *sql.Stmt
+	deleteInstanceAddressesStmt         *sql.Stmt
+	deleteInstanceLogsStmt              *sql.Stmt
+	deleteInstancePasswordStmt          *sql.Stmt
+	deleteInstanceProfilesStmt          *sql.Stmt
+	deleteInstanceStateStmt             *sql.Stmt
+	deleteInstanceTagsStmt              *sql.Stmt
+	deleteOldInstanceLogsStmt           *sql.Stmt
+	deleteOrphanedInstanceAddressesStmt *sql.Stmt
+	deleteOrphanedInstanceHostKeysStmt  *sql.Stmt
+	deleteOrphanedInstanceLogsStmt      *sql.Stmt
+	deleteOrphanedInstancePasswordsStmt *sql.Stmt
+	deleteOrphanedInstanceProfilesStmt  *sql.Stmt
+	deleteOrphanedInstanceStatesStmt    *sql.Stmt
+	deleteOrphanedInstanceTagsStmt      *sql.Stmt
+	deleteOrphanedSecretReadsStmt       *sql.Stmt
+	deleteProfileStmt                   *sql.Stmt
+	deleteSSHKeyStmt                    *sql.Stmt
+	deleteSecretStmt                    *sql.Stmt
+	deleteSecretReadsStmt               *sql.Stmt
+	deleteVendorDataStmt                *sql.Stmt
+	getCertificateStmt                  *sql.Stmt
+	getInstanceStmt                     *sql.Stmt
+	getInstanceByAddressStmt            *sql.Stmt
+	getInstanceByIDStmt                 *sql.Stmt
+	getInstanceByIPStmt                 *sql.Stmt
+	getInstanceByVsockIDStmt            *sql.Stmt
+	getInstanceLogsStmt                 *sql.Stmt
+	getInstanceLogsByLevelStmt          *sql.Stmt
+	getInstanceLogsByTypeStmt           *sql.Stmt
+	getInstancePasswordStmt             *sql.Stmt
+	getInstanceStateStmt                *sql.Stmt
+	getProfileStmt                      *sql.Stmt
+	getSSHKeyStmt                       *sql.Stmt
+	getSecretStmt                       *sql.Stmt
+	getVendorDataStmt                   *sql.Stmt
+	hardDeleteInstanceStmt              *sql.Stmt
+	invalidateInstanceStmt              *sql.Stmt
+	listActiveEphemeralSSHKeysStmt      *sql.Stmt
+	listCertificateTokensStmt           *sql.Stmt
+	listCertificatesStmt                *sql.Stmt
+	listInstanceAddressesStmt           *sql.Stmt
+	listInstanceHostKeysStmt            *sql.Stmt
+	listInstanceLogsStmt                *sql.Stmt
+	listInstanceLogsAfterStmt           *sql.Stmt
+	listInstanceProfilesStmt            *sql.Stmt
+	listInstanceSSHKeysStmt             *sql.Stmt
+	listInstanceSecretsStmt             *sql.Stmt
+	listInstanceStatesStmt              *sql.Stmt
+	listInstanceTagsStmt                *sql.Stmt
+	listInstancesStmt                   *sql.Stmt
+	listInstancesByAddressIPStmt        *sql.Stmt
+	listInstancesByProjectStmt          *sql.Stmt
+	listInstancesByRemoteStmt           *sql.Stmt
+	listProfilesStmt                    *sql.Stmt
+	listProfilesByProjectStmt           *sql.Stmt
+	listProjectHostKeysStmt             *sql.Stmt
+	listProjectInstanceAddressesStmt    *sql.Stmt
+	listProjectInstanceTagsStmt         *sql.Stmt
+	listSSHKeysStmt                     *sql.Stmt
+	listSecretsStmt                     *sql.Stmt
+	listSigningKeysStmt                 *sql.Stmt
+	purgeDeletedInstancesStmt           *sql.Stmt
+	purgeDeletedProfilesStmt            *sql.Stmt
+	purgeDeletedVendorDataStmt          *sql.Stmt
+	revokeInstanceHostKeysStmt          *sql.Stmt
+	revokeStaleInstanceHostKeysStmt     *sql.Stmt
+	touchInstanceHostKeyStmt            *sql.Stmt
+	updateCertificateStmt               *sql.Stmt
+	updateInstanceStmt                  *sql.Stmt
+	updateInstanceIPStmt                *sql.Stmt
+	updateProfileStmt                   *sql.Stmt
+	updateSSHKeyStmt                    *sql.Stmt
+	updateSecretStmt                    *sql.Stmt
+	updateSigningKeyStmt                *sql.Stmt
+	updateVendorDataStmt                *sql.Stmt
+	upsertInstanceStmt                  *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                              tx,
-		tx:                              tx,
-		createInstanceStmt:              q.createInstanceStmt,
-		createInstanceLogStmt:           q.createInstanceLogStmt,
-		createOrUpdateInstanceStateStmt: q.createOrUpdateInstanceStateStmt,
-		createProfileStmt:               q.createProfileStmt,
-		createVendorDataStmt:            q.createVendorDataStmt,
-		deleteInstanceStmt:              q.deleteInstanceStmt,
-		deleteInstanceLogsStmt:          q.deleteInstanceLogsStmt,
-		deleteInstanceStateStmt:         q.deleteInstanceStateStmt,
-		deleteOldInstanceLogsStmt:       q.deleteOldInstanceLogsStmt,
-		deleteProfileStmt:               q.deleteProfileStmt,
-		deleteVendorDataStmt:            q.deleteVendorDataStmt,
-		getInstanceStmt:                 q.getInstanceStmt,
-		getInstanceByIDStmt:             q.getInstanceByIDStmt,
-		getInstanceByIPStmt:             q.getInstanceByIPStmt,
-		getInstanceLogsStmt:             q.getInstanceLogsStmt,
-		getInstanceLogsByLevelStmt:      q.getInstanceLogsByLevelStmt,
-		getInstanceLogsByTypeStmt:       q.getInstanceLogsByTypeStmt,
-		getInstanceStateStmt:            q.getInstanceStateStmt,
-		getProfileStmt:                  q.getProfileStmt,
-		getVendorDataStmt:               q.getVendorDataStmt,
-		hardDeleteInstanceStmt:          q.hardDeleteInstanceStmt,
-		listInstancesStmt:               q.listInstancesStmt,
-		listInstancesByProjectStmt:      q.listInstancesByProjectStmt,
-		listProfilesStmt:                q.listProfilesStmt,
-		listProfilesByProjectStmt:       q.listProfilesByProjectStmt,
-		updateInstanceStmt:              q.updateInstanceStmt,
-		updateInstanceIPStmt:            q.updateInstanceIPStmt,
-		updateProfileStmt:               q.updateProfileStmt,
-		updateVendorDataStmt:            q.updateVendorDataStmt,
+		db:                                  tx,
+		tx:                                  tx,
+		claimCertificateTokenStmt:           q.claimCertificateTokenStmt,
+		claimInstancePasswordStmt:           q.claimInstancePasswordStmt,
+		claimSecretReadStmt:                 q.claimSecretReadStmt,
+		createCertificateStmt:               q.createCertificateStmt,
+		createCertificateTokenStmt:          q.createCertificateTokenStmt,
+		createEphemeralSSHKeyStmt:           q.createEphemeralSSHKeyStmt,
+		createInstanceStmt:                  q.createInstanceStmt,
+		createInstanceAddressStmt:           q.createInstanceAddressStmt,
+		createInstanceHostKeyStmt:           q.createInstanceHostKeyStmt,
+		createInstanceLogStmt:               q.createInstanceLogStmt,
+		createInstancePasswordStmt:          q.createInstancePasswordStmt,
+		createInstanceProfileStmt:           q.createInstanceProfileStmt,
+		createInstanceTagStmt:               q.createInstanceTagStmt,
+		createOrUpdateInstanceStateStmt:     q.createOrUpdateInstanceStateStmt,
+		createProfileStmt:                   q.createProfileStmt,
+		createSSHKeyStmt:                    q.createSSHKeyStmt,
+		createSecretStmt:                    q.createSecretStmt,
+		createSigningKeyStmt:                q.createSigningKeyStmt,
+		createVendorDataStmt:                q.createVendorDataStmt,
+		deleteCertificateStmt:               q.deleteCertificateStmt,
+		deleteExcessInstanceLogsStmt:        q.deleteExcessInstanceLogsStmt,
+		deleteExpiredCertificateTokensStmt:  q.deleteExpiredCertificateTokensStmt,
+		deleteExpiredEphemeralSSHKeysStmt:   q.deleteExpiredEphemeralSSHKeysStmt,
+		deleteExpiredInstanceLogsStmt:       q.deleteExpiredInstanceLogsStmt,
+		deleteExpiredSigningKeysStmt:        q.deleteExpiredSigningKeysStmt,
+		deleteInstanceStmt:                  q.deleteInstanceStmt,
+		deleteInstanceAddressesStmt:         q.deleteInstanceAddressesStmt,
+		deleteInstanceLogsStmt:              q.deleteInstanceLogsStmt,
+		deleteInstancePasswordStmt:          q.deleteInstancePasswordStmt,
+		deleteInstanceProfilesStmt:          q.deleteInstanceProfilesStmt,
+		deleteInstanceStateStmt:             q.deleteInstanceStateStmt,
+		deleteInstanceTagsStmt:              q.deleteInstanceTagsStmt,
+		deleteOldInstanceLogsStmt:           q.deleteOldInstanceLogsStmt,
+		deleteOrphanedInstanceAddressesStmt: q.deleteOrphanedInstanceAddressesStmt,
+		deleteOrphanedInstanceHostKeysStmt:  q.deleteOrphanedInstanceHostKeysStmt,
+		deleteOrphanedInstanceLogsStmt:      q.deleteOrphanedInstanceLogsStmt,
+		deleteOrphanedInstancePasswordsStmt: q.deleteOrphanedInstancePasswordsStmt,
+		deleteOrphanedInstanceProfilesStmt:  q.deleteOrphanedInstanceProfilesStmt,
+		deleteOrphanedInstanceStatesStmt:    q.deleteOrphanedInstanceStatesStmt,
+		deleteOrphanedInstanceTagsStmt:      q.deleteOrphanedInstanceTagsStmt,
+		deleteOrphanedSecretReadsStmt:       q.deleteOrphanedSecretReadsStmt,
+		deleteProfileStmt:                   q.deleteProfileStmt,
+		deleteSSHKeyStmt:                    q.deleteSSHKeyStmt,
+		deleteSecretStmt:                    q.deleteSecretStmt,
+		deleteSecretReadsStmt:               q.deleteSecretReadsStmt,
+		deleteVendorDataStmt:                q.deleteVendorDataStmt,
+		getCertificateStmt:                  q.getCertificateStmt,
+		getInstanceStmt:                     q.getInstanceStmt,
+		getInstanceByAddressStmt:            q.getInstanceByAddressStmt,
+		getInstanceByIDStmt:                 q.getInstanceByIDStmt,
+		getInstanceByIPStmt:                 q.getInstanceByIPStmt,
+		getInstanceByVsockIDStmt:            q.getInstanceByVsockIDStmt,
+		getInstanceLogsStmt:                 q.getInstanceLogsStmt,
+		getInstanceLogsByLevelStmt:          q.getInstanceLogsByLevelStmt,
+		getInstanceLogsByTypeStmt:           q.getInstanceLogsByTypeStmt,
+		getInstancePasswordStmt:             q.getInstancePasswordStmt,
+		getInstanceStateStmt:                q.getInstanceStateStmt,
+		getProfileStmt:                      q.getProfileStmt,
+		getSSHKeyStmt:                       q.getSSHKeyStmt,
+		getSecretStmt:                       q.getSecretStmt,
+		getVendorDataStmt:                   q.getVendorDataStmt,
+		hardDeleteInstanceStmt:              q.hardDeleteInstanceStmt,
+		invalidateInstanceStmt:              q.invalidateInstanceStmt,
+		listActiveEphemeralSSHKeysStmt:      q.listActiveEphemeralSSHKeysStmt,
+		listCertificateTokensStmt:           q.listCertificateTokensStmt,
+		listCertificatesStmt:                q.listCertificatesStmt,
+		listInstanceAddressesStmt:           q.listInstanceAddressesStmt,
+		listInstanceHostKeysStmt:            q.listInstanceHostKeysStmt,
+		listInstanceLogsStmt:                q.listInstanceLogsStmt,
+		listInstanceLogsAfterStmt:           q.listInstanceLogsAfterStmt,
+		listInstanceProfilesStmt:            q.listInstanceProfilesStmt,
+		listInstanceSSHKeysStmt:             q.listInstanceSSHKeysStmt,
+		listInstanceSecretsStmt:             q.listInstanceSecretsStmt,
+		listInstanceStatesStmt:              q.listInstanceStatesStmt,
+		listInstanceTagsStmt:                q.listInstanceTagsStmt,
+		listInstancesStmt:                   q.listInstancesStmt,
+		listInstancesByAddressIPStmt:        q.listInstancesByAddressIPStmt,
+		listInstancesByProjectStmt:          q.listInstancesByProjectStmt,
+		listInstancesByRemoteStmt:           q.listInstancesByRemoteStmt,
+		listProfilesStmt:                    q.listProfilesStmt,
+		listProfilesByProjectStmt:           q.listProfilesByProjectStmt,
+		listProjectHostKeysStmt:             q.listProjectHostKeysStmt,
+		listProjectInstanceAddressesStmt:    q.listProjectInstanceAddressesStmt,
+		listProjectInstanceTagsStmt:         q.listProjectInstanceTagsStmt,
+		listSSHKeysStmt:                     q.listSSHKeysStmt,
+		listSecretsStmt:                     q.listSecretsStmt,
+		listSigningKeysStmt:                 q.listSigningKeysStmt,
+		purgeDeletedInstancesStmt:           q.purgeDeletedInstancesStmt,
+		purgeDeletedProfilesStmt:            q.purgeDeletedProfilesStmt,
+		purgeDeletedVendorDataStmt:          q.purgeDeletedVendorDataStmt,
+		revokeInstanceHostKeysStmt:          q.revokeInstanceHostKeysStmt,
+		revokeStaleInstanceHostKeysStmt:     q.revokeStaleInstanceHostKeysStmt,
+		touchInstanceHostKeyStmt:            q.touchInstanceHostKeyStmt,
+		updateCertificateStmt:               q.updateCertificateStmt,
+		updateInstanceStmt:                  q.updateInstanceStmt,
+		updateInstanceIPStmt:                q.updateInstanceIPStmt,
+		updateProfileStmt:                   q.updateProfileStmt,
+		updateSSHKeyStmt:                    q.updateSSHKeyStmt,
+		updateSecretStmt:                    q.updateSecretStmt,
+		updateSigningKeyStmt:                q.updateSigningKeyStmt,
+		updateVendorDataStmt:                q.updateVendorDataStmt,
+		upsertInstanceStmt:                  q.upsertInstanceStmt,
 	}
 }
@@ -0,0 +1,271 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+)
+
+// migration changes the tables of an existing database, which schema.sql leaves untouched as it
+// only creates what is missing.
+type migration func(ctx context.Context, tx *sql.Tx, cfg *config.Config) error
+
+// migrations bring databases created by earlier versions of the service up to schema.sql. They
+// run in order, once: PRAGMA user_version holds the number of migrations a database has had.
+// New databases are created by schema.sql directly and skip them, so only append to this list.
+var migrations = []migration{
+	// 1: remote, vsock_id and uuid of instances, cached from the primary remote until then
+	func(ctx context.Context, tx *sql.Tx, cfg *config.Config) error {
+		if err := addColumns(ctx, tx, "instances", "vsock_id INTEGER", "uuid TEXT"); err != nil {
+			return err
+		}
+
+		return addRemote(ctx, tx, cfg, "instances")
+	},
+	// 2: invalidated_at, which replaced the soft deletion of instances changed in Incus
+	func(ctx context.Context, tx *sql.Tx, cfg *config.Config) error {
+		return addColumns(ctx, tx, "instances", "invalidated_at TIMESTAMP")
+	},
+	// 3: instances unique per remote, so instances of the same name on two remotes don't collide
+	func(ctx context.Context, tx *sql.Tx, cfg *config.Config) error {
+		return rebuildTable(ctx, tx, "instances", `
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  project TEXT NOT NULL DEFAULT 'default',
+  remote TEXT NOT NULL DEFAULT 'local', -- Name of the Incus remote hosting the instance
+  ip_address TEXT, -- IP address for instance identification
+  vsock_id INTEGER, -- volatile.vsock_id of virtual machines, identifies callers over vsock
+  uuid TEXT, -- volatile.uuid, changes when the instance is recreated under the same name
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  deleted_at TIMESTAMP,
+  invalidated_at TIMESTAMP, -- Set when Incus reports a change, the next request resolves the instance again
+  UNIQUE(remote, name, project)
+`, "id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at")
+	},
+	// 4: SSH keys of the primary remote, so keys don't apply to projects of the same name on other remotes
+	func(ctx context.Context, tx *sql.Tx, cfg *config.Config) error {
+		if err := addRemote(ctx, tx, cfg, "ssh_keys"); err != nil {
+			return err
+		}
+
+		return rebuildTable(ctx, tx, "ssh_keys", `
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  owner TEXT NOT NULL, -- Person or system the key belongs to
+  comment TEXT NOT NULL DEFAULT '',
+  key_type TEXT NOT NULL, -- e.g. ssh-ed25519
+  public_key TEXT NOT NULL, -- authorized_keys format, without comment
+  fingerprint TEXT NOT NULL, -- SHA256 fingerprint
+  project TEXT NOT NULL DEFAULT 'default',
+  remote TEXT NOT NULL DEFAULT 'local', -- Name of the Incus remote of the project
+  scope TEXT NOT NULL CHECK (scope IN ('project', 'profile', 'instance')),
+  target TEXT NOT NULL DEFAULT '', -- Profile or instance name, empty for the project scope
+  expires_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(remote, project, scope, target, fingerprint)
+`, "id, owner, comment, key_type, public_key, fingerprint, project, remote, scope, target, expires_at, created_at, updated_at")
+	},
+	// 5: secrets of the primary remote, for the same reason
+	func(ctx context.Context, tx *sql.Tx, cfg *config.Config) error {
+		if err := addRemote(ctx, tx, cfg, "secrets"); err != nil {
+			return err
+		}
+
+		return rebuildTable(ctx, tx, "secrets", `
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  project TEXT NOT NULL DEFAULT 'default',
+  remote TEXT NOT NULL DEFAULT 'local', -- Name of the Incus remote of the project
+  scope TEXT NOT NULL CHECK (scope IN ('project', 'profile', 'instance')),
+  target TEXT NOT NULL DEFAULT '', -- Profile or instance name, empty for the project scope
+  key_id TEXT NOT NULL, -- Identifies the key encryption key the data key is wrapped with
+  wrapped_key BLOB NOT NULL, -- AES-256-GCM encrypted data key, prefixed with its nonce
+  ciphertext BLOB NOT NULL, -- AES-256-GCM encrypted value, prefixed with its nonce
+  one_time BOOLEAN NOT NULL DEFAULT FALSE, -- Served once to each instance
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(remote, project, scope, target, name)
+`, "id, name, project, remote, scope, target, key_id, wrapped_key, ciphertext, one_time, created_by, created_at, updated_at")
+	},
+	// 6: volatile.uuid of the instances that read one-time secrets, taken from the cached instance
+	func(ctx context.Context, tx *sql.Tx, cfg *config.Config) error {
+		existing, err := tableColumns(ctx, tx, "secret_reads")
+		if err != nil || len(existing) == 0 || existing["instance_uuid"] {
+			return err
+		}
+
+		if err := addColumns(ctx, tx, "secret_reads", "instance_uuid TEXT"); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE secret_reads SET instance_uuid = (SELECT uuid FROM instances WHERE instances.id = secret_reads.instance_id)")
+		return err
+	},
+}
+
+// migrate applies the migrations a database is missing, before schema.sql runs.
+func migrate(ctx context.Context, db *sql.DB, cfg *config.Config) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	if version >= len(migrations) {
+		return nil
+	}
+
+	// Rebuilding a table drops it, which would delete the rows referring to it if foreign keys
+	// were enforced. The pragma can't change within a transaction, so it is set on the
+	// connection the migrations run on.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var foreignKeys bool
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return err
+	}
+
+	if foreignKeys {
+		if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A database without instances is new, schema.sql creates it with the latest tables
+	existing, err := tableExists(ctx, tx, "instances")
+	if err != nil {
+		return err
+	}
+
+	if existing {
+		for i := version; i < len(migrations); i++ {
+			if err := migrations[i](ctx, tx, cfg); err != nil {
+				return fmt.Errorf("migration %d failed: %w", i+1, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", len(migrations))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func tableExists(ctx context.Context, tx *sql.Tx, table string) (bool, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count)
+	return count > 0, err
+}
+
+// rebuildTable recreates a table with a new definition, for changes ALTER TABLE can't make such as
+// unique constraints, copying the listed columns over. Its indexes are dropped along with it and
+// recreated by schema.sql. IDs carry on from the old table, so rows left behind by purged rows
+// never refer to new ones.
+func rebuildTable(ctx context.Context, tx *sql.Tx, table string, definition string, columns string) error {
+	existing, err := tableExists(ctx, tx, table)
+	if err != nil || !existing {
+		return err
+	}
+
+	var seq int64
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM sqlite_sequence WHERE name = ?", table).Scan(&seq)
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s_new (%s)", table, definition),
+		fmt.Sprintf("INSERT INTO %s_new (%s) SELECT %s FROM %s", table, columns, columns, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s_new RENAME TO %s", table, table),
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sqlite_sequence WHERE name = ?", table); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO sqlite_sequence (name, seq) SELECT ?, MAX(?, COALESCE(MAX(id), 0)) FROM "+table, table, seq)
+	return err
+}
+
+// addRemote adds the remote column to a table, assigning the rows already there to the primary
+// remote, the only one before remotes could be configured.
+func addRemote(ctx context.Context, tx *sql.Tx, cfg *config.Config, table string) error {
+	existing, err := tableColumns(ctx, tx, table)
+	if err != nil || len(existing) == 0 || existing["remote"] {
+		return err
+	}
+
+	if err := addColumns(ctx, tx, table, "remote TEXT NOT NULL DEFAULT 'local'"); err != nil || cfg.Incus == nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET remote = ?", table), cfg.Incus.Name)
+	return err
+}
+
+// addColumns adds the columns, given as in CREATE TABLE, that table doesn't have yet. Tables
+// created by development builds may already have some of them, and tables that don't exist
+// yet are left to schema.sql.
+func addColumns(ctx context.Context, tx *sql.Tx, table string, columns ...string) error {
+	existing, err := tableColumns(ctx, tx, table)
+	if err != nil || len(existing) == 0 {
+		return err
+	}
+
+	for _, column := range columns {
+		name, _, _ := strings.Cut(column, " ")
+		if existing[name] {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, column)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// tableColumns returns the names of the columns of a table, none when it doesn't exist.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		columns[name] = true
+	}
+
+	return columns, rows.Err()
+}
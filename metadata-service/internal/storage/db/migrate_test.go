@@ -0,0 +1,317 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// baselineSchema is the schema of the first release, which had no migrations.
+const baselineSchema = `CREATE TABLE IF NOT EXISTS vendor_data (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  description TEXT,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  deleted_at TIMESTAMP,
+  data JSONB
+);
+
+-- Index for faster lookups by name
+CREATE INDEX IF NOT EXISTS idx_vendor_data_name ON vendor_data(name);
+
+-- Index for only one active record per name
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vendor_data_active_name ON vendor_data(name, deleted_at)
+WHERE
+  deleted_at IS NULL;
+
+-- Instances table to store VMs/containers created in Incus
+CREATE TABLE IF NOT EXISTS instances (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  project TEXT NOT NULL DEFAULT 'default',
+  ip_address TEXT, -- IP address for instance identification
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  deleted_at TIMESTAMP,
+  UNIQUE(name, project)
+);
+
+-- Instance state table for current runtime state
+CREATE TABLE IF NOT EXISTS instance_state (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  instance_id INTEGER NOT NULL UNIQUE,
+  status TEXT NOT NULL,
+  status_code INTEGER NOT NULL,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
+-- Instance logs table for operation and event logs
+CREATE TABLE IF NOT EXISTS instance_logs (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  instance_id INTEGER NOT NULL,
+  log_type TEXT NOT NULL CHECK (log_type IN ('operation', 'event', 'console', 'audit')),
+  level TEXT NOT NULL CHECK (level IN ('debug', 'info', 'warn', 'error', 'fatal')),
+  message TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
+-- Profiles table to store Incus profiles
+CREATE TABLE IF NOT EXISTS profiles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  project TEXT NOT NULL DEFAULT 'default',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  deleted_at TIMESTAMP,
+  UNIQUE(name, project)
+);
+
+-- Indexes for better performance
+CREATE INDEX IF NOT EXISTS idx_instances_name ON instances(name);
+CREATE INDEX IF NOT EXISTS idx_instances_project ON instances(project);
+CREATE INDEX IF NOT EXISTS idx_instances_ip_address ON instances(ip_address);
+CREATE INDEX IF NOT EXISTS idx_instances_deleted_at ON instances(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_instances_active ON instances(name, project, deleted_at) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_instance_state_instance_id ON instance_state(instance_id);
+CREATE INDEX IF NOT EXISTS idx_instance_state_status ON instance_state(status);
+CREATE INDEX IF NOT EXISTS idx_instance_state_updated_at ON instance_state(updated_at);
+
+CREATE INDEX IF NOT EXISTS idx_instance_logs_instance_id ON instance_logs(instance_id);
+CREATE INDEX IF NOT EXISTS idx_instance_logs_type ON instance_logs(log_type);
+CREATE INDEX IF NOT EXISTS idx_instance_logs_level ON instance_logs(level);
+CREATE INDEX IF NOT EXISTS idx_instance_logs_created_at ON instance_logs(created_at);
+
+CREATE INDEX IF NOT EXISTS idx_profiles_name_project ON profiles(name, project);
+CREATE INDEX IF NOT EXISTS idx_profiles_deleted_at ON profiles(deleted_at);
+`
+
+// baselineDatabase returns the path of a database created with the baseline schema, holding an
+// instance named c1 and having held a second one.
+func baselineDatabase(t *testing.T) string {
+	source := filepath.Join(t.TempDir(), "metadata.db")
+	database, err := sql.Open("sqlite", source)
+	require.NoError(t, err)
+	defer database.Close()
+
+	_, err = database.Exec(baselineSchema)
+	require.NoError(t, err)
+
+	_, err = database.Exec("INSERT INTO instances (name, project, ip_address) VALUES ('c1', 'default', '10.0.0.5')")
+	require.NoError(t, err)
+
+	// A purged instance, its ID must not be reused
+	_, err = database.Exec("INSERT INTO instances (name, project) VALUES ('c0', 'default')")
+	require.NoError(t, err)
+
+	_, err = database.Exec("DELETE FROM instances WHERE name = 'c0'")
+	require.NoError(t, err)
+
+	return source
+}
+
+func connectTestDB(t *testing.T, source string) *Queries {
+	queries, err := ConnectDB(&config.Config{
+		Database: &config.DatabaseConfig{DBDriver: "sqlite", DBSource: source},
+		Incus:    &config.IncusConfig{Name: "dc1"},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { queries.db.(*sql.DB).Close() })
+
+	return queries
+}
+
+func userVersion(t *testing.T, queries *Queries) int {
+	var version int
+	require.NoError(t, queries.db.QueryRowContext(context.Background(), "PRAGMA user_version").Scan(&version))
+	return version
+}
+
+func TestConnectDB_MigratesBaselineDatabase(t *testing.T) {
+	source := baselineDatabase(t)
+	ctx := context.Background()
+
+	queries := connectTestDB(t, source)
+	assert.Equal(t, len(migrations), userVersion(t, queries))
+
+	// Instances cached before remotes existed belong to the primary remote
+	instance, err := queries.GetInstance(ctx, GetInstanceParams{Remote: "dc1", Name: "c1", Project: "default"})
+	require.NoError(t, err)
+	assert.Nil(t, instance.VsockID)
+	assert.Nil(t, instance.Uuid)
+
+	vsockID := int64(42)
+	_, err = queries.db.ExecContext(ctx, "UPDATE instances SET vsock_id = ? WHERE id = ?", vsockID, instance.ID)
+	require.NoError(t, err)
+
+	instance, err = queries.GetInstanceByVsockID(ctx, GetInstanceByVsockIDParams{Remote: "dc1", VsockID: &vsockID})
+	require.NoError(t, err)
+	assert.Equal(t, "c1", instance.Name)
+}
+
+func TestConnectDB_MigratesInstancesToRemotes(t *testing.T) {
+	source := baselineDatabase(t)
+	ctx := context.Background()
+
+	queries := connectTestDB(t, source)
+
+	// Instances of the same name on two remotes are cached separately
+	cached, err := queries.UpsertInstance(ctx, UpsertInstanceParams{Remote: "dc1", Name: "c1", Project: "default"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), cached.ID)
+
+	other, err := queries.UpsertInstance(ctx, UpsertInstanceParams{Remote: "dc2", Name: "c1", Project: "default"})
+	require.NoError(t, err)
+	assert.Greater(t, other.ID, int64(2), "IDs carry on after the purged instance")
+
+	var index string
+	require.NoError(t, queries.db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE name = 'idx_instances_active'").Scan(&index))
+	assert.Contains(t, index, "instances(remote, name, project, deleted_at)")
+}
+
+func TestConnectDB_NewDatabaseSkipsMigrations(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "metadata.db")
+
+	queries := connectTestDB(t, source)
+	assert.Equal(t, len(migrations), userVersion(t, queries))
+
+	// Reconnecting to an up to date database changes nothing
+	queries = connectTestDB(t, source)
+	assert.Equal(t, len(migrations), userVersion(t, queries))
+}
+
+func TestConnectDB_MigratesSecrets(t *testing.T) {
+	source := baselineDatabase(t)
+	ctx := context.Background()
+
+	// The secrets tables of the first release with secrets, before remotes and instance UUIDs
+	database, err := sql.Open("sqlite", source)
+	require.NoError(t, err)
+
+	_, err = database.Exec(`CREATE TABLE secrets (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  project TEXT NOT NULL DEFAULT 'default',
+  scope TEXT NOT NULL CHECK (scope IN ('project', 'profile', 'instance')),
+  target TEXT NOT NULL DEFAULT '',
+  key_id TEXT NOT NULL,
+  wrapped_key BLOB NOT NULL,
+  ciphertext BLOB NOT NULL,
+  one_time BOOLEAN NOT NULL DEFAULT FALSE,
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(project, scope, target, name)
+);
+INSERT INTO secrets (name, scope, key_id, wrapped_key, ciphertext, created_by, one_time) VALUES ('db_password', 'project', 'k1', x'00', x'00', 'operator', TRUE);
+CREATE TABLE secret_reads (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  secret_id INTEGER NOT NULL,
+  instance_id INTEGER NOT NULL,
+  read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(secret_id, instance_id)
+);
+INSERT INTO secret_reads (secret_id, instance_id) VALUES (1, 1);
+ALTER TABLE instances ADD COLUMN uuid TEXT;
+UPDATE instances SET uuid = 'u1' WHERE id = 1;`)
+	require.NoError(t, err)
+	require.NoError(t, database.Close())
+
+	queries := connectTestDB(t, source)
+
+	migrated, err := queries.ListSecrets(ctx, ListSecretsParams{Remote: "dc1", Project: "default"})
+	require.NoError(t, err)
+	require.Len(t, migrated, 1)
+	assert.Equal(t, "db_password", migrated[0].Name)
+
+	// The instance read the one-time secret, until it is rebuilt
+	uuid := "u1"
+	claimed, err := queries.ClaimSecretRead(ctx, ClaimSecretReadParams{SecretID: 1, InstanceID: 1, InstanceUuid: &uuid})
+	require.NoError(t, err)
+	assert.Zero(t, claimed)
+
+	uuid = "u2"
+	claimed, err = queries.ClaimSecretRead(ctx, ClaimSecretReadParams{SecretID: 1, InstanceID: 1, InstanceUuid: &uuid})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claimed)
+
+	// A secret of the same name in the project of the same name on another remote
+	_, err = queries.CreateSecret(ctx, CreateSecretParams{
+		Name:       "db_password",
+		Project:    "default",
+		Remote:     "dc2",
+		Scope:      "project",
+		KeyID:      "k1",
+		WrappedKey: []byte{0},
+		Ciphertext: []byte{0},
+		CreatedBy:  "operator",
+	})
+	require.NoError(t, err)
+
+	secrets, err := queries.ListInstanceSecrets(ctx, ListInstanceSecretsParams{Remote: "dc2", Project: "default", InstanceName: "c1", InstanceID: 1})
+	require.NoError(t, err)
+	require.Len(t, secrets, 1)
+	assert.Equal(t, "dc2", secrets[0].Secret.Remote)
+
+	// Consumed one-time secrets are still listed, so they keep shadowing broader scopes
+	secrets, err = queries.ListInstanceSecrets(ctx, ListInstanceSecretsParams{Remote: "dc1", Project: "default", InstanceName: "c1", InstanceID: 1, InstanceUuid: &uuid})
+	require.NoError(t, err)
+	require.Len(t, secrets, 1)
+	assert.True(t, secrets[0].Consumed)
+}
+
+func TestInTx_RollsBackOnError(t *testing.T) {
+	queries := connectTestDB(t, filepath.Join(t.TempDir(), "metadata.db"))
+	ctx := context.Background()
+
+	failure := errors.New("failed to cache profiles")
+	err := queries.InTx(ctx, func(tx Store) error {
+		if _, err := tx.UpsertInstance(ctx, UpsertInstanceParams{Remote: "dc1", Name: "c1", Project: "default"}); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	require.ErrorIs(t, err, failure)
+
+	_, err = queries.GetInstance(ctx, GetInstanceParams{Remote: "dc1", Name: "c1", Project: "default"})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, queries.InTx(ctx, func(tx Store) error {
+		_, err := tx.UpsertInstance(ctx, UpsertInstanceParams{Remote: "dc1", Name: "c1", Project: "default"})
+		return err
+	}))
+
+	_, err = queries.GetInstance(ctx, GetInstanceParams{Remote: "dc1", Name: "c1", Project: "default"})
+	assert.NoError(t, err)
+}
+
+func TestUpsertInstance_KeepsAddressOnlyWhenUnknown(t *testing.T) {
+	queries := connectTestDB(t, filepath.Join(t.TempDir(), "metadata.db"))
+	ctx := context.Background()
+
+	ip := "10.0.0.5"
+	_, err := queries.UpsertInstance(ctx, UpsertInstanceParams{Remote: "dc1", Name: "c1", Project: "default", IpAddress: &ip, AddressesKnown: true})
+	require.NoError(t, err)
+
+	// Without a state the last known address is kept
+	instance, err := queries.UpsertInstance(ctx, UpsertInstanceParams{Remote: "dc1", Name: "c1", Project: "default"})
+	require.NoError(t, err)
+	require.NotNil(t, instance.IpAddress)
+	assert.Equal(t, ip, *instance.IpAddress)
+
+	// A state without addresses means the instance no longer holds one
+	instance, err = queries.UpsertInstance(ctx, UpsertInstanceParams{Remote: "dc1", Name: "c1", Project: "default", AddressesKnown: true})
+	require.NoError(t, err)
+	assert.Nil(t, instance.IpAddress)
+}
@@ -12,6 +12,12 @@ type MockQuerier struct {
 	mock.Mock
 }
 
+// InTx runs fn against the mock itself, so the queries made in a transaction are
+// matched against the same expectations.
+func (m *MockQuerier) InTx(ctx context.Context, fn func(db.Store) error) error {
+	return fn(m)
+}
+
 // Vendor data methods
 func (m *MockQuerier) CreateVendorData(ctx context.Context, arg db.CreateVendorDataParams) (db.VendorDatum, error) {
 	args := m.Called(ctx, arg)
@@ -49,8 +55,18 @@ func (m *MockQuerier) GetInstanceByID(ctx context.Context, id int64) (db.Instanc
 	return args.Get(0).(db.Instance), args.Error(1)
 }
 
-func (m *MockQuerier) GetInstanceByIP(ctx context.Context, ipAddress *string) (db.Instance, error) {
-	args := m.Called(ctx, ipAddress)
+func (m *MockQuerier) GetInstanceByIP(ctx context.Context, arg db.GetInstanceByIPParams) (db.Instance, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Instance), args.Error(1)
+}
+
+func (m *MockQuerier) GetInstanceByVsockID(ctx context.Context, arg db.GetInstanceByVsockIDParams) (db.Instance, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Instance), args.Error(1)
+}
+
+func (m *MockQuerier) UpsertInstance(ctx context.Context, arg db.UpsertInstanceParams) (db.Instance, error) {
+	args := m.Called(ctx, arg)
 	return args.Get(0).(db.Instance), args.Error(1)
 }
 
@@ -59,6 +75,36 @@ func (m *MockQuerier) ListInstances(ctx context.Context) ([]db.Instance, error)
 	return args.Get(0).([]db.Instance), args.Error(1)
 }
 
+func (m *MockQuerier) GetInstanceByAddress(ctx context.Context, arg db.GetInstanceByAddressParams) (db.Instance, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Instance), args.Error(1)
+}
+
+func (m *MockQuerier) ListInstancesByAddressIP(ctx context.Context, arg db.ListInstancesByAddressIPParams) ([]db.Instance, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.Instance), args.Error(1)
+}
+
+func (m *MockQuerier) CreateInstanceAddress(ctx context.Context, arg db.CreateInstanceAddressParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteInstanceAddresses(ctx context.Context, instanceID int64) error {
+	args := m.Called(ctx, instanceID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListInstanceAddresses(ctx context.Context, instanceID int64) ([]string, error) {
+	args := m.Called(ctx, instanceID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockQuerier) ListInstancesByRemote(ctx context.Context, remote string) ([]db.Instance, error) {
+	args := m.Called(ctx, remote)
+	return args.Get(0).([]db.Instance), args.Error(1)
+}
+
 func (m *MockQuerier) ListInstancesByProject(ctx context.Context, project string) ([]db.Instance, error) {
 	args := m.Called(ctx, project)
 	return args.Get(0).([]db.Instance), args.Error(1)
@@ -79,6 +125,11 @@ func (m *MockQuerier) DeleteInstance(ctx context.Context, id int64) error {
 	return args.Error(0)
 }
 
+func (m *MockQuerier) InvalidateInstance(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
 func (m *MockQuerier) HardDeleteInstance(ctx context.Context, id int64) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -100,6 +151,11 @@ func (m *MockQuerier) DeleteInstanceState(ctx context.Context, instanceID int64)
 	return args.Error(0)
 }
 
+func (m *MockQuerier) ListInstanceStates(ctx context.Context, arg db.ListInstanceStatesParams) ([]db.ListInstanceStatesRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ListInstanceStatesRow), args.Error(1)
+}
+
 // Instance logs methods
 func (m *MockQuerier) CreateInstanceLog(ctx context.Context, arg db.CreateInstanceLogParams) (db.InstanceLog, error) {
 	args := m.Called(ctx, arg)
@@ -121,6 +177,16 @@ func (m *MockQuerier) GetInstanceLogsByLevel(ctx context.Context, arg db.GetInst
 	return args.Get(0).([]db.InstanceLog), args.Error(1)
 }
 
+func (m *MockQuerier) ListInstanceLogs(ctx context.Context, arg db.ListInstanceLogsParams) ([]db.InstanceLog, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.InstanceLog), args.Error(1)
+}
+
+func (m *MockQuerier) ListInstanceLogsAfter(ctx context.Context, arg db.ListInstanceLogsAfterParams) ([]db.InstanceLog, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.InstanceLog), args.Error(1)
+}
+
 func (m *MockQuerier) DeleteInstanceLogs(ctx context.Context, instanceID int64) error {
 	args := m.Called(ctx, instanceID)
 	return args.Error(0)
@@ -161,3 +227,310 @@ func (m *MockQuerier) DeleteProfile(ctx context.Context, id int64) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
+
+// Certificate methods
+func (m *MockQuerier) CreateCertificate(ctx context.Context, arg db.CreateCertificateParams) (db.Certificate, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Certificate), args.Error(1)
+}
+
+func (m *MockQuerier) GetCertificate(ctx context.Context, fingerprint string) (db.Certificate, error) {
+	args := m.Called(ctx, fingerprint)
+	return args.Get(0).(db.Certificate), args.Error(1)
+}
+
+func (m *MockQuerier) ListCertificates(ctx context.Context) ([]db.Certificate, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]db.Certificate), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateCertificate(ctx context.Context, arg db.UpdateCertificateParams) (db.Certificate, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Certificate), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteCertificate(ctx context.Context, fingerprint string) error {
+	args := m.Called(ctx, fingerprint)
+	return args.Error(0)
+}
+
+// Certificate token methods
+func (m *MockQuerier) CreateCertificateToken(ctx context.Context, arg db.CreateCertificateTokenParams) (db.CertificateToken, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.CertificateToken), args.Error(1)
+}
+
+func (m *MockQuerier) ClaimCertificateToken(ctx context.Context, secret string) (db.CertificateToken, error) {
+	args := m.Called(ctx, secret)
+	return args.Get(0).(db.CertificateToken), args.Error(1)
+}
+
+func (m *MockQuerier) ListCertificateTokens(ctx context.Context) ([]db.CertificateToken, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]db.CertificateToken), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteExpiredCertificateTokens(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteExpiredInstanceLogs(ctx context.Context, arg db.DeleteExpiredInstanceLogsParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteExcessInstanceLogs(ctx context.Context, arg db.DeleteExcessInstanceLogsParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteOrphanedInstanceLogs(ctx context.Context, limit int64) (int64, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateInstanceHostKey(ctx context.Context, arg db.CreateInstanceHostKeyParams) (db.InstanceHostKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.InstanceHostKey), args.Error(1)
+}
+
+func (m *MockQuerier) TouchInstanceHostKey(ctx context.Context, id int64) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListInstanceHostKeys(ctx context.Context, instanceID int64) ([]db.InstanceHostKey, error) {
+	args := m.Called(ctx, instanceID)
+	return args.Get(0).([]db.InstanceHostKey), args.Error(1)
+}
+
+func (m *MockQuerier) RevokeInstanceHostKeys(ctx context.Context, arg db.RevokeInstanceHostKeysParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) RevokeStaleInstanceHostKeys(ctx context.Context, arg db.RevokeStaleInstanceHostKeysParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) ListProjectHostKeys(ctx context.Context, arg db.ListProjectHostKeysParams) ([]db.ListProjectHostKeysRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ListProjectHostKeysRow), args.Error(1)
+}
+
+func (m *MockQuerier) ListProjectInstanceAddresses(ctx context.Context, arg db.ListProjectInstanceAddressesParams) ([]db.ListProjectInstanceAddressesRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ListProjectInstanceAddressesRow), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteOrphanedInstanceHostKeys(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteOrphanedInstanceAddresses(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteOrphanedInstanceStates(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) PurgeDeletedInstances(ctx context.Context, before int64) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) PurgeDeletedProfiles(ctx context.Context, before int64) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) PurgeDeletedVendorData(ctx context.Context, before int64) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateInstanceProfile(ctx context.Context, arg db.CreateInstanceProfileParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListInstanceProfiles(ctx context.Context, instanceID int64) ([]string, error) {
+	args := m.Called(ctx, instanceID)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteInstanceProfiles(ctx context.Context, instanceID int64) error {
+	args := m.Called(ctx, instanceID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteOrphanedInstanceProfiles(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateSSHKey(ctx context.Context, arg db.CreateSSHKeyParams) (db.SshKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.SshKey), args.Error(1)
+}
+
+func (m *MockQuerier) GetSSHKey(ctx context.Context, id int64) (db.SshKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(db.SshKey), args.Error(1)
+}
+
+func (m *MockQuerier) ListSSHKeys(ctx context.Context, arg db.ListSSHKeysParams) ([]db.SshKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.SshKey), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateSSHKey(ctx context.Context, arg db.UpdateSSHKeyParams) (db.SshKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.SshKey), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteSSHKey(ctx context.Context, id int64) (int64, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) ListInstanceSSHKeys(ctx context.Context, arg db.ListInstanceSSHKeysParams) ([]db.SshKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.SshKey), args.Error(1)
+}
+
+func (m *MockQuerier) CreateEphemeralSSHKey(ctx context.Context, arg db.CreateEphemeralSSHKeyParams) (db.EphemeralSshKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.EphemeralSshKey), args.Error(1)
+}
+
+func (m *MockQuerier) ListActiveEphemeralSSHKeys(ctx context.Context, arg db.ListActiveEphemeralSSHKeysParams) ([]db.EphemeralSshKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.EphemeralSshKey), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteExpiredEphemeralSSHKeys(ctx context.Context, before int64) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateSigningKey(ctx context.Context, arg db.CreateSigningKeyParams) (db.SigningKey, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.SigningKey), args.Error(1)
+}
+
+func (m *MockQuerier) ListSigningKeys(ctx context.Context, now int64) ([]db.SigningKey, error) {
+	args := m.Called(ctx, now)
+	return args.Get(0).([]db.SigningKey), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateSigningKey(ctx context.Context, arg db.UpdateSigningKeyParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteExpiredSigningKeys(ctx context.Context, before int64) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateSecret(ctx context.Context, arg db.CreateSecretParams) (db.Secret, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Secret), args.Error(1)
+}
+
+func (m *MockQuerier) GetSecret(ctx context.Context, id int64) (db.Secret, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(db.Secret), args.Error(1)
+}
+
+func (m *MockQuerier) ListSecrets(ctx context.Context, arg db.ListSecretsParams) ([]db.Secret, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.Secret), args.Error(1)
+}
+
+func (m *MockQuerier) UpdateSecret(ctx context.Context, arg db.UpdateSecretParams) (db.Secret, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(db.Secret), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteSecret(ctx context.Context, id int64) (int64, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) ListInstanceSecrets(ctx context.Context, arg db.ListInstanceSecretsParams) ([]db.ListInstanceSecretsRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ListInstanceSecretsRow), args.Error(1)
+}
+
+func (m *MockQuerier) ClaimSecretRead(ctx context.Context, arg db.ClaimSecretReadParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteSecretReads(ctx context.Context, secretID int64) error {
+	args := m.Called(ctx, secretID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteOrphanedSecretReads(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateInstancePassword(ctx context.Context, arg db.CreateInstancePasswordParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) GetInstancePassword(ctx context.Context, instanceID int64) (db.InstancePassword, error) {
+	args := m.Called(ctx, instanceID)
+	return args.Get(0).(db.InstancePassword), args.Error(1)
+}
+
+func (m *MockQuerier) ClaimInstancePassword(ctx context.Context, arg db.ClaimInstancePasswordParams) (int64, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteInstancePassword(ctx context.Context, instanceID int64) (int64, error) {
+	args := m.Called(ctx, instanceID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteOrphanedInstancePasswords(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockQuerier) CreateInstanceTag(ctx context.Context, arg db.CreateInstanceTagParams) error {
+	args := m.Called(ctx, arg)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) ListInstanceTags(ctx context.Context, instanceID int64) ([]db.InstanceTag, error) {
+	args := m.Called(ctx, instanceID)
+	return args.Get(0).([]db.InstanceTag), args.Error(1)
+}
+
+func (m *MockQuerier) ListProjectInstanceTags(ctx context.Context, arg db.ListProjectInstanceTagsParams) ([]db.ListProjectInstanceTagsRow, error) {
+	args := m.Called(ctx, arg)
+	return args.Get(0).([]db.ListProjectInstanceTagsRow), args.Error(1)
+}
+
+func (m *MockQuerier) DeleteInstanceTags(ctx context.Context, instanceID int64) error {
+	args := m.Called(ctx, instanceID)
+	return args.Error(0)
+}
+
+func (m *MockQuerier) DeleteOrphanedInstanceTags(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -8,14 +8,65 @@ import (
 	"time"
 )
 
+type Certificate struct {
+	ID          int64
+	Fingerprint string
+	Name        string
+	Role        string
+	Restricted  bool
+	Projects    interface{}
+	Certificate string
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+}
+
+type CertificateToken struct {
+	ID         int64
+	Name       string
+	Secret     string
+	Role       string
+	Restricted bool
+	Projects   interface{}
+	ExpiresAt  time.Time
+	CreatedAt  *time.Time
+}
+
+type EphemeralSshKey struct {
+	ID          int64
+	InstanceID  int64
+	OsUser      string
+	PublicKey   string
+	Fingerprint string
+	PushedBy    string
+	ExpiresAt   time.Time
+	CreatedAt   *time.Time
+}
+
 type Instance struct {
-	ID        int64
-	Name      string
-	Project   string
-	IpAddress *string
-	CreatedAt *time.Time
-	UpdatedAt *time.Time
-	DeletedAt *time.Time
+	ID            int64
+	Name          string
+	Project       string
+	Remote        string
+	IpAddress     *string
+	VsockID       *int64
+	Uuid          *string
+	CreatedAt     *time.Time
+	UpdatedAt     *time.Time
+	DeletedAt     *time.Time
+	InvalidatedAt *time.Time
+}
+
+type InstanceHostKey struct {
+	ID            int64
+	InstanceID    int64
+	InstanceUuid  *string
+	KeyType       string
+	PublicKey     string
+	Fingerprint   string
+	CreatedAt     *time.Time
+	LastSeenAt    *time.Time
+	RevokedAt     *time.Time
+	RevokedReason *string
 }
 
 type InstanceLog struct {
@@ -27,6 +78,30 @@ type InstanceLog struct {
 	CreatedAt  *time.Time
 }
 
+type InstancePassword struct {
+	ID                int64
+	InstanceID        int64
+	InstanceUuid      *string
+	KeyFingerprint    string
+	EncryptedPassword []byte
+	CreatedAt         *time.Time
+	RetrievedAt       *time.Time
+	RetrievedBy       *string
+}
+
+type InstanceProfile struct {
+	ID         int64
+	InstanceID int64
+	Profile    string
+}
+
+type InstanceTag struct {
+	ID         int64
+	InstanceID int64
+	Key        string
+	Value      string
+}
+
 type InstanceState struct {
 	ID         int64
 	InstanceID int64
@@ -44,6 +119,56 @@ type Profile struct {
 	DeletedAt *time.Time
 }
 
+type Secret struct {
+	ID         int64
+	Name       string
+	Project    string
+	Remote     string
+	Scope      string
+	Target     string
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+	OneTime    bool
+	CreatedBy  string
+	CreatedAt  *time.Time
+	UpdatedAt  *time.Time
+}
+
+type SecretRead struct {
+	ID           int64
+	SecretID     int64
+	InstanceID   int64
+	InstanceUuid *string
+	ReadAt       *time.Time
+}
+
+type SigningKey struct {
+	ID          int64
+	Kid         string
+	Algorithm   string
+	PrivateKey  string
+	Certificate string
+	ExpiresAt   time.Time
+	CreatedAt   *time.Time
+}
+
+type SshKey struct {
+	ID          int64
+	Owner       string
+	Comment     string
+	KeyType     string
+	PublicKey   string
+	Fingerprint string
+	Project     string
+	Remote      string
+	Scope       string
+	Target      string
+	ExpiresAt   *time.Time
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+}
+
 type VendorDatum struct {
 	ID          int64
 	Name        string
@@ -9,39 +9,119 @@ import (
 )
 
 type Querier interface {
+	ClaimCertificateToken(ctx context.Context, secret string) (CertificateToken, error)
+	ClaimInstancePassword(ctx context.Context, arg ClaimInstancePasswordParams) (int64, error)
+	ClaimSecretRead(ctx context.Context, arg ClaimSecretReadParams) (int64, error)
+	// ===== CERTIFICATES QUERIES =====
+	CreateCertificate(ctx context.Context, arg CreateCertificateParams) (Certificate, error)
+	// ===== CERTIFICATE TOKENS QUERIES =====
+	CreateCertificateToken(ctx context.Context, arg CreateCertificateTokenParams) (CertificateToken, error)
+	CreateEphemeralSSHKey(ctx context.Context, arg CreateEphemeralSSHKeyParams) (EphemeralSshKey, error)
 	// ===== INSTANCES QUERIES =====
 	CreateInstance(ctx context.Context, arg CreateInstanceParams) (Instance, error)
+	// ===== INSTANCE ADDRESS QUERIES =====
+	CreateInstanceAddress(ctx context.Context, arg CreateInstanceAddressParams) error
+	CreateInstanceHostKey(ctx context.Context, arg CreateInstanceHostKeyParams) (InstanceHostKey, error)
 	// ===== INSTANCE LOGS QUERIES =====
 	CreateInstanceLog(ctx context.Context, arg CreateInstanceLogParams) (InstanceLog, error)
+	// ===== INSTANCE PASSWORDS QUERIES =====
+	CreateInstancePassword(ctx context.Context, arg CreateInstancePasswordParams) (int64, error)
+	CreateInstanceProfile(ctx context.Context, arg CreateInstanceProfileParams) error
+	// ===== INSTANCE TAGS QUERIES =====
+	CreateInstanceTag(ctx context.Context, arg CreateInstanceTagParams) error
 	// ===== INSTANCE STATE QUERIES =====
 	CreateOrUpdateInstanceState(ctx context.Context, arg CreateOrUpdateInstanceStateParams) (InstanceState, error)
 	// ===== PROFILES QUERIES =====
 	CreateProfile(ctx context.Context, arg CreateProfileParams) (Profile, error)
+	CreateSSHKey(ctx context.Context, arg CreateSSHKeyParams) (SshKey, error)
+	// ===== SECRETS QUERIES =====
+	CreateSecret(ctx context.Context, arg CreateSecretParams) (Secret, error)
+	// ===== SIGNING KEYS QUERIES =====
+	CreateSigningKey(ctx context.Context, arg CreateSigningKeyParams) (SigningKey, error)
 	CreateVendorData(ctx context.Context, arg CreateVendorDataParams) (VendorDatum, error)
+	DeleteCertificate(ctx context.Context, fingerprint string) error
+	DeleteExcessInstanceLogs(ctx context.Context, arg DeleteExcessInstanceLogsParams) (int64, error)
+	DeleteExpiredCertificateTokens(ctx context.Context) error
+	DeleteExpiredEphemeralSSHKeys(ctx context.Context, before int64) (int64, error)
+	DeleteExpiredInstanceLogs(ctx context.Context, arg DeleteExpiredInstanceLogsParams) (int64, error)
+	DeleteExpiredSigningKeys(ctx context.Context, before int64) (int64, error)
 	DeleteInstance(ctx context.Context, id int64) error
+	DeleteInstanceAddresses(ctx context.Context, instanceID int64) error
 	DeleteInstanceLogs(ctx context.Context, instanceID int64) error
+	DeleteInstancePassword(ctx context.Context, instanceID int64) (int64, error)
+	DeleteInstanceProfiles(ctx context.Context, instanceID int64) error
 	DeleteInstanceState(ctx context.Context, instanceID int64) error
+	DeleteInstanceTags(ctx context.Context, instanceID int64) error
 	DeleteOldInstanceLogs(ctx context.Context, arg DeleteOldInstanceLogsParams) error
+	DeleteOrphanedInstanceAddresses(ctx context.Context) (int64, error)
+	DeleteOrphanedInstanceHostKeys(ctx context.Context) (int64, error)
+	DeleteOrphanedInstanceLogs(ctx context.Context, limit int64) (int64, error)
+	DeleteOrphanedInstancePasswords(ctx context.Context) (int64, error)
+	DeleteOrphanedInstanceProfiles(ctx context.Context) (int64, error)
+	DeleteOrphanedInstanceStates(ctx context.Context) (int64, error)
+	DeleteOrphanedInstanceTags(ctx context.Context) (int64, error)
+	DeleteOrphanedSecretReads(ctx context.Context) (int64, error)
 	DeleteProfile(ctx context.Context, id int64) error
+	DeleteSSHKey(ctx context.Context, id int64) (int64, error)
+	DeleteSecret(ctx context.Context, id int64) (int64, error)
+	DeleteSecretReads(ctx context.Context, secretID int64) error
 	DeleteVendorData(ctx context.Context, id int64) error
+	GetCertificate(ctx context.Context, fingerprint string) (Certificate, error)
 	GetInstance(ctx context.Context, arg GetInstanceParams) (Instance, error)
+	GetInstanceByAddress(ctx context.Context, arg GetInstanceByAddressParams) (Instance, error)
 	GetInstanceByID(ctx context.Context, id int64) (Instance, error)
-	GetInstanceByIP(ctx context.Context, ipAddress *string) (Instance, error)
+	GetInstanceByIP(ctx context.Context, arg GetInstanceByIPParams) (Instance, error)
+	GetInstanceByVsockID(ctx context.Context, arg GetInstanceByVsockIDParams) (Instance, error)
 	GetInstanceLogs(ctx context.Context, arg GetInstanceLogsParams) ([]InstanceLog, error)
 	GetInstanceLogsByLevel(ctx context.Context, arg GetInstanceLogsByLevelParams) ([]InstanceLog, error)
 	GetInstanceLogsByType(ctx context.Context, arg GetInstanceLogsByTypeParams) ([]InstanceLog, error)
+	GetInstancePassword(ctx context.Context, instanceID int64) (InstancePassword, error)
 	GetInstanceState(ctx context.Context, instanceID int64) (InstanceState, error)
 	GetProfile(ctx context.Context, arg GetProfileParams) (Profile, error)
+	GetSSHKey(ctx context.Context, id int64) (SshKey, error)
+	GetSecret(ctx context.Context, id int64) (Secret, error)
 	GetVendorData(ctx context.Context, name string) (GetVendorDataRow, error)
 	HardDeleteInstance(ctx context.Context, id int64) error
+	InvalidateInstance(ctx context.Context, id int64) error
+	ListActiveEphemeralSSHKeys(ctx context.Context, arg ListActiveEphemeralSSHKeysParams) ([]EphemeralSshKey, error)
+	ListCertificateTokens(ctx context.Context) ([]CertificateToken, error)
+	ListCertificates(ctx context.Context) ([]Certificate, error)
+	ListInstanceAddresses(ctx context.Context, instanceID int64) ([]string, error)
+	ListInstanceHostKeys(ctx context.Context, instanceID int64) ([]InstanceHostKey, error)
+	ListInstanceLogs(ctx context.Context, arg ListInstanceLogsParams) ([]InstanceLog, error)
+	ListInstanceLogsAfter(ctx context.Context, arg ListInstanceLogsAfterParams) ([]InstanceLog, error)
+	ListInstanceProfiles(ctx context.Context, instanceID int64) ([]string, error)
+	ListInstanceSSHKeys(ctx context.Context, arg ListInstanceSSHKeysParams) ([]SshKey, error)
+	ListInstanceSecrets(ctx context.Context, arg ListInstanceSecretsParams) ([]ListInstanceSecretsRow, error)
+	ListInstanceStates(ctx context.Context, arg ListInstanceStatesParams) ([]ListInstanceStatesRow, error)
+	ListInstanceTags(ctx context.Context, instanceID int64) ([]InstanceTag, error)
 	ListInstances(ctx context.Context) ([]Instance, error)
+	ListInstancesByAddressIP(ctx context.Context, arg ListInstancesByAddressIPParams) ([]Instance, error)
 	ListInstancesByProject(ctx context.Context, project string) ([]Instance, error)
+	ListInstancesByRemote(ctx context.Context, remote string) ([]Instance, error)
 	ListProfiles(ctx context.Context) ([]Profile, error)
 	ListProfilesByProject(ctx context.Context, project string) ([]Profile, error)
+	ListProjectHostKeys(ctx context.Context, arg ListProjectHostKeysParams) ([]ListProjectHostKeysRow, error)
+	ListProjectInstanceAddresses(ctx context.Context, arg ListProjectInstanceAddressesParams) ([]ListProjectInstanceAddressesRow, error)
+	ListProjectInstanceTags(ctx context.Context, arg ListProjectInstanceTagsParams) ([]ListProjectInstanceTagsRow, error)
+	ListSSHKeys(ctx context.Context, arg ListSSHKeysParams) ([]SshKey, error)
+	ListSecrets(ctx context.Context, arg ListSecretsParams) ([]Secret, error)
+	ListSigningKeys(ctx context.Context, now int64) ([]SigningKey, error)
+	PurgeDeletedInstances(ctx context.Context, before int64) (int64, error)
+	PurgeDeletedProfiles(ctx context.Context, before int64) (int64, error)
+	PurgeDeletedVendorData(ctx context.Context, before int64) (int64, error)
+	RevokeInstanceHostKeys(ctx context.Context, arg RevokeInstanceHostKeysParams) (int64, error)
+	RevokeStaleInstanceHostKeys(ctx context.Context, arg RevokeStaleInstanceHostKeysParams) (int64, error)
+	TouchInstanceHostKey(ctx context.Context, id int64) error
+	UpdateCertificate(ctx context.Context, arg UpdateCertificateParams) (Certificate, error)
 	UpdateInstance(ctx context.Context, arg UpdateInstanceParams) (Instance, error)
 	UpdateInstanceIP(ctx context.Context, arg UpdateInstanceIPParams) error
 	UpdateProfile(ctx context.Context, id int64) (Profile, error)
+	UpdateSSHKey(ctx context.Context, arg UpdateSSHKeyParams) (SshKey, error)
+	UpdateSecret(ctx context.Context, arg UpdateSecretParams) (Secret, error)
+	UpdateSigningKey(ctx context.Context, arg UpdateSigningKeyParams) error
 	UpdateVendorData(ctx context.Context, arg UpdateVendorDataParams) (VendorDatum, error)
+	UpsertInstance(ctx context.Context, arg UpsertInstanceParams) (Instance, error)
 }
 
 var _ Querier = (*Queries)(nil)
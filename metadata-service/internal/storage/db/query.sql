@@ -36,12 +36,18 @@ SET
 WHERE
   id = ?;
 
+-- name: PurgeDeletedVendorData :execrows
+DELETE FROM
+  vendor_data
+WHERE
+  deleted_at < datetime(sqlc.arg(before), 'unixepoch');
+
 -- ===== INSTANCES QUERIES =====
 -- name: CreateInstance :one
 INSERT INTO
-  instances (name, project, ip_address)
+  instances (remote, name, project, ip_address)
 VALUES
-  (?, ?, ?) RETURNING *;
+  (?, ?, ?, ?) RETURNING *;
 
 -- name: GetInstance :one
 SELECT
@@ -49,7 +55,8 @@ SELECT
 FROM
   instances
 WHERE
-  name = ?
+  remote = ?
+  AND name = ?
   AND project = ?
   AND deleted_at IS NULL;
 
@@ -68,9 +75,38 @@ SELECT
 FROM
   instances
 WHERE
-  ip_address = ?
+  remote = ?
+  AND ip_address = ?
   AND deleted_at IS NULL;
 
+-- name: GetInstanceByVsockID :one
+SELECT
+  *
+FROM
+  instances
+WHERE
+  remote = ?
+  AND vsock_id = ?
+  AND deleted_at IS NULL
+  AND invalidated_at IS NULL;
+
+-- name: UpsertInstance :one
+INSERT INTO
+  instances (remote, name, project, ip_address, vsock_id, uuid)
+VALUES
+  (?1, ?2, ?3, ?4, ?5, ?6) ON CONFLICT(remote, name, project) DO
+UPDATE
+SET
+  ip_address = CASE
+    WHEN CAST(sqlc.arg(addresses_known) AS BOOLEAN) THEN excluded.ip_address
+    ELSE COALESCE(excluded.ip_address, instances.ip_address)
+  END,
+  vsock_id = COALESCE(excluded.vsock_id, instances.vsock_id),
+  uuid = COALESCE(excluded.uuid, instances.uuid),
+  deleted_at = NULL,
+  invalidated_at = NULL,
+  updated_at = CURRENT_TIMESTAMP RETURNING *;
+
 -- name: ListInstances :many
 SELECT
   *
@@ -81,6 +117,17 @@ WHERE
 ORDER BY
   created_at DESC;
 
+-- name: ListInstancesByRemote :many
+SELECT
+  *
+FROM
+  instances
+WHERE
+  remote = ?
+  AND deleted_at IS NULL
+ORDER BY
+  created_at DESC;
+
 -- name: ListInstancesByProject :many
 SELECT
   *
@@ -118,12 +165,200 @@ SET
 WHERE
   id = ?;
 
+-- name: InvalidateInstance :exec
+UPDATE
+  instances
+SET
+  invalidated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?;
+
 -- name: HardDeleteInstance :exec
 DELETE FROM
   instances
 WHERE
   id = ?;
 
+-- ===== INSTANCE ADDRESS QUERIES =====
+-- name: CreateInstanceAddress :exec
+INSERT INTO
+  instance_addresses (instance_id, network, ip_address, mac_address)
+VALUES
+  (?, ?, ?, ?) ON CONFLICT(instance_id, network, ip_address) DO
+UPDATE
+SET
+  mac_address = excluded.mac_address;
+
+-- name: PurgeDeletedInstances :execrows
+DELETE FROM
+  instances
+WHERE
+  deleted_at < datetime(sqlc.arg(before), 'unixepoch');
+
+-- name: DeleteOrphanedInstanceAddresses :execrows
+DELETE FROM
+  instance_addresses
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  );
+
+-- name: DeleteOrphanedInstanceStates :execrows
+DELETE FROM
+  instance_state
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  );
+
+-- name: DeleteInstanceAddresses :exec
+DELETE FROM
+  instance_addresses
+WHERE
+  instance_id = ?;
+
+-- name: ListInstanceAddresses :many
+SELECT
+  instance_addresses.ip_address
+FROM
+  instance_addresses
+  JOIN instances ON instances.id = instance_addresses.instance_id
+WHERE
+  instance_addresses.instance_id = ?
+  AND instances.invalidated_at IS NULL
+ORDER BY
+  instance_addresses.id;
+
+-- ===== INSTANCE PROFILE QUERIES =====
+-- name: CreateInstanceProfile :exec
+INSERT INTO
+  instance_profiles (instance_id, profile)
+VALUES
+  (?, ?) ON CONFLICT(instance_id, profile) DO NOTHING;
+
+-- name: ListInstanceProfiles :many
+SELECT
+  profile
+FROM
+  instance_profiles
+WHERE
+  instance_id = ?
+ORDER BY
+  id;
+
+-- name: DeleteInstanceProfiles :exec
+DELETE FROM
+  instance_profiles
+WHERE
+  instance_id = ?;
+
+-- name: DeleteOrphanedInstanceProfiles :execrows
+DELETE FROM
+  instance_profiles
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  );
+
+-- name: GetInstanceByAddress :one
+SELECT
+  instances.*
+FROM
+  instances
+  JOIN instance_addresses ON instance_addresses.instance_id = instances.id
+WHERE
+  instances.remote = sqlc.arg(remote)
+  AND instance_addresses.network = sqlc.arg(network)
+  AND instance_addresses.ip_address = sqlc.arg(ip_address)
+  AND (
+    sqlc.arg(mac_address) = ''
+    OR instance_addresses.mac_address = sqlc.arg(mac_address)
+  )
+  AND instances.deleted_at IS NULL
+  AND instances.invalidated_at IS NULL
+LIMIT
+  1;
+
+-- name: ListInstancesByAddressIP :many
+SELECT DISTINCT
+  instances.*
+FROM
+  instances
+  JOIN instance_addresses ON instance_addresses.instance_id = instances.id
+WHERE
+  instances.remote = sqlc.arg(remote)
+  AND instance_addresses.ip_address = sqlc.arg(ip_address)
+  AND (
+    sqlc.arg(mac_address) = ''
+    OR instance_addresses.mac_address = sqlc.arg(mac_address)
+  )
+  AND instances.deleted_at IS NULL
+  AND instances.invalidated_at IS NULL;
+
+-- ===== INSTANCE TAGS QUERIES =====
+-- name: CreateInstanceTag :exec
+INSERT INTO
+  instance_tags (instance_id, key, value)
+VALUES
+  (?, ?, ?) ON CONFLICT(instance_id, key) DO
+UPDATE
+SET
+  value = excluded.value;
+
+-- name: ListInstanceTags :many
+SELECT
+  *
+FROM
+  instance_tags
+WHERE
+  instance_id = ?
+ORDER BY
+  key;
+
+-- name: ListProjectInstanceTags :many
+SELECT
+  instances.id AS instance_id,
+  instances.name,
+  instances.uuid,
+  instance_tags.key,
+  instance_tags.value
+FROM
+  instances
+  LEFT JOIN instance_tags ON instance_tags.instance_id = instances.id
+WHERE
+  instances.remote = ?
+  AND instances.project = ?
+  AND instances.deleted_at IS NULL
+ORDER BY
+  instances.name,
+  instance_tags.key;
+
+-- name: DeleteInstanceTags :exec
+DELETE FROM
+  instance_tags
+WHERE
+  instance_id = ?;
+
+-- name: DeleteOrphanedInstanceTags :execrows
+DELETE FROM
+  instance_tags
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  );
+
 -- ===== INSTANCE STATE QUERIES =====
 -- name: CreateOrUpdateInstanceState :one
 INSERT INTO
@@ -144,6 +379,31 @@ FROM
 WHERE
   instance_id = ?;
 
+-- name: ListInstanceStates :many
+SELECT
+  instances.name,
+  instances.project,
+  instance_state.status,
+  instance_state.status_code,
+  instance_state.updated_at
+FROM
+  instance_state
+  JOIN instances ON instances.id = instance_state.instance_id
+WHERE
+  instances.remote = sqlc.arg(remote)
+  AND instances.project = sqlc.arg(project)
+  AND instances.deleted_at IS NULL
+  AND (
+    sqlc.arg(status) = ''
+    OR instance_state.status = sqlc.arg(status)
+  )
+  AND (
+    sqlc.narg(since) IS NULL
+    OR unixepoch(instance_state.updated_at) >= sqlc.narg(since)
+  )
+ORDER BY
+  instances.name;
+
 -- name: DeleteInstanceState :exec
 DELETE FROM
   instance_state
@@ -195,6 +455,59 @@ ORDER BY
 LIMIT
   ? OFFSET ?;
 
+-- name: ListInstanceLogs :many
+SELECT
+  *
+FROM
+  instance_logs
+WHERE
+  instance_id = sqlc.arg(instance_id)
+  AND (
+    sqlc.arg(log_type) = ''
+    OR log_type = sqlc.arg(log_type)
+  )
+  AND (
+    sqlc.arg(level) = ''
+    OR level = sqlc.arg(level)
+  )
+  AND (
+    sqlc.narg(since) IS NULL
+    OR unixepoch(created_at) >= sqlc.narg(since)
+  )
+  AND (
+    sqlc.narg(until) IS NULL
+    OR unixepoch(created_at) < sqlc.narg(until)
+  )
+  AND (
+    sqlc.arg(before_id) = 0
+    OR id < sqlc.arg(before_id)
+  )
+ORDER BY
+  id DESC
+LIMIT
+  sqlc.arg(limit);
+
+-- name: ListInstanceLogsAfter :many
+SELECT
+  *
+FROM
+  instance_logs
+WHERE
+  instance_id = sqlc.arg(instance_id)
+  AND (
+    sqlc.arg(log_type) = ''
+    OR log_type = sqlc.arg(log_type)
+  )
+  AND (
+    sqlc.arg(level) = ''
+    OR level = sqlc.arg(level)
+  )
+  AND id > sqlc.arg(after_id)
+ORDER BY
+  id ASC
+LIMIT
+  sqlc.arg(limit);
+
 -- name: DeleteInstanceLogs :exec
 DELETE FROM
   instance_logs
@@ -208,6 +521,173 @@ WHERE
   created_at < ?
   AND instance_id = ?;
 
+-- name: DeleteExpiredInstanceLogs :execrows
+DELETE FROM
+  instance_logs
+WHERE
+  id IN (
+    SELECT
+      id
+    FROM
+      instance_logs
+    WHERE
+      log_type = sqlc.arg(log_type)
+      AND created_at < datetime(sqlc.arg(before), 'unixepoch')
+    LIMIT
+      sqlc.arg(limit)
+  );
+
+-- name: DeleteExcessInstanceLogs :execrows
+DELETE FROM
+  instance_logs
+WHERE
+  id IN (
+    SELECT
+      id
+    FROM
+      (
+        SELECT
+          id,
+          ROW_NUMBER() OVER (
+            PARTITION BY
+              instance_id
+            ORDER BY
+              id DESC
+          ) AS position
+        FROM
+          instance_logs
+        WHERE
+          log_type = sqlc.arg(log_type)
+      )
+    WHERE
+      position > sqlc.arg(max_rows)
+    LIMIT
+      sqlc.arg(limit)
+  );
+
+-- name: DeleteOrphanedInstanceLogs :execrows
+DELETE FROM
+  instance_logs
+WHERE
+  id IN (
+    SELECT
+      id
+    FROM
+      instance_logs
+    WHERE
+      instance_id NOT IN (
+        SELECT
+          id
+        FROM
+          instances
+      )
+    LIMIT
+      sqlc.arg(limit)
+  );
+
+-- ===== INSTANCE HOST KEYS QUERIES =====
+-- name: CreateInstanceHostKey :one
+INSERT INTO
+  instance_host_keys (
+    instance_id,
+    instance_uuid,
+    key_type,
+    public_key,
+    fingerprint
+  )
+VALUES
+  (?, ?, ?, ?, ?) RETURNING *;
+
+-- name: TouchInstanceHostKey :exec
+UPDATE
+  instance_host_keys
+SET
+  last_seen_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?;
+
+-- name: ListInstanceHostKeys :many
+SELECT
+  *
+FROM
+  instance_host_keys
+WHERE
+  instance_id = ?
+ORDER BY
+  id DESC;
+
+-- name: RevokeInstanceHostKeys :execrows
+UPDATE
+  instance_host_keys
+SET
+  revoked_at = CURRENT_TIMESTAMP,
+  revoked_reason = sqlc.arg(revoked_reason)
+WHERE
+  instance_id = sqlc.arg(instance_id)
+  AND revoked_at IS NULL
+  AND (
+    sqlc.arg(key_type) = ''
+    OR key_type = sqlc.arg(key_type)
+  );
+
+-- name: RevokeStaleInstanceHostKeys :execrows
+UPDATE
+  instance_host_keys
+SET
+  revoked_at = CURRENT_TIMESTAMP,
+  revoked_reason = 'rebuilt'
+WHERE
+  instance_id = sqlc.arg(instance_id)
+  AND revoked_at IS NULL
+  AND (
+    instance_uuid IS NULL
+    OR instance_uuid != sqlc.arg(instance_uuid)
+  );
+
+-- name: ListProjectHostKeys :many
+SELECT
+  instances.id AS instance_id,
+  instances.name,
+  instance_host_keys.public_key
+FROM
+  instance_host_keys
+  JOIN instances ON instances.id = instance_host_keys.instance_id
+WHERE
+  instances.remote = ?
+  AND instances.project = ?
+  AND instances.deleted_at IS NULL
+  AND instance_host_keys.revoked_at IS NULL
+ORDER BY
+  instances.name,
+  instance_host_keys.key_type;
+
+-- name: ListProjectInstanceAddresses :many
+SELECT
+  DISTINCT instance_addresses.instance_id,
+  instance_addresses.ip_address
+FROM
+  instance_addresses
+  JOIN instances ON instances.id = instance_addresses.instance_id
+WHERE
+  instances.remote = ?
+  AND instances.project = ?
+  AND instances.deleted_at IS NULL
+  AND instances.invalidated_at IS NULL
+ORDER BY
+  instance_addresses.instance_id,
+  instance_addresses.ip_address;
+
+-- name: DeleteOrphanedInstanceHostKeys :execrows
+DELETE FROM
+  instance_host_keys
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  );
+
 -- ===== PROFILES QUERIES =====
 -- name: CreateProfile :one
 INSERT INTO
@@ -260,4 +740,445 @@ UPDATE
 SET
   deleted_at = CURRENT_TIMESTAMP
 WHERE
-  id = ?;
\ No newline at end of file
+  id = ?;
+
+-- name: PurgeDeletedProfiles :execrows
+DELETE FROM
+  profiles
+WHERE
+  deleted_at < datetime(sqlc.arg(before), 'unixepoch');
+
+-- ===== SSH KEYS QUERIES =====
+-- name: CreateSSHKey :one
+INSERT INTO
+  ssh_keys (
+    owner,
+    comment,
+    key_type,
+    public_key,
+    fingerprint,
+    project,
+    remote,
+    scope,
+    target,
+    expires_at
+  )
+VALUES
+  (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    datetime(sqlc.narg(expires_at), 'unixepoch')
+  ) RETURNING *;
+
+-- name: GetSSHKey :one
+SELECT
+  *
+FROM
+  ssh_keys
+WHERE
+  id = ?;
+
+-- name: ListSSHKeys :many
+SELECT
+  *
+FROM
+  ssh_keys
+WHERE
+  remote = sqlc.arg(remote)
+  AND project = sqlc.arg(project)
+  AND (
+    sqlc.arg(owner) = ''
+    OR owner = sqlc.arg(owner)
+  )
+ORDER BY
+  id;
+
+-- name: UpdateSSHKey :one
+UPDATE
+  ssh_keys
+SET
+  owner = ?,
+  comment = ?,
+  key_type = ?,
+  public_key = ?,
+  fingerprint = ?,
+  scope = ?,
+  target = ?,
+  expires_at = datetime(sqlc.narg(expires_at), 'unixepoch'),
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = sqlc.arg(id) RETURNING *;
+
+-- name: DeleteSSHKey :execrows
+DELETE FROM
+  ssh_keys
+WHERE
+  id = ?;
+
+-- name: ListInstanceSSHKeys :many
+SELECT
+  *
+FROM
+  ssh_keys
+WHERE
+  remote = sqlc.arg(remote)
+  AND project = sqlc.arg(project)
+  AND (
+    expires_at IS NULL
+    OR unixepoch(expires_at) > sqlc.arg(now)
+  )
+  AND (
+    scope = 'project'
+    OR (
+      scope = 'instance'
+      AND target = sqlc.arg(instance_name)
+    )
+    OR (
+      scope = 'profile'
+      AND target IN (
+        SELECT
+          profile
+        FROM
+          instance_profiles
+        WHERE
+          instance_id = sqlc.arg(instance_id)
+      )
+    )
+  )
+ORDER BY
+  id;
+
+-- name: CreateEphemeralSSHKey :one
+INSERT INTO
+  ephemeral_ssh_keys (
+    instance_id,
+    os_user,
+    public_key,
+    fingerprint,
+    pushed_by,
+    expires_at
+  )
+VALUES
+  (?, ?, ?, ?, ?, datetime(sqlc.arg(expires_at), 'unixepoch')) RETURNING *;
+
+-- name: ListActiveEphemeralSSHKeys :many
+SELECT
+  *
+FROM
+  ephemeral_ssh_keys
+WHERE
+  instance_id = sqlc.arg(instance_id)
+  AND os_user = sqlc.arg(os_user)
+  AND unixepoch(expires_at) > sqlc.arg(now)
+ORDER BY
+  id;
+
+-- name: DeleteExpiredEphemeralSSHKeys :execrows
+DELETE FROM
+  ephemeral_ssh_keys
+WHERE
+  unixepoch(expires_at) <= sqlc.arg(before);
+
+-- ===== CERTIFICATES QUERIES =====
+-- name: CreateCertificate :one
+INSERT INTO
+  certificates (fingerprint, name, role, restricted, projects, certificate)
+VALUES
+  (?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: GetCertificate :one
+SELECT
+  *
+FROM
+  certificates
+WHERE
+  fingerprint = ?;
+
+-- name: ListCertificates :many
+SELECT
+  *
+FROM
+  certificates
+ORDER BY
+  name ASC;
+
+-- name: UpdateCertificate :one
+UPDATE
+  certificates
+SET
+  name = ?,
+  role = ?,
+  restricted = ?,
+  projects = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  fingerprint = ? RETURNING *;
+
+-- name: DeleteCertificate :exec
+DELETE FROM
+  certificates
+WHERE
+  fingerprint = ?;
+
+-- ===== CERTIFICATE TOKENS QUERIES =====
+-- name: CreateCertificateToken :one
+INSERT INTO
+  certificate_tokens (name, secret, role, restricted, projects, expires_at)
+VALUES
+  (?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: ClaimCertificateToken :one
+DELETE FROM
+  certificate_tokens
+WHERE
+  secret = ? RETURNING *;
+
+-- name: ListCertificateTokens :many
+SELECT
+  *
+FROM
+  certificate_tokens
+WHERE
+  expires_at > CURRENT_TIMESTAMP
+ORDER BY
+  created_at DESC;
+
+-- name: DeleteExpiredCertificateTokens :exec
+DELETE FROM
+  certificate_tokens
+WHERE
+  expires_at <= CURRENT_TIMESTAMP;
+
+-- ===== SIGNING KEYS QUERIES =====
+-- name: CreateSigningKey :one
+INSERT INTO
+  signing_keys (kid, algorithm, private_key, certificate, expires_at)
+VALUES
+  (?, ?, ?, ?, datetime(sqlc.arg(expires_at), 'unixepoch')) RETURNING *;
+
+-- name: ListSigningKeys :many
+SELECT
+  *
+FROM
+  signing_keys
+WHERE
+  unixepoch(expires_at) > sqlc.arg(now)
+ORDER BY
+  id DESC;
+
+-- name: UpdateSigningKey :exec
+UPDATE
+  signing_keys
+SET
+  private_key = ?
+WHERE
+  id = ?;
+
+-- name: DeleteExpiredSigningKeys :execrows
+DELETE FROM
+  signing_keys
+WHERE
+  unixepoch(expires_at) <= sqlc.arg(before);
+
+-- ===== SECRETS QUERIES =====
+-- name: CreateSecret :one
+INSERT INTO
+  secrets (
+    name,
+    project,
+    remote,
+    scope,
+    target,
+    key_id,
+    wrapped_key,
+    ciphertext,
+    one_time,
+    created_by
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING *;
+
+-- name: GetSecret :one
+SELECT
+  *
+FROM
+  secrets
+WHERE
+  id = ?;
+
+-- name: ListSecrets :many
+SELECT
+  *
+FROM
+  secrets
+WHERE
+  remote = ?
+  AND project = ?
+ORDER BY
+  id;
+
+-- name: UpdateSecret :one
+UPDATE
+  secrets
+SET
+  key_id = ?,
+  wrapped_key = ?,
+  ciphertext = ?,
+  one_time = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = sqlc.arg(id) RETURNING *;
+
+-- name: DeleteSecret :execrows
+DELETE FROM
+  secrets
+WHERE
+  id = ?;
+
+-- name: ListInstanceSecrets :many
+SELECT
+  sqlc.embed(secrets),
+  CAST(
+    secrets.one_time
+    AND EXISTS (
+      SELECT
+        1
+      FROM
+        secret_reads
+      WHERE
+        secret_reads.secret_id = secrets.id
+        AND secret_reads.instance_id = sqlc.arg(instance_id)
+        AND secret_reads.instance_uuid IS sqlc.arg(instance_uuid)
+    ) AS BOOLEAN
+  ) AS consumed
+FROM
+  secrets
+WHERE
+  secrets.remote = sqlc.arg(remote)
+  AND secrets.project = sqlc.arg(project)
+  AND (
+    secrets.scope = 'project'
+    OR (
+      secrets.scope = 'instance'
+      AND secrets.target = sqlc.arg(instance_name)
+    )
+    OR (
+      secrets.scope = 'profile'
+      AND secrets.target IN (
+        SELECT
+          profile
+        FROM
+          instance_profiles
+        WHERE
+          instance_id = sqlc.arg(instance_id)
+      )
+    )
+  )
+ORDER BY
+  CASE
+    secrets.scope
+    WHEN 'project' THEN 0
+    WHEN 'profile' THEN 1
+    ELSE 2
+  END,
+  secrets.id;
+
+-- name: ClaimSecretRead :execrows
+INSERT INTO
+  secret_reads (secret_id, instance_id, instance_uuid)
+VALUES
+  (?1, ?2, ?3) ON CONFLICT(secret_id, instance_id) DO
+UPDATE
+SET
+  instance_uuid = excluded.instance_uuid,
+  read_at = CURRENT_TIMESTAMP
+WHERE
+  secret_reads.instance_uuid IS NOT excluded.instance_uuid;
+
+-- name: DeleteSecretReads :exec
+DELETE FROM
+  secret_reads
+WHERE
+  secret_id = ?;
+
+-- name: DeleteOrphanedSecretReads :execrows
+DELETE FROM
+  secret_reads
+WHERE
+  secret_id NOT IN (
+    SELECT
+      id
+    FROM
+      secrets
+  )
+  OR instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  );
+
+-- ===== INSTANCE PASSWORDS QUERIES =====
+-- name: CreateInstancePassword :execrows
+INSERT INTO
+  instance_passwords (
+    instance_id,
+    instance_uuid,
+    key_fingerprint,
+    encrypted_password
+  )
+VALUES
+  (?1, ?2, ?3, ?4) ON CONFLICT(instance_id) DO
+UPDATE
+SET
+  instance_uuid = excluded.instance_uuid,
+  key_fingerprint = excluded.key_fingerprint,
+  encrypted_password = excluded.encrypted_password,
+  created_at = CURRENT_TIMESTAMP,
+  retrieved_at = NULL,
+  retrieved_by = NULL
+WHERE
+  instance_passwords.instance_uuid IS NOT excluded.instance_uuid;
+
+-- name: GetInstancePassword :one
+SELECT
+  *
+FROM
+  instance_passwords
+WHERE
+  instance_id = ?;
+
+-- name: ClaimInstancePassword :execrows
+UPDATE
+  instance_passwords
+SET
+  encrypted_password = NULL,
+  retrieved_at = CURRENT_TIMESTAMP,
+  retrieved_by = ?
+WHERE
+  id = ?
+  AND retrieved_at IS NULL;
+
+-- name: DeleteInstancePassword :execrows
+DELETE FROM
+  instance_passwords
+WHERE
+  instance_id = ?;
+
+-- name: DeleteOrphanedInstancePasswords :execrows
+DELETE FROM
+  instance_passwords
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  );
@@ -10,14 +10,217 @@ import (
 	"time"
 )
 
+const claimCertificateToken = `-- name: ClaimCertificateToken :one
+DELETE FROM
+  certificate_tokens
+WHERE
+  secret = ? RETURNING id, name, secret, role, restricted, projects, expires_at, created_at
+`
+
+func (q *Queries) ClaimCertificateToken(ctx context.Context, secret string) (CertificateToken, error) {
+	row := q.queryRow(ctx, q.claimCertificateTokenStmt, claimCertificateToken, secret)
+	var i CertificateToken
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Secret,
+		&i.Role,
+		&i.Restricted,
+		&i.Projects,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const claimInstancePassword = `-- name: ClaimInstancePassword :execrows
+UPDATE
+  instance_passwords
+SET
+  encrypted_password = NULL,
+  retrieved_at = CURRENT_TIMESTAMP,
+  retrieved_by = ?
+WHERE
+  id = ?
+  AND retrieved_at IS NULL
+`
+
+type ClaimInstancePasswordParams struct {
+	RetrievedBy *string
+	ID          int64
+}
+
+func (q *Queries) ClaimInstancePassword(ctx context.Context, arg ClaimInstancePasswordParams) (int64, error) {
+	result, err := q.exec(ctx, q.claimInstancePasswordStmt, claimInstancePassword, arg.RetrievedBy, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const claimSecretRead = `-- name: ClaimSecretRead :execrows
+INSERT INTO
+  secret_reads (secret_id, instance_id, instance_uuid)
+VALUES
+  (?1, ?2, ?3) ON CONFLICT(secret_id, instance_id) DO
+UPDATE
+SET
+  instance_uuid = excluded.instance_uuid,
+  read_at = CURRENT_TIMESTAMP
+WHERE
+  secret_reads.instance_uuid IS NOT excluded.instance_uuid
+`
+
+type ClaimSecretReadParams struct {
+	SecretID     int64
+	InstanceID   int64
+	InstanceUuid *string
+}
+
+func (q *Queries) ClaimSecretRead(ctx context.Context, arg ClaimSecretReadParams) (int64, error) {
+	result, err := q.exec(ctx, q.claimSecretReadStmt, claimSecretRead, arg.SecretID, arg.InstanceID, arg.InstanceUuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createCertificate = `-- name: CreateCertificate :one
+INSERT INTO
+  certificates (fingerprint, name, role, restricted, projects, certificate)
+VALUES
+  (?, ?, ?, ?, ?, ?) RETURNING id, fingerprint, name, role, restricted, projects, certificate, created_at, updated_at
+`
+
+type CreateCertificateParams struct {
+	Fingerprint string
+	Name        string
+	Role        string
+	Restricted  bool
+	Projects    interface{}
+	Certificate string
+}
+
+// ===== CERTIFICATES QUERIES =====
+func (q *Queries) CreateCertificate(ctx context.Context, arg CreateCertificateParams) (Certificate, error) {
+	row := q.queryRow(ctx, q.createCertificateStmt, createCertificate,
+		arg.Fingerprint,
+		arg.Name,
+		arg.Role,
+		arg.Restricted,
+		arg.Projects,
+		arg.Certificate,
+	)
+	var i Certificate
+	err := row.Scan(
+		&i.ID,
+		&i.Fingerprint,
+		&i.Name,
+		&i.Role,
+		&i.Restricted,
+		&i.Projects,
+		&i.Certificate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createCertificateToken = `-- name: CreateCertificateToken :one
+INSERT INTO
+  certificate_tokens (name, secret, role, restricted, projects, expires_at)
+VALUES
+  (?, ?, ?, ?, ?, ?) RETURNING id, name, secret, role, restricted, projects, expires_at, created_at
+`
+
+type CreateCertificateTokenParams struct {
+	Name       string
+	Secret     string
+	Role       string
+	Restricted bool
+	Projects   interface{}
+	ExpiresAt  time.Time
+}
+
+// ===== CERTIFICATE TOKENS QUERIES =====
+func (q *Queries) CreateCertificateToken(ctx context.Context, arg CreateCertificateTokenParams) (CertificateToken, error) {
+	row := q.queryRow(ctx, q.createCertificateTokenStmt, createCertificateToken,
+		arg.Name,
+		arg.Secret,
+		arg.Role,
+		arg.Restricted,
+		arg.Projects,
+		arg.ExpiresAt,
+	)
+	var i CertificateToken
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Secret,
+		&i.Role,
+		&i.Restricted,
+		&i.Projects,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createEphemeralSSHKey = `-- name: CreateEphemeralSSHKey :one
+INSERT INTO
+  ephemeral_ssh_keys (
+    instance_id,
+    os_user,
+    public_key,
+    fingerprint,
+    pushed_by,
+    expires_at
+  )
+VALUES
+  (?, ?, ?, ?, ?, datetime(?, 'unixepoch')) RETURNING id, instance_id, os_user, public_key, fingerprint, pushed_by, expires_at, created_at
+`
+
+type CreateEphemeralSSHKeyParams struct {
+	InstanceID  int64
+	OsUser      string
+	PublicKey   string
+	Fingerprint string
+	PushedBy    string
+	ExpiresAt   int64
+}
+
+func (q *Queries) CreateEphemeralSSHKey(ctx context.Context, arg CreateEphemeralSSHKeyParams) (EphemeralSshKey, error) {
+	row := q.queryRow(ctx, q.createEphemeralSSHKeyStmt, createEphemeralSSHKey,
+		arg.InstanceID,
+		arg.OsUser,
+		arg.PublicKey,
+		arg.Fingerprint,
+		arg.PushedBy,
+		arg.ExpiresAt,
+	)
+	var i EphemeralSshKey
+	err := row.Scan(
+		&i.ID,
+		&i.InstanceID,
+		&i.OsUser,
+		&i.PublicKey,
+		&i.Fingerprint,
+		&i.PushedBy,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createInstance = `-- name: CreateInstance :one
 INSERT INTO
-  instances (name, project, ip_address)
+  instances (remote, name, project, ip_address)
 VALUES
-  (?, ?, ?) RETURNING id, name, project, ip_address, created_at, updated_at, deleted_at
+  (?, ?, ?, ?) RETURNING id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
 `
 
 type CreateInstanceParams struct {
+	Remote    string
 	Name      string
 	Project   string
 	IpAddress *string
@@ -25,16 +228,98 @@ type CreateInstanceParams struct {
 
 // ===== INSTANCES QUERIES =====
 func (q *Queries) CreateInstance(ctx context.Context, arg CreateInstanceParams) (Instance, error) {
-	row := q.queryRow(ctx, q.createInstanceStmt, createInstance, arg.Name, arg.Project, arg.IpAddress)
+	row := q.queryRow(ctx, q.createInstanceStmt, createInstance,
+		arg.Remote,
+		arg.Name,
+		arg.Project,
+		arg.IpAddress,
+	)
 	var i Instance
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Project,
+		&i.Remote,
 		&i.IpAddress,
+		&i.VsockID,
+		&i.Uuid,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.InvalidatedAt,
+	)
+	return i, err
+}
+
+const createInstanceAddress = `-- name: CreateInstanceAddress :exec
+INSERT INTO
+  instance_addresses (instance_id, network, ip_address, mac_address)
+VALUES
+  (?, ?, ?, ?) ON CONFLICT(instance_id, network, ip_address) DO
+UPDATE
+SET
+  mac_address = excluded.mac_address
+`
+
+type CreateInstanceAddressParams struct {
+	InstanceID int64
+	Network    string
+	IpAddress  string
+	MacAddress string
+}
+
+// ===== INSTANCE ADDRESS QUERIES =====
+func (q *Queries) CreateInstanceAddress(ctx context.Context, arg CreateInstanceAddressParams) error {
+	_, err := q.exec(ctx, q.createInstanceAddressStmt, createInstanceAddress,
+		arg.InstanceID,
+		arg.Network,
+		arg.IpAddress,
+		arg.MacAddress,
+	)
+	return err
+}
+
+const createInstanceHostKey = `-- name: CreateInstanceHostKey :one
+INSERT INTO
+  instance_host_keys (
+    instance_id,
+    instance_uuid,
+    key_type,
+    public_key,
+    fingerprint
+  )
+VALUES
+  (?, ?, ?, ?, ?) RETURNING id, instance_id, instance_uuid, key_type, public_key, fingerprint, created_at, last_seen_at, revoked_at, revoked_reason
+`
+
+type CreateInstanceHostKeyParams struct {
+	InstanceID   int64
+	InstanceUuid *string
+	KeyType      string
+	PublicKey    string
+	Fingerprint  string
+}
+
+func (q *Queries) CreateInstanceHostKey(ctx context.Context, arg CreateInstanceHostKeyParams) (InstanceHostKey, error) {
+	row := q.queryRow(ctx, q.createInstanceHostKeyStmt, createInstanceHostKey,
+		arg.InstanceID,
+		arg.InstanceUuid,
+		arg.KeyType,
+		arg.PublicKey,
+		arg.Fingerprint,
+	)
+	var i InstanceHostKey
+	err := row.Scan(
+		&i.ID,
+		&i.InstanceID,
+		&i.InstanceUuid,
+		&i.KeyType,
+		&i.PublicKey,
+		&i.Fingerprint,
+		&i.CreatedAt,
+		&i.LastSeenAt,
+		&i.RevokedAt,
+		&i.RevokedReason,
 	)
 	return i, err
 }
@@ -73,6 +358,88 @@ func (q *Queries) CreateInstanceLog(ctx context.Context, arg CreateInstanceLogPa
 	return i, err
 }
 
+const createInstancePassword = `-- name: CreateInstancePassword :execrows
+INSERT INTO
+  instance_passwords (
+    instance_id,
+    instance_uuid,
+    key_fingerprint,
+    encrypted_password
+  )
+VALUES
+  (?1, ?2, ?3, ?4) ON CONFLICT(instance_id) DO
+UPDATE
+SET
+  instance_uuid = excluded.instance_uuid,
+  key_fingerprint = excluded.key_fingerprint,
+  encrypted_password = excluded.encrypted_password,
+  created_at = CURRENT_TIMESTAMP,
+  retrieved_at = NULL,
+  retrieved_by = NULL
+WHERE
+  instance_passwords.instance_uuid IS NOT excluded.instance_uuid
+`
+
+type CreateInstancePasswordParams struct {
+	InstanceID        int64
+	InstanceUuid      *string
+	KeyFingerprint    string
+	EncryptedPassword []byte
+}
+
+// ===== INSTANCE PASSWORDS QUERIES =====
+func (q *Queries) CreateInstancePassword(ctx context.Context, arg CreateInstancePasswordParams) (int64, error) {
+	result, err := q.exec(ctx, q.createInstancePasswordStmt, createInstancePassword,
+		arg.InstanceID,
+		arg.InstanceUuid,
+		arg.KeyFingerprint,
+		arg.EncryptedPassword,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createInstanceProfile = `-- name: CreateInstanceProfile :exec
+INSERT INTO
+  instance_profiles (instance_id, profile)
+VALUES
+  (?, ?) ON CONFLICT(instance_id, profile) DO NOTHING
+`
+
+type CreateInstanceProfileParams struct {
+	InstanceID int64
+	Profile    string
+}
+
+func (q *Queries) CreateInstanceProfile(ctx context.Context, arg CreateInstanceProfileParams) error {
+	_, err := q.exec(ctx, q.createInstanceProfileStmt, createInstanceProfile, arg.InstanceID, arg.Profile)
+	return err
+}
+
+const createInstanceTag = `-- name: CreateInstanceTag :exec
+INSERT INTO
+  instance_tags (instance_id, key, value)
+VALUES
+  (?, ?, ?) ON CONFLICT(instance_id, key) DO
+UPDATE
+SET
+  value = excluded.value
+`
+
+type CreateInstanceTagParams struct {
+	InstanceID int64
+	Key        string
+	Value      string
+}
+
+// ===== INSTANCE TAGS QUERIES =====
+func (q *Queries) CreateInstanceTag(ctx context.Context, arg CreateInstanceTagParams) error {
+	_, err := q.exec(ctx, q.createInstanceTagStmt, createInstanceTag, arg.InstanceID, arg.Key, arg.Value)
+	return err
+}
+
 const createOrUpdateInstanceState = `-- name: CreateOrUpdateInstanceState :one
 INSERT INTO
   instance_state (instance_id, status, status_code, updated_at)
@@ -132,6 +499,181 @@ func (q *Queries) CreateProfile(ctx context.Context, arg CreateProfileParams) (P
 	return i, err
 }
 
+const createSSHKey = `-- name: CreateSSHKey :one
+INSERT INTO
+  ssh_keys (
+    owner,
+    comment,
+    key_type,
+    public_key,
+    fingerprint,
+    project,
+    remote,
+    scope,
+    target,
+    expires_at
+  )
+VALUES
+  (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    datetime(?, 'unixepoch')
+  ) RETURNING id, owner, comment, key_type, public_key, fingerprint, project, remote, scope, target, expires_at, created_at, updated_at
+`
+
+type CreateSSHKeyParams struct {
+	Owner       string
+	Comment     string
+	KeyType     string
+	PublicKey   string
+	Fingerprint string
+	Project     string
+	Remote      string
+	Scope       string
+	Target      string
+	ExpiresAt   *int64
+}
+
+func (q *Queries) CreateSSHKey(ctx context.Context, arg CreateSSHKeyParams) (SshKey, error) {
+	row := q.queryRow(ctx, q.createSSHKeyStmt, createSSHKey,
+		arg.Owner,
+		arg.Comment,
+		arg.KeyType,
+		arg.PublicKey,
+		arg.Fingerprint,
+		arg.Project,
+		arg.Remote,
+		arg.Scope,
+		arg.Target,
+		arg.ExpiresAt,
+	)
+	var i SshKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Comment,
+		&i.KeyType,
+		&i.PublicKey,
+		&i.Fingerprint,
+		&i.Project,
+		&i.Remote,
+		&i.Scope,
+		&i.Target,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createSecret = `-- name: CreateSecret :one
+INSERT INTO
+  secrets (
+    name,
+    project,
+    remote,
+    scope,
+    target,
+    key_id,
+    wrapped_key,
+    ciphertext,
+    one_time,
+    created_by
+  )
+VALUES
+  (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, name, project, remote, scope, target, key_id, wrapped_key, ciphertext, one_time, created_by, created_at, updated_at
+`
+
+type CreateSecretParams struct {
+	Name       string
+	Project    string
+	Remote     string
+	Scope      string
+	Target     string
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+	OneTime    bool
+	CreatedBy  string
+}
+
+// ===== SECRETS QUERIES =====
+func (q *Queries) CreateSecret(ctx context.Context, arg CreateSecretParams) (Secret, error) {
+	row := q.queryRow(ctx, q.createSecretStmt, createSecret,
+		arg.Name,
+		arg.Project,
+		arg.Remote,
+		arg.Scope,
+		arg.Target,
+		arg.KeyID,
+		arg.WrappedKey,
+		arg.Ciphertext,
+		arg.OneTime,
+		arg.CreatedBy,
+	)
+	var i Secret
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Project,
+		&i.Remote,
+		&i.Scope,
+		&i.Target,
+		&i.KeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.OneTime,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const createSigningKey = `-- name: CreateSigningKey :one
+INSERT INTO
+  signing_keys (kid, algorithm, private_key, certificate, expires_at)
+VALUES
+  (?, ?, ?, ?, datetime(?, 'unixepoch')) RETURNING id, kid, algorithm, private_key, certificate, expires_at, created_at
+`
+
+type CreateSigningKeyParams struct {
+	Kid         string
+	Algorithm   string
+	PrivateKey  string
+	Certificate string
+	ExpiresAt   int64
+}
+
+// ===== SIGNING KEYS QUERIES =====
+func (q *Queries) CreateSigningKey(ctx context.Context, arg CreateSigningKeyParams) (SigningKey, error) {
+	row := q.queryRow(ctx, q.createSigningKeyStmt, createSigningKey,
+		arg.Kid,
+		arg.Algorithm,
+		arg.PrivateKey,
+		arg.Certificate,
+		arg.ExpiresAt,
+	)
+	var i SigningKey
+	err := row.Scan(
+		&i.ID,
+		&i.Kid,
+		&i.Algorithm,
+		&i.PrivateKey,
+		&i.Certificate,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createVendorData = `-- name: CreateVendorData :one
 INSERT INTO
   vendor_data (name, description, data)
@@ -160,69 +702,431 @@ func (q *Queries) CreateVendorData(ctx context.Context, arg CreateVendorDataPara
 	return i, err
 }
 
-const deleteInstance = `-- name: DeleteInstance :exec
-UPDATE
-  instances
-SET
-  deleted_at = CURRENT_TIMESTAMP
+const deleteCertificate = `-- name: DeleteCertificate :exec
+DELETE FROM
+  certificates
 WHERE
-  id = ?
+  fingerprint = ?
 `
 
-func (q *Queries) DeleteInstance(ctx context.Context, id int64) error {
-	_, err := q.exec(ctx, q.deleteInstanceStmt, deleteInstance, id)
+func (q *Queries) DeleteCertificate(ctx context.Context, fingerprint string) error {
+	_, err := q.exec(ctx, q.deleteCertificateStmt, deleteCertificate, fingerprint)
 	return err
 }
 
-const deleteInstanceLogs = `-- name: DeleteInstanceLogs :exec
+const deleteExcessInstanceLogs = `-- name: DeleteExcessInstanceLogs :execrows
 DELETE FROM
   instance_logs
 WHERE
-  instance_id = ?
+  id IN (
+    SELECT
+      id
+    FROM
+      (
+        SELECT
+          id,
+          ROW_NUMBER() OVER (
+            PARTITION BY
+              instance_id
+            ORDER BY
+              id DESC
+          ) AS position
+        FROM
+          instance_logs
+        WHERE
+          log_type = ?1
+      )
+    WHERE
+      position > ?2
+    LIMIT
+      ?3
+  )
 `
 
-func (q *Queries) DeleteInstanceLogs(ctx context.Context, instanceID int64) error {
-	_, err := q.exec(ctx, q.deleteInstanceLogsStmt, deleteInstanceLogs, instanceID)
-	return err
+type DeleteExcessInstanceLogsParams struct {
+	LogType string
+	MaxRows int64
+	Limit   int64
 }
 
-const deleteInstanceState = `-- name: DeleteInstanceState :exec
+func (q *Queries) DeleteExcessInstanceLogs(ctx context.Context, arg DeleteExcessInstanceLogsParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteExcessInstanceLogsStmt, deleteExcessInstanceLogs,
+		arg.LogType,
+		arg.MaxRows,
+		arg.Limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteExpiredCertificateTokens = `-- name: DeleteExpiredCertificateTokens :exec
 DELETE FROM
-  instance_state
+  certificate_tokens
 WHERE
-  instance_id = ?
+  expires_at <= CURRENT_TIMESTAMP
 `
 
-func (q *Queries) DeleteInstanceState(ctx context.Context, instanceID int64) error {
-	_, err := q.exec(ctx, q.deleteInstanceStateStmt, deleteInstanceState, instanceID)
+func (q *Queries) DeleteExpiredCertificateTokens(ctx context.Context) error {
+	_, err := q.exec(ctx, q.deleteExpiredCertificateTokensStmt, deleteExpiredCertificateTokens)
 	return err
 }
 
-const deleteOldInstanceLogs = `-- name: DeleteOldInstanceLogs :exec
+const deleteExpiredEphemeralSSHKeys = `-- name: DeleteExpiredEphemeralSSHKeys :execrows
+DELETE FROM
+  ephemeral_ssh_keys
+WHERE
+  unixepoch(expires_at) <= ?
+`
+
+func (q *Queries) DeleteExpiredEphemeralSSHKeys(ctx context.Context, before int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteExpiredEphemeralSSHKeysStmt, deleteExpiredEphemeralSSHKeys, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteExpiredInstanceLogs = `-- name: DeleteExpiredInstanceLogs :execrows
 DELETE FROM
   instance_logs
 WHERE
-  created_at < ?
-  AND instance_id = ?
+  id IN (
+    SELECT
+      id
+    FROM
+      instance_logs
+    WHERE
+      log_type = ?1
+      AND created_at < datetime(?2, 'unixepoch')
+    LIMIT
+      ?3
+  )
 `
 
-type DeleteOldInstanceLogsParams struct {
-	CreatedAt  *time.Time
-	InstanceID int64
+type DeleteExpiredInstanceLogsParams struct {
+	LogType string
+	Before  int64
+	Limit   int64
 }
 
-func (q *Queries) DeleteOldInstanceLogs(ctx context.Context, arg DeleteOldInstanceLogsParams) error {
-	_, err := q.exec(ctx, q.deleteOldInstanceLogsStmt, deleteOldInstanceLogs, arg.CreatedAt, arg.InstanceID)
-	return err
+func (q *Queries) DeleteExpiredInstanceLogs(ctx context.Context, arg DeleteExpiredInstanceLogsParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteExpiredInstanceLogsStmt, deleteExpiredInstanceLogs,
+		arg.LogType,
+		arg.Before,
+		arg.Limit,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-const deleteProfile = `-- name: DeleteProfile :exec
-UPDATE
-  profiles
-SET
-  deleted_at = CURRENT_TIMESTAMP
+const deleteExpiredSigningKeys = `-- name: DeleteExpiredSigningKeys :execrows
+DELETE FROM
+  signing_keys
 WHERE
-  id = ?
+  unixepoch(expires_at) <= ?
+`
+
+func (q *Queries) DeleteExpiredSigningKeys(ctx context.Context, before int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteExpiredSigningKeysStmt, deleteExpiredSigningKeys, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteInstance = `-- name: DeleteInstance :exec
+UPDATE
+  instances
+SET
+  deleted_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?
+`
+
+func (q *Queries) DeleteInstance(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.deleteInstanceStmt, deleteInstance, id)
+	return err
+}
+
+const deleteInstanceAddresses = `-- name: DeleteInstanceAddresses :exec
+DELETE FROM
+  instance_addresses
+WHERE
+  instance_id = ?
+`
+
+func (q *Queries) DeleteInstanceAddresses(ctx context.Context, instanceID int64) error {
+	_, err := q.exec(ctx, q.deleteInstanceAddressesStmt, deleteInstanceAddresses, instanceID)
+	return err
+}
+
+const deleteInstanceLogs = `-- name: DeleteInstanceLogs :exec
+DELETE FROM
+  instance_logs
+WHERE
+  instance_id = ?
+`
+
+func (q *Queries) DeleteInstanceLogs(ctx context.Context, instanceID int64) error {
+	_, err := q.exec(ctx, q.deleteInstanceLogsStmt, deleteInstanceLogs, instanceID)
+	return err
+}
+
+const deleteInstancePassword = `-- name: DeleteInstancePassword :execrows
+DELETE FROM
+  instance_passwords
+WHERE
+  instance_id = ?
+`
+
+func (q *Queries) DeleteInstancePassword(ctx context.Context, instanceID int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteInstancePasswordStmt, deleteInstancePassword, instanceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteInstanceProfiles = `-- name: DeleteInstanceProfiles :exec
+DELETE FROM
+  instance_profiles
+WHERE
+  instance_id = ?
+`
+
+func (q *Queries) DeleteInstanceProfiles(ctx context.Context, instanceID int64) error {
+	_, err := q.exec(ctx, q.deleteInstanceProfilesStmt, deleteInstanceProfiles, instanceID)
+	return err
+}
+
+const deleteInstanceState = `-- name: DeleteInstanceState :exec
+DELETE FROM
+  instance_state
+WHERE
+  instance_id = ?
+`
+
+func (q *Queries) DeleteInstanceState(ctx context.Context, instanceID int64) error {
+	_, err := q.exec(ctx, q.deleteInstanceStateStmt, deleteInstanceState, instanceID)
+	return err
+}
+
+const deleteInstanceTags = `-- name: DeleteInstanceTags :exec
+DELETE FROM
+  instance_tags
+WHERE
+  instance_id = ?
+`
+
+func (q *Queries) DeleteInstanceTags(ctx context.Context, instanceID int64) error {
+	_, err := q.exec(ctx, q.deleteInstanceTagsStmt, deleteInstanceTags, instanceID)
+	return err
+}
+
+const deleteOldInstanceLogs = `-- name: DeleteOldInstanceLogs :exec
+DELETE FROM
+  instance_logs
+WHERE
+  created_at < ?
+  AND instance_id = ?
+`
+
+type DeleteOldInstanceLogsParams struct {
+	CreatedAt  *time.Time
+	InstanceID int64
+}
+
+func (q *Queries) DeleteOldInstanceLogs(ctx context.Context, arg DeleteOldInstanceLogsParams) error {
+	_, err := q.exec(ctx, q.deleteOldInstanceLogsStmt, deleteOldInstanceLogs, arg.CreatedAt, arg.InstanceID)
+	return err
+}
+
+const deleteOrphanedInstanceAddresses = `-- name: DeleteOrphanedInstanceAddresses :execrows
+DELETE FROM
+  instance_addresses
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  )
+`
+
+func (q *Queries) DeleteOrphanedInstanceAddresses(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanedInstanceAddressesStmt, deleteOrphanedInstanceAddresses)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedInstanceHostKeys = `-- name: DeleteOrphanedInstanceHostKeys :execrows
+DELETE FROM
+  instance_host_keys
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  )
+`
+
+func (q *Queries) DeleteOrphanedInstanceHostKeys(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanedInstanceHostKeysStmt, deleteOrphanedInstanceHostKeys)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedInstanceLogs = `-- name: DeleteOrphanedInstanceLogs :execrows
+DELETE FROM
+  instance_logs
+WHERE
+  id IN (
+    SELECT
+      id
+    FROM
+      instance_logs
+    WHERE
+      instance_id NOT IN (
+        SELECT
+          id
+        FROM
+          instances
+      )
+    LIMIT
+      ?
+  )
+`
+
+func (q *Queries) DeleteOrphanedInstanceLogs(ctx context.Context, limit int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanedInstanceLogsStmt, deleteOrphanedInstanceLogs, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedInstancePasswords = `-- name: DeleteOrphanedInstancePasswords :execrows
+DELETE FROM
+  instance_passwords
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  )
+`
+
+func (q *Queries) DeleteOrphanedInstancePasswords(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanedInstancePasswordsStmt, deleteOrphanedInstancePasswords)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedInstanceProfiles = `-- name: DeleteOrphanedInstanceProfiles :execrows
+DELETE FROM
+  instance_profiles
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  )
+`
+
+func (q *Queries) DeleteOrphanedInstanceProfiles(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanedInstanceProfilesStmt, deleteOrphanedInstanceProfiles)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedInstanceStates = `-- name: DeleteOrphanedInstanceStates :execrows
+DELETE FROM
+  instance_state
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  )
+`
+
+func (q *Queries) DeleteOrphanedInstanceStates(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanedInstanceStatesStmt, deleteOrphanedInstanceStates)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedInstanceTags = `-- name: DeleteOrphanedInstanceTags :execrows
+DELETE FROM
+  instance_tags
+WHERE
+  instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  )
+`
+
+func (q *Queries) DeleteOrphanedInstanceTags(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanedInstanceTagsStmt, deleteOrphanedInstanceTags)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOrphanedSecretReads = `-- name: DeleteOrphanedSecretReads :execrows
+DELETE FROM
+  secret_reads
+WHERE
+  secret_id NOT IN (
+    SELECT
+      id
+    FROM
+      secrets
+  )
+  OR instance_id NOT IN (
+    SELECT
+      id
+    FROM
+      instances
+  )
+`
+
+func (q *Queries) DeleteOrphanedSecretReads(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteOrphanedSecretReadsStmt, deleteOrphanedSecretReads)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteProfile = `-- name: DeleteProfile :exec
+UPDATE
+  profiles
+SET
+  deleted_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?
 `
 
 func (q *Queries) DeleteProfile(ctx context.Context, id int64) error {
@@ -230,6 +1134,48 @@ func (q *Queries) DeleteProfile(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteSSHKey = `-- name: DeleteSSHKey :execrows
+DELETE FROM
+  ssh_keys
+WHERE
+  id = ?
+`
+
+func (q *Queries) DeleteSSHKey(ctx context.Context, id int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteSSHKeyStmt, deleteSSHKey, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSecret = `-- name: DeleteSecret :execrows
+DELETE FROM
+  secrets
+WHERE
+  id = ?
+`
+
+func (q *Queries) DeleteSecret(ctx context.Context, id int64) (int64, error) {
+	result, err := q.exec(ctx, q.deleteSecretStmt, deleteSecret, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteSecretReads = `-- name: DeleteSecretReads :exec
+DELETE FROM
+  secret_reads
+WHERE
+  secret_id = ?
+`
+
+func (q *Queries) DeleteSecretReads(ctx context.Context, secretID int64) error {
+	_, err := q.exec(ctx, q.deleteSecretReadsStmt, deleteSecretReads, secretID)
+	return err
+}
+
 const deleteVendorData = `-- name: DeleteVendorData :exec
 UPDATE
   vendor_data
@@ -244,40 +1190,123 @@ func (q *Queries) DeleteVendorData(ctx context.Context, id int64) error {
 	return err
 }
 
+const getCertificate = `-- name: GetCertificate :one
+SELECT
+  id, fingerprint, name, role, restricted, projects, certificate, created_at, updated_at
+FROM
+  certificates
+WHERE
+  fingerprint = ?
+`
+
+func (q *Queries) GetCertificate(ctx context.Context, fingerprint string) (Certificate, error) {
+	row := q.queryRow(ctx, q.getCertificateStmt, getCertificate, fingerprint)
+	var i Certificate
+	err := row.Scan(
+		&i.ID,
+		&i.Fingerprint,
+		&i.Name,
+		&i.Role,
+		&i.Restricted,
+		&i.Projects,
+		&i.Certificate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getInstance = `-- name: GetInstance :one
 SELECT
-  id, name, project, ip_address, created_at, updated_at, deleted_at
+  id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
 FROM
   instances
 WHERE
-  name = ?
+  remote = ?
+  AND name = ?
   AND project = ?
   AND deleted_at IS NULL
 `
 
 type GetInstanceParams struct {
+	Remote  string
 	Name    string
 	Project string
 }
 
 func (q *Queries) GetInstance(ctx context.Context, arg GetInstanceParams) (Instance, error) {
-	row := q.queryRow(ctx, q.getInstanceStmt, getInstance, arg.Name, arg.Project)
+	row := q.queryRow(ctx, q.getInstanceStmt, getInstance, arg.Remote, arg.Name, arg.Project)
+	var i Instance
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Project,
+		&i.Remote,
+		&i.IpAddress,
+		&i.VsockID,
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.InvalidatedAt,
+	)
+	return i, err
+}
+
+const getInstanceByAddress = `-- name: GetInstanceByAddress :one
+SELECT
+  instances.id, instances.name, instances.project, instances.remote, instances.ip_address, instances.vsock_id, instances.uuid, instances.created_at, instances.updated_at, instances.deleted_at, instances.invalidated_at
+FROM
+  instances
+  JOIN instance_addresses ON instance_addresses.instance_id = instances.id
+WHERE
+  instances.remote = ?1
+  AND instance_addresses.network = ?2
+  AND instance_addresses.ip_address = ?3
+  AND (
+    ?4 = ''
+    OR instance_addresses.mac_address = ?4
+  )
+  AND instances.deleted_at IS NULL
+  AND instances.invalidated_at IS NULL
+LIMIT
+  1
+`
+
+type GetInstanceByAddressParams struct {
+	Remote     string
+	Network    string
+	IpAddress  string
+	MacAddress string
+}
+
+func (q *Queries) GetInstanceByAddress(ctx context.Context, arg GetInstanceByAddressParams) (Instance, error) {
+	row := q.queryRow(ctx, q.getInstanceByAddressStmt, getInstanceByAddress,
+		arg.Remote,
+		arg.Network,
+		arg.IpAddress,
+		arg.MacAddress,
+	)
 	var i Instance
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Project,
+		&i.Remote,
 		&i.IpAddress,
+		&i.VsockID,
+		&i.Uuid,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.InvalidatedAt,
 	)
 	return i, err
 }
 
 const getInstanceByID = `-- name: GetInstanceByID :one
 SELECT
-  id, name, project, ip_address, created_at, updated_at, deleted_at
+  id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
 FROM
   instances
 WHERE
@@ -292,35 +1321,85 @@ func (q *Queries) GetInstanceByID(ctx context.Context, id int64) (Instance, erro
 		&i.ID,
 		&i.Name,
 		&i.Project,
+		&i.Remote,
 		&i.IpAddress,
+		&i.VsockID,
+		&i.Uuid,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.InvalidatedAt,
 	)
 	return i, err
 }
 
 const getInstanceByIP = `-- name: GetInstanceByIP :one
 SELECT
-  id, name, project, ip_address, created_at, updated_at, deleted_at
+  id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
+FROM
+  instances
+WHERE
+  remote = ?
+  AND ip_address = ?
+  AND deleted_at IS NULL
+`
+
+type GetInstanceByIPParams struct {
+	Remote    string
+	IpAddress *string
+}
+
+func (q *Queries) GetInstanceByIP(ctx context.Context, arg GetInstanceByIPParams) (Instance, error) {
+	row := q.queryRow(ctx, q.getInstanceByIPStmt, getInstanceByIP, arg.Remote, arg.IpAddress)
+	var i Instance
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Project,
+		&i.Remote,
+		&i.IpAddress,
+		&i.VsockID,
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.InvalidatedAt,
+	)
+	return i, err
+}
+
+const getInstanceByVsockID = `-- name: GetInstanceByVsockID :one
+SELECT
+  id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
 FROM
   instances
 WHERE
-  ip_address = ?
+  remote = ?
+  AND vsock_id = ?
   AND deleted_at IS NULL
+  AND invalidated_at IS NULL
 `
 
-func (q *Queries) GetInstanceByIP(ctx context.Context, ipAddress *string) (Instance, error) {
-	row := q.queryRow(ctx, q.getInstanceByIPStmt, getInstanceByIP, ipAddress)
+type GetInstanceByVsockIDParams struct {
+	Remote  string
+	VsockID *int64
+}
+
+func (q *Queries) GetInstanceByVsockID(ctx context.Context, arg GetInstanceByVsockIDParams) (Instance, error) {
+	row := q.queryRow(ctx, q.getInstanceByVsockIDStmt, getInstanceByVsockID, arg.Remote, arg.VsockID)
 	var i Instance
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
 		&i.Project,
+		&i.Remote,
 		&i.IpAddress,
+		&i.VsockID,
+		&i.Uuid,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.InvalidatedAt,
 	)
 	return i, err
 }
@@ -486,6 +1565,31 @@ func (q *Queries) GetInstanceLogsByType(ctx context.Context, arg GetInstanceLogs
 	return items, nil
 }
 
+const getInstancePassword = `-- name: GetInstancePassword :one
+SELECT
+  id, instance_id, instance_uuid, key_fingerprint, encrypted_password, created_at, retrieved_at, retrieved_by
+FROM
+  instance_passwords
+WHERE
+  instance_id = ?
+`
+
+func (q *Queries) GetInstancePassword(ctx context.Context, instanceID int64) (InstancePassword, error) {
+	row := q.queryRow(ctx, q.getInstancePasswordStmt, getInstancePassword, instanceID)
+	var i InstancePassword
+	err := row.Scan(
+		&i.ID,
+		&i.InstanceID,
+		&i.InstanceUuid,
+		&i.KeyFingerprint,
+		&i.EncryptedPassword,
+		&i.CreatedAt,
+		&i.RetrievedAt,
+		&i.RetrievedBy,
+	)
+	return i, err
+}
+
 const getInstanceState = `-- name: GetInstanceState :one
 SELECT
   id, instance_id, status, status_code, updated_at
@@ -538,6 +1642,66 @@ func (q *Queries) GetProfile(ctx context.Context, arg GetProfileParams) (Profile
 	return i, err
 }
 
+const getSSHKey = `-- name: GetSSHKey :one
+SELECT
+  id, owner, comment, key_type, public_key, fingerprint, project, remote, scope, target, expires_at, created_at, updated_at
+FROM
+  ssh_keys
+WHERE
+  id = ?
+`
+
+func (q *Queries) GetSSHKey(ctx context.Context, id int64) (SshKey, error) {
+	row := q.queryRow(ctx, q.getSSHKeyStmt, getSSHKey, id)
+	var i SshKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Comment,
+		&i.KeyType,
+		&i.PublicKey,
+		&i.Fingerprint,
+		&i.Project,
+		&i.Remote,
+		&i.Scope,
+		&i.Target,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getSecret = `-- name: GetSecret :one
+SELECT
+  id, name, project, remote, scope, target, key_id, wrapped_key, ciphertext, one_time, created_by, created_at, updated_at
+FROM
+  secrets
+WHERE
+  id = ?
+`
+
+func (q *Queries) GetSecret(ctx context.Context, id int64) (Secret, error) {
+	row := q.queryRow(ctx, q.getSecretStmt, getSecret, id)
+	var i Secret
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Project,
+		&i.Remote,
+		&i.Scope,
+		&i.Target,
+		&i.KeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.OneTime,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getVendorData = `-- name: GetVendorData :one
 SELECT
   id,
@@ -576,46 +1740,1172 @@ func (q *Queries) GetVendorData(ctx context.Context, name string) (GetVendorData
 	return i, err
 }
 
-const hardDeleteInstance = `-- name: HardDeleteInstance :exec
-DELETE FROM
-  instances
+const hardDeleteInstance = `-- name: HardDeleteInstance :exec
+DELETE FROM
+  instances
+WHERE
+  id = ?
+`
+
+func (q *Queries) HardDeleteInstance(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.hardDeleteInstanceStmt, hardDeleteInstance, id)
+	return err
+}
+
+const invalidateInstance = `-- name: InvalidateInstance :exec
+UPDATE
+  instances
+SET
+  invalidated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?
+`
+
+func (q *Queries) InvalidateInstance(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.invalidateInstanceStmt, invalidateInstance, id)
+	return err
+}
+
+const listActiveEphemeralSSHKeys = `-- name: ListActiveEphemeralSSHKeys :many
+SELECT
+  id, instance_id, os_user, public_key, fingerprint, pushed_by, expires_at, created_at
+FROM
+  ephemeral_ssh_keys
+WHERE
+  instance_id = ?1
+  AND os_user = ?2
+  AND unixepoch(expires_at) > ?3
+ORDER BY
+  id
+`
+
+type ListActiveEphemeralSSHKeysParams struct {
+	InstanceID int64
+	OsUser     string
+	Now        int64
+}
+
+func (q *Queries) ListActiveEphemeralSSHKeys(ctx context.Context, arg ListActiveEphemeralSSHKeysParams) ([]EphemeralSshKey, error) {
+	rows, err := q.query(ctx, q.listActiveEphemeralSSHKeysStmt, listActiveEphemeralSSHKeys, arg.InstanceID, arg.OsUser, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EphemeralSshKey
+	for rows.Next() {
+		var i EphemeralSshKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.InstanceID,
+			&i.OsUser,
+			&i.PublicKey,
+			&i.Fingerprint,
+			&i.PushedBy,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCertificateTokens = `-- name: ListCertificateTokens :many
+SELECT
+  id, name, secret, role, restricted, projects, expires_at, created_at
+FROM
+  certificate_tokens
+WHERE
+  expires_at > CURRENT_TIMESTAMP
+ORDER BY
+  created_at DESC
+`
+
+func (q *Queries) ListCertificateTokens(ctx context.Context) ([]CertificateToken, error) {
+	rows, err := q.query(ctx, q.listCertificateTokensStmt, listCertificateTokens)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CertificateToken
+	for rows.Next() {
+		var i CertificateToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Secret,
+			&i.Role,
+			&i.Restricted,
+			&i.Projects,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCertificates = `-- name: ListCertificates :many
+SELECT
+  id, fingerprint, name, role, restricted, projects, certificate, created_at, updated_at
+FROM
+  certificates
+ORDER BY
+  name ASC
+`
+
+func (q *Queries) ListCertificates(ctx context.Context) ([]Certificate, error) {
+	rows, err := q.query(ctx, q.listCertificatesStmt, listCertificates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Certificate
+	for rows.Next() {
+		var i Certificate
+		if err := rows.Scan(
+			&i.ID,
+			&i.Fingerprint,
+			&i.Name,
+			&i.Role,
+			&i.Restricted,
+			&i.Projects,
+			&i.Certificate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceAddresses = `-- name: ListInstanceAddresses :many
+SELECT
+  instance_addresses.ip_address
+FROM
+  instance_addresses
+  JOIN instances ON instances.id = instance_addresses.instance_id
+WHERE
+  instance_addresses.instance_id = ?
+  AND instances.invalidated_at IS NULL
+ORDER BY
+  instance_addresses.id
+`
+
+func (q *Queries) ListInstanceAddresses(ctx context.Context, instanceID int64) ([]string, error) {
+	rows, err := q.query(ctx, q.listInstanceAddressesStmt, listInstanceAddresses, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var ip_address string
+		if err := rows.Scan(&ip_address); err != nil {
+			return nil, err
+		}
+		items = append(items, ip_address)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceHostKeys = `-- name: ListInstanceHostKeys :many
+SELECT
+  id, instance_id, instance_uuid, key_type, public_key, fingerprint, created_at, last_seen_at, revoked_at, revoked_reason
+FROM
+  instance_host_keys
+WHERE
+  instance_id = ?
+ORDER BY
+  id DESC
+`
+
+func (q *Queries) ListInstanceHostKeys(ctx context.Context, instanceID int64) ([]InstanceHostKey, error) {
+	rows, err := q.query(ctx, q.listInstanceHostKeysStmt, listInstanceHostKeys, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []InstanceHostKey
+	for rows.Next() {
+		var i InstanceHostKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.InstanceID,
+			&i.InstanceUuid,
+			&i.KeyType,
+			&i.PublicKey,
+			&i.Fingerprint,
+			&i.CreatedAt,
+			&i.LastSeenAt,
+			&i.RevokedAt,
+			&i.RevokedReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceLogs = `-- name: ListInstanceLogs :many
+SELECT
+  id, instance_id, log_type, level, message, created_at
+FROM
+  instance_logs
+WHERE
+  instance_id = ?1
+  AND (
+    ?2 = ''
+    OR log_type = ?2
+  )
+  AND (
+    ?3 = ''
+    OR level = ?3
+  )
+  AND (
+    ?4 IS NULL
+    OR unixepoch(created_at) >= ?4
+  )
+  AND (
+    ?5 IS NULL
+    OR unixepoch(created_at) < ?5
+  )
+  AND (
+    ?6 = 0
+    OR id < ?6
+  )
+ORDER BY
+  id DESC
+LIMIT
+  ?7
+`
+
+type ListInstanceLogsParams struct {
+	InstanceID int64
+	LogType    string
+	Level      string
+	Since      *int64
+	Until      *int64
+	BeforeID   int64
+	Limit      int64
+}
+
+func (q *Queries) ListInstanceLogs(ctx context.Context, arg ListInstanceLogsParams) ([]InstanceLog, error) {
+	rows, err := q.query(ctx, q.listInstanceLogsStmt, listInstanceLogs,
+		arg.InstanceID,
+		arg.LogType,
+		arg.Level,
+		arg.Since,
+		arg.Until,
+		arg.BeforeID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []InstanceLog
+	for rows.Next() {
+		var i InstanceLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.InstanceID,
+			&i.LogType,
+			&i.Level,
+			&i.Message,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceLogsAfter = `-- name: ListInstanceLogsAfter :many
+SELECT
+  id, instance_id, log_type, level, message, created_at
+FROM
+  instance_logs
+WHERE
+  instance_id = ?1
+  AND (
+    ?2 = ''
+    OR log_type = ?2
+  )
+  AND (
+    ?3 = ''
+    OR level = ?3
+  )
+  AND id > ?4
+ORDER BY
+  id ASC
+LIMIT
+  ?5
+`
+
+type ListInstanceLogsAfterParams struct {
+	InstanceID int64
+	LogType    string
+	Level      string
+	AfterID    int64
+	Limit      int64
+}
+
+func (q *Queries) ListInstanceLogsAfter(ctx context.Context, arg ListInstanceLogsAfterParams) ([]InstanceLog, error) {
+	rows, err := q.query(ctx, q.listInstanceLogsAfterStmt, listInstanceLogsAfter,
+		arg.InstanceID,
+		arg.LogType,
+		arg.Level,
+		arg.AfterID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []InstanceLog
+	for rows.Next() {
+		var i InstanceLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.InstanceID,
+			&i.LogType,
+			&i.Level,
+			&i.Message,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceProfiles = `-- name: ListInstanceProfiles :many
+SELECT
+  profile
+FROM
+  instance_profiles
+WHERE
+  instance_id = ?
+ORDER BY
+  id
+`
+
+func (q *Queries) ListInstanceProfiles(ctx context.Context, instanceID int64) ([]string, error) {
+	rows, err := q.query(ctx, q.listInstanceProfilesStmt, listInstanceProfiles, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var profile string
+		if err := rows.Scan(&profile); err != nil {
+			return nil, err
+		}
+		items = append(items, profile)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceSSHKeys = `-- name: ListInstanceSSHKeys :many
+SELECT
+  id, owner, comment, key_type, public_key, fingerprint, project, remote, scope, target, expires_at, created_at, updated_at
+FROM
+  ssh_keys
+WHERE
+  remote = ?1
+  AND project = ?2
+  AND (
+    expires_at IS NULL
+    OR unixepoch(expires_at) > ?3
+  )
+  AND (
+    scope = 'project'
+    OR (
+      scope = 'instance'
+      AND target = ?4
+    )
+    OR (
+      scope = 'profile'
+      AND target IN (
+        SELECT
+          profile
+        FROM
+          instance_profiles
+        WHERE
+          instance_id = ?5
+      )
+    )
+  )
+ORDER BY
+  id
+`
+
+type ListInstanceSSHKeysParams struct {
+	Remote       string
+	Project      string
+	Now          int64
+	InstanceName string
+	InstanceID   int64
+}
+
+func (q *Queries) ListInstanceSSHKeys(ctx context.Context, arg ListInstanceSSHKeysParams) ([]SshKey, error) {
+	rows, err := q.query(ctx, q.listInstanceSSHKeysStmt, listInstanceSSHKeys,
+		arg.Remote,
+		arg.Project,
+		arg.Now,
+		arg.InstanceName,
+		arg.InstanceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SshKey
+	for rows.Next() {
+		var i SshKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Comment,
+			&i.KeyType,
+			&i.PublicKey,
+			&i.Fingerprint,
+			&i.Project,
+			&i.Remote,
+			&i.Scope,
+			&i.Target,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceSecrets = `-- name: ListInstanceSecrets :many
+SELECT
+  secrets.id, secrets.name, secrets.project, secrets.remote, secrets.scope, secrets.target, secrets.key_id, secrets.wrapped_key, secrets.ciphertext, secrets.one_time, secrets.created_by, secrets.created_at, secrets.updated_at,
+  CAST(
+    secrets.one_time
+    AND EXISTS (
+      SELECT
+        1
+      FROM
+        secret_reads
+      WHERE
+        secret_reads.secret_id = secrets.id
+        AND secret_reads.instance_id = ?1
+        AND secret_reads.instance_uuid IS ?2
+    ) AS BOOLEAN
+  ) AS consumed
+FROM
+  secrets
+WHERE
+  secrets.remote = ?3
+  AND secrets.project = ?4
+  AND (
+    secrets.scope = 'project'
+    OR (
+      secrets.scope = 'instance'
+      AND secrets.target = ?5
+    )
+    OR (
+      secrets.scope = 'profile'
+      AND secrets.target IN (
+        SELECT
+          profile
+        FROM
+          instance_profiles
+        WHERE
+          instance_id = ?1
+      )
+    )
+  )
+ORDER BY
+  CASE
+    secrets.scope
+    WHEN 'project' THEN 0
+    WHEN 'profile' THEN 1
+    ELSE 2
+  END,
+  secrets.id
+`
+
+type ListInstanceSecretsParams struct {
+	InstanceID   int64
+	InstanceUuid *string
+	Remote       string
+	Project      string
+	InstanceName string
+}
+
+type ListInstanceSecretsRow struct {
+	Secret   Secret
+	Consumed bool
+}
+
+func (q *Queries) ListInstanceSecrets(ctx context.Context, arg ListInstanceSecretsParams) ([]ListInstanceSecretsRow, error) {
+	rows, err := q.query(ctx, q.listInstanceSecretsStmt, listInstanceSecrets,
+		arg.InstanceID,
+		arg.InstanceUuid,
+		arg.Remote,
+		arg.Project,
+		arg.InstanceName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListInstanceSecretsRow
+	for rows.Next() {
+		var i ListInstanceSecretsRow
+		if err := rows.Scan(
+			&i.Secret.ID,
+			&i.Secret.Name,
+			&i.Secret.Project,
+			&i.Secret.Remote,
+			&i.Secret.Scope,
+			&i.Secret.Target,
+			&i.Secret.KeyID,
+			&i.Secret.WrappedKey,
+			&i.Secret.Ciphertext,
+			&i.Secret.OneTime,
+			&i.Secret.CreatedBy,
+			&i.Secret.CreatedAt,
+			&i.Secret.UpdatedAt,
+			&i.Consumed,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceStates = `-- name: ListInstanceStates :many
+SELECT
+  instances.name,
+  instances.project,
+  instance_state.status,
+  instance_state.status_code,
+  instance_state.updated_at
+FROM
+  instance_state
+  JOIN instances ON instances.id = instance_state.instance_id
+WHERE
+  instances.remote = ?1
+  AND instances.project = ?2
+  AND instances.deleted_at IS NULL
+  AND (
+    ?3 = ''
+    OR instance_state.status = ?3
+  )
+  AND (
+    ?4 IS NULL
+    OR unixepoch(instance_state.updated_at) >= ?4
+  )
+ORDER BY
+  instances.name
+`
+
+type ListInstanceStatesParams struct {
+	Remote  string
+	Project string
+	Status  string
+	Since   *int64
+}
+
+type ListInstanceStatesRow struct {
+	Name       string
+	Project    string
+	Status     string
+	StatusCode int64
+	UpdatedAt  *time.Time
+}
+
+func (q *Queries) ListInstanceStates(ctx context.Context, arg ListInstanceStatesParams) ([]ListInstanceStatesRow, error) {
+	rows, err := q.query(ctx, q.listInstanceStatesStmt, listInstanceStates,
+		arg.Remote,
+		arg.Project,
+		arg.Status,
+		arg.Since,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListInstanceStatesRow
+	for rows.Next() {
+		var i ListInstanceStatesRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Project,
+			&i.Status,
+			&i.StatusCode,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstanceTags = `-- name: ListInstanceTags :many
+SELECT
+  id, instance_id, key, value
+FROM
+  instance_tags
+WHERE
+  instance_id = ?
+ORDER BY
+  key
+`
+
+func (q *Queries) ListInstanceTags(ctx context.Context, instanceID int64) ([]InstanceTag, error) {
+	rows, err := q.query(ctx, q.listInstanceTagsStmt, listInstanceTags, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []InstanceTag
+	for rows.Next() {
+		var i InstanceTag
+		if err := rows.Scan(
+			&i.ID,
+			&i.InstanceID,
+			&i.Key,
+			&i.Value,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstances = `-- name: ListInstances :many
+SELECT
+  id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
+FROM
+  instances
+WHERE
+  deleted_at IS NULL
+ORDER BY
+  created_at DESC
+`
+
+func (q *Queries) ListInstances(ctx context.Context) ([]Instance, error) {
+	rows, err := q.query(ctx, q.listInstancesStmt, listInstances)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Instance
+	for rows.Next() {
+		var i Instance
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Project,
+			&i.Remote,
+			&i.IpAddress,
+			&i.VsockID,
+			&i.Uuid,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.InvalidatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstancesByAddressIP = `-- name: ListInstancesByAddressIP :many
+SELECT DISTINCT
+  instances.id, instances.name, instances.project, instances.remote, instances.ip_address, instances.vsock_id, instances.uuid, instances.created_at, instances.updated_at, instances.deleted_at, instances.invalidated_at
+FROM
+  instances
+  JOIN instance_addresses ON instance_addresses.instance_id = instances.id
+WHERE
+  instances.remote = ?1
+  AND instance_addresses.ip_address = ?2
+  AND (
+    ?3 = ''
+    OR instance_addresses.mac_address = ?3
+  )
+  AND instances.deleted_at IS NULL
+  AND instances.invalidated_at IS NULL
+`
+
+type ListInstancesByAddressIPParams struct {
+	Remote     string
+	IpAddress  string
+	MacAddress string
+}
+
+func (q *Queries) ListInstancesByAddressIP(ctx context.Context, arg ListInstancesByAddressIPParams) ([]Instance, error) {
+	rows, err := q.query(ctx, q.listInstancesByAddressIPStmt, listInstancesByAddressIP, arg.Remote, arg.IpAddress, arg.MacAddress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Instance
+	for rows.Next() {
+		var i Instance
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Project,
+			&i.Remote,
+			&i.IpAddress,
+			&i.VsockID,
+			&i.Uuid,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.InvalidatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstancesByProject = `-- name: ListInstancesByProject :many
+SELECT
+  id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
+FROM
+  instances
+WHERE
+  project = ?
+  AND deleted_at IS NULL
+ORDER BY
+  created_at DESC
+`
+
+func (q *Queries) ListInstancesByProject(ctx context.Context, project string) ([]Instance, error) {
+	rows, err := q.query(ctx, q.listInstancesByProjectStmt, listInstancesByProject, project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Instance
+	for rows.Next() {
+		var i Instance
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Project,
+			&i.Remote,
+			&i.IpAddress,
+			&i.VsockID,
+			&i.Uuid,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.InvalidatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInstancesByRemote = `-- name: ListInstancesByRemote :many
+SELECT
+  id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
+FROM
+  instances
+WHERE
+  remote = ?
+  AND deleted_at IS NULL
+ORDER BY
+  created_at DESC
+`
+
+func (q *Queries) ListInstancesByRemote(ctx context.Context, remote string) ([]Instance, error) {
+	rows, err := q.query(ctx, q.listInstancesByRemoteStmt, listInstancesByRemote, remote)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Instance
+	for rows.Next() {
+		var i Instance
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Project,
+			&i.Remote,
+			&i.IpAddress,
+			&i.VsockID,
+			&i.Uuid,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.InvalidatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProfiles = `-- name: ListProfiles :many
+SELECT
+  id, name, project, created_at, updated_at, deleted_at
+FROM
+  profiles
+WHERE
+  deleted_at IS NULL
+ORDER BY
+  created_at DESC
+`
+
+func (q *Queries) ListProfiles(ctx context.Context) ([]Profile, error) {
+	rows, err := q.query(ctx, q.listProfilesStmt, listProfiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Profile
+	for rows.Next() {
+		var i Profile
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Project,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProfilesByProject = `-- name: ListProfilesByProject :many
+SELECT
+  id, name, project, created_at, updated_at, deleted_at
+FROM
+  profiles
+WHERE
+  project = ?
+  AND deleted_at IS NULL
+ORDER BY
+  created_at DESC
+`
+
+func (q *Queries) ListProfilesByProject(ctx context.Context, project string) ([]Profile, error) {
+	rows, err := q.query(ctx, q.listProfilesByProjectStmt, listProfilesByProject, project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Profile
+	for rows.Next() {
+		var i Profile
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Project,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectHostKeys = `-- name: ListProjectHostKeys :many
+SELECT
+  instances.id AS instance_id,
+  instances.name,
+  instance_host_keys.public_key
+FROM
+  instance_host_keys
+  JOIN instances ON instances.id = instance_host_keys.instance_id
+WHERE
+  instances.remote = ?
+  AND instances.project = ?
+  AND instances.deleted_at IS NULL
+  AND instance_host_keys.revoked_at IS NULL
+ORDER BY
+  instances.name,
+  instance_host_keys.key_type
+`
+
+type ListProjectHostKeysParams struct {
+	Remote  string
+	Project string
+}
+
+type ListProjectHostKeysRow struct {
+	InstanceID int64
+	Name       string
+	PublicKey  string
+}
+
+func (q *Queries) ListProjectHostKeys(ctx context.Context, arg ListProjectHostKeysParams) ([]ListProjectHostKeysRow, error) {
+	rows, err := q.query(ctx, q.listProjectHostKeysStmt, listProjectHostKeys, arg.Remote, arg.Project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProjectHostKeysRow
+	for rows.Next() {
+		var i ListProjectHostKeysRow
+		if err := rows.Scan(
+			&i.InstanceID,
+			&i.Name,
+			&i.PublicKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectInstanceAddresses = `-- name: ListProjectInstanceAddresses :many
+SELECT
+  DISTINCT instance_addresses.instance_id,
+  instance_addresses.ip_address
+FROM
+  instance_addresses
+  JOIN instances ON instances.id = instance_addresses.instance_id
 WHERE
-  id = ?
+  instances.remote = ?
+  AND instances.project = ?
+  AND instances.deleted_at IS NULL
+  AND instances.invalidated_at IS NULL
+ORDER BY
+  instance_addresses.instance_id,
+  instance_addresses.ip_address
 `
 
-func (q *Queries) HardDeleteInstance(ctx context.Context, id int64) error {
-	_, err := q.exec(ctx, q.hardDeleteInstanceStmt, hardDeleteInstance, id)
-	return err
+type ListProjectInstanceAddressesParams struct {
+	Remote  string
+	Project string
 }
 
-const listInstances = `-- name: ListInstances :many
+type ListProjectInstanceAddressesRow struct {
+	InstanceID int64
+	IpAddress  string
+}
+
+func (q *Queries) ListProjectInstanceAddresses(ctx context.Context, arg ListProjectInstanceAddressesParams) ([]ListProjectInstanceAddressesRow, error) {
+	rows, err := q.query(ctx, q.listProjectInstanceAddressesStmt, listProjectInstanceAddresses, arg.Remote, arg.Project)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListProjectInstanceAddressesRow
+	for rows.Next() {
+		var i ListProjectInstanceAddressesRow
+		if err := rows.Scan(
+			&i.InstanceID,
+			&i.IpAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProjectInstanceTags = `-- name: ListProjectInstanceTags :many
 SELECT
-  id, name, project, ip_address, created_at, updated_at, deleted_at
+  instances.id AS instance_id,
+  instances.name,
+  instances.uuid,
+  instance_tags.key,
+  instance_tags.value
 FROM
   instances
+  LEFT JOIN instance_tags ON instance_tags.instance_id = instances.id
 WHERE
-  deleted_at IS NULL
+  instances.remote = ?
+  AND instances.project = ?
+  AND instances.deleted_at IS NULL
 ORDER BY
-  created_at DESC
+  instances.name,
+  instance_tags.key
 `
 
-func (q *Queries) ListInstances(ctx context.Context) ([]Instance, error) {
-	rows, err := q.query(ctx, q.listInstancesStmt, listInstances)
+type ListProjectInstanceTagsParams struct {
+	Remote  string
+	Project string
+}
+
+type ListProjectInstanceTagsRow struct {
+	InstanceID int64
+	Name       string
+	Uuid       *string
+	Key        *string
+	Value      *string
+}
+
+func (q *Queries) ListProjectInstanceTags(ctx context.Context, arg ListProjectInstanceTagsParams) ([]ListProjectInstanceTagsRow, error) {
+	rows, err := q.query(ctx, q.listProjectInstanceTagsStmt, listProjectInstanceTags, arg.Remote, arg.Project)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Instance
+	var items []ListProjectInstanceTagsRow
 	for rows.Next() {
-		var i Instance
+		var i ListProjectInstanceTagsRow
 		if err := rows.Scan(
-			&i.ID,
+			&i.InstanceID,
 			&i.Name,
-			&i.Project,
-			&i.IpAddress,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.DeletedAt,
+			&i.Uuid,
+			&i.Key,
+			&i.Value,
 		); err != nil {
 			return nil, err
 		}
@@ -630,35 +2920,51 @@ func (q *Queries) ListInstances(ctx context.Context) ([]Instance, error) {
 	return items, nil
 }
 
-const listInstancesByProject = `-- name: ListInstancesByProject :many
+const listSSHKeys = `-- name: ListSSHKeys :many
 SELECT
-  id, name, project, ip_address, created_at, updated_at, deleted_at
+  id, owner, comment, key_type, public_key, fingerprint, project, remote, scope, target, expires_at, created_at, updated_at
 FROM
-  instances
+  ssh_keys
 WHERE
-  project = ?
-  AND deleted_at IS NULL
+  remote = ?1
+  AND project = ?2
+  AND (
+    ?3 = ''
+    OR owner = ?3
+  )
 ORDER BY
-  created_at DESC
+  id
 `
 
-func (q *Queries) ListInstancesByProject(ctx context.Context, project string) ([]Instance, error) {
-	rows, err := q.query(ctx, q.listInstancesByProjectStmt, listInstancesByProject, project)
+type ListSSHKeysParams struct {
+	Remote  string
+	Project string
+	Owner   string
+}
+
+func (q *Queries) ListSSHKeys(ctx context.Context, arg ListSSHKeysParams) ([]SshKey, error) {
+	rows, err := q.query(ctx, q.listSSHKeysStmt, listSSHKeys, arg.Remote, arg.Project, arg.Owner)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Instance
+	var items []SshKey
 	for rows.Next() {
-		var i Instance
+		var i SshKey
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
+			&i.Owner,
+			&i.Comment,
+			&i.KeyType,
+			&i.PublicKey,
+			&i.Fingerprint,
 			&i.Project,
-			&i.IpAddress,
+			&i.Remote,
+			&i.Scope,
+			&i.Target,
+			&i.ExpiresAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -673,33 +2979,46 @@ func (q *Queries) ListInstancesByProject(ctx context.Context, project string) ([
 	return items, nil
 }
 
-const listProfiles = `-- name: ListProfiles :many
+const listSecrets = `-- name: ListSecrets :many
 SELECT
-  id, name, project, created_at, updated_at, deleted_at
+  id, name, project, remote, scope, target, key_id, wrapped_key, ciphertext, one_time, created_by, created_at, updated_at
 FROM
-  profiles
+  secrets
 WHERE
-  deleted_at IS NULL
+  remote = ?
+  AND project = ?
 ORDER BY
-  created_at DESC
+  id
 `
 
-func (q *Queries) ListProfiles(ctx context.Context) ([]Profile, error) {
-	rows, err := q.query(ctx, q.listProfilesStmt, listProfiles)
+type ListSecretsParams struct {
+	Remote  string
+	Project string
+}
+
+func (q *Queries) ListSecrets(ctx context.Context, arg ListSecretsParams) ([]Secret, error) {
+	rows, err := q.query(ctx, q.listSecretsStmt, listSecrets, arg.Remote, arg.Project)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Profile
+	var items []Secret
 	for rows.Next() {
-		var i Profile
+		var i Secret
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
 			&i.Project,
+			&i.Remote,
+			&i.Scope,
+			&i.Target,
+			&i.KeyID,
+			&i.WrappedKey,
+			&i.Ciphertext,
+			&i.OneTime,
+			&i.CreatedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -714,34 +3033,34 @@ func (q *Queries) ListProfiles(ctx context.Context) ([]Profile, error) {
 	return items, nil
 }
 
-const listProfilesByProject = `-- name: ListProfilesByProject :many
+const listSigningKeys = `-- name: ListSigningKeys :many
 SELECT
-  id, name, project, created_at, updated_at, deleted_at
+  id, kid, algorithm, private_key, certificate, expires_at, created_at
 FROM
-  profiles
+  signing_keys
 WHERE
-  project = ?
-  AND deleted_at IS NULL
+  unixepoch(expires_at) > ?
 ORDER BY
-  created_at DESC
+  id DESC
 `
 
-func (q *Queries) ListProfilesByProject(ctx context.Context, project string) ([]Profile, error) {
-	rows, err := q.query(ctx, q.listProfilesByProjectStmt, listProfilesByProject, project)
+func (q *Queries) ListSigningKeys(ctx context.Context, now int64) ([]SigningKey, error) {
+	rows, err := q.query(ctx, q.listSigningKeysStmt, listSigningKeys, now)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Profile
+	var items []SigningKey
 	for rows.Next() {
-		var i Profile
+		var i SigningKey
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
-			&i.Project,
+			&i.Kid,
+			&i.Algorithm,
+			&i.PrivateKey,
+			&i.Certificate,
+			&i.ExpiresAt,
 			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -756,6 +3075,166 @@ func (q *Queries) ListProfilesByProject(ctx context.Context, project string) ([]
 	return items, nil
 }
 
+const purgeDeletedInstances = `-- name: PurgeDeletedInstances :execrows
+DELETE FROM
+  instances
+WHERE
+  deleted_at < datetime(?, 'unixepoch')
+`
+
+func (q *Queries) PurgeDeletedInstances(ctx context.Context, before int64) (int64, error) {
+	result, err := q.exec(ctx, q.purgeDeletedInstancesStmt, purgeDeletedInstances, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeDeletedProfiles = `-- name: PurgeDeletedProfiles :execrows
+DELETE FROM
+  profiles
+WHERE
+  deleted_at < datetime(?, 'unixepoch')
+`
+
+func (q *Queries) PurgeDeletedProfiles(ctx context.Context, before int64) (int64, error) {
+	result, err := q.exec(ctx, q.purgeDeletedProfilesStmt, purgeDeletedProfiles, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeDeletedVendorData = `-- name: PurgeDeletedVendorData :execrows
+DELETE FROM
+  vendor_data
+WHERE
+  deleted_at < datetime(?, 'unixepoch')
+`
+
+func (q *Queries) PurgeDeletedVendorData(ctx context.Context, before int64) (int64, error) {
+	result, err := q.exec(ctx, q.purgeDeletedVendorDataStmt, purgeDeletedVendorData, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeInstanceHostKeys = `-- name: RevokeInstanceHostKeys :execrows
+UPDATE
+  instance_host_keys
+SET
+  revoked_at = CURRENT_TIMESTAMP,
+  revoked_reason = ?1
+WHERE
+  instance_id = ?2
+  AND revoked_at IS NULL
+  AND (
+    ?3 = ''
+    OR key_type = ?3
+  )
+`
+
+type RevokeInstanceHostKeysParams struct {
+	RevokedReason string
+	InstanceID    int64
+	KeyType       string
+}
+
+func (q *Queries) RevokeInstanceHostKeys(ctx context.Context, arg RevokeInstanceHostKeysParams) (int64, error) {
+	result, err := q.exec(ctx, q.revokeInstanceHostKeysStmt, revokeInstanceHostKeys, arg.RevokedReason, arg.InstanceID, arg.KeyType)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeStaleInstanceHostKeys = `-- name: RevokeStaleInstanceHostKeys :execrows
+UPDATE
+  instance_host_keys
+SET
+  revoked_at = CURRENT_TIMESTAMP,
+  revoked_reason = 'rebuilt'
+WHERE
+  instance_id = ?1
+  AND revoked_at IS NULL
+  AND (
+    instance_uuid IS NULL
+    OR instance_uuid != ?2
+  )
+`
+
+type RevokeStaleInstanceHostKeysParams struct {
+	InstanceID   int64
+	InstanceUuid string
+}
+
+func (q *Queries) RevokeStaleInstanceHostKeys(ctx context.Context, arg RevokeStaleInstanceHostKeysParams) (int64, error) {
+	result, err := q.exec(ctx, q.revokeStaleInstanceHostKeysStmt, revokeStaleInstanceHostKeys, arg.InstanceID, arg.InstanceUuid)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const touchInstanceHostKey = `-- name: TouchInstanceHostKey :exec
+UPDATE
+  instance_host_keys
+SET
+  last_seen_at = CURRENT_TIMESTAMP
+WHERE
+  id = ?
+`
+
+func (q *Queries) TouchInstanceHostKey(ctx context.Context, id int64) error {
+	_, err := q.exec(ctx, q.touchInstanceHostKeyStmt, touchInstanceHostKey, id)
+	return err
+}
+
+const updateCertificate = `-- name: UpdateCertificate :one
+UPDATE
+  certificates
+SET
+  name = ?,
+  role = ?,
+  restricted = ?,
+  projects = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  fingerprint = ? RETURNING id, fingerprint, name, role, restricted, projects, certificate, created_at, updated_at
+`
+
+type UpdateCertificateParams struct {
+	Name        string
+	Role        string
+	Restricted  bool
+	Projects    interface{}
+	Fingerprint string
+}
+
+func (q *Queries) UpdateCertificate(ctx context.Context, arg UpdateCertificateParams) (Certificate, error) {
+	row := q.queryRow(ctx, q.updateCertificateStmt, updateCertificate,
+		arg.Name,
+		arg.Role,
+		arg.Restricted,
+		arg.Projects,
+		arg.Fingerprint,
+	)
+	var i Certificate
+	err := row.Scan(
+		&i.ID,
+		&i.Fingerprint,
+		&i.Name,
+		&i.Role,
+		&i.Restricted,
+		&i.Projects,
+		&i.Certificate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const updateInstance = `-- name: UpdateInstance :one
 UPDATE
   instances
@@ -763,7 +3242,7 @@ SET
   ip_address = ?,
   updated_at = CURRENT_TIMESTAMP
 WHERE
-  id = ? RETURNING id, name, project, ip_address, created_at, updated_at, deleted_at
+  id = ? RETURNING id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
 `
 
 type UpdateInstanceParams struct {
@@ -778,10 +3257,14 @@ func (q *Queries) UpdateInstance(ctx context.Context, arg UpdateInstanceParams)
 		&i.ID,
 		&i.Name,
 		&i.Project,
+		&i.Remote,
 		&i.IpAddress,
+		&i.VsockID,
+		&i.Uuid,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.DeletedAt,
+		&i.InvalidatedAt,
 	)
 	return i, err
 }
@@ -829,6 +3312,133 @@ func (q *Queries) UpdateProfile(ctx context.Context, id int64) (Profile, error)
 	return i, err
 }
 
+const updateSSHKey = `-- name: UpdateSSHKey :one
+UPDATE
+  ssh_keys
+SET
+  owner = ?,
+  comment = ?,
+  key_type = ?,
+  public_key = ?,
+  fingerprint = ?,
+  scope = ?,
+  target = ?,
+  expires_at = datetime(?, 'unixepoch'),
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ? RETURNING id, owner, comment, key_type, public_key, fingerprint, project, remote, scope, target, expires_at, created_at, updated_at
+`
+
+type UpdateSSHKeyParams struct {
+	Owner       string
+	Comment     string
+	KeyType     string
+	PublicKey   string
+	Fingerprint string
+	Scope       string
+	Target      string
+	ExpiresAt   *int64
+	ID          int64
+}
+
+func (q *Queries) UpdateSSHKey(ctx context.Context, arg UpdateSSHKeyParams) (SshKey, error) {
+	row := q.queryRow(ctx, q.updateSSHKeyStmt, updateSSHKey,
+		arg.Owner,
+		arg.Comment,
+		arg.KeyType,
+		arg.PublicKey,
+		arg.Fingerprint,
+		arg.Scope,
+		arg.Target,
+		arg.ExpiresAt,
+		arg.ID,
+	)
+	var i SshKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Comment,
+		&i.KeyType,
+		&i.PublicKey,
+		&i.Fingerprint,
+		&i.Project,
+		&i.Remote,
+		&i.Scope,
+		&i.Target,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateSecret = `-- name: UpdateSecret :one
+UPDATE
+  secrets
+SET
+  key_id = ?,
+  wrapped_key = ?,
+  ciphertext = ?,
+  one_time = ?,
+  updated_at = CURRENT_TIMESTAMP
+WHERE
+  id = ? RETURNING id, name, project, remote, scope, target, key_id, wrapped_key, ciphertext, one_time, created_by, created_at, updated_at
+`
+
+type UpdateSecretParams struct {
+	KeyID      string
+	WrappedKey []byte
+	Ciphertext []byte
+	OneTime    bool
+	ID         int64
+}
+
+func (q *Queries) UpdateSecret(ctx context.Context, arg UpdateSecretParams) (Secret, error) {
+	row := q.queryRow(ctx, q.updateSecretStmt, updateSecret,
+		arg.KeyID,
+		arg.WrappedKey,
+		arg.Ciphertext,
+		arg.OneTime,
+		arg.ID,
+	)
+	var i Secret
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Project,
+		&i.Remote,
+		&i.Scope,
+		&i.Target,
+		&i.KeyID,
+		&i.WrappedKey,
+		&i.Ciphertext,
+		&i.OneTime,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const updateSigningKey = `-- name: UpdateSigningKey :exec
+UPDATE
+  signing_keys
+SET
+  private_key = ?
+WHERE
+  id = ?
+`
+
+type UpdateSigningKeyParams struct {
+	PrivateKey string
+	ID         int64
+}
+
+func (q *Queries) UpdateSigningKey(ctx context.Context, arg UpdateSigningKeyParams) error {
+	_, err := q.exec(ctx, q.updateSigningKeyStmt, updateSigningKey, arg.PrivateKey, arg.ID)
+	return err
+}
+
 const updateVendorData = `-- name: UpdateVendorData :one
 UPDATE
   vendor_data
@@ -860,3 +3470,58 @@ func (q *Queries) UpdateVendorData(ctx context.Context, arg UpdateVendorDataPara
 	)
 	return i, err
 }
+
+const upsertInstance = `-- name: UpsertInstance :one
+INSERT INTO
+  instances (remote, name, project, ip_address, vsock_id, uuid)
+VALUES
+  (?1, ?2, ?3, ?4, ?5, ?6) ON CONFLICT(remote, name, project) DO
+UPDATE
+SET
+  ip_address = CASE
+    WHEN CAST(?7 AS BOOLEAN) THEN excluded.ip_address
+    ELSE COALESCE(excluded.ip_address, instances.ip_address)
+  END,
+  vsock_id = COALESCE(excluded.vsock_id, instances.vsock_id),
+  uuid = COALESCE(excluded.uuid, instances.uuid),
+  deleted_at = NULL,
+  invalidated_at = NULL,
+  updated_at = CURRENT_TIMESTAMP RETURNING id, name, project, remote, ip_address, vsock_id, uuid, created_at, updated_at, deleted_at, invalidated_at
+`
+
+type UpsertInstanceParams struct {
+	Remote         string
+	Name           string
+	Project        string
+	IpAddress      *string
+	VsockID        *int64
+	Uuid           *string
+	AddressesKnown bool
+}
+
+func (q *Queries) UpsertInstance(ctx context.Context, arg UpsertInstanceParams) (Instance, error) {
+	row := q.queryRow(ctx, q.upsertInstanceStmt, upsertInstance,
+		arg.Remote,
+		arg.Name,
+		arg.Project,
+		arg.IpAddress,
+		arg.VsockID,
+		arg.Uuid,
+		arg.AddressesKnown,
+	)
+	var i Instance
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Project,
+		&i.Remote,
+		&i.IpAddress,
+		&i.VsockID,
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.InvalidatedAt,
+	)
+	return i, err
+}
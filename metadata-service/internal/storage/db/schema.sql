@@ -21,11 +21,46 @@ CREATE TABLE IF NOT EXISTS instances (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
   name TEXT NOT NULL,
   project TEXT NOT NULL DEFAULT 'default',
+  remote TEXT NOT NULL DEFAULT 'local', -- Name of the Incus remote hosting the instance
   ip_address TEXT, -- IP address for instance identification
+  vsock_id INTEGER, -- volatile.vsock_id of virtual machines, identifies callers over vsock
+  uuid TEXT, -- volatile.uuid, changes when the instance is recreated under the same name
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   deleted_at TIMESTAMP,
-  UNIQUE(name, project)
+  invalidated_at TIMESTAMP, -- Set when Incus reports a change, the next request resolves the instance again
+  UNIQUE(remote, name, project)
+);
+
+-- Addresses of each instance NIC, keyed by network since private ranges are reused across networks
+CREATE TABLE IF NOT EXISTS instance_addresses (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  instance_id INTEGER NOT NULL,
+  network TEXT NOT NULL, -- Incus network (or parent bridge) the NIC is attached to
+  ip_address TEXT NOT NULL,
+  mac_address TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(instance_id, network, ip_address),
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
+-- Profiles applied to each instance, used to scope SSH keys to profiles
+CREATE TABLE IF NOT EXISTS instance_profiles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  instance_id INTEGER NOT NULL,
+  profile TEXT NOT NULL,
+  UNIQUE(instance_id, profile),
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
+-- Tags of each instance, from the user.* keys of its expanded configuration
+CREATE TABLE IF NOT EXISTS instance_tags (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  instance_id INTEGER NOT NULL,
+  key TEXT NOT NULL, -- Config key without the user. prefix, e.g. team for user.team
+  value TEXT NOT NULL,
+  UNIQUE(instance_id, key),
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
 );
 
 -- Instance state table for current runtime state
@@ -49,6 +84,22 @@ CREATE TABLE IF NOT EXISTS instance_logs (
   FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
 );
 
+-- SSH host keys published by guests. Replaced and invalidated keys are revoked rather than
+-- deleted, to keep the key history of each instance
+CREATE TABLE IF NOT EXISTS instance_host_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  instance_id INTEGER NOT NULL,
+  instance_uuid TEXT, -- volatile.uuid of the instance when the key was published
+  key_type TEXT NOT NULL, -- e.g. ssh-ed25519
+  public_key TEXT NOT NULL, -- authorized_keys format, without comment
+  fingerprint TEXT NOT NULL, -- SHA256 fingerprint
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  revoked_at TIMESTAMP,
+  revoked_reason TEXT, -- replaced, rebuilt or deleted
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
 -- Profiles table to store Incus profiles
 CREATE TABLE IF NOT EXISTS profiles (
   id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -60,12 +111,130 @@ CREATE TABLE IF NOT EXISTS profiles (
   UNIQUE(name, project)
 );
 
+-- SSH public keys authorized on the instances of a project, a profile or a single instance
+CREATE TABLE IF NOT EXISTS ssh_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  owner TEXT NOT NULL, -- Person or system the key belongs to
+  comment TEXT NOT NULL DEFAULT '',
+  key_type TEXT NOT NULL, -- e.g. ssh-ed25519
+  public_key TEXT NOT NULL, -- authorized_keys format, without comment
+  fingerprint TEXT NOT NULL, -- SHA256 fingerprint
+  project TEXT NOT NULL DEFAULT 'default',
+  remote TEXT NOT NULL DEFAULT 'local', -- Name of the Incus remote of the project
+  scope TEXT NOT NULL CHECK (scope IN ('project', 'profile', 'instance')),
+  target TEXT NOT NULL DEFAULT '', -- Profile or instance name, empty for the project scope
+  expires_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(remote, project, scope, target, fingerprint)
+);
+
+-- Ephemeral SSH keys pushed by operators for just-in-time access to an instance
+CREATE TABLE IF NOT EXISTS ephemeral_ssh_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  instance_id INTEGER NOT NULL,
+  os_user TEXT NOT NULL, -- Guest user the key logs in as
+  public_key TEXT NOT NULL, -- authorized_keys format, without comment
+  fingerprint TEXT NOT NULL, -- SHA256 fingerprint
+  pushed_by TEXT NOT NULL, -- Name of the admin API client that pushed the key
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
+-- Certificates table for clients trusted by the admin API
+CREATE TABLE IF NOT EXISTS certificates (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  fingerprint TEXT NOT NULL UNIQUE, -- SHA256 fingerprint of the client certificate
+  name TEXT NOT NULL,
+  role TEXT NOT NULL CHECK (role IN ('admin', 'operator', 'reader')),
+  restricted BOOLEAN NOT NULL DEFAULT FALSE, -- Limit access to the listed projects
+  projects JSONB,
+  certificate TEXT NOT NULL, -- PEM encoded certificate
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One-time join tokens used to add a client certificate to the trust store
+CREATE TABLE IF NOT EXISTS certificate_tokens (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  secret TEXT NOT NULL UNIQUE,
+  role TEXT NOT NULL CHECK (role IN ('admin', 'operator', 'reader')),
+  restricted BOOLEAN NOT NULL DEFAULT FALSE,
+  projects JSONB,
+  expires_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Rotating keys instance identity documents are signed with
+CREATE TABLE IF NOT EXISTS signing_keys (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  kid TEXT NOT NULL UNIQUE, -- JWK thumbprint of the public key
+  algorithm TEXT NOT NULL, -- e.g. RS256
+  private_key TEXT NOT NULL, -- PEM encoded PKCS #8 private key, sealed with the secrets KEK when one is configured
+  certificate TEXT NOT NULL, -- PEM encoded self-signed certificate, embedded in PKCS #7 signatures
+  expires_at TIMESTAMP NOT NULL, -- End of the grace period, when the key stops being published
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Secrets delivered to the instances in their scope, encrypted with a data key that is itself
+-- encrypted with the key encryption key, which is never stored in the database
+CREATE TABLE IF NOT EXISTS secrets (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name TEXT NOT NULL,
+  project TEXT NOT NULL DEFAULT 'default',
+  remote TEXT NOT NULL DEFAULT 'local', -- Name of the Incus remote of the project
+  scope TEXT NOT NULL CHECK (scope IN ('project', 'profile', 'instance')),
+  target TEXT NOT NULL DEFAULT '', -- Profile or instance name, empty for the project scope
+  key_id TEXT NOT NULL, -- Identifies the key encryption key the data key is wrapped with
+  wrapped_key BLOB NOT NULL, -- AES-256-GCM encrypted data key, prefixed with its nonce
+  ciphertext BLOB NOT NULL, -- AES-256-GCM encrypted value, prefixed with its nonce
+  one_time BOOLEAN NOT NULL DEFAULT FALSE, -- Served once to each instance
+  created_by TEXT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(remote, project, scope, target, name)
+);
+
+-- Instances that read a one-time secret
+CREATE TABLE IF NOT EXISTS secret_reads (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  secret_id INTEGER NOT NULL,
+  instance_id INTEGER NOT NULL,
+  instance_uuid TEXT, -- volatile.uuid of the instance, a rebuilt instance can read the secret again
+  read_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE(secret_id, instance_id),
+  FOREIGN KEY (secret_id) REFERENCES secrets(id) ON DELETE CASCADE,
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
+-- Passwords generated for the users of instances. Only a copy encrypted to the public key of
+-- the operators is kept, until an operator retrieves it
+CREATE TABLE IF NOT EXISTS instance_passwords (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  instance_id INTEGER NOT NULL UNIQUE,
+  instance_uuid TEXT, -- volatile.uuid of the instance, a rebuilt instance gets a new password
+  key_fingerprint TEXT NOT NULL, -- SHA256 of the public key the password is encrypted to
+  encrypted_password BLOB, -- RSA-OAEP encrypted password, cleared once retrieved
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  retrieved_at TIMESTAMP,
+  retrieved_by TEXT, -- Name of the certificate that retrieved the password
+  FOREIGN KEY (instance_id) REFERENCES instances(id) ON DELETE CASCADE
+);
+
 -- Indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_instances_name ON instances(name);
 CREATE INDEX IF NOT EXISTS idx_instances_project ON instances(project);
-CREATE INDEX IF NOT EXISTS idx_instances_ip_address ON instances(ip_address);
+CREATE INDEX IF NOT EXISTS idx_instances_ip_address ON instances(remote, ip_address);
+CREATE INDEX IF NOT EXISTS idx_instances_vsock_id ON instances(remote, vsock_id);
 CREATE INDEX IF NOT EXISTS idx_instances_deleted_at ON instances(deleted_at);
-CREATE INDEX IF NOT EXISTS idx_instances_active ON instances(name, project, deleted_at) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_instances_active ON instances(remote, name, project, deleted_at) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_instance_addresses_lookup ON instance_addresses(network, ip_address);
+CREATE INDEX IF NOT EXISTS idx_instance_addresses_ip_address ON instance_addresses(ip_address);
+
+CREATE INDEX IF NOT EXISTS idx_instance_tags_key_value ON instance_tags(key, value);
 
 CREATE INDEX IF NOT EXISTS idx_instance_state_instance_id ON instance_state(instance_id);
 CREATE INDEX IF NOT EXISTS idx_instance_state_status ON instance_state(status);
@@ -76,5 +245,17 @@ CREATE INDEX IF NOT EXISTS idx_instance_logs_type ON instance_logs(log_type);
 CREATE INDEX IF NOT EXISTS idx_instance_logs_level ON instance_logs(level);
 CREATE INDEX IF NOT EXISTS idx_instance_logs_created_at ON instance_logs(created_at);
 
+CREATE INDEX IF NOT EXISTS idx_instance_host_keys_instance_id ON instance_host_keys(instance_id, revoked_at);
+
 CREATE INDEX IF NOT EXISTS idx_profiles_name_project ON profiles(name, project);
-CREATE INDEX IF NOT EXISTS idx_profiles_deleted_at ON profiles(deleted_at);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_profiles_deleted_at ON profiles(deleted_at);
+
+CREATE INDEX IF NOT EXISTS idx_instance_profiles_profile ON instance_profiles(profile);
+
+CREATE INDEX IF NOT EXISTS idx_ssh_keys_project_scope ON ssh_keys(remote, project, scope, target);
+
+CREATE INDEX IF NOT EXISTS idx_ephemeral_ssh_keys_instance_user ON ephemeral_ssh_keys(instance_id, os_user);
+
+CREATE INDEX IF NOT EXISTS idx_secrets_project_scope ON secrets(remote, project, scope, target);
+
+CREATE INDEX IF NOT EXISTS idx_certificate_tokens_expires_at ON certificate_tokens(expires_at);
\ No newline at end of file
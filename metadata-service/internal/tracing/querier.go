@@ -0,0 +1,755 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Querier creates a span for every query made through the wrapped db.Store.
+type Querier struct {
+	inner db.Store
+}
+
+var _ db.Store = (*Querier)(nil)
+
+// NewQuerier wraps a db.Store so its queries show up in traces.
+func NewQuerier(inner db.Store) *Querier {
+	return &Querier{inner: inner}
+}
+
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	return Tracer.Start(ctx, "db."+query,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "sqlite"),
+			attribute.String("db.operation.name", query),
+		),
+	)
+}
+
+func (q *Querier) InTx(ctx context.Context, fn func(db.Store) error) error {
+	ctx, span := startQuery(ctx, "InTx")
+	err := q.inner.InTx(ctx, func(tx db.Store) error {
+		return fn(NewQuerier(tx))
+	})
+	End(span, err)
+	return err
+}
+
+func (q *Querier) ClaimCertificateToken(ctx context.Context, secret string) (db.CertificateToken, error) {
+	ctx, span := startQuery(ctx, "ClaimCertificateToken")
+	result, err := q.inner.ClaimCertificateToken(ctx, secret)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ClaimInstancePassword(ctx context.Context, arg db.ClaimInstancePasswordParams) (int64, error) {
+	ctx, span := startQuery(ctx, "ClaimInstancePassword")
+	result, err := q.inner.ClaimInstancePassword(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ClaimSecretRead(ctx context.Context, arg db.ClaimSecretReadParams) (int64, error) {
+	ctx, span := startQuery(ctx, "ClaimSecretRead")
+	result, err := q.inner.ClaimSecretRead(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateCertificate(ctx context.Context, arg db.CreateCertificateParams) (db.Certificate, error) {
+	ctx, span := startQuery(ctx, "CreateCertificate")
+	result, err := q.inner.CreateCertificate(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateCertificateToken(ctx context.Context, arg db.CreateCertificateTokenParams) (db.CertificateToken, error) {
+	ctx, span := startQuery(ctx, "CreateCertificateToken")
+	result, err := q.inner.CreateCertificateToken(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateEphemeralSSHKey(ctx context.Context, arg db.CreateEphemeralSSHKeyParams) (db.EphemeralSshKey, error) {
+	ctx, span := startQuery(ctx, "CreateEphemeralSSHKey")
+	result, err := q.inner.CreateEphemeralSSHKey(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateInstance(ctx context.Context, arg db.CreateInstanceParams) (db.Instance, error) {
+	ctx, span := startQuery(ctx, "CreateInstance")
+	result, err := q.inner.CreateInstance(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateInstanceAddress(ctx context.Context, arg db.CreateInstanceAddressParams) error {
+	ctx, span := startQuery(ctx, "CreateInstanceAddress")
+	err := q.inner.CreateInstanceAddress(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) CreateInstanceHostKey(ctx context.Context, arg db.CreateInstanceHostKeyParams) (db.InstanceHostKey, error) {
+	ctx, span := startQuery(ctx, "CreateInstanceHostKey")
+	result, err := q.inner.CreateInstanceHostKey(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateInstanceLog(ctx context.Context, arg db.CreateInstanceLogParams) (db.InstanceLog, error) {
+	ctx, span := startQuery(ctx, "CreateInstanceLog")
+	result, err := q.inner.CreateInstanceLog(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateInstancePassword(ctx context.Context, arg db.CreateInstancePasswordParams) (int64, error) {
+	ctx, span := startQuery(ctx, "CreateInstancePassword")
+	result, err := q.inner.CreateInstancePassword(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateInstanceProfile(ctx context.Context, arg db.CreateInstanceProfileParams) error {
+	ctx, span := startQuery(ctx, "CreateInstanceProfile")
+	err := q.inner.CreateInstanceProfile(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) CreateInstanceTag(ctx context.Context, arg db.CreateInstanceTagParams) error {
+	ctx, span := startQuery(ctx, "CreateInstanceTag")
+	err := q.inner.CreateInstanceTag(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) CreateOrUpdateInstanceState(ctx context.Context, arg db.CreateOrUpdateInstanceStateParams) (db.InstanceState, error) {
+	ctx, span := startQuery(ctx, "CreateOrUpdateInstanceState")
+	result, err := q.inner.CreateOrUpdateInstanceState(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateProfile(ctx context.Context, arg db.CreateProfileParams) (db.Profile, error) {
+	ctx, span := startQuery(ctx, "CreateProfile")
+	result, err := q.inner.CreateProfile(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateSSHKey(ctx context.Context, arg db.CreateSSHKeyParams) (db.SshKey, error) {
+	ctx, span := startQuery(ctx, "CreateSSHKey")
+	result, err := q.inner.CreateSSHKey(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateSecret(ctx context.Context, arg db.CreateSecretParams) (db.Secret, error) {
+	ctx, span := startQuery(ctx, "CreateSecret")
+	result, err := q.inner.CreateSecret(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateSigningKey(ctx context.Context, arg db.CreateSigningKeyParams) (db.SigningKey, error) {
+	ctx, span := startQuery(ctx, "CreateSigningKey")
+	result, err := q.inner.CreateSigningKey(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) CreateVendorData(ctx context.Context, arg db.CreateVendorDataParams) (db.VendorDatum, error) {
+	ctx, span := startQuery(ctx, "CreateVendorData")
+	result, err := q.inner.CreateVendorData(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteCertificate(ctx context.Context, fingerprint string) error {
+	ctx, span := startQuery(ctx, "DeleteCertificate")
+	err := q.inner.DeleteCertificate(ctx, fingerprint)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteExcessInstanceLogs(ctx context.Context, arg db.DeleteExcessInstanceLogsParams) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteExcessInstanceLogs")
+	result, err := q.inner.DeleteExcessInstanceLogs(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteExpiredCertificateTokens(ctx context.Context) error {
+	ctx, span := startQuery(ctx, "DeleteExpiredCertificateTokens")
+	err := q.inner.DeleteExpiredCertificateTokens(ctx)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteExpiredEphemeralSSHKeys(ctx context.Context, before int64) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteExpiredEphemeralSSHKeys")
+	result, err := q.inner.DeleteExpiredEphemeralSSHKeys(ctx, before)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteExpiredInstanceLogs(ctx context.Context, arg db.DeleteExpiredInstanceLogsParams) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteExpiredInstanceLogs")
+	result, err := q.inner.DeleteExpiredInstanceLogs(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteExpiredSigningKeys(ctx context.Context, before int64) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteExpiredSigningKeys")
+	result, err := q.inner.DeleteExpiredSigningKeys(ctx, before)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteInstance(ctx context.Context, id int64) error {
+	ctx, span := startQuery(ctx, "DeleteInstance")
+	err := q.inner.DeleteInstance(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteInstanceAddresses(ctx context.Context, instanceID int64) error {
+	ctx, span := startQuery(ctx, "DeleteInstanceAddresses")
+	err := q.inner.DeleteInstanceAddresses(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteInstanceLogs(ctx context.Context, instanceID int64) error {
+	ctx, span := startQuery(ctx, "DeleteInstanceLogs")
+	err := q.inner.DeleteInstanceLogs(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteInstancePassword(ctx context.Context, instanceID int64) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteInstancePassword")
+	result, err := q.inner.DeleteInstancePassword(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteInstanceProfiles(ctx context.Context, instanceID int64) error {
+	ctx, span := startQuery(ctx, "DeleteInstanceProfiles")
+	err := q.inner.DeleteInstanceProfiles(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteInstanceState(ctx context.Context, instanceID int64) error {
+	ctx, span := startQuery(ctx, "DeleteInstanceState")
+	err := q.inner.DeleteInstanceState(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteInstanceTags(ctx context.Context, instanceID int64) error {
+	ctx, span := startQuery(ctx, "DeleteInstanceTags")
+	err := q.inner.DeleteInstanceTags(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteOldInstanceLogs(ctx context.Context, arg db.DeleteOldInstanceLogsParams) error {
+	ctx, span := startQuery(ctx, "DeleteOldInstanceLogs")
+	err := q.inner.DeleteOldInstanceLogs(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteOrphanedInstanceAddresses(ctx context.Context) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteOrphanedInstanceAddresses")
+	result, err := q.inner.DeleteOrphanedInstanceAddresses(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceHostKeys(ctx context.Context) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteOrphanedInstanceHostKeys")
+	result, err := q.inner.DeleteOrphanedInstanceHostKeys(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceLogs(ctx context.Context, limit int64) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteOrphanedInstanceLogs")
+	result, err := q.inner.DeleteOrphanedInstanceLogs(ctx, limit)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstancePasswords(ctx context.Context) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteOrphanedInstancePasswords")
+	result, err := q.inner.DeleteOrphanedInstancePasswords(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceProfiles(ctx context.Context) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteOrphanedInstanceProfiles")
+	result, err := q.inner.DeleteOrphanedInstanceProfiles(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceStates(ctx context.Context) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteOrphanedInstanceStates")
+	result, err := q.inner.DeleteOrphanedInstanceStates(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedInstanceTags(ctx context.Context) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteOrphanedInstanceTags")
+	result, err := q.inner.DeleteOrphanedInstanceTags(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteOrphanedSecretReads(ctx context.Context) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteOrphanedSecretReads")
+	result, err := q.inner.DeleteOrphanedSecretReads(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteProfile(ctx context.Context, id int64) error {
+	ctx, span := startQuery(ctx, "DeleteProfile")
+	err := q.inner.DeleteProfile(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteSSHKey(ctx context.Context, id int64) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteSSHKey")
+	result, err := q.inner.DeleteSSHKey(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteSecret(ctx context.Context, id int64) (int64, error) {
+	ctx, span := startQuery(ctx, "DeleteSecret")
+	result, err := q.inner.DeleteSecret(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) DeleteSecretReads(ctx context.Context, secretID int64) error {
+	ctx, span := startQuery(ctx, "DeleteSecretReads")
+	err := q.inner.DeleteSecretReads(ctx, secretID)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) DeleteVendorData(ctx context.Context, id int64) error {
+	ctx, span := startQuery(ctx, "DeleteVendorData")
+	err := q.inner.DeleteVendorData(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) GetCertificate(ctx context.Context, fingerprint string) (db.Certificate, error) {
+	ctx, span := startQuery(ctx, "GetCertificate")
+	result, err := q.inner.GetCertificate(ctx, fingerprint)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstance(ctx context.Context, arg db.GetInstanceParams) (db.Instance, error) {
+	ctx, span := startQuery(ctx, "GetInstance")
+	result, err := q.inner.GetInstance(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstanceByAddress(ctx context.Context, arg db.GetInstanceByAddressParams) (db.Instance, error) {
+	ctx, span := startQuery(ctx, "GetInstanceByAddress")
+	result, err := q.inner.GetInstanceByAddress(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstanceByID(ctx context.Context, id int64) (db.Instance, error) {
+	ctx, span := startQuery(ctx, "GetInstanceByID")
+	result, err := q.inner.GetInstanceByID(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstanceByIP(ctx context.Context, arg db.GetInstanceByIPParams) (db.Instance, error) {
+	ctx, span := startQuery(ctx, "GetInstanceByIP")
+	result, err := q.inner.GetInstanceByIP(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstanceByVsockID(ctx context.Context, arg db.GetInstanceByVsockIDParams) (db.Instance, error) {
+	ctx, span := startQuery(ctx, "GetInstanceByVsockID")
+	result, err := q.inner.GetInstanceByVsockID(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstanceLogs(ctx context.Context, arg db.GetInstanceLogsParams) ([]db.InstanceLog, error) {
+	ctx, span := startQuery(ctx, "GetInstanceLogs")
+	result, err := q.inner.GetInstanceLogs(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstanceLogsByLevel(ctx context.Context, arg db.GetInstanceLogsByLevelParams) ([]db.InstanceLog, error) {
+	ctx, span := startQuery(ctx, "GetInstanceLogsByLevel")
+	result, err := q.inner.GetInstanceLogsByLevel(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstanceLogsByType(ctx context.Context, arg db.GetInstanceLogsByTypeParams) ([]db.InstanceLog, error) {
+	ctx, span := startQuery(ctx, "GetInstanceLogsByType")
+	result, err := q.inner.GetInstanceLogsByType(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstancePassword(ctx context.Context, instanceID int64) (db.InstancePassword, error) {
+	ctx, span := startQuery(ctx, "GetInstancePassword")
+	result, err := q.inner.GetInstancePassword(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetInstanceState(ctx context.Context, instanceID int64) (db.InstanceState, error) {
+	ctx, span := startQuery(ctx, "GetInstanceState")
+	result, err := q.inner.GetInstanceState(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetProfile(ctx context.Context, arg db.GetProfileParams) (db.Profile, error) {
+	ctx, span := startQuery(ctx, "GetProfile")
+	result, err := q.inner.GetProfile(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetSSHKey(ctx context.Context, id int64) (db.SshKey, error) {
+	ctx, span := startQuery(ctx, "GetSSHKey")
+	result, err := q.inner.GetSSHKey(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetSecret(ctx context.Context, id int64) (db.Secret, error) {
+	ctx, span := startQuery(ctx, "GetSecret")
+	result, err := q.inner.GetSecret(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) GetVendorData(ctx context.Context, name string) (db.GetVendorDataRow, error) {
+	ctx, span := startQuery(ctx, "GetVendorData")
+	result, err := q.inner.GetVendorData(ctx, name)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) HardDeleteInstance(ctx context.Context, id int64) error {
+	ctx, span := startQuery(ctx, "HardDeleteInstance")
+	err := q.inner.HardDeleteInstance(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) InvalidateInstance(ctx context.Context, id int64) error {
+	ctx, span := startQuery(ctx, "InvalidateInstance")
+	err := q.inner.InvalidateInstance(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) ListActiveEphemeralSSHKeys(ctx context.Context, arg db.ListActiveEphemeralSSHKeysParams) ([]db.EphemeralSshKey, error) {
+	ctx, span := startQuery(ctx, "ListActiveEphemeralSSHKeys")
+	result, err := q.inner.ListActiveEphemeralSSHKeys(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListCertificateTokens(ctx context.Context) ([]db.CertificateToken, error) {
+	ctx, span := startQuery(ctx, "ListCertificateTokens")
+	result, err := q.inner.ListCertificateTokens(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListCertificates(ctx context.Context) ([]db.Certificate, error) {
+	ctx, span := startQuery(ctx, "ListCertificates")
+	result, err := q.inner.ListCertificates(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceAddresses(ctx context.Context, instanceID int64) ([]string, error) {
+	ctx, span := startQuery(ctx, "ListInstanceAddresses")
+	result, err := q.inner.ListInstanceAddresses(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceHostKeys(ctx context.Context, instanceID int64) ([]db.InstanceHostKey, error) {
+	ctx, span := startQuery(ctx, "ListInstanceHostKeys")
+	result, err := q.inner.ListInstanceHostKeys(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceLogs(ctx context.Context, arg db.ListInstanceLogsParams) ([]db.InstanceLog, error) {
+	ctx, span := startQuery(ctx, "ListInstanceLogs")
+	result, err := q.inner.ListInstanceLogs(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceLogsAfter(ctx context.Context, arg db.ListInstanceLogsAfterParams) ([]db.InstanceLog, error) {
+	ctx, span := startQuery(ctx, "ListInstanceLogsAfter")
+	result, err := q.inner.ListInstanceLogsAfter(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceProfiles(ctx context.Context, instanceID int64) ([]string, error) {
+	ctx, span := startQuery(ctx, "ListInstanceProfiles")
+	result, err := q.inner.ListInstanceProfiles(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceSSHKeys(ctx context.Context, arg db.ListInstanceSSHKeysParams) ([]db.SshKey, error) {
+	ctx, span := startQuery(ctx, "ListInstanceSSHKeys")
+	result, err := q.inner.ListInstanceSSHKeys(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceSecrets(ctx context.Context, arg db.ListInstanceSecretsParams) ([]db.ListInstanceSecretsRow, error) {
+	ctx, span := startQuery(ctx, "ListInstanceSecrets")
+	result, err := q.inner.ListInstanceSecrets(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceStates(ctx context.Context, arg db.ListInstanceStatesParams) ([]db.ListInstanceStatesRow, error) {
+	ctx, span := startQuery(ctx, "ListInstanceStates")
+	result, err := q.inner.ListInstanceStates(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstanceTags(ctx context.Context, instanceID int64) ([]db.InstanceTag, error) {
+	ctx, span := startQuery(ctx, "ListInstanceTags")
+	result, err := q.inner.ListInstanceTags(ctx, instanceID)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstances(ctx context.Context) ([]db.Instance, error) {
+	ctx, span := startQuery(ctx, "ListInstances")
+	result, err := q.inner.ListInstances(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstancesByAddressIP(ctx context.Context, arg db.ListInstancesByAddressIPParams) ([]db.Instance, error) {
+	ctx, span := startQuery(ctx, "ListInstancesByAddressIP")
+	result, err := q.inner.ListInstancesByAddressIP(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstancesByProject(ctx context.Context, project string) ([]db.Instance, error) {
+	ctx, span := startQuery(ctx, "ListInstancesByProject")
+	result, err := q.inner.ListInstancesByProject(ctx, project)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListInstancesByRemote(ctx context.Context, remote string) ([]db.Instance, error) {
+	ctx, span := startQuery(ctx, "ListInstancesByRemote")
+	result, err := q.inner.ListInstancesByRemote(ctx, remote)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListProfiles(ctx context.Context) ([]db.Profile, error) {
+	ctx, span := startQuery(ctx, "ListProfiles")
+	result, err := q.inner.ListProfiles(ctx)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListProfilesByProject(ctx context.Context, project string) ([]db.Profile, error) {
+	ctx, span := startQuery(ctx, "ListProfilesByProject")
+	result, err := q.inner.ListProfilesByProject(ctx, project)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListProjectHostKeys(ctx context.Context, arg db.ListProjectHostKeysParams) ([]db.ListProjectHostKeysRow, error) {
+	ctx, span := startQuery(ctx, "ListProjectHostKeys")
+	result, err := q.inner.ListProjectHostKeys(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListProjectInstanceAddresses(ctx context.Context, arg db.ListProjectInstanceAddressesParams) ([]db.ListProjectInstanceAddressesRow, error) {
+	ctx, span := startQuery(ctx, "ListProjectInstanceAddresses")
+	result, err := q.inner.ListProjectInstanceAddresses(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListProjectInstanceTags(ctx context.Context, arg db.ListProjectInstanceTagsParams) ([]db.ListProjectInstanceTagsRow, error) {
+	ctx, span := startQuery(ctx, "ListProjectInstanceTags")
+	result, err := q.inner.ListProjectInstanceTags(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListSSHKeys(ctx context.Context, arg db.ListSSHKeysParams) ([]db.SshKey, error) {
+	ctx, span := startQuery(ctx, "ListSSHKeys")
+	result, err := q.inner.ListSSHKeys(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListSecrets(ctx context.Context, arg db.ListSecretsParams) ([]db.Secret, error) {
+	ctx, span := startQuery(ctx, "ListSecrets")
+	result, err := q.inner.ListSecrets(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) ListSigningKeys(ctx context.Context, now int64) ([]db.SigningKey, error) {
+	ctx, span := startQuery(ctx, "ListSigningKeys")
+	result, err := q.inner.ListSigningKeys(ctx, now)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) PurgeDeletedInstances(ctx context.Context, before int64) (int64, error) {
+	ctx, span := startQuery(ctx, "PurgeDeletedInstances")
+	result, err := q.inner.PurgeDeletedInstances(ctx, before)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) PurgeDeletedProfiles(ctx context.Context, before int64) (int64, error) {
+	ctx, span := startQuery(ctx, "PurgeDeletedProfiles")
+	result, err := q.inner.PurgeDeletedProfiles(ctx, before)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) PurgeDeletedVendorData(ctx context.Context, before int64) (int64, error) {
+	ctx, span := startQuery(ctx, "PurgeDeletedVendorData")
+	result, err := q.inner.PurgeDeletedVendorData(ctx, before)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) RevokeInstanceHostKeys(ctx context.Context, arg db.RevokeInstanceHostKeysParams) (int64, error) {
+	ctx, span := startQuery(ctx, "RevokeInstanceHostKeys")
+	result, err := q.inner.RevokeInstanceHostKeys(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) RevokeStaleInstanceHostKeys(ctx context.Context, arg db.RevokeStaleInstanceHostKeysParams) (int64, error) {
+	ctx, span := startQuery(ctx, "RevokeStaleInstanceHostKeys")
+	result, err := q.inner.RevokeStaleInstanceHostKeys(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) TouchInstanceHostKey(ctx context.Context, id int64) error {
+	ctx, span := startQuery(ctx, "TouchInstanceHostKey")
+	err := q.inner.TouchInstanceHostKey(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) UpdateCertificate(ctx context.Context, arg db.UpdateCertificateParams) (db.Certificate, error) {
+	ctx, span := startQuery(ctx, "UpdateCertificate")
+	result, err := q.inner.UpdateCertificate(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) UpdateInstance(ctx context.Context, arg db.UpdateInstanceParams) (db.Instance, error) {
+	ctx, span := startQuery(ctx, "UpdateInstance")
+	result, err := q.inner.UpdateInstance(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) UpdateInstanceIP(ctx context.Context, arg db.UpdateInstanceIPParams) error {
+	ctx, span := startQuery(ctx, "UpdateInstanceIP")
+	err := q.inner.UpdateInstanceIP(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) UpdateProfile(ctx context.Context, id int64) (db.Profile, error) {
+	ctx, span := startQuery(ctx, "UpdateProfile")
+	result, err := q.inner.UpdateProfile(ctx, id)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) UpdateSSHKey(ctx context.Context, arg db.UpdateSSHKeyParams) (db.SshKey, error) {
+	ctx, span := startQuery(ctx, "UpdateSSHKey")
+	result, err := q.inner.UpdateSSHKey(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) UpdateSecret(ctx context.Context, arg db.UpdateSecretParams) (db.Secret, error) {
+	ctx, span := startQuery(ctx, "UpdateSecret")
+	result, err := q.inner.UpdateSecret(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) UpdateSigningKey(ctx context.Context, arg db.UpdateSigningKeyParams) error {
+	ctx, span := startQuery(ctx, "UpdateSigningKey")
+	err := q.inner.UpdateSigningKey(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return err
+}
+
+func (q *Querier) UpdateVendorData(ctx context.Context, arg db.UpdateVendorDataParams) (db.VendorDatum, error) {
+	ctx, span := startQuery(ctx, "UpdateVendorData")
+	result, err := q.inner.UpdateVendorData(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
+
+func (q *Querier) UpsertInstance(ctx context.Context, arg db.UpsertInstanceParams) (db.Instance, error) {
+	ctx, span := startQuery(ctx, "UpsertInstance")
+	result, err := q.inner.UpsertInstance(ctx, arg)
+	End(span, err, sql.ErrNoRows)
+	return result, err
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies the service in exported traces.
+const ServiceName = "metadata-service"
+
+// Tracer creates the service's own spans. It forwards to the provider installed by
+// Setup and records nothing while tracing is disabled.
+var Tracer = otel.Tracer("github.com/focadecombate/incus-metadata-service/metadata-service")
+
+// Setup installs a tracer provider exporting spans over OTLP/HTTP to the configured
+// endpoint. The returned function flushes pending spans and must be called on shutdown.
+// Tracing stays disabled when no endpoint is configured.
+func Setup(ctx context.Context, cfg *config.TracingConfig) (func(context.Context) error, error) {
+	if cfg == nil || cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// End records err on span, unless it is one of the expected errors such as
+// sql.ErrNoRows, and ends the span.
+func End(span trace.Span, err error, expected ...error) {
+	defer span.End()
+
+	if err == nil {
+		return
+	}
+
+	for _, target := range expected {
+		if errors.Is(err, target) {
+			return
+		}
+	}
+
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,134 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/api"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/config"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/incus"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db/mocks"
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/tracing"
+	"github.com/gin-gonic/gin"
+	incusapi "github.com/lxc/incus/shared/api"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// collectorStub is an in-process OTLP/HTTP collector that keeps the spans it receives
+type collectorStub struct {
+	mu    sync.Mutex
+	spans map[string]string // span name -> trace ID
+}
+
+func (s *collectorStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil || r.URL.Path != "/v1/traces" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var request collectortrace.ExportTraceServiceRequest
+	if err := proto.Unmarshal(body, &request); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, resourceSpans := range request.ResourceSpans {
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, span := range scopeSpans.Spans {
+				s.spans[span.Name] = string(span.TraceId)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+type fakeIncus struct{}
+
+func (fakeIncus) GetInstancesFullAllProjects(instanceType incusapi.InstanceType) ([]incusapi.InstanceFull, error) {
+	return []incusapi.InstanceFull{{Instance: incusapi.Instance{Name: "c1", Project: "default"}}}, nil
+}
+
+func TestSetup_ExportsRequestSpansOverOTLP(t *testing.T) {
+	collector := &collectorStub{spans: map[string]string{}}
+	stub := httptest.NewServer(collector)
+	defer stub.Close()
+
+	shutdown, err := tracing.Setup(context.Background(), &config.TracingConfig{Endpoint: stub.URL, SampleRatio: 1})
+	require.NoError(t, err)
+
+	var logOutput bytes.Buffer
+	logs.InitLogger(zerolog.InfoLevel)
+	logs.Logger = logs.Logger.Output(&logOutput)
+
+	mockDB := &mocks.MockQuerier{}
+	mockDB.On("GetInstance", mock.Anything, db.GetInstanceParams{Name: "c1", Project: "default"}).Return(db.Instance{ID: 1}, nil)
+	querier := tracing.NewQuerier(mockDB)
+
+	gin.SetMode(gin.TestMode)
+	router := api.NewGuestRouter(logs.NewAccessLog(&config.AccessLogConfig{}))
+	router.GET("/configs/meta-data", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if _, err := incus.ListInstances(ctx, fakeIncus{}, incusapi.InstanceTypeAny); err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := querier.GetInstance(ctx, db.GetInstanceParams{Name: "c1", Project: "default"}); err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+
+		logs.Logger.Info().Ctx(ctx).Msg("Rendered meta-data")
+		c.String(http.StatusOK, "ok")
+	})
+
+	// Trace context sent by guests is ignored
+	req := httptest.NewRequest("GET", "/configs/meta-data", nil)
+	req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.NoError(t, shutdown(context.Background()))
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	require.Contains(t, collector.spans, "GET /configs/meta-data")
+	require.Contains(t, collector.spans, "db.GetInstance")
+	require.Contains(t, collector.spans, "incus.GetInstancesFullAllProjects")
+
+	traceID := collector.spans["GET /configs/meta-data"]
+	assert.Equal(t, traceID, collector.spans["db.GetInstance"])
+	assert.Equal(t, traceID, collector.spans["incus.GetInstancesFullAllProjects"])
+	assert.NotEqual(t, "0af7651916cd43dd8448eb211c80319c", hex.EncodeToString([]byte(traceID)))
+
+	// Both the handler's log line and the access log line carry the trace ID
+	lines := bytes.Split(bytes.TrimSpace(logOutput.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	for _, raw := range lines {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(raw, &line))
+		assert.Equal(t, hex.EncodeToString([]byte(traceID)), line["trace_id"])
+		assert.NotEmpty(t, line["span_id"])
+	}
+}
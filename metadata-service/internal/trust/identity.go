@@ -0,0 +1,61 @@
+package trust
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Role is the level of access a trusted certificate grants on the admin API.
+type Role string
+
+const (
+	// RoleReader can only read resources.
+	RoleReader Role = "reader"
+	// RoleOperator can read and modify resources in the projects it has access to.
+	RoleOperator Role = "operator"
+	// RoleAdmin has full access, including managing the trust store itself.
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleReader:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole validates a role name, defaulting to RoleReader when empty.
+func ParseRole(name string) (Role, error) {
+	if name == "" {
+		return RoleReader, nil
+	}
+
+	role := Role(name)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("invalid role %q, must be one of admin, operator or reader", name)
+	}
+
+	return role, nil
+}
+
+// Identity is the caller of the admin API as identified by its client certificate.
+type Identity struct {
+	Fingerprint string   `json:"fingerprint"`
+	Name        string   `json:"name"`
+	Role        Role     `json:"role"`
+	Restricted  bool     `json:"restricted"`
+	Projects    []string `json:"projects"`
+}
+
+// HasRole reports whether the identity has at least the given role.
+func (i *Identity) HasRole(role Role) bool {
+	return roleRanks[i.Role] >= roleRanks[role]
+}
+
+// CanAccessProject reports whether the identity may act on resources of the given project.
+func (i *Identity) CanAccessProject(project string) bool {
+	if !i.Restricted {
+		return true
+	}
+
+	return slices.Contains(i.Projects, project)
+}
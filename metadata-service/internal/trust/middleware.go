@@ -0,0 +1,68 @@
+package trust
+
+import (
+	"net/http"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/logs"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+const identityKey = "trust.identity"
+
+// Authenticate resolves the client certificate of the request against the trust store.
+// Untrusted callers are let through without an identity so that endpoints such as
+// certificate enrollment with a join token remain reachable; use RequireRole to guard routes.
+func Authenticate(store *Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "A client certificate is required"})
+			return
+		}
+
+		identity, err := store.Lookup(c, c.Request.TLS.PeerCertificates[0])
+		if err != nil && err != ErrNotTrusted {
+			logs.FromContext(c).Error().Err(err).Msg("Failed to authenticate client certificate")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate client certificate"})
+			return
+		}
+
+		if identity != nil {
+			logs.UpdateContext(c, func(l zerolog.Context) zerolog.Context {
+				return l.Str("client", identity.Name)
+			})
+			c.Set(identityKey, identity)
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests whose identity does not have at least the given role.
+func RequireRole(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := IdentityFromContext(c)
+		if identity == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Client certificate is not trusted"})
+			return
+		}
+
+		if !identity.HasRole(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "required_role": role})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// IdentityFromContext returns the authenticated identity of the request, or nil if the caller is not trusted.
+func IdentityFromContext(c *gin.Context) *Identity {
+	value, ok := c.Get(identityKey)
+	if !ok {
+		return nil
+	}
+
+	identity, _ := value.(*Identity)
+	return identity
+}
@@ -0,0 +1,162 @@
+package trust
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/internal/storage/db"
+	"github.com/lxc/incus/shared/api"
+	localtls "github.com/lxc/incus/shared/tls"
+)
+
+var (
+	// ErrNotTrusted is returned when a certificate is not present in the trust store.
+	ErrNotTrusted = errors.New("certificate is not trusted")
+	// ErrAlreadyTrusted is returned when adding a certificate that is already in the trust store.
+	ErrAlreadyTrusted = errors.New("certificate is already trusted")
+	// ErrInvalidToken is returned when a join token does not exist or has expired.
+	ErrInvalidToken = errors.New("invalid or expired join token")
+)
+
+// Store manages the certificates trusted by the admin API and the join tokens used to add them.
+type Store struct {
+	Database db.Querier
+	// ServerFingerprint is the fingerprint of the admin listener certificate, embedded in join tokens.
+	ServerFingerprint string
+	// Addresses are the admin listener addresses embedded in join tokens.
+	Addresses []string
+	// TokenExpiry is how long an issued join token stays valid.
+	TokenExpiry time.Duration
+}
+
+// Lookup returns the identity mapped to the given client certificate.
+func (s *Store) Lookup(ctx context.Context, cert *x509.Certificate) (*Identity, error) {
+	row, err := s.Database.GetCertificate(ctx, localtls.CertFingerprint(cert))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotTrusted
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up certificate: %w", err)
+	}
+
+	return ToIdentity(row)
+}
+
+// Add stores a client certificate in the trust store with the given role and project restrictions.
+func (s *Store) Add(ctx context.Context, cert *x509.Certificate, name string, role Role, restricted bool, projects []string) (db.Certificate, error) {
+	fingerprint := localtls.CertFingerprint(cert)
+
+	_, err := s.Database.GetCertificate(ctx, fingerprint)
+	if err == nil {
+		return db.Certificate{}, ErrAlreadyTrusted
+	}
+
+	if err != sql.ErrNoRows {
+		return db.Certificate{}, fmt.Errorf("failed to check existing certificate: %w", err)
+	}
+
+	encodedProjects, err := json.Marshal(projects)
+	if err != nil {
+		return db.Certificate{}, fmt.Errorf("failed to encode projects: %w", err)
+	}
+
+	if name == "" {
+		name = cert.Subject.CommonName
+	}
+
+	return s.Database.CreateCertificate(ctx, db.CreateCertificateParams{
+		Fingerprint: fingerprint,
+		Name:        name,
+		Role:        string(role),
+		Restricted:  restricted,
+		Projects:    encodedProjects,
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+	})
+}
+
+// IssueToken creates a one-time join token that lets a client add its own certificate to the trust store.
+func (s *Store) IssueToken(ctx context.Context, name string, role Role, restricted bool, projects []string) (*api.CertificateAddToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate token secret: %w", err)
+	}
+
+	encodedProjects, err := json.Marshal(projects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode projects: %w", err)
+	}
+
+	token, err := s.Database.CreateCertificateToken(ctx, db.CreateCertificateTokenParams{
+		Name:       name,
+		Secret:     hex.EncodeToString(secret),
+		Role:       string(role),
+		Restricted: restricted,
+		Projects:   encodedProjects,
+		ExpiresAt:  time.Now().UTC().Add(s.TokenExpiry),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store join token: %w", err)
+	}
+
+	return &api.CertificateAddToken{
+		ClientName:  token.Name,
+		Fingerprint: s.ServerFingerprint,
+		Addresses:   s.Addresses,
+		Secret:      token.Secret,
+		ExpiresAt:   token.ExpiresAt,
+	}, nil
+}
+
+// Redeem consumes a join token and trusts the given certificate with the role and projects the token was issued for.
+func (s *Store) Redeem(ctx context.Context, secret string, cert *x509.Certificate) (db.Certificate, error) {
+	// Claiming deletes the token in the same statement, so concurrent requests can't both redeem it
+	token, err := s.Database.ClaimCertificateToken(ctx, secret)
+	if err == sql.ErrNoRows {
+		return db.Certificate{}, ErrInvalidToken
+	}
+
+	if err != nil {
+		return db.Certificate{}, fmt.Errorf("failed to consume join token: %w", err)
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return db.Certificate{}, ErrInvalidToken
+	}
+
+	var projects []string
+	if err := db.ToJSONB(token.Projects, &projects); err != nil {
+		return db.Certificate{}, fmt.Errorf("failed to parse token projects: %w", err)
+	}
+
+	return s.Add(ctx, cert, token.Name, Role(token.Role), token.Restricted, projects)
+}
+
+// ToIdentity converts a stored certificate into the identity it grants.
+func ToIdentity(row db.Certificate) (*Identity, error) {
+	identity := &Identity{
+		Fingerprint: row.Fingerprint,
+		Name:        row.Name,
+		Role:        Role(row.Role),
+		Restricted:  row.Restricted,
+		Projects:    []string{},
+	}
+
+	if err := db.ToJSONB(row.Projects, &identity.Projects); err != nil {
+		return nil, fmt.Errorf("failed to parse certificate projects: %w", err)
+	}
+
+	if identity.Projects == nil {
+		identity.Projects = []string{}
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,67 @@
+package trust
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+
+	localtls "github.com/lxc/incus/shared/tls"
+)
+
+// ServerTLSConfig loads the admin listener key pair, generating a self-signed one on first start,
+// and returns a TLS configuration that requires every client to present a certificate.
+// The certificate itself is checked against the trust store by Authenticate.
+func ServerTLSConfig(certFile string, keyFile string) (*tls.Config, string, error) {
+	if err := localtls.FindOrGenCert(certFile, keyFile, false, true); err != nil {
+		return nil, "", fmt.Errorf("failed to generate admin certificate: %w", err)
+	}
+
+	keyPair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load admin certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse admin certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{keyPair},
+		ClientAuth:   tls.RequireAnyClientCert,
+	}
+
+	return tlsConfig, localtls.CertFingerprint(leaf), nil
+}
+
+// AdvertisedAddresses returns the addresses clients can use to reach a listener bound to the given address.
+// Wildcard listeners are expanded to every global unicast address of the host.
+func AdvertisedAddresses(listen string) ([]string, error) {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", listen, err)
+	}
+
+	if host != "" && !net.ParseIP(host).IsUnspecified() {
+		return []string{listen}, nil
+	}
+
+	interfaceAddrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interface addresses: %w", err)
+	}
+
+	var addresses []string
+	for _, addr := range interfaceAddrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+
+		addresses = append(addresses, net.JoinHostPort(ipNet.IP.String(), port))
+	}
+
+	return addresses, nil
+}
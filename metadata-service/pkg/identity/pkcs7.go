@@ -0,0 +1,160 @@
+package identity
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// The subset of PKCS #7 (RFC 2315) needed for signed data with a single RSA signer over
+// SHA-256, without authenticated attributes, which `openssl smime -verify` accepts.
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	// Content is tagged [0] EXPLICIT, which encoding/asn1 doesn't apply to raw values, see explicit.
+	// Detached signatures leave it out.
+	Content asn1.RawValue `asn1:"optional"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+// SignPKCS7 signs content with key as DER encoded PKCS #7 signed data, embedding certificate
+// so verifiers can tell the signing key. Detached signatures leave the content out, it is then
+// passed alongside the signature.
+func SignPKCS7(content []byte, certificate *x509.Certificate, key *rsa.PrivateKey, detached bool) ([]byte, error) {
+	digest := sha256.Sum256(content)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sha256Algorithm := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+	data := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256Algorithm},
+		ContentInfo:      contentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certificate.Raw},
+		SignerInfos: []signerInfo{{
+			Version: 1,
+			IssuerAndSerialNumber: issuerAndSerialNumber{
+				Issuer:       asn1.RawValue{FullBytes: certificate.RawIssuer},
+				SerialNumber: certificate.SerialNumber,
+			},
+			DigestAlgorithm:           sha256Algorithm,
+			DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue},
+			EncryptedDigest:           signature,
+		}},
+	}
+
+	if !detached {
+		octets, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+
+		data.ContentInfo.Content = explicit(octets)
+	}
+
+	inner, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(contentInfo{ContentType: oidSignedData, Content: explicit(inner)})
+}
+
+// explicit tags an encoded value with [0] EXPLICIT.
+func explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// pkcs7Signature is the signature of a PKCS #7 signed data structure.
+type pkcs7Signature struct {
+	content     []byte
+	certificate *x509.Certificate
+	signature   []byte
+}
+
+// parsePKCS7 parses signed data produced by SignPKCS7. Detached content is nil.
+func parsePKCS7(der []byte) (*pkcs7Signature, error) {
+	var outer contentInfo
+	if rest, err := asn1.Unmarshal(der, &outer); err != nil || len(rest) > 0 {
+		return nil, errors.New("malformed PKCS #7 structure")
+	}
+
+	if !outer.ContentType.Equal(oidSignedData) || outer.Content.Class != asn1.ClassContextSpecific || outer.Content.Tag != 0 {
+		return nil, errors.New("PKCS #7 structure is not signed data")
+	}
+
+	var data signedData
+	if rest, err := asn1.Unmarshal(outer.Content.Bytes, &data); err != nil || len(rest) > 0 {
+		return nil, errors.New("malformed PKCS #7 signed data")
+	}
+
+	if len(data.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expected a single signer, found %d", len(data.SignerInfos))
+	}
+
+	signer := data.SignerInfos[0]
+	if !signer.DigestAlgorithm.Algorithm.Equal(oidSHA256) || !signer.DigestEncryptionAlgorithm.Algorithm.Equal(oidRSAEncryption) {
+		return nil, errors.New("unsupported PKCS #7 signature algorithm")
+	}
+
+	certificates, err := x509.ParseCertificates(data.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("malformed PKCS #7 certificates: %w", err)
+	}
+
+	parsed := &pkcs7Signature{signature: signer.EncryptedDigest}
+	for _, certificate := range certificates {
+		if bytes.Equal(certificate.RawIssuer, signer.IssuerAndSerialNumber.Issuer.FullBytes) && certificate.SerialNumber.Cmp(signer.IssuerAndSerialNumber.SerialNumber) == 0 {
+			parsed.certificate = certificate
+			break
+		}
+	}
+
+	if parsed.certificate == nil {
+		return nil, errors.New("PKCS #7 signed data doesn't include the signer certificate")
+	}
+
+	if len(data.ContentInfo.Content.FullBytes) > 0 {
+		if rest, err := asn1.Unmarshal(data.ContentInfo.Content.Bytes, &parsed.content); err != nil || len(rest) > 0 {
+			return nil, errors.New("malformed PKCS #7 content")
+		}
+	}
+
+	return parsed, nil
+}
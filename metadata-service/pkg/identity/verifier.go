@@ -0,0 +1,228 @@
+// Package identity verifies the instance identity documents signed by the metadata service.
+// Verification is offline: a Verifier only needs the JWKS the service publishes on its admin
+// API, at /internal/identity/jwks, which services can fetch once and refresh when they meet a
+// document signed by a key they don't know yet. Once an issuer is configured, the same JWKS is
+// served to relying parties of workload identity tokens at <issuer>/.well-known/jwks.json.
+//
+// A guest proves which instance it is by sending its document with one of the signatures
+// served under /latest/dynamic/instance-identity/. Documents don't expire, so a service hands
+// out a random nonce first, which the guest requests its document and signature with:
+//
+//	verifier, err := identity.NewVerifier(jwks)
+//	document, err := verifier.VerifySignature(documentBytes, signature)
+//	err = identity.CheckNonce(document, nonce)
+package identity
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	jose "gopkg.in/go-jose/go-jose.v2"
+	"gopkg.in/go-jose/go-jose.v2/jwt"
+)
+
+var (
+	// ErrInvalidSignature is returned for signatures that don't match the document.
+	ErrInvalidSignature = errors.New("invalid instance identity signature")
+	// ErrUnknownKey is returned for documents signed by a key missing from the JWKS, which
+	// may have been created since the JWKS was fetched.
+	ErrUnknownKey = errors.New("instance identity document signed by an unknown key")
+	// ErrInvalidToken is returned for workload identity tokens whose signature verifies but
+	// whose claims don't, such as expired tokens or tokens meant for another audience.
+	ErrInvalidToken = errors.New("invalid workload identity token")
+	// ErrNonceMismatch is returned for documents issued for another nonce, such as documents
+	// replayed from an earlier exchange.
+	ErrNonceMismatch = errors.New("instance identity document issued for another nonce")
+)
+
+// Verifier checks instance identity documents against the published signing keys.
+type Verifier struct {
+	keys jose.JSONWebKeySet
+}
+
+// NewVerifier returns a verifier trusting the RSA keys of a JWKS document.
+func NewVerifier(jwks []byte) (*Verifier, error) {
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(jwks, &keys); err != nil {
+		return nil, fmt.Errorf("malformed JWKS: %w", err)
+	}
+
+	verifier := &Verifier{}
+	for _, key := range keys.Keys {
+		if _, ok := key.Key.(*rsa.PublicKey); ok && key.Use != "enc" {
+			verifier.keys.Keys = append(verifier.keys.Keys, key)
+		}
+	}
+
+	if len(verifier.keys.Keys) == 0 {
+		return nil, errors.New("JWKS doesn't contain any RSA signing key")
+	}
+
+	return verifier, nil
+}
+
+// VerifySignature checks the base64 encoded RSA SHA-256 signature of a document, as served
+// at /latest/dynamic/instance-identity/signature.
+func (v *Verifier) VerifySignature(document []byte, signature string) (*types.InstanceIdentityDocument, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(signature), ""))
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	digest := sha256.Sum256(document)
+	for _, key := range v.keys.Keys {
+		if rsa.VerifyPKCS1v15(key.Key.(*rsa.PublicKey), crypto.SHA256, digest[:], raw) == nil {
+			return parseDocument(document)
+		}
+	}
+
+	return nil, ErrInvalidSignature
+}
+
+// VerifyPKCS7 checks a PKCS #7 signature, base64 encoded with or without PEM armor. The
+// detached signature served at /latest/dynamic/instance-identity/pkcs7 needs the document,
+// the rsa2048 variant embeds it and document can be nil.
+func (v *Verifier) VerifyPKCS7(signature string, document []byte) (*types.InstanceIdentityDocument, error) {
+	der, err := decodePEM(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := parsePKCS7(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	switch {
+	case signed.content == nil && document == nil:
+		return nil, fmt.Errorf("%w: detached signature verified without the document", ErrInvalidSignature)
+	case signed.content == nil:
+		signed.content = document
+	case document != nil && string(document) != string(signed.content):
+		return nil, fmt.Errorf("%w: document doesn't match the signed content", ErrInvalidSignature)
+	}
+
+	// The embedded certificate is self-signed, it's trusted because its key is published
+	key := v.lookup(signed.certificate.PublicKey)
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+
+	digest := sha256.Sum256(signed.content)
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signed.signature); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	return parseDocument(signed.content)
+}
+
+// VerifyJWS checks a compact JWS signed with RS256 whose payload is the document, as served at
+// /latest/dynamic/instance-identity/jws.
+func (v *Verifier) VerifyJWS(token string) (*types.InstanceIdentityDocument, error) {
+	jws, err := jose.ParseSigned(strings.TrimSpace(token))
+	if err != nil || len(jws.Signatures) != 1 {
+		return nil, ErrInvalidSignature
+	}
+
+	header := jws.Signatures[0].Header
+	if header.Algorithm != string(jose.RS256) {
+		return nil, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidSignature, header.Algorithm)
+	}
+
+	keys := v.keys.Key(header.KeyID)
+	if len(keys) == 0 {
+		return nil, ErrUnknownKey
+	}
+
+	document, err := jws.Verify(keys[0].Key)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	return parseDocument(document)
+}
+
+// VerifyToken checks a workload identity token, as served at /latest/identity/token, issued by
+// issuer for audience. Expiry is checked with a minute of leeway for clock skew.
+func (v *Verifier) VerifyToken(token, issuer, audience string) (*types.WorkloadIdentityClaims, error) {
+	parsed, err := jwt.ParseSigned(strings.TrimSpace(token))
+	if err != nil || len(parsed.Headers) != 1 {
+		return nil, ErrInvalidSignature
+	}
+
+	header := parsed.Headers[0]
+	if header.Algorithm != string(jose.RS256) {
+		return nil, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidSignature, header.Algorithm)
+	}
+
+	keys := v.keys.Key(header.KeyID)
+	if len(keys) == 0 {
+		return nil, ErrUnknownKey
+	}
+
+	var registered jwt.Claims
+	var claims types.WorkloadIdentityClaims
+	if err := parsed.Claims(keys[0].Key, &registered, &claims); err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	expected := jwt.Expected{Issuer: issuer, Audience: jwt.Audience{audience}, Time: time.Now()}
+	if err := registered.Validate(expected); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &claims, nil
+}
+
+// CheckNonce checks a verified document was issued for nonce, the value the verifying party
+// gave the guest for this exchange. Nonces must be random and used once, or a copy of an
+// earlier document passes the check.
+func CheckNonce(document *types.InstanceIdentityDocument, nonce string) error {
+	if nonce == "" || document.Nonce != nonce {
+		return ErrNonceMismatch
+	}
+
+	return nil
+}
+
+// lookup returns the published key equal to key, if any.
+func (v *Verifier) lookup(key crypto.PublicKey) *rsa.PublicKey {
+	for _, published := range v.keys.Keys {
+		if public := published.Key.(*rsa.PublicKey); public.Equal(key) {
+			return public
+		}
+	}
+
+	return nil
+}
+
+func decodePEM(value string) ([]byte, error) {
+	if block, _ := pem.Decode([]byte(value)); block != nil {
+		return block.Bytes, nil
+	}
+
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature is not base64 encoded", ErrInvalidSignature)
+	}
+
+	return der, nil
+}
+
+func parseDocument(document []byte) (*types.InstanceIdentityDocument, error) {
+	var parsed types.InstanceIdentityDocument
+	if err := json.Unmarshal(document, &parsed); err != nil {
+		return nil, fmt.Errorf("malformed instance identity document: %w", err)
+	}
+
+	return &parsed, nil
+}
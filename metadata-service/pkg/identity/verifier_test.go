@@ -0,0 +1,227 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/focadecombate/incus-metadata-service/metadata-service/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jose "gopkg.in/go-jose/go-jose.v2"
+)
+
+var testDocument = []byte(`{
+  "instanceId": "c1",
+  "project": "default",
+  "remote": "local",
+  "version": "2026-10-01"
+}`)
+
+type testKey struct {
+	id          string
+	privateKey  *rsa.PrivateKey
+	certificate *x509.Certificate
+}
+
+func newTestKey(t *testing.T, id string) testKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return testKey{id: id, privateKey: privateKey, certificate: certificate}
+}
+
+func newTestVerifier(t *testing.T, keys ...testKey) *Verifier {
+	set := jose.JSONWebKeySet{}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, jose.JSONWebKey{Key: key.privateKey.Public(), KeyID: key.id, Algorithm: "RS256", Use: "sig"})
+	}
+
+	jwks, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	verifier, err := NewVerifier(jwks)
+	require.NoError(t, err)
+
+	return verifier
+}
+
+func TestVerifySignature(t *testing.T) {
+	previous, current := newTestKey(t, "previous"), newTestKey(t, "current")
+	verifier := newTestVerifier(t, current, previous)
+
+	digest := sha256.Sum256(testDocument)
+	raw, err := rsa.SignPKCS1v15(rand.Reader, previous.privateKey, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	signature := base64.StdEncoding.EncodeToString(raw)
+
+	document, err := verifier.VerifySignature(testDocument, signature)
+	require.NoError(t, err)
+	assert.Equal(t, "c1", document.InstanceID)
+
+	tampered := []byte(string(testDocument[:len(testDocument)-1]) + " }")
+	_, err = verifier.VerifySignature(tampered, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = newTestVerifier(t, current).VerifySignature(testDocument, signature)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestVerifyPKCS7(t *testing.T) {
+	key := newTestKey(t, "current")
+	verifier := newTestVerifier(t, key)
+
+	detached, err := SignPKCS7(testDocument, key.certificate, key.privateKey, true)
+	require.NoError(t, err)
+
+	embedded, err := SignPKCS7(testDocument, key.certificate, key.privateKey, false)
+	require.NoError(t, err)
+
+	document, err := verifier.VerifyPKCS7(base64.StdEncoding.EncodeToString(detached), testDocument)
+	require.NoError(t, err)
+	assert.Equal(t, "default", document.Project)
+
+	document, err = verifier.VerifyPKCS7("-----BEGIN PKCS7-----\n"+base64.StdEncoding.EncodeToString(embedded)+"\n-----END PKCS7-----\n", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "c1", document.InstanceID)
+
+	_, err = verifier.VerifyPKCS7(base64.StdEncoding.EncodeToString(detached), nil)
+	assert.ErrorIs(t, err, ErrInvalidSignature, "a detached signature needs the document")
+
+	_, err = verifier.VerifyPKCS7(base64.StdEncoding.EncodeToString(embedded), []byte(`{"instanceId": "c2"}`))
+	assert.ErrorIs(t, err, ErrInvalidSignature, "the document must match the signed content")
+
+	_, err = verifier.VerifyPKCS7(base64.StdEncoding.EncodeToString(detached), []byte(`{"instanceId": "c2"}`))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// A certificate embedded by anyone else isn't trusted
+	other := newTestKey(t, "other")
+	forged, err := SignPKCS7(testDocument, other.certificate, other.privateKey, false)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyPKCS7(base64.StdEncoding.EncodeToString(forged), nil)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestVerifyJWS(t *testing.T) {
+	key := newTestKey(t, "current")
+	verifier := newTestVerifier(t, key)
+
+	sign := func(key testKey, alg jose.SignatureAlgorithm) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: jose.JSONWebKey{Key: key.privateKey, KeyID: key.id}}, nil)
+		require.NoError(t, err)
+
+		signed, err := signer.Sign(testDocument)
+		require.NoError(t, err)
+
+		token, err := signed.CompactSerialize()
+		require.NoError(t, err)
+
+		return token
+	}
+
+	document, err := verifier.VerifyJWS(sign(key, jose.RS256))
+	require.NoError(t, err)
+	assert.Equal(t, "local", document.Remote)
+
+	_, err = verifier.VerifyJWS(sign(newTestKey(t, "other"), jose.RS256))
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = verifier.VerifyJWS(sign(newTestKey(t, "current"), jose.RS256))
+	assert.ErrorIs(t, err, ErrInvalidSignature, "a key reusing a known key ID doesn't verify")
+
+	_, err = verifier.VerifyJWS(sign(key, jose.PS256))
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestVerifyToken(t *testing.T) {
+	key := newTestKey(t, "current")
+	verifier := newTestVerifier(t, key)
+	now := time.Now().Unix()
+
+	sign := func(key testKey, claims types.WorkloadIdentityClaims) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key.privateKey, KeyID: key.id}}, (&jose.SignerOptions{}).WithType("JWT"))
+		require.NoError(t, err)
+
+		payload, err := json.Marshal(claims)
+		require.NoError(t, err)
+
+		signed, err := signer.Sign(payload)
+		require.NoError(t, err)
+
+		token, err := signed.CompactSerialize()
+		require.NoError(t, err)
+
+		return token
+	}
+
+	valid := types.WorkloadIdentityClaims{
+		Issuer:    "https://metadata.example.com",
+		Subject:   "instance:local:default:c1",
+		Audience:  "vault",
+		IssuedAt:  now,
+		NotBefore: now,
+		Expiry:    now + 600,
+		Instance:  "c1",
+		Project:   "default",
+		Profiles:  []string{"default", "web"},
+		Remote:    "local",
+	}
+
+	claims, err := verifier.VerifyToken(sign(key, valid), "https://metadata.example.com", "vault")
+	require.NoError(t, err)
+	assert.Equal(t, valid, *claims)
+
+	_, err = verifier.VerifyToken(sign(key, valid), "https://metadata.example.com", "s3")
+	assert.ErrorIs(t, err, ErrInvalidToken, "a token is only accepted by its audience")
+
+	_, err = verifier.VerifyToken(sign(key, valid), "https://other.example.com", "vault")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	expired := valid
+	expired.Expiry = now - 120
+	_, err = verifier.VerifyToken(sign(key, expired), "https://metadata.example.com", "vault")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = verifier.VerifyToken(sign(newTestKey(t, "other"), valid), "https://metadata.example.com", "vault")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = verifier.VerifyToken(sign(newTestKey(t, "current"), valid), "https://metadata.example.com", "vault")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestCheckNonce(t *testing.T) {
+	document := &types.InstanceIdentityDocument{InstanceID: "c1", Nonce: "n0nce"}
+
+	assert.NoError(t, CheckNonce(document, "n0nce"))
+	assert.ErrorIs(t, CheckNonce(document, "other"), ErrNonceMismatch)
+	assert.ErrorIs(t, CheckNonce(&types.InstanceIdentityDocument{InstanceID: "c1"}, ""), ErrNonceMismatch, "a nonce is required")
+}
+
+func TestNewVerifier_RequiresSigningKeys(t *testing.T) {
+	_, err := NewVerifier([]byte(`{"keys": []}`))
+	assert.Error(t, err)
+
+	_, err = NewVerifier([]byte(`not json`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package types
+
+import "time"
+
+// Certificate is a client certificate trusted by the admin API.
+type Certificate struct {
+	Fingerprint string    `json:"fingerprint" yaml:"fingerprint"`
+	Name        string    `json:"name" yaml:"name"`
+	Role        string    `json:"role" yaml:"role"`
+	Restricted  bool      `json:"restricted" yaml:"restricted"`
+	Projects    []string  `json:"projects" yaml:"projects"`
+	Certificate string    `json:"certificate" yaml:"certificate"`
+	CreatedAt   time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// CertificatesPost is the request used to add a certificate to the trust store.
+// Either Certificate is set by an admin, or TrustToken is set by an untrusted
+// client enrolling the certificate it is connecting with.
+type CertificatesPost struct {
+	Name        string   `json:"name" yaml:"name"`
+	Certificate string   `json:"certificate,omitempty" yaml:"certificate,omitempty"`
+	Role        string   `json:"role,omitempty" yaml:"role,omitempty"`
+	Restricted  bool     `json:"restricted" yaml:"restricted"`
+	Projects    []string `json:"projects,omitempty" yaml:"projects,omitempty"`
+	TrustToken  string   `json:"trust_token,omitempty" yaml:"trust_token,omitempty"`
+}
+
+// CertificatePut is the request used to update the role and projects of a trusted certificate.
+type CertificatePut struct {
+	Name       string   `json:"name" yaml:"name"`
+	Role       string   `json:"role" yaml:"role"`
+	Restricted bool     `json:"restricted" yaml:"restricted"`
+	Projects   []string `json:"projects" yaml:"projects"`
+}
+
+// CertificateTokensPost is the request used to issue a one-time join token.
+type CertificateTokensPost struct {
+	Name       string   `json:"name" yaml:"name" binding:"required"`
+	Role       string   `json:"role,omitempty" yaml:"role,omitempty"`
+	Restricted bool     `json:"restricted" yaml:"restricted"`
+	Projects   []string `json:"projects,omitempty" yaml:"projects,omitempty"`
+}
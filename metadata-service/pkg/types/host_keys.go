@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+// HostKey is an SSH host key published by an instance. Revoked keys were replaced by the
+// instance, or invalidated when it was deleted or rebuilt.
+type HostKey struct {
+	Type          string     `json:"type" yaml:"type"`
+	PublicKey     string     `json:"public_key" yaml:"public_key"`
+	Fingerprint   string     `json:"fingerprint" yaml:"fingerprint"`
+	CreatedAt     time.Time  `json:"created_at" yaml:"created_at"`
+	LastSeenAt    time.Time  `json:"last_seen_at" yaml:"last_seen_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" yaml:"revoked_at,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty" yaml:"revoked_reason,omitempty"`
+}
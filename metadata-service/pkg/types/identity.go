@@ -0,0 +1,40 @@
+package types
+
+// InstanceIdentityDocumentVersion is the version of the instance identity document format.
+const InstanceIdentityDocumentVersion = "2026-10-01"
+
+// InstanceIdentityDocument describes the instance it is served to, signed by the service so
+// workloads can prove which instance they run on. Field names follow the EC2 document.
+type InstanceIdentityDocument struct {
+	InstanceID string `json:"instanceId" yaml:"instanceId"`
+	// InstanceUUID is the volatile.uuid of the instance, which changes when an instance is
+	// recreated under the same name.
+	InstanceUUID string `json:"instanceUuid,omitempty" yaml:"instanceUuid,omitempty"`
+	Project      string `json:"project" yaml:"project"`
+	Remote       string `json:"remote" yaml:"remote"`
+	PrivateIP    string `json:"privateIp,omitempty" yaml:"privateIp,omitempty"`
+	// Nonce is the value the guest requested the document with, chosen by the party verifying
+	// it so a document copied from an earlier exchange can't be replayed.
+	Nonce   string `json:"nonce,omitempty" yaml:"nonce,omitempty"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// WorkloadIdentityClaims are the claims of the workload identity tokens served to instances,
+// JWTs whose audience is chosen by the guest. Claim names are the registered JWT claims,
+// followed by the claims describing the instance.
+type WorkloadIdentityClaims struct {
+	Issuer string `json:"iss"`
+	// Subject is instance:<remote>:<project>:<name>, unique across the instances of a service.
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	NotBefore int64  `json:"nbf"`
+	Expiry    int64  `json:"exp"`
+	ID        string `json:"jti"`
+
+	Instance     string   `json:"instance"`
+	InstanceUUID string   `json:"instance_uuid,omitempty"`
+	Project      string   `json:"project"`
+	Profiles     []string `json:"profiles"`
+	Remote       string   `json:"remote"`
+}
@@ -0,0 +1,11 @@
+package types
+
+// Instance is an instance cached from Incus, along with its tags, the user.* keys of its
+// expanded configuration without their prefix.
+type Instance struct {
+	Name    string            `json:"name" yaml:"name"`
+	Project string            `json:"project" yaml:"project"`
+	Remote  string            `json:"remote" yaml:"remote"`
+	UUID    string            `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+	Tags    map[string]string `json:"tags" yaml:"tags"`
+}
@@ -0,0 +1,19 @@
+package types
+
+import "time"
+
+// InstanceLog is an entry of an instance's log, such as a cloud-init reporting event.
+type InstanceLog struct {
+	ID        int64     `json:"id" yaml:"id"`
+	Type      string    `json:"type" yaml:"type"`
+	Level     string    `json:"level" yaml:"level"`
+	Message   string    `json:"message" yaml:"message"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// InstanceLogs is a page of log entries, newest first. NextCursor fetches the next, older
+// page and is empty on the last page.
+type InstanceLogs struct {
+	Logs       []InstanceLog `json:"logs" yaml:"logs"`
+	NextCursor string        `json:"next_cursor,omitempty" yaml:"next_cursor,omitempty"`
+}
@@ -45,4 +45,6 @@ type Metadata struct {
 	SecurityGroups []string `json:"security-groups" yaml:"security-groups"`
 	Placement 		Placement `json:"placement" yaml:"placement"`
 	Network				Network `json:"network" yaml:"network"`
+	// Tags are the user.* keys of the instance, without their prefix, that guests may see.
+	Tags map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,15 @@
+package types
+
+import "time"
+
+// InstancePassword is the password generated for the users of an instance, encrypted to the
+// public key of the operators. EncryptedPassword is only returned by the first retrieval.
+type InstancePassword struct {
+	Instance          string     `json:"instance" yaml:"instance"`
+	Project           string     `json:"project" yaml:"project"`
+	KeyFingerprint    string     `json:"key_fingerprint" yaml:"key_fingerprint"`
+	EncryptedPassword []byte     `json:"encrypted_password,omitempty" yaml:"encrypted_password,omitempty"`
+	CreatedAt         time.Time  `json:"created_at" yaml:"created_at"`
+	RetrievedAt       *time.Time `json:"retrieved_at,omitempty" yaml:"retrieved_at,omitempty"`
+	RetrievedBy       string     `json:"retrieved_by,omitempty" yaml:"retrieved_by,omitempty"`
+}
@@ -0,0 +1,16 @@
+package types
+
+// ReportingEvent is an event posted by cloud-init's webhook reporting handler, e.g.
+// {"name": "modules-config/config-ssh", "event_type": "finish", "result": "FAIL", ...}.
+type ReportingEvent struct {
+	// Name is the stage and module the event is about, e.g. init-network/config-ssh.
+	Name        string `json:"name" yaml:"name" binding:"required"`
+	Description string `json:"description" yaml:"description"`
+	// EventType is start or finish.
+	EventType string `json:"event_type" yaml:"event_type" binding:"required,oneof=start finish"`
+	Origin    string `json:"origin" yaml:"origin"`
+	// Timestamp is the guest time of the event in seconds since the epoch.
+	Timestamp float64 `json:"timestamp" yaml:"timestamp"`
+	// Result is SUCCESS, WARN or FAIL, and only set on finish events.
+	Result string `json:"result,omitempty" yaml:"result,omitempty"`
+}
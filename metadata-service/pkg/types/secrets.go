@@ -0,0 +1,39 @@
+package types
+
+import "time"
+
+// Secret describes a secret delivered to the instances of a project, of a profile or to a
+// single instance, depending on its scope. Its value is never returned by the admin API.
+type Secret struct {
+	ID        int64     `json:"id" yaml:"id"`
+	Name      string    `json:"name" yaml:"name"`
+	Project   string    `json:"project" yaml:"project"`
+	Remote    string    `json:"remote" yaml:"remote"`
+	Scope     string    `json:"scope" yaml:"scope"`
+	Target    string    `json:"target,omitempty" yaml:"target,omitempty"`
+	OneTime   bool      `json:"one_time" yaml:"one_time"`
+	CreatedBy string    `json:"created_by" yaml:"created_by"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// SecretsPost is the request used to store a secret. Remote is the Incus remote of the project,
+// the primary one by default. Scope is project, the default, profile or instance, Target names
+// the profile or instance. One-time secrets are served once to each
+// instance, instance scoped ones are deleted once read.
+type SecretsPost struct {
+	Name    string `json:"name" yaml:"name" binding:"required"`
+	Value   string `json:"value" yaml:"value" binding:"required"`
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+	Remote  string `json:"remote,omitempty" yaml:"remote,omitempty"`
+	Scope   string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Target  string `json:"target,omitempty" yaml:"target,omitempty"`
+	OneTime bool   `json:"one_time,omitempty" yaml:"one_time,omitempty"`
+}
+
+// SecretPut is the request used to replace the value of a secret. Instances that already read
+// a one-time secret are served the new value once more.
+type SecretPut struct {
+	Value   string `json:"value" yaml:"value" binding:"required"`
+	OneTime bool   `json:"one_time,omitempty" yaml:"one_time,omitempty"`
+}
@@ -0,0 +1,64 @@
+package types
+
+import "time"
+
+// SSHKey is an SSH public key authorized on the instances of a project, on the instances
+// using a profile, or on a single instance, depending on its scope.
+type SSHKey struct {
+	ID          int64      `json:"id" yaml:"id"`
+	Owner       string     `json:"owner" yaml:"owner"`
+	Comment     string     `json:"comment" yaml:"comment"`
+	Type        string     `json:"type" yaml:"type"`
+	PublicKey   string     `json:"public_key" yaml:"public_key"`
+	Fingerprint string     `json:"fingerprint" yaml:"fingerprint"`
+	Project     string     `json:"project" yaml:"project"`
+	Remote      string     `json:"remote" yaml:"remote"`
+	Scope       string     `json:"scope" yaml:"scope"`
+	Target      string     `json:"target,omitempty" yaml:"target,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" yaml:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" yaml:"updated_at"`
+}
+
+// SSHKeysPost is the request used to register an SSH key. PublicKey is in authorized_keys
+// format, its comment is used when Comment is empty. Remote is the Incus remote of the project,
+// the primary one by default. Scope is project, the default, profile or instance, Target names
+// the profile or instance.
+type SSHKeysPost struct {
+	Owner     string     `json:"owner" yaml:"owner" binding:"required"`
+	PublicKey string     `json:"public_key" yaml:"public_key" binding:"required"`
+	Comment   string     `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Project   string     `json:"project,omitempty" yaml:"project,omitempty"`
+	Remote    string     `json:"remote,omitempty" yaml:"remote,omitempty"`
+	Scope     string     `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Target    string     `json:"target,omitempty" yaml:"target,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// SSHKeyPut is the request used to update a registered SSH key, e.g. to rotate it in place.
+// The project and remote of a key can't be changed.
+type SSHKeyPut struct {
+	Owner     string     `json:"owner" yaml:"owner" binding:"required"`
+	PublicKey string     `json:"public_key" yaml:"public_key" binding:"required"`
+	Comment   string     `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Scope     string     `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Target    string     `json:"target,omitempty" yaml:"target,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at,omitempty"`
+}
+
+// EphemeralSSHKeyPost is the request used to push a short-lived SSH key to an instance, in the
+// manner of EC2 Instance Connect. The key is authorized for OSUser only until it expires.
+type EphemeralSSHKeyPost struct {
+	OSUser    string `json:"os_user" yaml:"os_user" binding:"required"`
+	PublicKey string `json:"public_key" yaml:"public_key" binding:"required"`
+}
+
+// EphemeralSSHKey is an SSH key pushed to an instance.
+type EphemeralSSHKey struct {
+	Instance    string    `json:"instance" yaml:"instance"`
+	Project     string    `json:"project" yaml:"project"`
+	OSUser      string    `json:"os_user" yaml:"os_user"`
+	Fingerprint string    `json:"fingerprint" yaml:"fingerprint"`
+	PushedBy    string    `json:"pushed_by" yaml:"pushed_by"`
+	ExpiresAt   time.Time `json:"expires_at" yaml:"expires_at"`
+}
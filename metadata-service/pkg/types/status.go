@@ -0,0 +1,47 @@
+package types
+
+import "time"
+
+// Statuses guests report while they are being configured.
+const (
+	StatusBooting    = "booting"
+	StatusConfigDone = "config-done"
+	StatusFailed     = "failed"
+)
+
+// StatusReport is posted by guests to /configs/status, form-encoded or as JSON.
+type StatusReport struct {
+	Status string `json:"status" yaml:"status" form:"status" binding:"required,oneof=booting config-done failed"`
+	// Message optionally explains the status, e.g. the module that failed.
+	Message string `json:"message,omitempty" yaml:"message,omitempty" form:"message"`
+}
+
+// PhoneHome is posted by cloud-init's phone_home module once the guest finished booting.
+// Fields are only sent when listed in the module's post setting, which defaults to all.
+type PhoneHome struct {
+	InstanceID    string `form:"instance_id"`
+	Hostname      string `form:"hostname"`
+	FQDN          string `form:"fqdn"`
+	PubKeyDSA     string `form:"pub_key_dsa"`
+	PubKeyRSA     string `form:"pub_key_rsa"`
+	PubKeyECDSA   string `form:"pub_key_ecdsa"`
+	PubKeyEd25519 string `form:"pub_key_ed25519"`
+}
+
+// InstanceStatus is the last status reported by an instance.
+type InstanceStatus struct {
+	Name      string    `json:"name" yaml:"name"`
+	Project   string    `json:"project" yaml:"project"`
+	Status    string    `json:"status" yaml:"status"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// InstanceStatuses is the answer to a status wait. Done tells whether the expected number
+// of instances reported the status before the wait timed out.
+type InstanceStatuses struct {
+	Done      bool             `json:"done" yaml:"done"`
+	Count     int              `json:"count" yaml:"count"`
+	Instances []InstanceStatus `json:"instances" yaml:"instances"`
+	// Failed lists the instances that reported failed, so a rollout can stop early.
+	Failed []InstanceStatus `json:"failed" yaml:"failed"`
+}
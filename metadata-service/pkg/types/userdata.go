@@ -29,4 +29,19 @@ type UserData struct {
 	WriteFiles     []File   `json:"write_files" yaml:"write_files"`
 	RunCommands    []string `json:"runcmd" yaml:"runcmd"`
 	FinalMessage   string   `json:"final_message" yaml:"final_message"`
+	// Chpasswd sets the passwords generated for the users, on the first request only.
+	Chpasswd *Chpasswd `json:"chpasswd,omitempty" yaml:"chpasswd,omitempty"`
+}
+
+// Chpasswd is the configuration of cloud-init's set_passwords module.
+type Chpasswd struct {
+	Expire bool           `json:"expire" yaml:"expire"`
+	Users  []UserPassword `json:"users" yaml:"users"`
+}
+
+// UserPassword sets the password of a user, Type is text for a plain text password.
+type UserPassword struct {
+	Name     string `json:"name" yaml:"name"`
+	Password string `json:"password" yaml:"password"`
+	Type     string `json:"type" yaml:"type"`
 }